@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"gm58-hr-backend/internal/api/routes"
 	"gm58-hr-backend/internal/config"
 	"gm58-hr-backend/internal/database"
+	"gm58-hr-backend/internal/services/email"
+	"gm58-hr-backend/internal/services/payslip"
 	"gm58-hr-backend/pkg/logger"
 	"gm58-hr-backend/pkg/redis"
 	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -49,10 +57,43 @@ func main() {
 		AllowCredentials: true,
 	}))
 
+	// Payslip email delivery runs in the background for as long as the server does
+	payslipService := payslip.NewPayslipService(db)
+	mailer := email.NewSMTPMailer(email.SMTPConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	})
+	distributionService := payslip.NewDistributionService(db, payslipService, mailer, logger, payslip.DistributionOptions{
+		RatePerMinute: cfg.EmailRatePerMinute,
+		MaxAttempts:   cfg.EmailMaxAttempts,
+	})
+
 	// Setup routes
-	routes.SetupRoutes(r, db, redisClient, logger)
+	routes.SetupRoutes(r, db, redisClient, logger, cfg, payslipService, distributionService)
 
 	// Start server
-	logger.Info("Server starting on port " + cfg.Port)
-	log.Fatal(r.Run(":" + cfg.Port))
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: ":" + cfg.Port, Handler: r}
+	distributionService.Start()
+	go func() {
+		logger.Info("Server starting on port " + cfg.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Server failed:", err)
+		}
+	}()
+
+	// Shut down on SIGINT or SIGTERM, letting in-flight requests finish
+	<-ctx.Done()
+	logger.Info("Server shutting down")
+	distributionService.Stop()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Fatal("Server shutdown failed:", err)
+	}
 }
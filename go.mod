@@ -5,9 +5,12 @@ go 1.21
 require (
 	github.com/gin-contrib/cors v1.4.0
 	github.com/gin-gonic/gin v1.9.1
+	github.com/go-pdf/fpdf v0.9.0
 	github.com/go-redis/redis/v8 v8.11.5
+	github.com/shopspring/decimal v1.4.0
 	github.com/stretchr/testify v1.8.4
 	golang.org/x/crypto v0.31.0
+	golang.org/x/term v0.27.0
 	gorm.io/driver/postgres v1.6.0
 	gorm.io/driver/sqlite v1.5.4
 	gorm.io/gorm v1.25.10
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"gm58-hr-backend/internal/api/middleware"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/services/accounting"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AccountingHandler struct {
+	db             *gorm.DB
+	journalService *accounting.JournalService
+}
+
+func NewAccountingHandler(db *gorm.DB, journalService *accounting.JournalService) *AccountingHandler {
+	return &AccountingHandler{
+		db:             db,
+		journalService: journalService,
+	}
+}
+
+// GetGLMappings returns the company's chart-of-accounts mapping
+func (ah *AccountingHandler) GetGLMappings(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	mappings, err := ah.journalService.GetMappings(companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch GL mappings"})
+		return
+	}
+
+	components := make([]gin.H, len(accounting.Components))
+	for i, component := range accounting.Components {
+		components[i] = gin.H{"component": component.Name, "component_type": component.Type}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mappings":   mappings,
+		"components": components,
+	})
+}
+
+// UpdateGLMappings creates or replaces mappings for the submitted components
+func (ah *AccountingHandler) UpdateGLMappings(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	var req struct {
+		Mappings []models.GLAccountMapping `json:"mappings" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ah.journalService.SaveMappings(companyID, req.Mappings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mappings, err := ah.journalService.GetMappings(companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch GL mappings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, mappings)
+}
+
+// GetPayrollJournal returns the GL journal for an approved period as JSON or as an import file
+func (ah *AccountingHandler) GetPayrollJournal(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	// Verify period belongs to company
+	var period models.PayrollPeriod
+	if err := ah.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+		return
+	}
+
+	journal, err := ah.journalService.BuildPayrollJournal(uint(periodID), companyID)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format == "json" {
+		c.JSON(http.StatusOK, journal)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := accounting.ExportJournal(&buf, journal, format); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("payroll-journal-%04d-%02d-%s.csv", period.Year, period.Month, format)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, "text/csv", buf.Bytes())
+}
@@ -279,7 +279,7 @@ func (ah *AuthHandler) RegisterCompany(c *gin.Context) {
 
 	// Create default leave types
 	leaveTypes := []models.LeaveType{
-		{CompanyID: company.ID, Name: "Annual Leave", DaysPerYear: 21, IsPaid: true, IsActive: true},
+		{CompanyID: company.ID, Name: "Annual Leave", DaysPerYear: 21, IsPaid: true, PaidOnExit: true, IsActive: true},
 		{CompanyID: company.ID, Name: "Sick Leave", DaysPerYear: 10, IsPaid: true, IsActive: true},
 		{CompanyID: company.ID, Name: "Personal Leave", DaysPerYear: 3, IsPaid: false, IsActive: true},
 	}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"fmt"
+	"gm58-hr-backend/internal/api/middleware"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/services/tax"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CalculatorHandler struct {
+	db            *gorm.DB
+	taxCalculator *tax.TaxCalculator
+}
+
+func NewCalculatorHandler(db *gorm.DB, taxCalculator *tax.TaxCalculator) *CalculatorHandler {
+	return &CalculatorHandler{
+		db:            db,
+		taxCalculator: taxCalculator,
+	}
+}
+
+type salaryCalculationRequest struct {
+	Amount      float64 `json:"amount" binding:"gte=0"`
+	Currency    string  `json:"currency"`     // Defaults to the company base currency
+	PensionRate float64 `json:"pension_rate"` // Percentage of gross salary
+}
+
+// GrossToNet breaks a monthly gross salary down into deductions and net pay
+func (ch *CalculatorHandler) GrossToNet(c *gin.Context) {
+	var req salaryCalculationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	options, err := ch.salaryOptions(c, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	breakdown, err := ch.taxCalculator.CalculateNetSalary(req.Amount, options)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, breakdown)
+}
+
+// NetToGross finds the monthly gross salary that pays the requested net amount
+func (ch *CalculatorHandler) NetToGross(c *gin.Context) {
+	var req salaryCalculationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	options, err := ch.salaryOptions(c, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	breakdown, err := ch.taxCalculator.CalculateGrossSalary(req.Amount, options)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, breakdown)
+}
+
+// salaryOptions applies the company's tax settings so results match payroll processing
+func (ch *CalculatorHandler) salaryOptions(c *gin.Context, req salaryCalculationRequest) (tax.SalaryOptions, error) {
+	companyID := middleware.GetCompanyID(c)
+
+	options := tax.SalaryOptions{
+		Currency:       strings.ToUpper(req.Currency),
+		PensionRate:    req.PensionRate,
+		EnablePAYE:     true,
+		EnableAidsLevy: true,
+		EnableNSSA:     true,
+	}
+
+	var settings models.CompanySettings
+	if err := ch.db.Where("company_id = ?", companyID).First(&settings).Error; err == nil {
+		options.EnablePAYE = settings.EnablePAYE
+		options.EnableAidsLevy = settings.EnableAidsLevy
+		options.EnableNSSA = settings.EnableNSSA
+	}
+
+	if options.Currency == "" {
+		var company models.Company
+		if err := ch.db.Preload("BaseCurrency").First(&company, companyID).Error; err != nil {
+			return options, err
+		}
+		options.Currency = company.BaseCurrency.Code
+	}
+
+	var currency models.Currency
+	if err := ch.db.Where("code = ? AND is_active = ?", options.Currency, true).First(&currency).Error; err != nil {
+		return options, fmt.Errorf("unsupported currency %q", options.Currency)
+	}
+
+	return options, nil
+}
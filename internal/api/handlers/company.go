@@ -3,6 +3,7 @@ package handlers
 import (
 	"gm58-hr-backend/internal/api/middleware"
 	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
 	"net/http"
 	"strconv"
 
@@ -109,6 +110,21 @@ func (ch *CompanyHandler) UpdateCompanySettings(c *gin.Context) {
 		return
 	}
 
+	if !money.ValidMode(updateData.RoundingMode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rounding_mode must be half_up or half_even"})
+		return
+	}
+	if !money.ValidLevel(updateData.RoundingLevel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rounding_level must be line or total"})
+		return
+	}
+	switch updateData.SalaryBandPolicy {
+	case "", models.SalaryBandPolicyWarn, models.SalaryBandPolicyEnforce:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "salary_band_policy must be warn or enforce"})
+		return
+	}
+
 	updateData.ID = settings.ID
 	updateData.CompanyID = companyID
 
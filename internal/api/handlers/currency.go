@@ -7,6 +7,7 @@ import (
 	"gm58-hr-backend/internal/services/currency"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -79,13 +80,13 @@ func (ch *CurrencyHandler) ConvertAmount(c *gin.Context) {
 		return
 	}
 
-	amount, err := strconv.ParseFloat(amountStr, 64)
+	amount, err := decimal.NewFromString(amountStr)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
 		return
 	}
 
-	convertedAmount, err := ch.currencyService.ConvertAmount(amount, fromCurrency, toCurrency)
+	convertedAmount, err := ch.currencyService.ConvertMoney(amount, fromCurrency, toCurrency)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert amount"})
 		return
@@ -1,25 +1,32 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"gm58-hr-backend/internal/api/middleware"
 	"gm58-hr-backend/internal/models"
 	"gm58-hr-backend/internal/services/currency"
+	"gm58-hr-backend/internal/services/payroll"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
 type EmployeeHandler struct {
 	db              *gorm.DB
 	currencyService *currency.CurrencyService
+	processor       *payroll.PayrollProcessor
 }
 
-func NewEmployeeHandler(db *gorm.DB, currencyService *currency.CurrencyService) *EmployeeHandler {
+func NewEmployeeHandler(db *gorm.DB, currencyService *currency.CurrencyService, processor *payroll.PayrollProcessor) *EmployeeHandler {
 	return &EmployeeHandler{
 		db:              db,
 		currencyService: currencyService,
+		processor:       processor,
 	}
 }
 
@@ -62,46 +69,62 @@ func (eh *EmployeeHandler) GetEmployees(c *gin.Context) {
 	})
 }
 
+var payTypes = map[string]bool{
+	models.PayTypeSalaried:  true,
+	models.PayTypeHourly:    true,
+	models.PayTypeDaily:     true,
+	models.PayTypePieceRate: true,
+}
+
+// validWithholdingRate accepts an unset rate or a percentage
+func validWithholdingRate(rate *decimal.Decimal) bool {
+	return rate == nil || (!rate.IsNegative() && rate.LessThanOrEqual(decimal.NewFromInt(100)))
+}
+
 func (eh *EmployeeHandler) CreateEmployee(c *gin.Context) {
 	// Use a temporary struct to handle string dates and manager_id
 	var tempEmployee struct {
-		UserID                *uint   `json:"user_id"`
-		EmployeeNumber        string  `json:"employee_number"`
-		FirstName             string  `json:"first_name"`
-		LastName              string  `json:"last_name"`
-		MiddleName            string  `json:"middle_name"`
-		NationalID            string  `json:"national_id"`
-		TaxNumber             string  `json:"tax_number"`
-		PassportNumber        string  `json:"passport_number"`
-		Email                 string  `json:"email"`
-		Phone                 string  `json:"phone"`
-		AlternativePhone      string  `json:"alternative_phone"`
-		Address               string  `json:"address"`
-		City                  string  `json:"city"`
-		Country               string  `json:"country"`
-		PositionID            uint    `json:"position_id"`
-		DepartmentID          uint    `json:"department_id"`
-		ManagerID             string  `json:"manager_id"` // Handle as string
-		BasicSalary           float64 `json:"basic_salary"`
-		CurrencyID            uint    `json:"currency_id"`
-		PaymentMethod         string  `json:"payment_method"`
-		PaymentSchedule       string  `json:"payment_schedule"`
-		BankName              string  `json:"bank_name"`
-		BankAccount           string  `json:"bank_account"`
-		BankBranch            string  `json:"bank_branch"`
-		BankCode              string  `json:"bank_code"`
-		SwiftCode             string  `json:"swift_code"`
-		HireDate              string  `json:"hire_date"`          // Handle as string
-		ProbationEndDate      string  `json:"probation_end_date"` // Handle as string
-		ContractEndDate       string  `json:"contract_end_date"`  // Handle as string
-		TerminationDate       string  `json:"termination_date"`   // Handle as string
-		EmploymentType        string  `json:"employment_type"`
-		EmploymentStatus      string  `json:"employment_status"`
-		IsActive              bool    `json:"is_active"`
-		EmergencyContactName  string  `json:"emergency_contact_name"`
-		EmergencyContactPhone string  `json:"emergency_contact_phone"`
-		MedicalAidNumber      string  `json:"medical_aid_number"`
-		MedicalAidProvider    string  `json:"medical_aid_provider"`
+		UserID                *uint            `json:"user_id"`
+		EmployeeNumber        string           `json:"employee_number"`
+		FirstName             string           `json:"first_name"`
+		LastName              string           `json:"last_name"`
+		MiddleName            string           `json:"middle_name"`
+		NationalID            string           `json:"national_id"`
+		TaxNumber             string           `json:"tax_number"`
+		PassportNumber        string           `json:"passport_number"`
+		Email                 string           `json:"email"`
+		Phone                 string           `json:"phone"`
+		AlternativePhone      string           `json:"alternative_phone"`
+		Address               string           `json:"address"`
+		City                  string           `json:"city"`
+		Country               string           `json:"country"`
+		PositionID            uint             `json:"position_id"`
+		DepartmentID          uint             `json:"department_id"`
+		ManagerID             string           `json:"manager_id"` // Handle as string
+		PayType               string           `json:"pay_type"`
+		BasicSalary           decimal.Decimal  `json:"basic_salary"`
+		PayRate               decimal.Decimal  `json:"pay_rate"`
+		WithholdingTaxRate    *decimal.Decimal `json:"withholding_tax_rate"`
+		CurrencyID            uint             `json:"currency_id"`
+		PaymentMethod         string           `json:"payment_method"`
+		PaymentSchedule       string           `json:"payment_schedule"`
+		BankName              string           `json:"bank_name"`
+		BankAccount           string           `json:"bank_account"`
+		BankBranch            string           `json:"bank_branch"`
+		BankCode              string           `json:"bank_code"`
+		SwiftCode             string           `json:"swift_code"`
+		HireDate              string           `json:"hire_date"`          // Handle as string
+		ProbationEndDate      string           `json:"probation_end_date"` // Handle as string
+		ContractEndDate       string           `json:"contract_end_date"`  // Handle as string
+		TerminationDate       string           `json:"termination_date"`   // Handle as string
+		EmploymentType        string           `json:"employment_type"`
+		EmploymentStatus      string           `json:"employment_status"`
+		IsActive              bool             `json:"is_active"`
+		EmergencyContactName  string           `json:"emergency_contact_name"`
+		EmergencyContactPhone string           `json:"emergency_contact_phone"`
+		MedicalAidNumber      string           `json:"medical_aid_number"`
+		MedicalAidProvider    string           `json:"medical_aid_provider"`
+		SalaryBandOverride    string           `json:"salary_band_override"` // Reason for a salary outside the position's band
 	}
 
 	if err := c.ShouldBindJSON(&tempEmployee); err != nil {
@@ -127,7 +150,10 @@ func (eh *EmployeeHandler) CreateEmployee(c *gin.Context) {
 		Country:               tempEmployee.Country,
 		PositionID:            tempEmployee.PositionID,
 		DepartmentID:          tempEmployee.DepartmentID,
+		PayType:               tempEmployee.PayType,
 		BasicSalary:           tempEmployee.BasicSalary,
+		PayRate:               tempEmployee.PayRate,
+		WithholdingTaxRate:    tempEmployee.WithholdingTaxRate,
 		CurrencyID:            tempEmployee.CurrencyID,
 		PaymentMethod:         tempEmployee.PaymentMethod,
 		PaymentSchedule:       tempEmployee.PaymentSchedule,
@@ -181,6 +207,19 @@ func (eh *EmployeeHandler) CreateEmployee(c *gin.Context) {
 	// 	}
 	// }
 
+	if employee.PayType == "" {
+		employee.PayType = models.PayTypeSalaried
+	}
+	if !payTypes[employee.PayType] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pay type, expected salaried, hourly, daily or piece_rate"})
+		return
+	}
+
+	if !validWithholdingRate(employee.WithholdingTaxRate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Withholding tax rate must be between 0 and 100"})
+		return
+	}
+
 	// Validate currency
 	var currency models.Currency
 	if err := eh.db.First(&currency, employee.CurrencyID).Error; err != nil {
@@ -188,6 +227,10 @@ func (eh *EmployeeHandler) CreateEmployee(c *gin.Context) {
 		return
 	}
 
+	if employee.IsSalaried() && !eh.checkSalaryBand(c, &employee, employee.BasicSalary, employee.CurrencyID, tempEmployee.SalaryBandOverride) {
+		return
+	}
+
 	// Generate employee number if not provided
 	if employee.EmployeeNumber == "" {
 		employee.EmployeeNumber = eh.generateEmployeeNumber()
@@ -214,7 +257,11 @@ func (eh *EmployeeHandler) GetEmployee(c *gin.Context) {
 
 	var employee models.Employee
 	if err := eh.db.Preload("Currency").Preload("Position").Preload("Department").
-		Preload("Manager").First(&employee, uint(id)).Error; err != nil {
+		Preload("Manager").Preload("SalaryComponents.Currency").
+		Preload("CompensationChanges", func(db *gorm.DB) *gorm.DB {
+			return db.Order("effective_date DESC, id DESC")
+		}).Preload("CompensationChanges.Currency").
+		First(&employee, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
 			return
@@ -245,49 +292,59 @@ func (eh *EmployeeHandler) UpdateEmployee(c *gin.Context) {
 
 	// Use the same temporary struct for updates
 	var tempEmployee struct {
-		UserID                *uint   `json:"user_id"`
-		EmployeeNumber        string  `json:"employee_number"`
-		FirstName             string  `json:"first_name"`
-		LastName              string  `json:"last_name"`
-		MiddleName            string  `json:"middle_name"`
-		NationalID            string  `json:"national_id"`
-		TaxNumber             string  `json:"tax_number"`
-		PassportNumber        string  `json:"passport_number"`
-		Email                 string  `json:"email"`
-		Phone                 string  `json:"phone"`
-		AlternativePhone      string  `json:"alternative_phone"`
-		Address               string  `json:"address"`
-		City                  string  `json:"city"`
-		Country               string  `json:"country"`
-		PositionID            uint    `json:"position_id"`
-		DepartmentID          uint    `json:"department_id"`
-		ManagerID             string  `json:"manager_id"`
-		BasicSalary           float64 `json:"basic_salary"`
-		CurrencyID            uint    `json:"currency_id"`
-		PaymentMethod         string  `json:"payment_method"`
-		PaymentSchedule       string  `json:"payment_schedule"`
-		BankName              string  `json:"bank_name"`
-		BankAccount           string  `json:"bank_account"`
-		BankBranch            string  `json:"bank_branch"`
-		BankCode              string  `json:"bank_code"`
-		SwiftCode             string  `json:"swift_code"`
-		HireDate              string  `json:"hire_date"`
-		ProbationEndDate      string  `json:"probation_end_date"`
-		ContractEndDate       string  `json:"contract_end_date"`
-		TerminationDate       string  `json:"termination_date"`
-		EmploymentType        string  `json:"employment_type"`
-		EmploymentStatus      string  `json:"employment_status"`
-		IsActive              bool    `json:"is_active"`
-		EmergencyContactName  string  `json:"emergency_contact_name"`
-		EmergencyContactPhone string  `json:"emergency_contact_phone"`
-		MedicalAidNumber      string  `json:"medical_aid_number"`
-		MedicalAidProvider    string  `json:"medical_aid_provider"`
+		UserID                *uint            `json:"user_id"`
+		EmployeeNumber        string           `json:"employee_number"`
+		FirstName             string           `json:"first_name"`
+		LastName              string           `json:"last_name"`
+		MiddleName            string           `json:"middle_name"`
+		NationalID            string           `json:"national_id"`
+		TaxNumber             string           `json:"tax_number"`
+		PassportNumber        string           `json:"passport_number"`
+		Email                 string           `json:"email"`
+		Phone                 string           `json:"phone"`
+		AlternativePhone      string           `json:"alternative_phone"`
+		Address               string           `json:"address"`
+		City                  string           `json:"city"`
+		Country               string           `json:"country"`
+		PositionID            uint             `json:"position_id"`
+		DepartmentID          uint             `json:"department_id"`
+		ManagerID             string           `json:"manager_id"`
+		PayType               string           `json:"pay_type"`
+		BasicSalary           decimal.Decimal  `json:"basic_salary"`
+		PayRate               decimal.Decimal  `json:"pay_rate"`
+		WithholdingTaxRate    *decimal.Decimal `json:"withholding_tax_rate"`
+		CurrencyID            uint             `json:"currency_id"`
+		PaymentMethod         string           `json:"payment_method"`
+		PaymentSchedule       string           `json:"payment_schedule"`
+		BankName              string           `json:"bank_name"`
+		BankAccount           string           `json:"bank_account"`
+		BankBranch            string           `json:"bank_branch"`
+		BankCode              string           `json:"bank_code"`
+		SwiftCode             string           `json:"swift_code"`
+		HireDate              string           `json:"hire_date"`
+		ProbationEndDate      string           `json:"probation_end_date"`
+		ContractEndDate       string           `json:"contract_end_date"`
+		TerminationDate       string           `json:"termination_date"`
+		EmploymentType        string           `json:"employment_type"`
+		EmploymentStatus      string           `json:"employment_status"`
+		IsActive              bool             `json:"is_active"`
+		EmergencyContactName  string           `json:"emergency_contact_name"`
+		EmergencyContactPhone string           `json:"emergency_contact_phone"`
+		MedicalAidNumber      string           `json:"medical_aid_number"`
+		MedicalAidProvider    string           `json:"medical_aid_provider"`
+
+		// Recorded with a salary or currency change; the date defaults to today
+		SalaryEffectiveDate string `json:"salary_effective_date"`
+		SalaryChangeReason  string `json:"salary_change_reason"`
+		SalaryChangeNotes   string `json:"salary_change_notes"`
+		SalaryBandOverride  string `json:"salary_band_override"` // Reason for a salary outside the position's band
 	}
 
 	if err := c.ShouldBindJSON(&tempEmployee); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	wasSalaried := employee.IsSalaried()
 
 	// Update fields selectively (only if provided and not empty)
 	if tempEmployee.FirstName != "" {
@@ -313,8 +370,26 @@ func (eh *EmployeeHandler) UpdateEmployee(c *gin.Context) {
 	if tempEmployee.Country != "" {
 		employee.Country = tempEmployee.Country
 	}
-	if tempEmployee.BasicSalary > 0 {
-		employee.BasicSalary = tempEmployee.BasicSalary
+	if tempEmployee.PayType != "" {
+		if !payTypes[tempEmployee.PayType] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pay type, expected salaried, hourly, daily or piece_rate"})
+			return
+		}
+		employee.PayType = tempEmployee.PayType
+	}
+	newSalary := employee.BasicSalary
+	if tempEmployee.BasicSalary.IsPositive() {
+		newSalary = tempEmployee.BasicSalary
+	}
+	if tempEmployee.PayRate.IsPositive() {
+		employee.PayRate = tempEmployee.PayRate
+	}
+	if tempEmployee.WithholdingTaxRate != nil {
+		if !validWithholdingRate(tempEmployee.WithholdingTaxRate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Withholding tax rate must be between 0 and 100"})
+			return
+		}
+		employee.WithholdingTaxRate = tempEmployee.WithholdingTaxRate
 	}
 	if tempEmployee.EmploymentType != "" {
 		employee.EmploymentType = tempEmployee.EmploymentType
@@ -359,14 +434,77 @@ func (eh *EmployeeHandler) UpdateEmployee(c *gin.Context) {
 	// }
 
 	// Validate currency if changed
+	newCurrencyID := employee.CurrencyID
 	if tempEmployee.CurrencyID != 0 && tempEmployee.CurrencyID != employee.CurrencyID {
 		var currency models.Currency
 		if err := eh.db.First(&currency, tempEmployee.CurrencyID).Error; err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency"})
 			return
 		}
-		employee.CurrencyID = tempEmployee.CurrencyID
+		newCurrencyID = tempEmployee.CurrencyID
+	}
+
+	positionChanged := tempEmployee.PositionID != 0 && tempEmployee.PositionID != employee.PositionID
+	if positionChanged {
+		var position models.Position
+		if err := eh.db.Where("id = ? AND company_id = ?", tempEmployee.PositionID, employee.CompanyID).First(&position).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid position"})
+			return
+		}
+		employee.PositionID = position.ID
+	}
+
+	salaryChanged := wasSalaried && (!newSalary.Equal(employee.BasicSalary) || newCurrencyID != employee.CurrencyID)
+	effectiveDate := time.Now()
+	if salaryChanged && tempEmployee.SalaryEffectiveDate != "" {
+		effectiveDate, err = time.Parse("2006-01-02", tempEmployee.SalaryEffectiveDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid salary effective date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+
+	// A new position is checked against the salary carried into it, unless the salary
+	// changes with it today
+	if positionChanged && employee.IsSalaried() && !(salaryChanged && !effectiveDate.After(time.Now())) {
+		if !eh.checkSalaryBand(c, &employee, employee.BasicSalary, employee.CurrencyID, tempEmployee.SalaryBandOverride) {
+			return
+		}
+	}
+
+	// A salaried employee's salary and currency changes are kept in their compensation
+	// history, which holds them to the position's band; a change dated in the future leaves
+	// the current salary until it falls due
+	if salaryChanged {
+		reason := tempEmployee.SalaryChangeReason
+		if reason == "" {
+			reason = models.CompensationReasonOther
+		}
+
+		userID := c.GetUint("user_id")
+		change, err := eh.processor.RecordCompensationChange(middleware.GetCompanyID(c), employee.ID, models.CompensationChange{
+			NewSalary:          newSalary,
+			CurrencyID:         newCurrencyID,
+			PositionID:         employee.PositionID,
+			EffectiveDate:      effectiveDate,
+			Reason:             reason,
+			Notes:              tempEmployee.SalaryChangeNotes,
+			SalaryBandOverride: tempEmployee.SalaryBandOverride,
+			ApprovedBy:         &userID,
+		}, userID)
+		if err != nil {
+			salaryChangeError(c, err)
+			return
+		}
+		if change.AppliedAt == nil {
+			newSalary, newCurrencyID = employee.BasicSalary, employee.CurrencyID
+		} else {
+			employee.SalaryBandOverride = change.SalaryBandOverride
+			employee.SalaryBandWarning = change.SalaryBandWarning
+		}
 	}
+	employee.BasicSalary = newSalary
+	employee.CurrencyID = newCurrencyID
 
 	// Save updates
 	if err := eh.db.Save(&employee).Error; err != nil {
@@ -433,3 +571,203 @@ func (eh *EmployeeHandler) GetEmployeePayslips(c *gin.Context) {
 
 	c.JSON(http.StatusOK, payslips)
 }
+
+func (eh *EmployeeHandler) GetSalaryComponents(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
+		return
+	}
+
+	var components []models.SalaryComponent
+	if err := eh.db.Preload("Currency").
+		Where("employee_id = ? AND company_id = ?", uint(id), companyID).
+		Find(&components).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch salary components"})
+		return
+	}
+
+	c.JSON(http.StatusOK, components)
+}
+
+// UpdateSalaryComponents replaces the employee's currency split. Send an empty list
+// to pay the full basic salary in the employee's currency again.
+func (eh *EmployeeHandler) UpdateSalaryComponents(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
+		return
+	}
+
+	var employee models.Employee
+	if err := eh.db.Where("id = ? AND company_id = ?", uint(id), companyID).First(&employee).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+		return
+	}
+
+	var req struct {
+		Components []models.SalaryComponent `json:"components"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	totalPercentage := 0.0
+	for _, component := range req.Components {
+		var currency models.Currency
+		if err := eh.db.Where("id = ? AND is_active = ?", component.CurrencyID, true).First(&currency).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency"})
+			return
+		}
+		if component.IsPercentage {
+			if component.Percentage <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Percentage must be greater than zero"})
+				return
+			}
+			totalPercentage += component.Percentage
+		} else if !component.Amount.IsPositive() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Amount must be greater than zero"})
+			return
+		}
+	}
+	if totalPercentage > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Salary component percentages cannot exceed 100"})
+		return
+	}
+
+	err = eh.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("employee_id = ? AND company_id = ?", employee.ID, companyID).
+			Delete(&models.SalaryComponent{}).Error; err != nil {
+			return err
+		}
+		for _, component := range req.Components {
+			component.ID = 0
+			component.CompanyID = companyID
+			component.EmployeeID = employee.ID
+			component.IsActive = true
+			if err := tx.Create(&component).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update salary components"})
+		return
+	}
+
+	var components []models.SalaryComponent
+	eh.db.Preload("Currency").Where("employee_id = ? AND company_id = ?", employee.ID, companyID).Find(&components)
+
+	c.JSON(http.StatusOK, components)
+}
+
+// GetCompensationChanges returns the employee's salary history, latest first
+func (eh *EmployeeHandler) GetCompensationChanges(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
+		return
+	}
+
+	if !canViewPayslip(eh.db, c, uint(id)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this employee's salary history"})
+		return
+	}
+
+	changes, err := eh.processor.GetCompensationHistory(companyID, uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch salary history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// CreateCompensationChange records an effective-dated salary or pay currency change
+func (eh *EmployeeHandler) CreateCompensationChange(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
+		return
+	}
+
+	var req struct {
+		NewSalary     decimal.Decimal `json:"new_salary" binding:"required"`
+		CurrencyID    uint            `json:"currency_id"`
+		EffectiveDate string          `json:"effective_date" binding:"required"`
+		Reason        string          `json:"reason" binding:"required"`
+		Notes         string          `json:"notes"`
+		Override      string          `json:"salary_band_override"` // Reason for a salary outside the position's band
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	effectiveDate, err := time.Parse("2006-01-02", req.EffectiveDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid effective date, expected YYYY-MM-DD"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	change, err := eh.processor.RecordCompensationChange(companyID, uint(id), models.CompensationChange{
+		NewSalary:          req.NewSalary,
+		CurrencyID:         req.CurrencyID,
+		EffectiveDate:      effectiveDate,
+		Reason:             req.Reason,
+		Notes:              req.Notes,
+		SalaryBandOverride: req.Override,
+		ApprovedBy:         &userID,
+	}, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+		salaryChangeError(c, err)
+		return
+	}
+	// The employee carries the override for the salary they are paid now
+	if change.AppliedAt != nil {
+		eh.db.Model(&models.Employee{}).Where("id = ? AND company_id = ?", id, companyID).
+			Update("salary_band_override", change.SalaryBandOverride)
+	}
+
+	c.JSON(http.StatusCreated, change)
+}
+
+// salaryChangeError writes the response for a salary change that was not recorded, with
+// the band check when the salary was rejected for falling outside it.
+func salaryChangeError(c *gin.Context, err error) {
+	var bandErr *payroll.SalaryBandError
+	if errors.As(err, &bandErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "salary_band": bandErr.Check})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// checkSalaryBand applies the company's salary band policy to a new salary for the employee.
+// An accepted out-of-band salary keeps its override reason on the employee and sets the
+// warning. It writes the error response and returns false when the salary is rejected.
+func (eh *EmployeeHandler) checkSalaryBand(c *gin.Context, employee *models.Employee, salary decimal.Decimal, currencyID uint, overrideReason string) bool {
+	check, err := eh.processor.EnforceSalaryBand(middleware.GetCompanyID(c), employee.PositionID, salary, currencyID, overrideReason)
+	if err != nil {
+		salaryChangeError(c, err)
+		return false
+	}
+
+	employee.SalaryBandOverride = ""
+	if check != nil && check.OutOfBand() {
+		employee.SalaryBandOverride = overrideReason
+		employee.SalaryBandWarning = check.Message()
+	}
+	return true
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"errors"
+	"gm58-hr-backend/internal/api/middleware"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/services/leave"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type LeaveHandler struct {
+	db           *gorm.DB
+	leaveService *leave.LeaveService
+}
+
+func NewLeaveHandler(db *gorm.DB, leaveService *leave.LeaveService) *LeaveHandler {
+	return &LeaveHandler{
+		db:           db,
+		leaveService: leaveService,
+	}
+}
+
+// GetLeaveTypes lists the company's active leave types
+func (lh *LeaveHandler) GetLeaveTypes(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	var leaveTypes []models.LeaveType
+	if err := lh.db.Where("company_id = ? AND is_active = ?", companyID, true).Order("name").Find(&leaveTypes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leave types"})
+		return
+	}
+
+	c.JSON(http.StatusOK, leaveTypes)
+}
+
+// CreateLeaveRequest applies for leave. Employees apply for themselves; HR and company admins
+// may apply for any employee.
+func (lh *LeaveHandler) CreateLeaveRequest(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	var req struct {
+		EmployeeID  uint   `json:"employee_id"`
+		LeaveTypeID uint   `json:"leave_type_id" binding:"required"`
+		StartDate   string `json:"start_date" binding:"required"`
+		EndDate     string `json:"end_date" binding:"required"`
+		Reason      string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.EmployeeID == 0 {
+		employee, ok := lh.currentEmployee(c)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "employee_id is required"})
+			return
+		}
+		req.EmployeeID = employee.ID
+	}
+	if !canViewPayslip(lh.db, c, req.EmployeeID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only apply for leave for yourself"})
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date, expected YYYY-MM-DD"})
+		return
+	}
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date, expected YYYY-MM-DD"})
+		return
+	}
+
+	request, err := lh.leaveService.CreateRequest(companyID, models.LeaveRequest{
+		EmployeeID:  req.EmployeeID,
+		LeaveTypeID: req.LeaveTypeID,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		Reason:      req.Reason,
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+		c.JSON(leaveErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
+
+// GetLeaveRequests lists leave requests, filtered by ?employee_id, ?status and a ?from/?to
+// date range. Employees who are not HR or company admins see their own requests and those of
+// the people who report to them.
+func (lh *LeaveHandler) GetLeaveRequests(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	var filter leave.RequestFilter
+	var err error
+	if value := c.Query("employee_id"); value != "" {
+		employeeID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
+			return
+		}
+		filter.EmployeeID = uint(employeeID)
+	}
+	filter.Status = c.Query("status")
+	if value := c.Query("from"); value != "" {
+		if filter.From, err = time.Parse("2006-01-02", value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if filter.To, err = time.Parse("2006-01-02", value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+
+	companyRole := middleware.GetCompanyRole(c)
+	if companyRole == "company_admin" || companyRole == "hr" {
+		requests, err := lh.leaveService.GetRequests(companyID, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leave requests"})
+			return
+		}
+		c.JSON(http.StatusOK, requests)
+		return
+	}
+
+	employee, ok := lh.currentEmployee(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No employee record for this user"})
+		return
+	}
+	requests, err := lh.leaveService.GetRequests(companyID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leave requests"})
+		return
+	}
+	visible := make([]models.LeaveRequest, 0, len(requests))
+	for _, request := range requests {
+		if request.EmployeeID == employee.ID || (request.Employee.ManagerID != nil && *request.Employee.ManagerID == employee.ID) {
+			visible = append(visible, request)
+		}
+	}
+
+	c.JSON(http.StatusOK, visible)
+}
+
+// CancelLeaveRequest withdraws a request; employees may cancel their own
+func (lh *LeaveHandler) CancelLeaveRequest(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	request, ok := lh.findRequest(c)
+	if !ok {
+		return
+	}
+	if !canViewPayslip(lh.db, c, request.EmployeeID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only cancel your own leave requests"})
+		return
+	}
+
+	cancelled, err := lh.leaveService.CancelRequest(companyID, request.ID)
+	if err != nil {
+		c.JSON(leaveErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Leave request cancelled", "request": cancelled})
+}
+
+// ApproveLeaveRequest approves a pending request
+func (lh *LeaveHandler) ApproveLeaveRequest(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	request, ok := lh.findRequest(c)
+	if !ok {
+		return
+	}
+	approverID, ok := lh.checkLeaveApprover(c, request)
+	if !ok {
+		return
+	}
+
+	approved, err := lh.leaveService.ApproveRequest(companyID, request.ID, approverID)
+	if err != nil {
+		c.JSON(leaveErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Leave request approved", "request": approved})
+}
+
+// RejectLeaveRequest turns down a pending request with a reason
+func (lh *LeaveHandler) RejectLeaveRequest(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	request, ok := lh.findRequest(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required when rejecting leave"})
+		return
+	}
+
+	approverID, ok := lh.checkLeaveApprover(c, request)
+	if !ok {
+		return
+	}
+
+	rejected, err := lh.leaveService.RejectRequest(companyID, request.ID, req.Reason, approverID)
+	if err != nil {
+		c.JSON(leaveErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Leave request rejected", "request": rejected})
+}
+
+// findRequest loads the request named in the path, writing the error response if it cannot
+func (lh *LeaveHandler) findRequest(c *gin.Context) (*models.LeaveRequest, bool) {
+	requestID, err := strconv.ParseUint(c.Param("requestId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid leave request ID"})
+		return nil, false
+	}
+
+	request, err := lh.leaveService.GetRequest(middleware.GetCompanyID(c), uint(requestID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Leave request not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leave request"})
+		return nil, false
+	}
+	return request, true
+}
+
+// checkLeaveApprover allows HR, company admins and the employee's manager to decide a request,
+// but never the employee themselves. It returns the approver's employee record ID, if any.
+func (lh *LeaveHandler) checkLeaveApprover(c *gin.Context, request *models.LeaveRequest) (*uint, bool) {
+	var approverID *uint
+	employee, hasEmployee := lh.currentEmployee(c)
+	if hasEmployee {
+		approverID = &employee.ID
+		if employee.ID == request.EmployeeID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You cannot approve or reject your own leave"})
+			return nil, false
+		}
+	}
+
+	companyRole := middleware.GetCompanyRole(c)
+	if companyRole == "company_admin" || companyRole == "hr" {
+		return approverID, true
+	}
+	if hasEmployee && request.Employee.ManagerID != nil && *request.Employee.ManagerID == employee.ID {
+		return approverID, true
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "Only HR, company admins or the employee's manager can decide leave"})
+	return nil, false
+}
+
+// currentEmployee returns the acting user's employee record in the company
+func (lh *LeaveHandler) currentEmployee(c *gin.Context) (models.Employee, bool) {
+	var employee models.Employee
+	err := lh.db.Where("user_id = ? AND company_id = ?", c.GetUint("user_id"), middleware.GetCompanyID(c)).First(&employee).Error
+	return employee, err == nil
+}
+
+// leaveErrorStatus maps leave workflow errors to HTTP status codes
+func leaveErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, leave.ErrLeaveOverlap), errors.Is(err, leave.ErrLeaveState):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -1,26 +1,34 @@
 package handlers
 
 import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
 	"gm58-hr-backend/internal/api/middleware"
 	"gm58-hr-backend/internal/models"
 	"gm58-hr-backend/internal/services/payroll"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
 type PayrollHandler struct {
-	db        *gorm.DB
-	processor *payroll.PayrollProcessor
+	db              *gorm.DB
+	processor       *payroll.PayrollProcessor
+	varianceService *payroll.VarianceService
 }
 
-func NewPayrollHandler(db *gorm.DB, processor *payroll.PayrollProcessor) *PayrollHandler {
+func NewPayrollHandler(db *gorm.DB, processor *payroll.PayrollProcessor, varianceService *payroll.VarianceService) *PayrollHandler {
 	return &PayrollHandler{
-		db:        db,
-		processor: processor,
+		db:              db,
+		processor:       processor,
+		varianceService: varianceService,
 	}
 }
 
@@ -40,8 +48,9 @@ func (ph *PayrollHandler) CreatePeriod(c *gin.Context) {
 
 	// Check if period already exists for this company
 	var existingPeriod models.PayrollPeriod
-	if err := ph.db.Where("company_id = ? AND year = ? AND month = ?",
-		companyID, period.Year, period.Month).First(&existingPeriod).Error; err == nil {
+	period.PeriodType = models.PeriodTypeRegular
+	if err := ph.db.Where("company_id = ? AND year = ? AND month = ? AND period_type = ?",
+		companyID, period.Year, period.Month, models.PeriodTypeRegular).First(&existingPeriod).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "Payroll period already exists for this month"})
 		return
 	}
@@ -57,289 +66,1544 @@ func (ph *PayrollHandler) CreatePeriod(c *gin.Context) {
 	c.JSON(http.StatusCreated, period)
 }
 
+// CreateOffCyclePeriod creates a bonus or correction period that pays selected employees
+// outside the regular monthly run
+func (ph *PayrollHandler) CreateOffCyclePeriod(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	var req struct {
+		PeriodType  string `json:"period_type" binding:"required"`
+		Year        int    `json:"year" binding:"required"`
+		Month       int    `json:"month" binding:"required"`
+		Description string `json:"description"`
+		Employees   []struct {
+			EmployeeID      uint            `json:"employee_id" binding:"required"`
+			Bonus           decimal.Decimal `json:"bonus"`
+			OtherEarnings   decimal.Decimal `json:"other_earnings"`
+			OtherDeductions decimal.Decimal `json:"other_deductions"`
+			TaxFreeAmount   decimal.Decimal `json:"tax_free_amount"`
+			Description     string          `json:"description"`
+		} `json:"employees" binding:"required,min=1,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lines := make([]models.PayrollPeriodEmployee, 0, len(req.Employees))
+	for _, employee := range req.Employees {
+		lines = append(lines, models.PayrollPeriodEmployee{
+			EmployeeID:      employee.EmployeeID,
+			Bonus:           employee.Bonus,
+			OtherEarnings:   employee.OtherEarnings,
+			OtherDeductions: employee.OtherDeductions,
+			TaxFreeAmount:   employee.TaxFreeAmount,
+			Description:     employee.Description,
+		})
+	}
+
+	period, err := ph.processor.CreateOffCyclePeriod(companyID, req.PeriodType, req.Year, req.Month, req.Description, lines)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, period)
+}
+
+// GetBonusPolicy returns the company's annual bonus policy
+func (ph *PayrollHandler) GetBonusPolicy(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	policy, err := ph.processor.GetBonusPolicy(companyID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No bonus policy configured"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bonus policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdateBonusPolicy creates or updates the company's annual bonus policy; fields left out
+// of the request keep their current values
+func (ph *PayrollHandler) UpdateBonusPolicy(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	policy, err := ph.processor.GetBonusPolicy(companyID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bonus policy"})
+			return
+		}
+		policy = &models.BonusPolicy{
+			Name:           "13th cheque",
+			Multiple:       1,
+			ProrateService: true,
+			PayoutMonth:    12,
+			IsActive:       true,
+		}
+	}
+
+	if err := c.ShouldBindJSON(policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := ph.processor.SaveBonusPolicy(companyID, *policy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+// GenerateBonusPayout creates a draft bonus period paying the annual bonus to eligible
+// employees. The month defaults to the policy's payout month.
+func (ph *PayrollHandler) GenerateBonusPayout(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	var req struct {
+		Year  int `json:"year" binding:"required"`
+		Month int `json:"month"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Month == 0 {
+		policy, err := ph.processor.GetBonusPolicy(companyID)
+		if err == nil {
+			req.Month = policy.PayoutMonth
+		}
+	}
+
+	period, err := ph.processor.GenerateBonusPayout(companyID, req.Year, req.Month)
+	if err != nil {
+		if errors.Is(err, payroll.ErrNoBonusPolicy) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, period)
+}
+
+// CreateForecastScenario saves a payroll cost forecasting scenario
+func (ph *PayrollHandler) CreateForecastScenario(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	var scenario models.ForecastScenario
+	if err := c.ShouldBindJSON(&scenario); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := ph.processor.CreateForecastScenario(companyID, c.GetUint("user_id"), scenario)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, saved)
+}
+
+// GetForecastScenarios lists the company's saved forecasting scenarios
+func (ph *PayrollHandler) GetForecastScenarios(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	scenarios, err := ph.processor.GetForecastScenarios(companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch forecast scenarios"})
+		return
+	}
+
+	c.JSON(http.StatusOK, scenarios)
+}
+
+// GetForecastScenario returns a scenario with its assumptions
+func (ph *PayrollHandler) GetForecastScenario(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	scenarioID, err := strconv.ParseUint(c.Param("scenarioId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scenario ID"})
+		return
+	}
+
+	scenario, err := ph.processor.GetForecastScenario(companyID, uint(scenarioID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Forecast scenario not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch forecast scenario"})
+		return
+	}
+
+	c.JSON(http.StatusOK, scenario)
+}
+
+// DeleteForecastScenario removes a saved scenario
+func (ph *PayrollHandler) DeleteForecastScenario(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	scenarioID, err := strconv.ParseUint(c.Param("scenarioId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scenario ID"})
+		return
+	}
+
+	if err := ph.processor.DeleteForecastScenario(companyID, uint(scenarioID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Forecast scenario not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete forecast scenario"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Forecast scenario deleted"})
+}
+
+// GetForecastProjection projects a scenario's monthly payroll cost by department and currency
+func (ph *PayrollHandler) GetForecastProjection(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	scenarioID, err := strconv.ParseUint(c.Param("scenarioId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scenario ID"})
+		return
+	}
+
+	projection, err := ph.processor.ProjectForecast(companyID, uint(scenarioID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Forecast scenario not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, projection)
+}
+
+// CompareForecastScenarios projects the scenarios given as ?ids=1,2 side by side
+func (ph *PayrollHandler) CompareForecastScenarios(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	var scenarioIDs []uint
+	for _, idStr := range strings.Split(c.Query("ids"), ",") {
+		if idStr = strings.TrimSpace(idStr); idStr == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scenario ID"})
+			return
+		}
+		scenarioIDs = append(scenarioIDs, uint(id))
+	}
+
+	comparison, err := ph.processor.CompareForecasts(companyID, scenarioIDs)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Forecast scenario not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
+
+// CreateSalaryReview starts a salary review cycle and generates its proposals
+func (ph *PayrollHandler) CreateSalaryReview(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	var req struct {
+		Name          string                    `json:"name" binding:"required"`
+		EffectiveDate string                    `json:"effective_date" binding:"required"`
+		Reason        string                    `json:"reason"`
+		Budget        decimal.Decimal           `json:"budget"`
+		Rules         []models.SalaryReviewRule `json:"rules"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	effectiveDate, err := time.Parse("2006-01-02", req.EffectiveDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid effective date format"})
+		return
+	}
+
+	review, err := ph.processor.CreateSalaryReview(companyID, c.GetUint("user_id"), models.SalaryReview{
+		Name:          req.Name,
+		EffectiveDate: effectiveDate,
+		Reason:        req.Reason,
+		Budget:        req.Budget,
+		Rules:         req.Rules,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ph.respondSalaryReview(c, http.StatusCreated, review)
+}
+
+// GetSalaryReviews lists the company's salary review cycles
+func (ph *PayrollHandler) GetSalaryReviews(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	reviews, err := ph.processor.GetSalaryReviews(companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch salary reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// GetSalaryReview returns a review with its proposals and budget impact
+func (ph *PayrollHandler) GetSalaryReview(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	reviewID, err := strconv.ParseUint(c.Param("reviewId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	review, err := ph.processor.GetSalaryReview(companyID, uint(reviewID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Salary review not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch salary review"})
+		return
+	}
+
+	ph.respondSalaryReview(c, http.StatusOK, review)
+}
+
+// UpdateSalaryReviewRules replaces a draft review's rules and regenerates its proposals
+func (ph *PayrollHandler) UpdateSalaryReviewRules(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	reviewID, err := strconv.ParseUint(c.Param("reviewId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	var req struct {
+		Rules []models.SalaryReviewRule `json:"rules"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	review, err := ph.processor.UpdateSalaryReviewRules(companyID, uint(reviewID), req.Rules)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Salary review not found"})
+			return
+		}
+		c.JSON(approvalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ph.respondSalaryReview(c, http.StatusOK, review)
+}
+
+// UpdateSalaryReviewProposal sets one employee's proposed salary by hand
+func (ph *PayrollHandler) UpdateSalaryReviewProposal(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	reviewID, err := strconv.ParseUint(c.Param("reviewId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+	proposalID, err := strconv.ParseUint(c.Param("proposalId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposal ID"})
+		return
+	}
+
+	var req struct {
+		ProposedSalary decimal.Decimal `json:"proposed_salary" binding:"required"`
+		Notes          string          `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	proposal, err := ph.processor.UpdateSalaryReviewProposal(companyID, uint(reviewID), uint(proposalID), req.ProposedSalary, req.Notes)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Salary review proposal not found"})
+			return
+		}
+		c.JSON(approvalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, proposal)
+}
+
+// SubmitSalaryReview sends a draft review for approval
+func (ph *PayrollHandler) SubmitSalaryReview(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	reviewID, err := strconv.ParseUint(c.Param("reviewId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	review, err := ph.processor.SubmitSalaryReview(companyID, uint(reviewID), c.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Salary review not found"})
+			return
+		}
+		c.JSON(approvalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Salary review submitted for approval", "review": review})
+}
+
+// ApproveSalaryReview records an approval; the final level records the compensation changes
+func (ph *PayrollHandler) ApproveSalaryReview(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	reviewID, err := strconv.ParseUint(c.Param("reviewId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	// The comment is optional for approvals, so an empty body is accepted
+	var req struct {
+		Comment string `json:"comment"`
+	}
+	c.ShouldBindJSON(&req)
+
+	review, err := ph.processor.ApproveSalaryReview(companyID, uint(reviewID), c.GetUint("user_id"), middleware.GetCompanyRole(c), req.Comment)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Salary review not found"})
+			return
+		}
+		c.JSON(approvalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	message := "Salary review approval recorded"
+	if review.Status == "approved" {
+		message = "Salary review approved and compensation changes recorded"
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message, "review": review})
+}
+
+// RejectSalaryReview returns a submitted review to draft
+func (ph *PayrollHandler) RejectSalaryReview(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	reviewID, err := strconv.ParseUint(c.Param("reviewId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	var req struct {
+		Comment string `json:"comment" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A comment is required when rejecting a salary review"})
+		return
+	}
+
+	review, err := ph.processor.RejectSalaryReview(companyID, uint(reviewID), c.GetUint("user_id"), middleware.GetCompanyRole(c), req.Comment)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Salary review not found"})
+			return
+		}
+		c.JSON(approvalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Salary review rejected and returned to draft", "review": review})
+}
+
+// respondSalaryReview writes a review together with its budget impact
+func (ph *PayrollHandler) respondSalaryReview(c *gin.Context, status int, review *models.SalaryReview) {
+	impact, err := ph.processor.SalaryReviewImpact(*review)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(status, gin.H{"review": review, "impact": impact})
+}
+
+// GetSalaryBandReport reports compa-ratio, band penetration and out-of-band employees by department
+func (ph *PayrollHandler) GetSalaryBandReport(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	report, err := ph.processor.SalaryBandReport(companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
 func (ph *PayrollHandler) GetPeriods(c *gin.Context) {
 	// companyID := middleware.GetCompanyID(c)
 
-	var periods []models.PayrollPeriod
+	var periods []models.PayrollPeriod
+
+	query := ph.db.Scopes(middleware.CompanyScope(c))
+
+	if year := c.Query("year"); year != "" {
+		query = query.Where("year = ?", year)
+	}
+
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if periodType := c.Query("period_type"); periodType != "" {
+		query = query.Where("period_type = ?", periodType)
+	}
+
+	if err := query.Order("year DESC, month DESC").Find(&periods).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch periods"})
+		return
+	}
+
+	c.JSON(http.StatusOK, periods)
+}
+
+func (ph *PayrollHandler) ProcessPayroll(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	// Verify period belongs to company
+	var period models.PayrollPeriod
+	if err := ph.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := ph.processor.ProcessPayrollForCompany(uint(periodID), companyID, userID); err != nil {
+		var validationErr *payroll.ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "validation": validationErr.Report})
+			return
+		}
+		if errors.Is(err, payroll.ErrPeriodNotDraft) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Payroll processed successfully"})
+}
+
+// ValidatePayroll lists the data problems that would block or affect processing the period
+func (ph *PayrollHandler) ValidatePayroll(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	report, err := ph.processor.ValidatePayrollForCompany(uint(periodID), companyID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// AcknowledgeValidationWarnings accepts validation warnings so they no longer block processing
+func (ph *PayrollHandler) AcknowledgeValidationWarnings(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	var req struct {
+		Keys    []string `json:"keys" binding:"required,min=1"`
+		Comment string   `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := ph.processor.AcknowledgeValidationWarnings(uint(periodID), companyID, c.GetUint("user_id"), req.Keys, req.Comment)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (ph *PayrollHandler) GetPayslips(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	// Verify period belongs to company
+	var period models.PayrollPeriod
+	if err := ph.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+		return
+	}
+
+	var payslips []models.Payslip
+	if err := ph.db.Preload("Currency").Preload("PayrollPeriod").
+		Preload("NetPaySplits.Currency").
+		Where("payroll_period_id = ? AND company_id = ?", periodID, companyID).
+		Find(&payslips).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payslips"})
+		return
+	}
+
+	c.JSON(http.StatusOK, payslips)
+}
+
+func (ph *PayrollHandler) GetPayrollSummary(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	// Verify period belongs to company
+	var period models.PayrollPeriod
+	if err := ph.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+		return
+	}
+
+	summary, err := ph.processor.GetPayrollSummaryForCompany(uint(periodID), companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payroll summary"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetWithholdingTaxReport returns the tax withheld from contractors in a period
+func (ph *PayrollHandler) GetWithholdingTaxReport(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	report, err := ph.processor.GetWithholdingTaxReport(uint(periodID), companyID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build withholding tax report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (ph *PayrollHandler) ApprovePayroll(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	// Verify period belongs to company
+	var period models.PayrollPeriod
+	if err := ph.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+		return
+	}
+
+	// The comment is optional for approvals, so an empty body is accepted
+	var req struct {
+		Comment string `json:"comment"`
+	}
+	c.ShouldBindJSON(&req)
+
+	userID := c.GetUint("user_id")
+	updated, err := ph.processor.ApprovePayrollForCompany(uint(periodID), companyID, userID, middleware.GetCompanyRole(c), req.Comment)
+	if err != nil {
+		c.JSON(approvalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	message := "Payroll approval recorded"
+	if updated.Status == "approved" {
+		message = "Payroll approved successfully"
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message, "period": updated})
+}
+
+func (ph *PayrollHandler) RejectPayroll(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	var req struct {
+		Comment string `json:"comment" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A comment is required when rejecting payroll"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	updated, err := ph.processor.RejectPayrollForCompany(uint(periodID), companyID, userID, middleware.GetCompanyRole(c), req.Comment)
+	if err != nil {
+		c.JSON(approvalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Payroll rejected and returned to draft", "period": updated})
+}
+
+// GetApprovals returns the approval steps, history and the level the period is waiting on
+func (ph *PayrollHandler) GetApprovals(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	var period models.PayrollPeriod
+	if err := ph.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+		return
+	}
+
+	approvals, err := ph.approvalStatus(period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch approvals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, approvals)
+}
+
+func (ph *PayrollHandler) GetApprovalSteps(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	steps, err := ph.processor.GetApprovalSteps(companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch approval steps"})
+		return
+	}
+
+	c.JSON(http.StatusOK, steps)
+}
+
+// UpdateApprovalSteps replaces the approval chain; steps are numbered in the order given
+func (ph *PayrollHandler) UpdateApprovalSteps(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	var req struct {
+		Steps []models.PayrollApprovalStep `json:"steps"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, step := range req.Steps {
+		if step.ApproverUserID == nil {
+			continue
+		}
+		var companyUser models.CompanyUser
+		if err := ph.db.Where("company_id = ? AND user_id = ? AND is_active = ?",
+			companyID, *step.ApproverUserID, true).First(&companyUser).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Approver user does not belong to this company"})
+			return
+		}
+	}
+
+	if err := ph.processor.SaveApprovalSteps(companyID, req.Steps); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	steps, err := ph.processor.GetApprovalSteps(companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch approval steps"})
+		return
+	}
+
+	c.JSON(http.StatusOK, steps)
+}
+
+func (ph *PayrollHandler) approvalStatus(period models.PayrollPeriod) (gin.H, error) {
+	steps, err := ph.processor.GetApprovalSteps(period.CompanyID)
+	if err != nil {
+		return nil, err
+	}
+	nextStep, err := ph.processor.NextApprovalStep(period)
+	if err != nil {
+		return nil, err
+	}
+	history, err := ph.processor.GetApprovalHistory(period.ID, period.CompanyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"steps":          steps,
+		"approval_level": period.ApprovalLevel,
+		"next_step":      nextStep,
+		"history":        history,
+	}, nil
+}
+
+func approvalErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, payroll.ErrApprovalNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, payroll.ErrApprovalState), errors.Is(err, payroll.ErrReviewState):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func (ph *PayrollHandler) GetPayslip(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	payslipID, err := strconv.ParseUint(c.Param("payslipId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payslip ID"})
+		return
+	}
+
+	var payslip models.Payslip
+	if err := ph.db.Preload("Currency").Preload("PayrollPeriod").
+		Preload("NetPaySplits.Currency").
+		Where("id = ? AND company_id = ?", uint(payslipID), companyID).
+		First(&payslip).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payslip not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payslip"})
+		return
+	}
+
+	// Check if user has permission to view this payslip
+	if !canViewPayslip(ph.db, c, payslip.EmployeeID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this payslip"})
+		return
+	}
+
+	c.JSON(http.StatusOK, payslip)
+}
+
+// ExplainPayslip returns the calculation trace behind a payslip's figures
+func (ph *PayrollHandler) ExplainPayslip(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	payslipID, err := strconv.ParseUint(c.Param("payslipId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payslip ID"})
+		return
+	}
+
+	explanation, err := ph.processor.ExplainPayslip(companyID, uint(payslipID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payslip not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to explain payslip"})
+		return
+	}
+
+	if !canViewPayslip(ph.db, c, explanation.EmployeeID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this payslip"})
+		return
+	}
+
+	c.JSON(http.StatusOK, explanation)
+}
+
+// canViewPayslip allows employees to see their own payslips and HR or company admins to see any
+func canViewPayslip(db *gorm.DB, c *gin.Context, employeeID uint) bool {
+	companyRole := middleware.GetCompanyRole(c)
+	if companyRole == "company_admin" || companyRole == "hr" {
+		return true
+	}
+
+	// Check if user is viewing their own payslip
+	var employee models.Employee
+	return db.Where("id = ? AND user_id = ?", employeeID, c.GetUint("user_id")).First(&employee).Error == nil
+}
+
+// GetPayrollVariance compares a period with the previous one (or ?compare_to=periodId)
+func (ph *PayrollHandler) GetPayrollVariance(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	var compareTo uint64
+	if compareToStr := c.Query("compare_to"); compareToStr != "" {
+		compareTo, err = strconv.ParseUint(compareToStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comparison period ID"})
+			return
+		}
+	}
+
+	report, err := ph.varianceService.ComparePeriods(uint(periodID), uint(compareTo), companyID, varianceThresholds(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetApprovalData returns everything reviewers need on the approval screen
+func (ph *PayrollHandler) GetApprovalData(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	var period models.PayrollPeriod
+	if err := ph.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+		return
+	}
+
+	summary, err := ph.processor.GetPayrollSummaryForCompany(period.ID, companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payroll summary"})
+		return
+	}
+
+	approvals, err := ph.approvalStatus(period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch approvals"})
+		return
+	}
+
+	response := gin.H{
+		"period":    period,
+		"summary":   summary,
+		"approvals": approvals,
+	}
+
+	// The first period for a company has nothing to compare with
+	report, err := ph.varianceService.ComparePeriods(period.ID, 0, companyID, varianceThresholds(c))
+	if err != nil && !errors.Is(err, payroll.ErrNoPreviousPeriod) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare with the previous period"})
+		return
+	}
+	response["variance"] = report
+
+	c.JSON(http.StatusOK, response)
+}
+
+func varianceThresholds(c *gin.Context) payroll.VarianceThresholds {
+	thresholds := payroll.VarianceThresholds{
+		NetPayPercent: payroll.DefaultVariancePercentThreshold,
+		NetPayAmount:  payroll.DefaultVarianceAmountThreshold,
+	}
+	if percent, err := strconv.ParseFloat(c.Query("threshold_percent"), 64); err == nil {
+		thresholds.NetPayPercent = percent
+	}
+	if amount, err := strconv.ParseFloat(c.Query("threshold_amount"), 64); err == nil {
+		thresholds.NetPayAmount = amount
+	}
+	return thresholds
+}
+
+// GetPaymentBatches returns the period's net pay grouped by payout currency
+func (ph *PayrollHandler) GetPaymentBatches(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	batches, err := ph.processor.BuildPaymentBatches(uint(periodID), companyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, batches)
+}
+
+// DownloadPaymentFile returns the bank transfer file for one currency, or a ZIP
+// with one file per currency when no currency is given
+func (ph *PayrollHandler) DownloadPaymentFile(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	var period models.PayrollPeriod
+	if err := ph.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+		return
+	}
+
+	if period.Status != "approved" && period.Status != "paid" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payroll must be approved before generating payment files"})
+		return
+	}
+
+	batches, err := ph.processor.BuildPaymentBatches(uint(periodID), companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build payment batches"})
+		return
+	}
+
+	filePrefix := fmt.Sprintf("payments-%04d-%02d", period.Year, period.Month)
+
+	if currencyCode := c.Query("currency"); currencyCode != "" {
+		for _, batch := range batches {
+			if batch.Currency != currencyCode {
+				continue
+			}
+			var buf bytes.Buffer
+			if err := payroll.WritePaymentFile(&buf, batch); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write payment file"})
+				return
+			}
+			c.Header("Content-Disposition", "attachment; filename="+filePrefix+"-"+batch.Currency+".csv")
+			c.Data(http.StatusOK, "text/csv", buf.Bytes())
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "No payments in this currency"})
+		return
+	}
 
-	query := ph.db.Scopes(middleware.CompanyScope(c))
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, batch := range batches {
+		file, err := archive.Create(filePrefix + "-" + batch.Currency + ".csv")
+		if err == nil {
+			err = payroll.WritePaymentFile(file, batch)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write payment file"})
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write payment files"})
+		return
+	}
 
-	if year := c.Query("year"); year != "" {
-		query = query.Where("year = ?", year)
+	c.Header("Content-Disposition", "attachment; filename="+filePrefix+".zip")
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// CreateSalaryAdjustment records a salary change, calculating back pay when it is backdated
+// into periods that have already been approved
+func (ph *PayrollHandler) CreateSalaryAdjustment(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	employeeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
+		return
 	}
 
-	if status := c.Query("status"); status != "" {
-		query = query.Where("status = ?", status)
+	var req struct {
+		NewSalary     decimal.Decimal `json:"new_salary" binding:"required"`
+		EffectiveDate string          `json:"effective_date" binding:"required"`
+		Reason        string          `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	if err := query.Order("year DESC, month DESC").Find(&periods).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch periods"})
+	effectiveDate, err := time.Parse("2006-01-02", req.EffectiveDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid effective date, expected YYYY-MM-DD"})
 		return
 	}
 
-	c.JSON(http.StatusOK, periods)
+	adjustment, err := ph.processor.CreateSalaryAdjustment(companyID, uint(employeeID), req.NewSalary,
+		effectiveDate, req.Reason, c.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, adjustment)
 }
 
-func (ph *PayrollHandler) ProcessPayroll(c *gin.Context) {
+func (ph *PayrollHandler) GetSalaryAdjustments(c *gin.Context) {
 	companyID := middleware.GetCompanyID(c)
-	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	employeeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
 		return
 	}
 
-	// Verify period belongs to company
-	var period models.PayrollPeriod
-	if err := ph.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+	if !canViewPayslip(ph.db, c, uint(employeeID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this employee's salary adjustments"})
 		return
 	}
 
-	if err := ph.processor.ProcessPayrollForCompany(uint(periodID), companyID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	adjustments, err := ph.processor.GetSalaryAdjustments(companyID, uint(employeeID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch salary adjustments"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Payroll processed successfully"})
+	c.JSON(http.StatusOK, adjustments)
 }
 
-func (ph *PayrollHandler) GetPayslips(c *gin.Context) {
+// CreateFinalSettlement terminates an employee and calculates their final pay in an
+// off-cycle termination period
+func (ph *PayrollHandler) CreateFinalSettlement(c *gin.Context) {
 	companyID := middleware.GetCompanyID(c)
-	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	employeeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
 		return
 	}
 
-	// Verify period belongs to company
-	var period models.PayrollPeriod
-	if err := ph.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+	var req struct {
+		TerminationDate string `json:"termination_date" binding:"required"`
+		Reason          string `json:"reason" binding:"required"`
+		NoticeDays      int    `json:"notice_days"` // Working days of notice paid in lieu
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var payslips []models.Payslip
-	if err := ph.db.Preload("Employee").Preload("Currency").Preload("PayrollPeriod").
-		Where("payroll_period_id = ? AND company_id = ?", periodID, companyID).
-		Find(&payslips).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payslips"})
+	terminationDate, err := time.Parse("2006-01-02", req.TerminationDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid termination date, expected YYYY-MM-DD"})
 		return
 	}
 
-	c.JSON(http.StatusOK, payslips)
+	settlement, err := ph.processor.CreateFinalSettlement(companyID, uint(employeeID), terminationDate,
+		req.Reason, req.NoticeDays, c.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+		if errors.Is(err, payroll.ErrAlreadyTerminated) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, settlement)
 }
 
-func (ph *PayrollHandler) GetPayrollSummary(c *gin.Context) {
+func (ph *PayrollHandler) GetFinalSettlement(c *gin.Context) {
 	companyID := middleware.GetCompanyID(c)
-	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	employeeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
 		return
 	}
 
-	// Verify period belongs to company
-	var period models.PayrollPeriod
-	if err := ph.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+	if !canViewPayslip(ph.db, c, uint(employeeID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this employee's final settlement"})
 		return
 	}
 
-	summary, err := ph.processor.GetPayrollSummaryForCompany(uint(periodID), companyID)
+	settlement, err := ph.processor.GetFinalSettlement(companyID, uint(employeeID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payroll summary"})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Final settlement not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch final settlement"})
 		return
 	}
 
-	c.JSON(http.StatusOK, summary)
+	c.JSON(http.StatusOK, settlement)
 }
 
-func (ph *PayrollHandler) ApprovePayroll(c *gin.Context) {
+// CreateEmployeeLoan records a loan to an employee, recovered from final pay if they leave
+func (ph *PayrollHandler) CreateEmployeeLoan(c *gin.Context) {
 	companyID := middleware.GetCompanyID(c)
-	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	employeeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
 		return
 	}
 
-	// Verify period belongs to company
-	var period models.PayrollPeriod
-	if err := ph.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+	var req struct {
+		Amount      decimal.Decimal `json:"amount"`
+		Description string          `json:"description"`
+		IssuedDate  string          `json:"issued_date"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	userID := c.GetUint("user_id")
-	if err := ph.processor.ApprovePayroll(uint(periodID), userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	issuedDate := time.Now()
+	if req.IssuedDate != "" {
+		issuedDate, err = time.Parse("2006-01-02", req.IssuedDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid issued date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+
+	loan, err := ph.processor.CreateEmployeeLoan(companyID, uint(employeeID), req.Amount,
+		req.Description, issuedDate, c.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Payroll approved successfully"})
+	c.JSON(http.StatusCreated, loan)
 }
 
-func (ph *PayrollHandler) GetPayslip(c *gin.Context) {
+func (ph *PayrollHandler) GetEmployeeLoans(c *gin.Context) {
 	companyID := middleware.GetCompanyID(c)
-	payslipID, err := strconv.ParseUint(c.Param("payslipId"), 10, 32)
+	employeeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payslip ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
 		return
 	}
 
-	var payslip models.Payslip
-	if err := ph.db.Preload("Employee").Preload("Currency").Preload("PayrollPeriod").
-		Where("id = ? AND company_id = ?", uint(payslipID), companyID).
-		First(&payslip).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Payslip not found"})
+	if !canViewPayslip(ph.db, c, uint(employeeID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this employee's loans"})
+		return
+	}
+
+	loans, err := ph.processor.GetEmployeeLoans(companyID, uint(employeeID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch loans"})
+		return
+	}
+
+	c.JSON(http.StatusOK, loans)
+}
+
+// CreateTimeEntry captures hours, days or units worked by an hourly, daily or piece-rate employee
+func (ph *PayrollHandler) CreateTimeEntry(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	employeeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
+		return
+	}
+
+	var req struct {
+		WorkDate    string  `json:"work_date" binding:"required"`
+		Quantity    float64 `json:"quantity" binding:"required"`
+		Description string  `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workDate, err := time.Parse("2006-01-02", req.WorkDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid work date, expected YYYY-MM-DD"})
+		return
+	}
+
+	entry, err := ph.processor.CreateTimeEntry(companyID, uint(employeeID), workDate, req.Quantity,
+		req.Description, c.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payslip"})
+		if errors.Is(err, payroll.ErrPeriodClosed) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Check if user has permission to view this payslip
-	userID := c.GetUint("user_id")
-	companyRole := middleware.GetCompanyRole(c)
+	c.JSON(http.StatusCreated, entry)
+}
 
-	// Check if user is viewing their own payslip
-	var employee models.Employee
-	if err := ph.db.Where("id = ? AND user_id = ?", payslip.EmployeeID, userID).First(&employee).Error; err != nil {
-		// Not their payslip, check if they have permission
-		if companyRole != "company_admin" && companyRole != "hr" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this payslip"})
+// GetTimeEntries lists an employee's time entries, optionally between from and to dates and by status
+func (ph *PayrollHandler) GetTimeEntries(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	employeeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
+		return
+	}
+
+	if !canViewPayslip(ph.db, c, uint(employeeID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this employee's time entries"})
+		return
+	}
+
+	var from, to time.Time
+	if value := c.Query("from"); value != "" {
+		if from, err = time.Parse("2006-01-02", value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse("2006-01-02", value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
 			return
 		}
 	}
 
-	c.JSON(http.StatusOK, payslip)
+	entries, err := ph.processor.GetTimeEntries(companyID, uint(employeeID), from, to, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch time entries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// ApproveTimeEntry approves a pending time entry so it is paid in its period
+func (ph *PayrollHandler) ApproveTimeEntry(c *gin.Context) {
+	ph.reviewTimeEntry(c, true)
+}
+
+// RejectTimeEntry rejects a pending time entry
+func (ph *PayrollHandler) RejectTimeEntry(c *gin.Context) {
+	ph.reviewTimeEntry(c, false)
+}
+
+func (ph *PayrollHandler) reviewTimeEntry(c *gin.Context, approve bool) {
+	companyID := middleware.GetCompanyID(c)
+	entryID, err := strconv.ParseUint(c.Param("entryId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time entry ID"})
+		return
+	}
+
+	entry, err := ph.processor.ReviewTimeEntry(companyID, uint(entryID), approve, c.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Time entry not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// CreateTaxDirective records a tax directive issued for an employee
+func (ph *PayrollHandler) CreateTaxDirective(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	employeeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
+		return
+	}
+
+	var req struct {
+		DirectiveNumber string          `json:"directive_number" binding:"required"`
+		Type            string          `json:"type" binding:"required"`
+		Scope           string          `json:"scope"`
+		Rate            float64         `json:"rate"`
+		Amount          decimal.Decimal `json:"amount"`
+		ValidFrom       string          `json:"valid_from" binding:"required"`
+		ValidTo         string          `json:"valid_to"`
+		Notes           string          `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	directive := models.TaxDirective{
+		DirectiveNumber: req.DirectiveNumber,
+		Type:            req.Type,
+		Scope:           req.Scope,
+		Rate:            req.Rate,
+		Amount:          req.Amount,
+		Notes:           req.Notes,
+	}
+	if directive.ValidFrom, err = time.Parse("2006-01-02", req.ValidFrom); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid valid from date, expected YYYY-MM-DD"})
+		return
+	}
+	if req.ValidTo != "" {
+		validTo, err := time.Parse("2006-01-02", req.ValidTo)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid valid to date, expected YYYY-MM-DD"})
+			return
+		}
+		directive.ValidTo = &validTo
+	}
+
+	created, err := ph.processor.CreateTaxDirective(companyID, uint(employeeID), directive, c.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetTaxDirectives lists an employee's tax directives
+func (ph *PayrollHandler) GetTaxDirectives(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	employeeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
+		return
+	}
+
+	if !canViewPayslip(ph.db, c, uint(employeeID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this employee's tax directives"})
+		return
+	}
+
+	directives, err := ph.processor.GetTaxDirectives(companyID, uint(employeeID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tax directives"})
+		return
+	}
+
+	c.JSON(http.StatusOK, directives)
+}
+
+// CancelTaxDirective stops a tax directive applying to future payroll runs
+func (ph *PayrollHandler) CancelTaxDirective(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	directiveID, err := strconv.ParseUint(c.Param("directiveId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tax directive ID"})
+		return
+	}
+
+	directive, err := ph.processor.CancelTaxDirective(companyID, uint(directiveID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tax directive not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, directive)
+}
+
+// GenerateTaxCertificates issues the year's tax certificates from approved payslips
+func (ph *PayrollHandler) GenerateTaxCertificates(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	var req struct {
+		Year int `json:"year" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	certificates, err := ph.processor.GenerateTaxCertificates(companyID, req.Year)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, certificates)
 }
 
-// func (ph *PayrollHandler) CreatePeriod(c *gin.Context) {
-// 	var period models.PayrollPeriod
-// 	if err := c.ShouldBindJSON(&period); err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-// 		return
-// 	}
-
-// 	var existingPeriod models.PayrollPeriod
-// 	if err := ph.db.Where("year = ? AND month = ?", period.Year, period.Month).First(&existingPeriod).Error; err == nil {
-// 		c.JSON(http.StatusConflict, gin.H{"error": "Payroll period already exists"})
-// 		return
-// 	}
-
-// 	period.StartDate = time.Date(period.Year, time.Month(period.Month), 1, 0, 0, 0, 0, time.UTC)
-// 	period.EndDate = period.StartDate.AddDate(0, 1, -1)
-
-// 	if err := ph.db.Create(&period).Error; err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payroll period"})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusCreated, period)
-// }
-
-// func (ph *PayrollHandler) GetPeriods(c *gin.Context) {
-// 	var periods []models.PayrollPeriod
-
-// 	query := ph.db.Model(&models.PayrollPeriod{})
-
-// 	if year := c.Query("year"); year != "" {
-// 		query = query.Where("year = ?", year)
-// 	}
-
-// 	if status := c.Query("status"); status != "" {
-// 		query = query.Where("status = ?", status)
-// 	}
-
-// 	if err := query.Order("year DESC, month DESC").Find(&periods).Error; err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch periods"})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusOK, periods)
-// }
-
-// func (ph *PayrollHandler) ProcessPayroll(c *gin.Context) {
-// 	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
-// 	if err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
-// 		return
-// 	}
-
-// 	if err := ph.processor.ProcessPayroll(uint(periodID)); err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusOK, gin.H{"message": "Payroll processed successfully"})
-// }
-
-// func (ph *PayrollHandler) GetPayslips(c *gin.Context) {
-// 	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
-// 	if err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
-// 		return
-// 	}
-
-// 	var payslips []models.Payslip
-// 	if err := ph.db.Preload("Employee").Preload("Currency").Preload("PayrollPeriod").
-// 		Where("payroll_period_id = ?", periodID).Find(&payslips).Error; err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payslips"})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusOK, payslips)
-// }
-
-// func (ph *PayrollHandler) GetPayrollSummary(c *gin.Context) {
-// 	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
-// 	if err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
-// 		return
-// 	}
-
-// 	summary, err := ph.processor.GetPayrollSummary(uint(periodID))
-// 	if err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payroll summary"})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusOK, summary)
-// }
-
-// func (ph *PayrollHandler) ApprovePayroll(c *gin.Context) {
-// 	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
-// 	if err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
-// 		return
-// 	}
-
-// 	userID := c.GetUint("user_id")
-// 	if err := ph.processor.ApprovePayroll(uint(periodID), userID); err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusOK, gin.H{"message": "Payroll approved successfully"})
-// }
-
-// func (ph *PayrollHandler) GetPayslip(c *gin.Context) {
-// 	payslipID, err := strconv.ParseUint(c.Param("payslipId"), 10, 32)
-// 	if err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payslip ID"})
-// 		return
-// 	}
-
-// 	var payslip models.Payslip
-// 	if err := ph.db.Preload("Employee").Preload("Currency").Preload("PayrollPeriod").
-// 		First(&payslip, uint(payslipID)).Error; err != nil {
-// 		if err == gorm.ErrRecordNotFound {
-// 			c.JSON(http.StatusNotFound, gin.H{"error": "Payslip not found"})
-// 			return
-// 		}
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payslip"})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusOK, payslip)
-// }
+// GetTaxCertificates lists the tax certificates issued to an employee
+func (ph *PayrollHandler) GetTaxCertificates(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	employeeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
+		return
+	}
+
+	if !canViewPayslip(ph.db, c, uint(employeeID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this employee's tax certificates"})
+		return
+	}
+
+	certificates, err := ph.processor.GetTaxCertificates(companyID, uint(employeeID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tax certificates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, certificates)
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"gm58-hr-backend/internal/api/middleware"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/services/email"
+	"gm58-hr-backend/internal/services/payslip"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PayslipHandler struct {
+	db                  *gorm.DB
+	payslipService      *payslip.PayslipService
+	distributionService *payslip.DistributionService
+}
+
+func NewPayslipHandler(db *gorm.DB, payslipService *payslip.PayslipService, distributionService *payslip.DistributionService) *PayslipHandler {
+	return &PayslipHandler{
+		db:                  db,
+		payslipService:      payslipService,
+		distributionService: distributionService,
+	}
+}
+
+// DownloadPayslipPDF returns a printable payslip. With ?protect=true the PDF is
+// encrypted with the employee's national ID.
+func (psh *PayslipHandler) DownloadPayslipPDF(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	payslipID, err := strconv.ParseUint(c.Param("payslipId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payslip ID"})
+		return
+	}
+
+	doc, err := psh.payslipService.GetDocument(uint(payslipID), companyID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payslip not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payslip"})
+		return
+	}
+
+	if !canViewPayslip(psh.db, c, doc.Payslip.EmployeeID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this payslip"})
+		return
+	}
+
+	var password string
+	if protectPDF(c) {
+		password = payslip.Password(doc.Payslip)
+		if password == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Employee has no national ID to protect the payslip with"})
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := payslip.RenderPDF(&buf, *doc, password); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate payslip PDF"})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+payslip.FileName(doc.Payslip))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// DownloadPeriodPayslips returns a ZIP with one PDF payslip per employee in the period
+func (psh *PayslipHandler) DownloadPeriodPayslips(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	var period models.PayrollPeriod
+	if err := psh.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payroll period not found"})
+		return
+	}
+
+	documents, err := psh.payslipService.GetPeriodDocuments(period.ID, companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payslips"})
+		return
+	}
+	if len(documents) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No payslips generated for this period"})
+		return
+	}
+
+	protect := protectPDF(c)
+	if protect {
+		var missing []string
+		for _, doc := range documents {
+			if payslip.Password(doc.Payslip) == "" {
+				missing = append(missing, doc.Payslip.EmployeeNumber)
+			}
+		}
+		if len(missing) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":     "Some employees have no national ID to protect their payslips with",
+				"employees": missing,
+			})
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, doc := range documents {
+		var password string
+		if protect {
+			password = payslip.Password(doc.Payslip)
+		}
+
+		file, err := archive.Create(payslip.FileName(doc.Payslip))
+		if err == nil {
+			err = payslip.RenderPDF(file, doc, password)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate payslip PDFs"})
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate payslip PDFs"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=payslips-%04d-%02d.zip", period.Year, period.Month))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// EmailPayslips queues an email with the payslip PDF for each employee in an approved period
+func (psh *PayslipHandler) EmailPayslips(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	// Both fields are optional, so an empty body emails everyone without protection
+	var req struct {
+		Protect     bool   `json:"protect"`
+		EmployeeIDs []uint `json:"employee_ids"`
+	}
+	c.ShouldBindJSON(&req)
+
+	result, err := psh.distributionService.QueuePeriod(uint(periodID), companyID, c.GetUint("user_id"), req.Protect, req.EmployeeIDs)
+	if err != nil {
+		c.JSON(distributionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, result)
+}
+
+// GetPayslipEmails returns the per-recipient delivery status for a period
+func (psh *PayslipHandler) GetPayslipEmails(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	deliveries, summary, err := psh.distributionService.GetPeriodDeliveries(uint(periodID), companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payslip emails"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"summary": summary, "emails": deliveries})
+}
+
+// ResendPayslipEmail queues a single payslip email again
+func (psh *PayslipHandler) ResendPayslipEmail(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	emailID, err := strconv.ParseUint(c.Param("emailId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email ID"})
+		return
+	}
+
+	delivery, err := psh.distributionService.Resend(uint(emailID), companyID)
+	if err != nil {
+		c.JSON(distributionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// ResendFailedPayslipEmails queues every failed payslip email in a period again
+func (psh *PayslipHandler) ResendFailedPayslipEmails(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	periodID, err := strconv.ParseUint(c.Param("periodId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period ID"})
+		return
+	}
+
+	requeued, err := psh.distributionService.ResendFailed(uint(periodID), companyID)
+	if err != nil {
+		c.JSON(distributionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"queued": requeued})
+}
+
+func distributionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, email.ErrNotConfigured):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, payslip.ErrEmailDisabled):
+		return http.StatusForbidden
+	case errors.Is(err, payslip.ErrPeriodNotApproved):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func protectPDF(c *gin.Context) bool {
+	protect := strings.ToLower(c.Query("protect"))
+	return protect == "true" || protect == "1"
+}
@@ -79,7 +79,7 @@ func (ph *PositionHandler) CreatePosition(c *gin.Context) {
 	}
 
 	// Validate salary range
-	if position.MinSalary > position.MaxSalary {
+	if position.MinSalary.GreaterThan(position.MaxSalary) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Minimum salary cannot be greater than maximum salary"})
 		return
 	}
@@ -160,13 +160,13 @@ func (ph *PositionHandler) UpdatePosition(c *gin.Context) {
 	// Validate salary range
 	minSalary := updateData.MinSalary
 	maxSalary := updateData.MaxSalary
-	if minSalary == 0 {
+	if minSalary.IsZero() {
 		minSalary = position.MinSalary
 	}
-	if maxSalary == 0 {
+	if maxSalary.IsZero() {
 		maxSalary = position.MaxSalary
 	}
-	if minSalary > maxSalary {
+	if minSalary.GreaterThan(maxSalary) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Minimum salary cannot be greater than maximum salary"})
 		return
 	}
@@ -90,6 +90,22 @@ func CompanyAdminMiddleware() gin.HandlerFunc {
 	}
 }
 
+// CompanyHRMiddleware ensures user is HR or an admin for the current company
+func CompanyHRMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyRole := c.GetString("company_role")
+		isSuperAdmin := c.GetBool("is_super_admin")
+
+		if !isSuperAdmin && companyRole != "company_admin" && companyRole != "hr" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "HR or company admin access required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // CompanyScope returns a GORM scope that filters by company_id
 func CompanyScope(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
@@ -3,8 +3,13 @@ package routes
 import (
 	"gm58-hr-backend/internal/api/handlers"
 	"gm58-hr-backend/internal/api/middleware"
+	"gm58-hr-backend/internal/config"
+	"gm58-hr-backend/internal/services/accounting"
 	"gm58-hr-backend/internal/services/currency"
+	"gm58-hr-backend/internal/services/leave"
 	"gm58-hr-backend/internal/services/payroll"
+	"gm58-hr-backend/internal/services/payslip"
+	"gm58-hr-backend/internal/services/tax"
 	"gm58-hr-backend/pkg/logger"
 	"gm58-hr-backend/pkg/redis"
 	"time"
@@ -13,19 +18,29 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(r *gin.Engine, db *gorm.DB, redisClient *redis.Client, logger *logger.Logger) {
+// SetupRoutes registers the API. The payslip distribution worker is started and stopped
+// by the caller, alongside the server.
+func SetupRoutes(r *gin.Engine, db *gorm.DB, redisClient *redis.Client, logger *logger.Logger, cfg *config.Config,
+	payslipService *payslip.PayslipService, distributionService *payslip.DistributionService) {
 	// Initialize services
 	currencyService := currency.NewCurrencyService(db, "", "")
 	payrollProcessor := payroll.NewPayrollProcessor(db, currencyService)
+	payrollProcessor.SetWorkers(cfg.PayrollWorkers)
+	varianceService := payroll.NewVarianceService(db)
+	journalService := accounting.NewJournalService(db)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(db, "jwt-secret")
 	companyHandler := handlers.NewCompanyHandler(db)
-	employeeHandler := handlers.NewEmployeeHandler(db, currencyService)
-	payrollHandler := handlers.NewPayrollHandler(db, payrollProcessor)
+	employeeHandler := handlers.NewEmployeeHandler(db, currencyService, payrollProcessor)
+	payrollHandler := handlers.NewPayrollHandler(db, payrollProcessor, varianceService)
 	currencyHandler := handlers.NewCurrencyHandler(db, currencyService)
 	positionHandler := handlers.NewPositionHandler(db)
 	departmentHandler := handlers.NewDepartmentHandler(db)
+	accountingHandler := handlers.NewAccountingHandler(db, journalService)
+	payslipHandler := handlers.NewPayslipHandler(db, payslipService, distributionService)
+	calculatorHandler := handlers.NewCalculatorHandler(db, tax.NewTaxCalculator(currencyService))
+	leaveHandler := handlers.NewLeaveHandler(db, leave.NewLeaveService(db))
 
 	// Public routes (no authentication required)
 	public := r.Group("/api/v1")
@@ -87,6 +102,26 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB, redisClient *redis.Client, logger *
 			employees.PUT("/:id", employeeHandler.UpdateEmployee)
 			employees.DELETE("/:id", employeeHandler.DeleteEmployee)
 			employees.GET("/:id/payslips", employeeHandler.GetEmployeePayslips)
+			employees.GET("/:id/salary-components", employeeHandler.GetSalaryComponents)
+			employees.PUT("/:id/salary-components", employeeHandler.UpdateSalaryComponents)
+			employees.GET("/:id/salary-adjustments", payrollHandler.GetSalaryAdjustments)
+			employees.GET("/:id/compensation-changes", employeeHandler.GetCompensationChanges)
+			employees.GET("/:id/final-settlement", payrollHandler.GetFinalSettlement)
+			employees.GET("/:id/loans", payrollHandler.GetEmployeeLoans)
+			employees.GET("/:id/time-entries", payrollHandler.GetTimeEntries)
+			employees.GET("/:id/tax-directives", payrollHandler.GetTaxDirectives)
+			employees.GET("/:id/tax-certificates", payrollHandler.GetTaxCertificates)
+		}
+
+		// Pay changes, loans, time and tax directives are captured by HR
+		hrEmployees := company.Group("/employees", middleware.CompanyHRMiddleware())
+		{
+			hrEmployees.POST("/:id/salary-adjustments", payrollHandler.CreateSalaryAdjustment)
+			hrEmployees.POST("/:id/compensation-changes", employeeHandler.CreateCompensationChange)
+			hrEmployees.POST("/:id/final-settlement", payrollHandler.CreateFinalSettlement)
+			hrEmployees.POST("/:id/loans", payrollHandler.CreateEmployeeLoan)
+			hrEmployees.POST("/:id/time-entries", payrollHandler.CreateTimeEntry)
+			hrEmployees.POST("/:id/tax-directives", payrollHandler.CreateTaxDirective)
 		}
 
 		// Department routes
@@ -115,11 +150,78 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB, redisClient *redis.Client, logger *
 		{
 			payroll.POST("/periods", payrollHandler.CreatePeriod)
 			payroll.GET("/periods", payrollHandler.GetPeriods)
+			payroll.GET("/periods/:periodId/validation", payrollHandler.ValidatePayroll)
 			payroll.POST("/periods/:periodId/process", payrollHandler.ProcessPayroll)
 			payroll.POST("/periods/:periodId/approve", payrollHandler.ApprovePayroll)
+			payroll.POST("/periods/:periodId/reject", payrollHandler.RejectPayroll)
+			payroll.GET("/periods/:periodId/approvals", payrollHandler.GetApprovals)
+			payroll.GET("/approval-steps", payrollHandler.GetApprovalSteps)
+			payroll.PUT("/approval-steps", middleware.CompanyAdminMiddleware(), payrollHandler.UpdateApprovalSteps)
+			payroll.GET("/bonus-policy", payrollHandler.GetBonusPolicy)
+			payroll.GET("/salary-reviews/:reviewId", payrollHandler.GetSalaryReview)
+			payroll.POST("/salary-reviews/:reviewId/approve", payrollHandler.ApproveSalaryReview)
+			payroll.POST("/salary-reviews/:reviewId/reject", payrollHandler.RejectSalaryReview)
 			payroll.GET("/periods/:periodId/payslips", payrollHandler.GetPayslips)
+			payroll.GET("/periods/:periodId/payslips/emails", payslipHandler.GetPayslipEmails)
+			payroll.POST("/periods/:periodId/payslips/emails/resend-failed", middleware.CompanyAdminMiddleware(), payslipHandler.ResendFailedPayslipEmails)
 			payroll.GET("/periods/:periodId/summary", payrollHandler.GetPayrollSummary)
+			payroll.GET("/periods/:periodId/variance", payrollHandler.GetPayrollVariance)
+			payroll.GET("/periods/:periodId/withholding-tax", payrollHandler.GetWithholdingTaxReport)
+			payroll.GET("/periods/:periodId/approval", payrollHandler.GetApprovalData)
+			payroll.GET("/periods/:periodId/journal", accountingHandler.GetPayrollJournal)
+			payroll.GET("/periods/:periodId/payment-batches", payrollHandler.GetPaymentBatches)
+			payroll.GET("/periods/:periodId/payment-file", payrollHandler.DownloadPaymentFile)
 			payroll.GET("/payslips/:payslipId", payrollHandler.GetPayslip)
+			payroll.GET("/payslips/:payslipId/pdf", payslipHandler.DownloadPayslipPDF)
+			payroll.GET("/payslips/:payslipId/explain", payrollHandler.ExplainPayslip)
+			payroll.POST("/payslip-emails/:emailId/resend", middleware.CompanyAdminMiddleware(), payslipHandler.ResendPayslipEmail)
+			payroll.POST("/calculator/gross-to-net", calculatorHandler.GrossToNet)
+			payroll.POST("/calculator/net-to-gross", calculatorHandler.NetToGross)
+		}
+
+		// Payroll administration is limited to HR and company admins
+		hrPayroll := company.Group("/payroll", middleware.CompanyHRMiddleware())
+		{
+			hrPayroll.POST("/periods/off-cycle", payrollHandler.CreateOffCyclePeriod)
+			hrPayroll.POST("/periods/:periodId/validation/acknowledge", payrollHandler.AcknowledgeValidationWarnings)
+			hrPayroll.PUT("/bonus-policy", payrollHandler.UpdateBonusPolicy)
+			hrPayroll.POST("/bonus-policy/payout", payrollHandler.GenerateBonusPayout)
+			hrPayroll.POST("/forecasts", payrollHandler.CreateForecastScenario)
+			hrPayroll.GET("/forecasts", payrollHandler.GetForecastScenarios)
+			hrPayroll.GET("/forecasts/compare", payrollHandler.CompareForecastScenarios)
+			hrPayroll.GET("/forecasts/:scenarioId", payrollHandler.GetForecastScenario)
+			hrPayroll.DELETE("/forecasts/:scenarioId", payrollHandler.DeleteForecastScenario)
+			hrPayroll.GET("/forecasts/:scenarioId/projection", payrollHandler.GetForecastProjection)
+			hrPayroll.POST("/salary-reviews", payrollHandler.CreateSalaryReview)
+			hrPayroll.GET("/salary-reviews", payrollHandler.GetSalaryReviews)
+			hrPayroll.PUT("/salary-reviews/:reviewId/rules", payrollHandler.UpdateSalaryReviewRules)
+			hrPayroll.PUT("/salary-reviews/:reviewId/proposals/:proposalId", payrollHandler.UpdateSalaryReviewProposal)
+			hrPayroll.POST("/salary-reviews/:reviewId/submit", payrollHandler.SubmitSalaryReview)
+			hrPayroll.GET("/salary-bands", payrollHandler.GetSalaryBandReport)
+			hrPayroll.GET("/periods/:periodId/payslips/pdf", payslipHandler.DownloadPeriodPayslips)
+			hrPayroll.POST("/periods/:periodId/payslips/email", payslipHandler.EmailPayslips)
+			hrPayroll.POST("/time-entries/:entryId/approve", payrollHandler.ApproveTimeEntry)
+			hrPayroll.POST("/time-entries/:entryId/reject", payrollHandler.RejectTimeEntry)
+			hrPayroll.POST("/tax-directives/:directiveId/cancel", payrollHandler.CancelTaxDirective)
+			hrPayroll.POST("/tax-certificates", payrollHandler.GenerateTaxCertificates)
+		}
+
+		// Leave routes
+		leaveRoutes := company.Group("/leave")
+		{
+			leaveRoutes.GET("/types", leaveHandler.GetLeaveTypes)
+			leaveRoutes.POST("/requests", leaveHandler.CreateLeaveRequest)
+			leaveRoutes.GET("/requests", leaveHandler.GetLeaveRequests)
+			leaveRoutes.POST("/requests/:requestId/cancel", leaveHandler.CancelLeaveRequest)
+			leaveRoutes.POST("/requests/:requestId/approve", leaveHandler.ApproveLeaveRequest)
+			leaveRoutes.POST("/requests/:requestId/reject", leaveHandler.RejectLeaveRequest)
+		}
+
+		// Accounting routes
+		accountingRoutes := company.Group("/accounting")
+		{
+			accountingRoutes.GET("/gl-mappings", accountingHandler.GetGLMappings)
+			accountingRoutes.PUT("/gl-mappings", middleware.CompanyAdminMiddleware(), accountingHandler.UpdateGLMappings)
 		}
 
 		// Currency routes (some are global, some are company-specific)
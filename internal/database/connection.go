@@ -31,6 +31,7 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.Employee{},
 		&models.Department{},
 		&models.Position{},
+		&models.SalaryComponent{},
 
 		// Currency models
 		&models.Currency{},
@@ -38,10 +39,38 @@ func AutoMigrate(db *gorm.DB) error {
 
 		// Payroll models
 		&models.PayrollPeriod{},
+		&models.PayrollPeriodEmployee{},
 		&models.Payslip{},
+		&models.PayslipNetPay{},
+		&models.PayslipCalculationStep{},
+		&models.PayrollApprovalStep{},
+		&models.PayrollApproval{},
+		&models.PayrollValidationAcknowledgement{},
+		&models.SalaryAdjustment{},
+		&models.BackPayLine{},
+		&models.CompensationChange{},
+		&models.FinalSettlement{},
+		&models.BonusPolicy{},
+		&models.ForecastScenario{},
+		&models.ForecastIncrease{},
+		&models.ForecastHire{},
+		&models.ForecastExchangeRate{},
+		&models.SalaryReview{},
+		&models.SalaryReviewRule{},
+		&models.SalaryReviewProposal{},
+		&models.SalaryReviewApproval{},
+		&models.TimeEntry{},
+		&models.TaxDirective{},
+		&models.EmployeeLoan{},
 		&models.Allowance{},
 		&models.Deduction{},
 
+		// Notification models
+		&models.PayslipEmail{},
+
+		// Accounting models
+		&models.GLAccountMapping{},
+
 		// Leave models
 		&models.LeaveType{},
 		&models.LeaveRequest{},
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// GLAccountMapping maps a payslip component to a general-ledger account for a company.
+// Employer contributions post to both an expense account and a liability (contra) account.
+type GLAccountMapping struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	CompanyID         uint      `json:"company_id" gorm:"uniqueIndex:idx_gl_mapping_company_component"`
+	Company           Company   `json:"company,omitempty" gorm:"foreignKey:CompanyID"`
+	Component         string    `json:"component" gorm:"not null;uniqueIndex:idx_gl_mapping_company_component"` // basic_salary, paye_tax, net_pay, employer_nssa, etc.
+	ComponentType     string    `json:"component_type"`                                                         // earning, deduction, employer_contribution, net_pay
+	AccountCode       string    `json:"account_code" gorm:"not null"`
+	AccountName       string    `json:"account_name"`
+	ContraAccountCode string    `json:"contra_account_code"` // Liability side for employer contributions
+	ContraAccountName string    `json:"contra_account_name"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
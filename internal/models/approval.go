@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// PayrollApprovalStep configures who must approve a payroll run at each level.
+// A step is satisfied by the named user when ApproverUserID is set, otherwise by
+// any company user holding ApproverRole.
+type PayrollApprovalStep struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	CompanyID      uint      `json:"company_id" gorm:"uniqueIndex:idx_approval_step_company_level"`
+	Company        Company   `json:"company,omitempty" gorm:"foreignKey:CompanyID"`
+	Level          int       `json:"level" gorm:"uniqueIndex:idx_approval_step_company_level"`
+	Name           string    `json:"name"`
+	ApproverRole   string    `json:"approver_role"` // company_admin, hr, manager
+	ApproverUserID *uint     `json:"approver_user_id"`
+	ApproverUser   *User     `json:"approver_user,omitempty" gorm:"foreignKey:ApproverUserID"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// PayrollApproval records each approval or rejection of a payroll run.
+type PayrollApproval struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	CompanyID       uint      `json:"company_id"`
+	PayrollPeriodID uint      `json:"payroll_period_id" gorm:"index"`
+	Level           int       `json:"level"`
+	Action          string    `json:"action"` // approved, rejected
+	UserID          uint      `json:"user_id"`
+	User            User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Role            string    `json:"role"`
+	Comment         string    `json:"comment"`
+	CreatedAt       time.Time `json:"created_at"`
+}
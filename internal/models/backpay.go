@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// SalaryAdjustment is a backdated salary change. The arrears for periods that were
+// already approved are calculated per period when the adjustment is captured and
+// paid as back pay on the employee's next payslip.
+type SalaryAdjustment struct {
+	ID               uint            `json:"id" gorm:"primaryKey"`
+	CompanyID        uint            `json:"company_id" gorm:"index"`
+	EmployeeID       uint            `json:"employee_id" gorm:"index"`
+	Employee         Employee        `json:"employee,omitempty" gorm:"foreignKey:EmployeeID"`
+	CurrencyID       uint            `json:"currency_id"`
+	Currency         Currency        `json:"currency" gorm:"foreignKey:CurrencyID"`
+	PreviousSalary   decimal.Decimal `json:"previous_salary" gorm:"type:decimal(15,2)"`
+	NewSalary        decimal.Decimal `json:"new_salary" gorm:"type:decimal(15,2)"`
+	EffectiveDate    time.Time       `json:"effective_date"`
+	Reason           string          `json:"reason"`
+	Status           string          `json:"status" gorm:"default:'pending'"` // pending, applied
+	TotalArrears     decimal.Decimal `json:"total_arrears" gorm:"type:decimal(15,2)"`
+	TotalTax         decimal.Decimal `json:"total_tax" gorm:"type:decimal(15,2)"` // Extra PAYE and AIDS levy on the arrears
+	TotalNSSA        decimal.Decimal `json:"total_nssa" gorm:"type:decimal(15,2)"`
+	NetArrears       decimal.Decimal `json:"net_arrears" gorm:"type:decimal(15,2)"`
+	AppliedPeriodID  *uint           `json:"applied_period_id"`
+	AppliedPayslipID *uint           `json:"applied_payslip_id" gorm:"index"`
+	CreatedBy        uint            `json:"created_by"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+	Lines            []BackPayLine   `json:"lines,omitempty" gorm:"foreignKey:SalaryAdjustmentID"`
+}
+
+// BackPayLine is the recalculation of one past payslip under the adjusted salary.
+type BackPayLine struct {
+	ID                 uint            `json:"id" gorm:"primaryKey"`
+	SalaryAdjustmentID uint            `json:"salary_adjustment_id" gorm:"index"`
+	PayrollPeriodID    uint            `json:"payroll_period_id"`
+	PayrollPeriod      PayrollPeriod   `json:"payroll_period,omitempty" gorm:"foreignKey:PayrollPeriodID"`
+	OriginalPayslipID  uint            `json:"original_payslip_id" gorm:"index"`
+	ProrationFactor    float64         `json:"proration_factor" gorm:"type:decimal(7,6)"` // Share of the period after the effective date
+	SalaryDifference   decimal.Decimal `json:"salary_difference" gorm:"type:decimal(15,2)"`
+	OriginalEarnings   decimal.Decimal `json:"original_earnings" gorm:"type:decimal(15,2)"`
+	RevisedEarnings    decimal.Decimal `json:"revised_earnings" gorm:"type:decimal(15,2)"`
+	ExtraPAYE          decimal.Decimal `json:"extra_paye" gorm:"type:decimal(15,2)"`
+	ExtraAidsLevy      decimal.Decimal `json:"extra_aids_levy" gorm:"type:decimal(15,2)"`
+	ExtraNSSA          decimal.Decimal `json:"extra_nssa" gorm:"type:decimal(15,2)"`
+	NetArrears         decimal.Decimal `json:"net_arrears" gorm:"type:decimal(15,2)"`
+	CreatedAt          time.Time       `json:"created_at"`
+}
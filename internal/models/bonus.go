@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// BonusPolicy is a company's annual bonus (13th cheque) rule. The bonus is a multiple of
+// monthly basic salary, accrued monthly and paid out once a year.
+type BonusPolicy struct {
+	ID               uint            `json:"id" gorm:"primaryKey"`
+	CompanyID        uint            `json:"company_id" gorm:"unique"`
+	Name             string          `json:"name" gorm:"default:'13th cheque'"`
+	Multiple         float64         `json:"multiple" gorm:"type:decimal(5,2);default:1"`            // Months of basic salary paid
+	MinServiceMonths int             `json:"min_service_months"`                                     // Service needed at payout to qualify
+	ProrateService   bool            `json:"prorate_service" gorm:"default:true"`                    // Part-year employees get a share
+	PayoutMonth      int             `json:"payout_month" gorm:"default:12"`                         // Month the bonus year ends and is paid
+	TaxFreeThreshold decimal.Decimal `json:"tax_free_threshold" gorm:"type:decimal(15,2);default:0"` // Bonus exempt from PAYE each year, in USD
+	IsActive         bool            `json:"is_active" gorm:"default:true"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
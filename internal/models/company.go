@@ -1,8 +1,10 @@
 package models
 
 import (
+	"gm58-hr-backend/internal/money"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -76,13 +78,25 @@ type CompanySettings struct {
 	EnableAidsLevy bool   `json:"enable_aids_levy" gorm:"default:true"`
 	CustomTaxRates string `json:"custom_tax_rates" gorm:"type:jsonb"` // JSON for custom tax brackets
 
+	// Percentage withheld from contractor payments unless the contractor has their own rate
+	WithholdingTaxRate decimal.Decimal `json:"withholding_tax_rate" gorm:"type:decimal(5,2);default:30"`
+
 	// Leave Settings
 	LeaveYearStart     time.Time `json:"leave_year_start"`
 	AllowNegativeLeave bool      `json:"allow_negative_leave" gorm:"default:false"`
 
 	// Payroll Settings
-	PayrollApprovalLevels int  `json:"payroll_approval_levels" gorm:"default:1"`
-	RequireTimesheet      bool `json:"require_timesheet" gorm:"default:false"`
+	PayrollApprovalLevels int    `json:"payroll_approval_levels" gorm:"default:1"`
+	RequireTimesheet      bool   `json:"require_timesheet" gorm:"default:false"`
+	RoundingMode          string `json:"rounding_mode" gorm:"default:'half_up'"` // half_up, or half_even for banker's rounding
+	RoundingLevel         string `json:"rounding_level" gorm:"default:'line'"`   // line rounds each item, total rounds each payslip line once
+
+	// warn accepts a salary outside its position's band with a warning; enforce rejects it
+	// unless an override reason is given
+	SalaryBandPolicy string `json:"salary_band_policy" gorm:"default:'warn'"`
+
+	// Termination Settings
+	SeveranceMonthsPerYear float64 `json:"severance_months_per_year" gorm:"type:decimal(5,2);default:0.5"` // Months of basic salary per year of service on retrenchment
 
 	// Notification Settings
 	EmailNotifications bool `json:"email_notifications" gorm:"default:true"`
@@ -91,3 +105,8 @@ type CompanySettings struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// Rounding returns the rule payroll amounts are rounded by
+func (s CompanySettings) Rounding() money.Rounding {
+	return money.Rounding{Mode: s.RoundingMode, Level: s.RoundingLevel}
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"time"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -18,14 +19,14 @@ type Currency struct {
 }
 
 type ExchangeRate struct {
-	ID             uint      `json:"id" gorm:"primaryKey"`
-	FromCurrencyID uint      `json:"from_currency_id"`
-	ToCurrencyID   uint      `json:"to_currency_id"`
-	FromCurrency   Currency  `json:"from_currency" gorm:"foreignKey:FromCurrencyID"`
-	ToCurrency     Currency  `json:"to_currency" gorm:"foreignKey:ToCurrencyID"`
-	Rate           float64   `json:"rate" gorm:"type:decimal(15,6)"`
-	EffectiveDate  time.Time `json:"effective_date"`
-	Source         string    `json:"source"` // API, manual, etc.
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID             uint            `json:"id" gorm:"primaryKey"`
+	FromCurrencyID uint            `json:"from_currency_id"`
+	ToCurrencyID   uint            `json:"to_currency_id"`
+	FromCurrency   Currency        `json:"from_currency" gorm:"foreignKey:FromCurrencyID"`
+	ToCurrency     Currency        `json:"to_currency" gorm:"foreignKey:ToCurrencyID"`
+	Rate           decimal.Decimal `json:"rate" gorm:"type:decimal(15,6)"`
+	EffectiveDate  time.Time       `json:"effective_date"`
+	Source         string          `json:"source"` // API, manual, etc.
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
 }
@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -28,6 +29,7 @@ type Department struct {
 	Company     Company        `json:"company,omitempty" gorm:"foreignKey:CompanyID"`
 	Name        string         `json:"name" gorm:"not null"`
 	Description string         `json:"description"`
+	CostCentre  string         `json:"cost_centre"` // GL cost centre code, defaults to the department name
 	ManagerID   *uint          `json:"manager_id"`
 	Manager     *Employee      `json:"manager,omitempty" gorm:"foreignKey:ManagerID"`
 	IsActive    bool           `json:"is_active" gorm:"default:true"`
@@ -37,23 +39,32 @@ type Department struct {
 }
 
 type Position struct {
-	ID           uint           `json:"id" gorm:"primaryKey"`
-	CompanyID    uint           `json:"company_id"`
-	Company      Company        `json:"company,omitempty" gorm:"foreignKey:CompanyID"`
-	Title        string         `json:"title" gorm:"not null"`
-	DepartmentID uint           `json:"department_id"`
-	Department   Department     `json:"department" gorm:"foreignKey:DepartmentID"`
-	Description  string         `json:"description"`
-	MinSalary    float64        `json:"min_salary" gorm:"type:decimal(15,2)"`
-	MaxSalary    float64        `json:"max_salary" gorm:"type:decimal(15,2)"`
-	CurrencyID   uint           `json:"currency_id"`
-	Currency     Currency       `json:"currency" gorm:"foreignKey:CurrencyID"`
-	IsActive     bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	ID           uint            `json:"id" gorm:"primaryKey"`
+	CompanyID    uint            `json:"company_id"`
+	Company      Company         `json:"company,omitempty" gorm:"foreignKey:CompanyID"`
+	Title        string          `json:"title" gorm:"not null"`
+	DepartmentID uint            `json:"department_id"`
+	Department   Department      `json:"department" gorm:"foreignKey:DepartmentID"`
+	Description  string          `json:"description"`
+	MinSalary    decimal.Decimal `json:"min_salary" gorm:"type:decimal(15,2)"`
+	MaxSalary    decimal.Decimal `json:"max_salary" gorm:"type:decimal(15,2)"`
+	CurrencyID   uint            `json:"currency_id"`
+	Currency     Currency        `json:"currency" gorm:"foreignKey:CurrencyID"`
+	IsActive     bool            `json:"is_active" gorm:"default:true"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt  `json:"-" gorm:"index"`
 }
 
+// Employee pay types. Salaried employees are paid BasicSalary each month; the others are
+// paid PayRate for each approved hour, day or unit captured as a TimeEntry.
+const (
+	PayTypeSalaried  = "salaried"
+	PayTypeHourly    = "hourly"
+	PayTypeDaily     = "daily"
+	PayTypePieceRate = "piece_rate"
+)
+
 type Employee struct {
 	ID               uint    `json:"id" gorm:"primaryKey"`
 	CompanyID        uint    `json:"company_id"`
@@ -83,11 +94,21 @@ type Employee struct {
 	Manager      *Employee  `json:"manager,omitempty" gorm:"foreignKey:ManagerID"`
 
 	// Salary Information
-	BasicSalary     float64  `json:"basic_salary" gorm:"type:decimal(15,2)"`
-	CurrencyID      uint     `json:"currency_id"`
-	Currency        Currency `json:"currency" gorm:"foreignKey:CurrencyID"`
-	PaymentMethod   string   `json:"payment_method" gorm:"default:'bank_transfer'"`
-	PaymentSchedule string   `json:"payment_schedule" gorm:"default:'monthly'"` // weekly, bi-weekly, monthly
+	PayType         string          `json:"pay_type" gorm:"default:'salaried'"` // salaried, hourly, daily, piece_rate
+	BasicSalary     decimal.Decimal `json:"basic_salary" gorm:"type:decimal(15,2)"`
+	PayRate         decimal.Decimal `json:"pay_rate" gorm:"type:decimal(15,4)"` // Per hour, day or unit for non-salaried pay types
+	CurrencyID      uint            `json:"currency_id"`
+	Currency        Currency        `json:"currency" gorm:"foreignKey:CurrencyID"`
+	PaymentMethod   string          `json:"payment_method" gorm:"default:'bank_transfer'"`
+	PaymentSchedule string          `json:"payment_schedule" gorm:"default:'monthly'"` // weekly, bi-weekly, monthly
+
+	// Why the salary is outside its position's band; the warning is set on the response to a
+	// create or update that accepted an out-of-band salary and is not stored
+	SalaryBandOverride string `json:"salary_band_override"`
+	SalaryBandWarning  string `json:"salary_band_warning,omitempty" gorm:"-"`
+
+	// Percentage withheld from an independent contractor's pay; nil uses the company rate
+	WithholdingTaxRate *decimal.Decimal `json:"withholding_tax_rate" gorm:"type:decimal(5,2)"`
 
 	// Bank Details
 	BankName    string `json:"bank_name"`
@@ -103,7 +124,7 @@ type Employee struct {
 	TerminationDate  string `json:"termination_date"`
 
 	// Status
-	EmploymentType   string `json:"employment_type" gorm:"default:'permanent'"` // permanent, contract, temporary
+	EmploymentType   string `json:"employment_type" gorm:"default:'permanent'"` // permanent, contract, temporary, contractor
 	EmploymentStatus string `json:"employment_status" gorm:"default:'active'"`  // active, suspended, terminated
 	IsActive         bool   `json:"is_active" gorm:"default:true"`
 
@@ -118,11 +139,13 @@ type Employee struct {
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
-	Payslips        []Payslip        `json:"payslips,omitempty" gorm:"foreignKey:EmployeeID"`
-	Allowances      []Allowance      `json:"allowances,omitempty" gorm:"foreignKey:EmployeeID"`
-	Deductions      []Deduction      `json:"deductions,omitempty" gorm:"foreignKey:EmployeeID"`
-	LeaveRequests   []LeaveRequest   `json:"leave_requests,omitempty" gorm:"foreignKey:EmployeeID"`
-	TaxCertificates []TaxCertificate `json:"tax_certificates,omitempty" gorm:"foreignKey:EmployeeID"`
+	Payslips            []Payslip            `json:"payslips,omitempty" gorm:"foreignKey:EmployeeID"`
+	Allowances          []Allowance          `json:"allowances,omitempty" gorm:"foreignKey:EmployeeID"`
+	Deductions          []Deduction          `json:"deductions,omitempty" gorm:"foreignKey:EmployeeID"`
+	LeaveRequests       []LeaveRequest       `json:"leave_requests,omitempty" gorm:"foreignKey:EmployeeID"`
+	TaxCertificates     []TaxCertificate     `json:"tax_certificates,omitempty" gorm:"foreignKey:EmployeeID"`
+	SalaryComponents    []SalaryComponent    `json:"salary_components,omitempty" gorm:"foreignKey:EmployeeID"`
+	CompensationChanges []CompensationChange `json:"compensation_changes,omitempty" gorm:"foreignKey:EmployeeID"`
 }
 
 func (e *Employee) FullName() string {
@@ -132,6 +155,31 @@ func (e *Employee) FullName() string {
 	return e.FirstName + " " + e.LastName
 }
 
+// EmploymentTypeContractor marks an independent contractor paid through payroll. Contractors
+// are not taxed through PAYE or NSSA; withholding tax is deducted instead and they receive
+// remittance advices rather than payslips.
+const EmploymentTypeContractor = "contractor"
+
+// IsContractor reports whether the employee is an independent contractor.
+func (e *Employee) IsContractor() bool {
+	return e.EmploymentType == EmploymentTypeContractor
+}
+
+// IsSalaried reports whether the employee is paid a monthly salary rather than for
+// captured hours, days or units.
+func (e *Employee) IsSalaried() bool {
+	return e.PayType == "" || e.PayType == PayTypeSalaried
+}
+
+// HiredOn parses HireDate, which is stored as a YYYY-MM-DD string.
+func (e *Employee) HiredOn() (time.Time, bool) {
+	hired, err := time.Parse("2006-01-02", e.HireDate)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return hired, true
+}
+
 // Add unique indexes for multi-tenancy
 func (Department) TableName() string {
 	return "departments"
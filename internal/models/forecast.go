@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ForecastScenario is a saved set of assumptions used to project a company's monthly payroll
+// cost over a horizon. Projections start from the current employees and allowances and are
+// worked out when requested, so they follow changes to the workforce.
+type ForecastScenario struct {
+	ID            uint                   `json:"id" gorm:"primaryKey"`
+	CompanyID     uint                   `json:"company_id" gorm:"index"`
+	Name          string                 `json:"name" gorm:"not null"`
+	Description   string                 `json:"description"`
+	StartYear     int                    `json:"start_year"`
+	StartMonth    int                    `json:"start_month"`
+	Months        int                    `json:"months" gorm:"default:12"` // Horizon projected from the start month
+	Increases     []ForecastIncrease     `json:"increases" gorm:"foreignKey:ScenarioID;constraint:OnDelete:CASCADE"`
+	Hires         []ForecastHire         `json:"hires" gorm:"foreignKey:ScenarioID;constraint:OnDelete:CASCADE"`
+	ExchangeRates []ForecastExchangeRate `json:"exchange_rates" gorm:"foreignKey:ScenarioID;constraint:OnDelete:CASCADE"`
+	CreatedBy     uint                   `json:"created_by"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+}
+
+// ForecastIncrease raises basic salaries by Percentage from the given month. It applies to
+// one department, or to everyone when DepartmentID is nil.
+type ForecastIncrease struct {
+	ID           uint    `json:"id" gorm:"primaryKey"`
+	ScenarioID   uint    `json:"scenario_id" gorm:"index"`
+	Year         int     `json:"year"`
+	Month        int     `json:"month"`
+	Percentage   float64 `json:"percentage" gorm:"type:decimal(5,2)"`
+	DepartmentID *uint   `json:"department_id"`
+}
+
+// ForecastHire adds Headcount new employees in a position from the given month. A zero
+// BasicSalary uses the midpoint of the position's salary range, in the position's currency.
+type ForecastHire struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	ScenarioID  uint            `json:"scenario_id" gorm:"index"`
+	PositionID  uint            `json:"position_id"`
+	Position    Position        `json:"position,omitempty" gorm:"foreignKey:PositionID"`
+	Headcount   int             `json:"headcount"`
+	Year        int             `json:"year"`
+	Month       int             `json:"month"`
+	BasicSalary decimal.Decimal `json:"basic_salary" gorm:"type:decimal(15,2)"`
+}
+
+// ForecastExchangeRate assumes Rate units of a currency to one unit of the company's base
+// currency from the given month; a zero Year applies it from the start of the horizon.
+// Currencies without an assumption use the current exchange rate.
+type ForecastExchangeRate struct {
+	ID           uint            `json:"id" gorm:"primaryKey"`
+	ScenarioID   uint            `json:"scenario_id" gorm:"index"`
+	CurrencyCode string          `json:"currency_code" gorm:"size:3"`
+	Year         int             `json:"year"`
+	Month        int             `json:"month"`
+	Rate         decimal.Decimal `json:"rate" gorm:"type:decimal(15,6)"`
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -17,6 +18,7 @@ type LeaveType struct {
 	CarryForward     bool           `json:"carry_forward" gorm:"default:false"`
 	MaxCarryDays     int            `json:"max_carry_days"`
 	RequiresApproval bool           `json:"requires_approval" gorm:"default:true"`
+	PaidOnExit       bool           `json:"paid_on_exit" gorm:"default:false"` // Untaken days are paid out in the final settlement
 	IsActive         bool           `json:"is_active" gorm:"default:true"`
 	CreatedAt        time.Time      `json:"created_at"`
 	UpdatedAt        time.Time      `json:"updated_at"`
@@ -46,20 +48,21 @@ type LeaveRequest struct {
 }
 
 type TaxCertificate struct {
-	ID                uint      `json:"id" gorm:"primaryKey"`
-	CompanyID         uint      `json:"company_id"`
-	Company           Company   `json:"company,omitempty" gorm:"foreignKey:CompanyID"`
-	EmployeeID        uint      `json:"employee_id"`
-	Employee          Employee  `json:"employee" gorm:"foreignKey:EmployeeID"`
-	Year              int       `json:"year"`
-	TotalEarnings     float64   `json:"total_earnings" gorm:"type:decimal(15,2)"`
-	TotalTax          float64   `json:"total_tax" gorm:"type:decimal(15,2)"`
-	CurrencyID        uint      `json:"currency_id"`
-	Currency          Currency  `json:"currency" gorm:"foreignKey:CurrencyID"`
-	CertificateNumber string    `json:"certificate_number"`
-	IssuedAt          time.Time `json:"issued_at"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	ID                uint            `json:"id" gorm:"primaryKey"`
+	CompanyID         uint            `json:"company_id"`
+	Company           Company         `json:"company,omitempty" gorm:"foreignKey:CompanyID"`
+	EmployeeID        uint            `json:"employee_id"`
+	Employee          Employee        `json:"employee" gorm:"foreignKey:EmployeeID"`
+	Year              int             `json:"year"`
+	TotalEarnings     decimal.Decimal `json:"total_earnings" gorm:"type:decimal(15,2)"`
+	TotalTax          decimal.Decimal `json:"total_tax" gorm:"type:decimal(15,2)"`
+	CurrencyID        uint            `json:"currency_id"`
+	Currency          Currency        `json:"currency" gorm:"foreignKey:CurrencyID"`
+	CertificateNumber string          `json:"certificate_number"`
+	TaxDirectives     string          `json:"tax_directives"` // Directive numbers applied during the year, comma separated
+	IssuedAt          time.Time       `json:"issued_at"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
 }
 
 type AuditLog struct {
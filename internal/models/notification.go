@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// PayslipEmail is one queued payslip delivery to an employee. Rows are picked up by
+// the email dispatcher and retried with backoff until sent or out of attempts.
+type PayslipEmail struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	CompanyID       uint       `json:"company_id" gorm:"index"`
+	PayrollPeriodID uint       `json:"payroll_period_id" gorm:"index"`
+	PayslipID       uint       `json:"payslip_id" gorm:"index"`
+	Payslip         Payslip    `json:"-" gorm:"foreignKey:PayslipID"`
+	EmployeeID      uint       `json:"employee_id"`
+	Employee        Employee   `json:"employee,omitempty" gorm:"foreignKey:EmployeeID"`
+	Recipient       string     `json:"recipient"`
+	Protect         bool       `json:"protect"`                              // Encrypt the PDF with the employee's national ID
+	Status          string     `json:"status" gorm:"default:'queued';index"` // queued, sending, sent, failed
+	Attempts        int        `json:"attempts" gorm:"default:0"`
+	LastError       string     `json:"last_error"`
+	NextAttemptAt   time.Time  `json:"next_attempt_at"`
+	SentAt          *time.Time `json:"sent_at"`
+	RequestedBy     uint       `json:"requested_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
@@ -3,71 +3,146 @@ package models
 import (
 	"time"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
+// Payroll period types. Regular periods are unique per company and month. Off-cycle
+// periods pay selected employees and may share a month with the regular period: bonus and
+// correction periods pay the amounts captured per employee, and termination periods pay
+// one employee's final settlement.
+const (
+	PeriodTypeRegular     = "regular"
+	PeriodTypeBonus       = "bonus"
+	PeriodTypeCorrection  = "correction"
+	PeriodTypeTermination = "termination"
+)
+
 type PayrollPeriod struct {
-	ID          uint       `json:"id" gorm:"primaryKey"`
-	CompanyID   uint       `json:"company_id"`
-	Company     Company    `json:"company,omitempty" gorm:"foreignKey:CompanyID"`
-	Year        int        `json:"year"`
-	Month       int        `json:"month"`
-	StartDate   time.Time  `json:"start_date"`
-	EndDate     time.Time  `json:"end_date"`
-	Status      string     `json:"status" gorm:"default:'draft'"` // draft, processing, processed, approved, paid
-	Description string     `json:"description"`
-	ProcessedAt *time.Time `json:"processed_at"`
-	ProcessedBy *uint      `json:"processed_by"`
-	ApprovedAt  *time.Time `json:"approved_at"`
-	ApprovedBy  *uint      `json:"approved_by"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	CompanyID     uint       `json:"company_id"`
+	Company       Company    `json:"company,omitempty" gorm:"foreignKey:CompanyID"`
+	PeriodType    string     `json:"period_type" gorm:"default:'regular'"` // regular, bonus, correction, termination
+	Year          int        `json:"year"`
+	Month         int        `json:"month"`
+	StartDate     time.Time  `json:"start_date"`
+	EndDate       time.Time  `json:"end_date"`
+	Status        string     `json:"status" gorm:"default:'draft'"` // draft, processing, processed, approved, paid
+	Description   string     `json:"description"`
+	ProcessedAt   *time.Time `json:"processed_at"`
+	ProcessedBy   *uint      `json:"processed_by"`
+	ApprovedAt    *time.Time `json:"approved_at"`
+	ApprovedBy    *uint      `json:"approved_by"`
+	ApprovalLevel int        `json:"approval_level" gorm:"default:0"` // Approval levels completed so far
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 
 	// Relationships
-	Payslips []Payslip `json:"payslips,omitempty" gorm:"foreignKey:PayrollPeriodID"`
+	Payslips  []Payslip               `json:"payslips,omitempty" gorm:"foreignKey:PayrollPeriodID"`
+	Approvals []PayrollApproval       `json:"approvals,omitempty" gorm:"foreignKey:PayrollPeriodID"`
+	Employees []PayrollPeriodEmployee `json:"employees,omitempty" gorm:"foreignKey:PayrollPeriodID"`
+}
+
+// IsOffCycle reports whether the period pays selected employees outside the regular run.
+func (p *PayrollPeriod) IsOffCycle() bool {
+	return p.PeriodType != "" && p.PeriodType != PeriodTypeRegular
+}
+
+// PayrollPeriodEmployee is an employee included in a bonus or correction period, with
+// the amounts to pay them in their own currency.
+type PayrollPeriodEmployee struct {
+	ID              uint            `json:"id" gorm:"primaryKey"`
+	PayrollPeriodID uint            `json:"payroll_period_id" gorm:"uniqueIndex:idx_period_employee"`
+	EmployeeID      uint            `json:"employee_id" gorm:"uniqueIndex:idx_period_employee"`
+	Employee        Employee        `json:"employee,omitempty" gorm:"foreignKey:EmployeeID"`
+	Bonus           decimal.Decimal `json:"bonus" gorm:"type:decimal(15,2)"`
+	OtherEarnings   decimal.Decimal `json:"other_earnings" gorm:"type:decimal(15,2)"`
+	OtherDeductions decimal.Decimal `json:"other_deductions" gorm:"type:decimal(15,2)"`
+	TaxFreeAmount   decimal.Decimal `json:"tax_free_amount" gorm:"type:decimal(15,2)"` // Part of the bonus exempt from PAYE
+	Description     string          `json:"description"`
+	CreatedAt       time.Time       `json:"created_at"`
 }
 
 type Payslip struct {
 	ID              uint          `json:"id" gorm:"primaryKey"`
 	CompanyID       uint          `json:"company_id"`
 	Company         Company       `json:"company,omitempty" gorm:"foreignKey:CompanyID"`
-	EmployeeID      uint          `json:"employee_id"`
-	PayrollPeriodID uint          `json:"payroll_period_id"`
+	EmployeeID      uint          `json:"employee_id" gorm:"uniqueIndex:payslips_employee_id_payroll_period_id_key"`
+	PayrollPeriodID uint          `json:"payroll_period_id" gorm:"uniqueIndex:payslips_employee_id_payroll_period_id_key"`
 	Employee        Employee      `json:"employee" gorm:"foreignKey:EmployeeID"`
 	PayrollPeriod   PayrollPeriod `json:"payroll_period" gorm:"foreignKey:PayrollPeriodID"`
 
+	// Employee details as they were when the payslip was generated. Payslips and
+	// reports read these so later changes to the employee do not rewrite history.
+	EmployeeNumber string `json:"employee_number"`
+	EmployeeName   string `json:"employee_name"`
+	NationalID     string `json:"national_id"`
+	TaxNumber      string `json:"tax_number"`
+	PositionTitle  string `json:"position_title"`
+	DepartmentName string `json:"department_name"`
+	CostCentre     string `json:"cost_centre"`
+	PaymentMethod  string `json:"payment_method"`
+	BankName       string `json:"bank_name"`
+	BankBranch     string `json:"bank_branch"`
+	BankCode       string `json:"bank_code"`
+	BankAccount    string `json:"bank_account"`
+	SwiftCode      string `json:"swift_code"`
+	IsContractor   bool   `json:"is_contractor"` // Issued as a remittance advice rather than a payslip
+
 	// Currency Information
-	CurrencyID   uint     `json:"currency_id"`
-	Currency     Currency `json:"currency" gorm:"foreignKey:CurrencyID"`
-	ExchangeRate float64  `json:"exchange_rate" gorm:"type:decimal(15,6)"` // Rate to base currency
+	CurrencyID      uint            `json:"currency_id"`
+	Currency        Currency        `json:"currency" gorm:"foreignKey:CurrencyID"`
+	ExchangeRate    decimal.Decimal `json:"exchange_rate" gorm:"type:decimal(15,6)"` // Rate to base currency
+	TaxTableVersion string          `json:"tax_table_version"`                       // PAYE brackets used in the calculation
+
+	// Tax directive applied in place of the PAYE brackets, if any
+	TaxDirectiveID     *uint  `json:"tax_directive_id"`
+	TaxDirectiveNumber string `json:"tax_directive_number"`
 
 	// Earnings (in employee's currency)
-	BasicSalary   float64 `json:"basic_salary" gorm:"type:decimal(15,2)"`
-	Overtime      float64 `json:"overtime" gorm:"type:decimal(15,2)"`
-	Allowances    float64 `json:"allowances" gorm:"type:decimal(15,2)"`
-	Bonus         float64 `json:"bonus" gorm:"type:decimal(15,2)"`
-	Commission    float64 `json:"commission" gorm:"type:decimal(15,2)"`
-	OtherEarnings float64 `json:"other_earnings" gorm:"type:decimal(15,2)"`
-	TotalEarnings float64 `json:"total_earnings" gorm:"type:decimal(15,2)"`
+	BasicSalary   decimal.Decimal `json:"basic_salary" gorm:"type:decimal(15,2)"`
+	Overtime      decimal.Decimal `json:"overtime" gorm:"type:decimal(15,2)"`
+	Allowances    decimal.Decimal `json:"allowances" gorm:"type:decimal(15,2)"`
+	Bonus         decimal.Decimal `json:"bonus" gorm:"type:decimal(15,2)"`
+	Commission    decimal.Decimal `json:"commission" gorm:"type:decimal(15,2)"`
+	OtherEarnings decimal.Decimal `json:"other_earnings" gorm:"type:decimal(15,2)"`
+	BackPay       decimal.Decimal `json:"back_pay" gorm:"type:decimal(15,2)"`      // Arrears from backdated salary adjustments
+	LeavePay      decimal.Decimal `json:"leave_pay" gorm:"type:decimal(15,2)"`     // Accrued leave paid out on termination
+	NoticePay     decimal.Decimal `json:"notice_pay" gorm:"type:decimal(15,2)"`    // Pay in lieu of notice
+	SeverancePay  decimal.Decimal `json:"severance_pay" gorm:"type:decimal(15,2)"` // Severance by length of service
+	TotalEarnings decimal.Decimal `json:"total_earnings" gorm:"type:decimal(15,2)"`
+
+	// Hours, days or units paid at PayRate for employees not on a monthly salary; the
+	// resulting pay is reported as BasicSalary
+	PayQuantity float64         `json:"pay_quantity" gorm:"type:decimal(10,2)"`
+	PayRate     decimal.Decimal `json:"pay_rate" gorm:"type:decimal(15,4)"`
+
+	// Part of TotalEarnings exempt from PAYE, such as the tax-free portion of a bonus
+	TaxFreeEarnings decimal.Decimal `json:"tax_free_earnings" gorm:"type:decimal(15,2)"`
 
 	// Deductions (in employee's currency)
-	PayeeTax            float64 `json:"payee_tax" gorm:"type:decimal(15,2)"`
-	AidsLevy            float64 `json:"aids_levy" gorm:"type:decimal(15,2)"`
-	NSSAContribution    float64 `json:"nssa_contribution" gorm:"type:decimal(15,2)"`
-	PensionContribution float64 `json:"pension_contribution" gorm:"type:decimal(15,2)"`
-	MedicalAid          float64 `json:"medical_aid" gorm:"type:decimal(15,2)"`
-	UnionDues           float64 `json:"union_dues" gorm:"type:decimal(15,2)"`
-	LoanDeductions      float64 `json:"loan_deductions" gorm:"type:decimal(15,2)"`
-	OtherDeductions     float64 `json:"other_deductions" gorm:"type:decimal(15,2)"`
-	TotalDeductions     float64 `json:"total_deductions" gorm:"type:decimal(15,2)"`
+	PayeeTax            decimal.Decimal `json:"payee_tax" gorm:"type:decimal(15,2)"`
+	AidsLevy            decimal.Decimal `json:"aids_levy" gorm:"type:decimal(15,2)"`
+	NSSAContribution    decimal.Decimal `json:"nssa_contribution" gorm:"type:decimal(15,2)"`
+	WithholdingTax      decimal.Decimal `json:"withholding_tax" gorm:"type:decimal(15,2)"` // Contractors only, in place of PAYE and NSSA
+	WithholdingTaxRate  decimal.Decimal `json:"withholding_tax_rate" gorm:"type:decimal(5,2)"`
+	PensionContribution decimal.Decimal `json:"pension_contribution" gorm:"type:decimal(15,2)"`
+	MedicalAid          decimal.Decimal `json:"medical_aid" gorm:"type:decimal(15,2)"`
+	UnionDues           decimal.Decimal `json:"union_dues" gorm:"type:decimal(15,2)"`
+	LoanDeductions      decimal.Decimal `json:"loan_deductions" gorm:"type:decimal(15,2)"`
+	OtherDeductions     decimal.Decimal `json:"other_deductions" gorm:"type:decimal(15,2)"`
+	TotalDeductions     decimal.Decimal `json:"total_deductions" gorm:"type:decimal(15,2)"`
 
 	// Net Pay (in employee's currency)
-	NetPay float64 `json:"net_pay" gorm:"type:decimal(15,2)"`
+	NetPay decimal.Decimal `json:"net_pay" gorm:"type:decimal(15,2)"`
+
+	// Employer Contributions (in employee's currency, not deducted from net pay)
+	EmployerNSSA decimal.Decimal `json:"employer_nssa" gorm:"type:decimal(15,2)"`
 
 	// Base Currency Amounts (for reporting)
-	TotalEarningsBase   float64 `json:"total_earnings_base" gorm:"type:decimal(15,2)"`
-	TotalDeductionsBase float64 `json:"total_deductions_base" gorm:"type:decimal(15,2)"`
-	NetPayBase          float64 `json:"net_pay_base" gorm:"type:decimal(15,2)"`
+	TotalEarningsBase   decimal.Decimal `json:"total_earnings_base" gorm:"type:decimal(15,2)"`
+	TotalDeductionsBase decimal.Decimal `json:"total_deductions_base" gorm:"type:decimal(15,2)"`
+	NetPayBase          decimal.Decimal `json:"net_pay_base" gorm:"type:decimal(15,2)"`
 
 	// Working Days
 	WorkingDays int `json:"working_days"`
@@ -81,50 +156,119 @@ type Payslip struct {
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relationships
+	NetPaySplits      []PayslipNetPay          `json:"net_pay_splits,omitempty" gorm:"foreignKey:PayslipID"`
+	SalaryAdjustments []SalaryAdjustment       `json:"salary_adjustments,omitempty" gorm:"foreignKey:AppliedPayslipID"`
+	CalculationSteps  []PayslipCalculationStep `json:"calculation_steps,omitempty" gorm:"foreignKey:PayslipID"`
+}
+
+// SnapshotEmployee copies the employee details printed on the payslip. The employee's
+// Position and Department must be loaded.
+func (p *Payslip) SnapshotEmployee(employee Employee) {
+	p.EmployeeNumber = employee.EmployeeNumber
+	p.EmployeeName = employee.FullName()
+	p.NationalID = employee.NationalID
+	p.TaxNumber = employee.TaxNumber
+	p.PositionTitle = employee.Position.Title
+	p.DepartmentName = employee.Department.Name
+	p.CostCentre = employee.Department.CostCentre
+	p.PaymentMethod = employee.PaymentMethod
+	p.BankName = employee.BankName
+	p.BankBranch = employee.BankBranch
+	p.BankCode = employee.BankCode
+	p.BankAccount = employee.BankAccount
+	p.SwiftCode = employee.SwiftCode
+	p.IsContractor = employee.IsContractor()
+}
+
+// PayslipNetPay is the part of a payslip's net pay paid out in one currency
+// for employees whose salary is split across currencies.
+type PayslipNetPay struct {
+	ID                       uint            `json:"id" gorm:"primaryKey"`
+	PayslipID                uint            `json:"payslip_id"`
+	CurrencyID               uint            `json:"currency_id"`
+	Currency                 Currency        `json:"currency" gorm:"foreignKey:CurrencyID"`
+	ExchangeRate             decimal.Decimal `json:"exchange_rate" gorm:"type:decimal(15,6)"` // Payslip currency to this currency
+	GrossAmount              decimal.Decimal `json:"gross_amount" gorm:"type:decimal(15,2)"`  // Salary component in this currency
+	NetAmount                decimal.Decimal `json:"net_amount" gorm:"type:decimal(15,2)"`    // Amount paid in this currency
+	NetAmountPayslipCurrency decimal.Decimal `json:"net_amount_payslip_currency" gorm:"type:decimal(15,2)"`
+	CreatedAt                time.Time       `json:"created_at"`
+}
+
+// Sections of a payslip calculation trace, in the order they are worked out
+const (
+	TraceSectionEarnings   = "earnings"
+	TraceSectionTaxable    = "taxable_base"
+	TraceSectionTax        = "tax"
+	TraceSectionCredits    = "credits"
+	TraceSectionDeductions = "deductions"
+	TraceSectionNetPay     = "net_pay"
+)
+
+// PayslipCalculationStep is one step in working out a payslip, recorded when the payslip
+// is calculated so its figures can be explained later. Amount is in Currency; when the
+// step converts an amount, SourceAmount, SourceCurrency and ExchangeRate show the
+// conversion. Rate is a percentage, or the unit rate for time worked, applied to Basis.
+type PayslipCalculationStep struct {
+	ID             uint            `json:"id" gorm:"primaryKey"`
+	PayslipID      uint            `json:"payslip_id" gorm:"index"`
+	Sequence       int             `json:"sequence"`
+	Section        string          `json:"section"`   // earnings, taxable_base, tax, credits, deductions, net_pay
+	Component      string          `json:"component"` // basic_salary, allowance, paye_bracket, ...
+	Description    string          `json:"description"`
+	SourceAmount   decimal.Decimal `json:"source_amount,omitempty" gorm:"type:decimal(15,2)"`
+	SourceCurrency string          `json:"source_currency,omitempty"`
+	ExchangeRate   float64         `json:"exchange_rate,omitempty" gorm:"type:decimal(15,6)"`
+	Basis          float64         `json:"basis,omitempty" gorm:"type:decimal(15,2)"`
+	Rate           float64         `json:"rate,omitempty" gorm:"type:decimal(15,4)"`
+	Amount         decimal.Decimal `json:"amount" gorm:"type:decimal(15,2)"`
+	Currency       string          `json:"currency"`
+	CreatedAt      time.Time       `json:"created_at"`
 }
 
 type Allowance struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	CompanyID   uint           `json:"company_id"`
-	Company     Company        `json:"company,omitempty" gorm:"foreignKey:CompanyID"`
-	EmployeeID  uint           `json:"employee_id"`
-	Employee    Employee       `json:"employee" gorm:"foreignKey:EmployeeID"`
-	Name        string         `json:"name" gorm:"not null"`
-	Description string         `json:"description"`
-	Amount      float64        `json:"amount" gorm:"type:decimal(15,2)"`
-	CurrencyID  uint           `json:"currency_id"`
-	Currency    Currency       `json:"currency" gorm:"foreignKey:CurrencyID"`
-	IsFixed     bool           `json:"is_fixed" gorm:"default:true"`
-	Percentage  float64        `json:"percentage" gorm:"type:decimal(5,2)"`
-	IsTaxable   bool           `json:"is_taxable" gorm:"default:true"`
-	IsRecurring bool           `json:"is_recurring" gorm:"default:true"`
-	StartDate   time.Time      `json:"start_date"`
-	EndDate     *time.Time     `json:"end_date"`
-	IsActive    bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	CompanyID   uint            `json:"company_id"`
+	Company     Company         `json:"company,omitempty" gorm:"foreignKey:CompanyID"`
+	EmployeeID  uint            `json:"employee_id"`
+	Employee    Employee        `json:"employee" gorm:"foreignKey:EmployeeID"`
+	Name        string          `json:"name" gorm:"not null"`
+	Description string          `json:"description"`
+	Amount      decimal.Decimal `json:"amount" gorm:"type:decimal(15,2)"`
+	CurrencyID  uint            `json:"currency_id"`
+	Currency    Currency        `json:"currency" gorm:"foreignKey:CurrencyID"`
+	IsFixed     bool            `json:"is_fixed" gorm:"default:true"`
+	Percentage  float64         `json:"percentage" gorm:"type:decimal(5,2)"`
+	IsTaxable   bool            `json:"is_taxable" gorm:"default:true"`
+	IsRecurring bool            `json:"is_recurring" gorm:"default:true"`
+	StartDate   time.Time       `json:"start_date"`
+	EndDate     *time.Time      `json:"end_date"`
+	IsActive    bool            `json:"is_active" gorm:"default:true"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt  `json:"-" gorm:"index"`
 }
 
 type Deduction struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	CompanyID   uint           `json:"company_id"`
-	Company     Company        `json:"company,omitempty" gorm:"foreignKey:CompanyID"`
-	EmployeeID  uint           `json:"employee_id"`
-	Employee    Employee       `json:"employee" gorm:"foreignKey:EmployeeID"`
-	Name        string         `json:"name" gorm:"not null"`
-	Description string         `json:"description"`
-	Amount      float64        `json:"amount" gorm:"type:decimal(15,2)"`
-	CurrencyID  uint           `json:"currency_id"`
-	Currency    Currency       `json:"currency" gorm:"foreignKey:CurrencyID"`
-	IsFixed     bool           `json:"is_fixed" gorm:"default:true"`
-	Percentage  float64        `json:"percentage" gorm:"type:decimal(5,2)"`
-	IsStatutory bool           `json:"is_statutory" gorm:"default:false"`
-	IsRecurring bool           `json:"is_recurring" gorm:"default:true"`
-	StartDate   time.Time      `json:"start_date"`
-	EndDate     *time.Time     `json:"end_date"`
-	IsActive    bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	CompanyID   uint            `json:"company_id"`
+	Company     Company         `json:"company,omitempty" gorm:"foreignKey:CompanyID"`
+	EmployeeID  uint            `json:"employee_id"`
+	Employee    Employee        `json:"employee" gorm:"foreignKey:EmployeeID"`
+	Name        string          `json:"name" gorm:"not null"`
+	Description string          `json:"description"`
+	Amount      decimal.Decimal `json:"amount" gorm:"type:decimal(15,2)"`
+	CurrencyID  uint            `json:"currency_id"`
+	Currency    Currency        `json:"currency" gorm:"foreignKey:CurrencyID"`
+	IsFixed     bool            `json:"is_fixed" gorm:"default:true"`
+	Percentage  float64         `json:"percentage" gorm:"type:decimal(5,2)"`
+	IsStatutory bool            `json:"is_statutory" gorm:"default:false"`
+	IsRecurring bool            `json:"is_recurring" gorm:"default:true"`
+	StartDate   time.Time       `json:"start_date"`
+	EndDate     *time.Time      `json:"end_date"`
+	IsActive    bool            `json:"is_active" gorm:"default:true"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt  `json:"-" gorm:"index"`
 }
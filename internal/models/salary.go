@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// SalaryComponent splits an employee's basic salary across currencies.
+// A component is either a fixed amount in its own currency or a percentage of BasicSalary.
+type SalaryComponent struct {
+	ID           uint            `json:"id" gorm:"primaryKey"`
+	CompanyID    uint            `json:"company_id"`
+	Company      Company         `json:"company,omitempty" gorm:"foreignKey:CompanyID"`
+	EmployeeID   uint            `json:"employee_id"`
+	Name         string          `json:"name"`
+	CurrencyID   uint            `json:"currency_id"`
+	Currency     Currency        `json:"currency" gorm:"foreignKey:CurrencyID"`
+	Amount       decimal.Decimal `json:"amount" gorm:"type:decimal(15,2)"`
+	Percentage   float64         `json:"percentage" gorm:"type:decimal(5,2)"`
+	IsPercentage bool            `json:"is_percentage" gorm:"default:false"`
+	IsActive     bool            `json:"is_active" gorm:"default:true"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt  `json:"-" gorm:"index"`
+}
+
+// Compensation change reasons
+const (
+	CompensationReasonPromotion      = "promotion"
+	CompensationReasonAnnualIncrease = "annual_increase"
+	CompensationReasonCorrection     = "correction"
+	CompensationReasonOther          = "other"
+)
+
+// ValidCompensationReason reports whether reason is one of the compensation change reasons.
+func ValidCompensationReason(reason string) bool {
+	switch reason {
+	case CompensationReasonPromotion, CompensationReasonAnnualIncrease, CompensationReasonCorrection, CompensationReasonOther:
+		return true
+	}
+	return false
+}
+
+// Salary band policies
+const (
+	SalaryBandPolicyWarn    = "warn"
+	SalaryBandPolicyEnforce = "enforce"
+)
+
+// CompensationChange records a change to an employee's basic salary or pay currency from
+// EffectiveDate. Changes dated in the future are held until they fall due; AppliedAt is set
+// once the employee record carries the new salary. Payroll works out the salary in force
+// for each period from this history.
+type CompensationChange struct {
+	ID                 uint            `json:"id" gorm:"primaryKey"`
+	CompanyID          uint            `json:"company_id" gorm:"index"`
+	EmployeeID         uint            `json:"employee_id" gorm:"index"`
+	PreviousSalary     decimal.Decimal `json:"previous_salary" gorm:"type:decimal(15,2)"`
+	NewSalary          decimal.Decimal `json:"new_salary" gorm:"type:decimal(15,2)"`
+	PreviousCurrencyID uint            `json:"previous_currency_id"`
+	CurrencyID         uint            `json:"currency_id"`
+	Currency           Currency        `json:"currency" gorm:"foreignKey:CurrencyID"`
+	EffectiveDate      time.Time       `json:"effective_date"`
+	Reason             string          `json:"reason"` // promotion, annual_increase, correction, other
+	Notes              string          `json:"notes"`
+	ApprovedBy         *uint           `json:"approved_by"`
+	SalaryAdjustmentID *uint           `json:"salary_adjustment_id"` // Back pay raised for a backdated change
+	SalaryBandOverride string          `json:"salary_band_override"` // Reason for a salary outside the position's band
+	AppliedAt          *time.Time      `json:"applied_at"`
+	CreatedBy          uint            `json:"created_by"`
+	CreatedAt          time.Time       `json:"created_at"`
+
+	// PositionID is the position whose band the new salary is checked against, when the
+	// employee is moving to it with the change; their current position otherwise
+	PositionID        uint   `json:"position_id,omitempty" gorm:"-"`
+	SalaryBandWarning string `json:"salary_band_warning,omitempty" gorm:"-"`
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Salary review rule scopes, from the broadest to the most specific. An employee gets the
+// increase from the most specific rule that covers them.
+const (
+	ReviewScopeCompany    = "company"
+	ReviewScopeDepartment = "department"
+	ReviewScopePosition   = "position"
+	ReviewScopeEmployee   = "employee"
+)
+
+// Salary review increase types
+const (
+	ReviewIncreasePercentage = "percentage"
+	ReviewIncreaseAmount     = "amount"
+)
+
+// SalaryReview is a salary review cycle. HR sets increase rules, which generate a proposal
+// per employee; once submitted the review goes through the company's approval steps and
+// the final approval records a compensation change for every proposal.
+type SalaryReview struct {
+	ID            uint                   `json:"id" gorm:"primaryKey"`
+	CompanyID     uint                   `json:"company_id" gorm:"index"`
+	Name          string                 `json:"name" gorm:"not null"`
+	EffectiveDate time.Time              `json:"effective_date"`
+	Reason        string                 `json:"reason" gorm:"default:'annual_increase'"` // Compensation change reason recorded on approval
+	Budget        decimal.Decimal        `json:"budget" gorm:"type:decimal(15,2)"`        // Annual increase budget in base currency, zero for none
+	Status        string                 `json:"status" gorm:"default:'draft'"`           // draft, submitted, approved
+	ApprovalLevel int                    `json:"approval_level" gorm:"default:0"`
+	SubmittedBy   *uint                  `json:"submitted_by"`
+	SubmittedAt   *time.Time             `json:"submitted_at"`
+	ApprovedBy    *uint                  `json:"approved_by"`
+	ApprovedAt    *time.Time             `json:"approved_at"`
+	CreatedBy     uint                   `json:"created_by"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+	Rules         []SalaryReviewRule     `json:"rules,omitempty" gorm:"foreignKey:ReviewID"`
+	Proposals     []SalaryReviewProposal `json:"proposals,omitempty" gorm:"foreignKey:ReviewID"`
+	Approvals     []SalaryReviewApproval `json:"approvals,omitempty" gorm:"foreignKey:ReviewID"`
+}
+
+// SalaryReviewRule proposes an increase for everyone in its scope. Value is a percentage,
+// or an amount in each employee's own currency.
+type SalaryReviewRule struct {
+	ID       uint            `json:"id" gorm:"primaryKey"`
+	ReviewID uint            `json:"review_id" gorm:"index"`
+	Scope    string          `json:"scope"`    // company, department, position, employee
+	ScopeID  *uint           `json:"scope_id"` // Department, position or employee; nil for company
+	Type     string          `json:"type"`     // percentage, amount
+	Value    decimal.Decimal `json:"value" gorm:"type:decimal(15,2)"`
+}
+
+// SalaryReviewProposal is one employee's proposed salary. Overridden proposals were edited
+// by hand and keep their salary when the rules change. Once the review is approved each
+// proposal carries the compensation change it created, or the error that prevented it.
+type SalaryReviewProposal struct {
+	ID                   uint            `json:"id" gorm:"primaryKey"`
+	ReviewID             uint            `json:"review_id" gorm:"index"`
+	EmployeeID           uint            `json:"employee_id" gorm:"index"`
+	Employee             Employee        `json:"employee,omitempty" gorm:"foreignKey:EmployeeID"`
+	DepartmentID         uint            `json:"department_id"`
+	CurrencyID           uint            `json:"currency_id"`
+	Currency             Currency        `json:"currency" gorm:"foreignKey:CurrencyID"`
+	CurrentSalary        decimal.Decimal `json:"current_salary" gorm:"type:decimal(15,2)"`
+	ProposedSalary       decimal.Decimal `json:"proposed_salary" gorm:"type:decimal(15,2)"`
+	Increase             decimal.Decimal `json:"increase" gorm:"type:decimal(15,2)"`
+	IncreasePercent      float64         `json:"increase_percent" gorm:"type:decimal(7,2)"`
+	RuleScope            string          `json:"rule_scope"`
+	Overridden           bool            `json:"overridden"`
+	Notes                string          `json:"notes"`
+	CompensationChangeID *uint           `json:"compensation_change_id"`
+	Error                string          `json:"error,omitempty"`
+}
+
+// SalaryReviewApproval records each approval or rejection of a salary review.
+type SalaryReviewApproval struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CompanyID uint      `json:"company_id"`
+	ReviewID  uint      `json:"review_id" gorm:"index"`
+	Level     int       `json:"level"`
+	Action    string    `json:"action"` // approved, rejected
+	UserID    uint      `json:"user_id"`
+	Role      string    `json:"role"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FinalSettlement is the terminal pay worked out when an employee leaves. It is paid
+// through its own termination PayrollPeriod, which is approved like any other period.
+// Amounts are in the employee's currency.
+type FinalSettlement struct {
+	ID              uint          `json:"id" gorm:"primaryKey"`
+	CompanyID       uint          `json:"company_id" gorm:"index"`
+	EmployeeID      uint          `json:"employee_id" gorm:"index"`
+	Employee        Employee      `json:"employee,omitempty" gorm:"foreignKey:EmployeeID"`
+	PayrollPeriodID uint          `json:"payroll_period_id" gorm:"index"`
+	PayrollPeriod   PayrollPeriod `json:"payroll_period,omitempty" gorm:"foreignKey:PayrollPeriodID"`
+	PayslipID       *uint         `json:"payslip_id"`
+	Payslip         *Payslip      `json:"payslip,omitempty" gorm:"foreignKey:PayslipID"`
+	TerminationDate time.Time     `json:"termination_date"`
+	Reason          string        `json:"reason"` // resignation, dismissal, retrenchment, end_of_contract, retirement, death
+	NoticeDays      int           `json:"notice_days"`
+	CurrencyID      uint          `json:"currency_id"`
+	Currency        Currency      `json:"currency" gorm:"foreignKey:CurrencyID"`
+	Status          string        `json:"status" gorm:"default:'pending'"` // pending, processed, approved
+
+	DailyRate     decimal.Decimal `json:"daily_rate" gorm:"type:decimal(15,2)"`
+	ProRataDays   int             `json:"pro_rata_days"` // Working days in the final month not yet paid
+	ProRataSalary decimal.Decimal `json:"pro_rata_salary" gorm:"type:decimal(15,2)"`
+	LeaveDays     float64         `json:"leave_days" gorm:"type:decimal(8,2)"` // Accrued untaken leave paid out
+	LeavePay      decimal.Decimal `json:"leave_pay" gorm:"type:decimal(15,2)"`
+	NoticePay     decimal.Decimal `json:"notice_pay" gorm:"type:decimal(15,2)"`
+	ServiceYears  float64         `json:"service_years" gorm:"type:decimal(8,2)"`
+	SeverancePay  decimal.Decimal `json:"severance_pay" gorm:"type:decimal(15,2)"`
+	TotalEarnings decimal.Decimal `json:"total_earnings" gorm:"type:decimal(15,2)"`
+
+	PayeeTax         decimal.Decimal `json:"payee_tax" gorm:"type:decimal(15,2)"`
+	AidsLevy         decimal.Decimal `json:"aids_levy" gorm:"type:decimal(15,2)"`
+	NSSAContribution decimal.Decimal `json:"nssa_contribution" gorm:"type:decimal(15,2)"`
+	LoanRecovery     decimal.Decimal `json:"loan_recovery" gorm:"type:decimal(15,2)"`
+	LoanBalance      decimal.Decimal `json:"loan_balance" gorm:"type:decimal(15,2)"` // Loans still outstanding after recovery
+	NetPay           decimal.Decimal `json:"net_pay" gorm:"type:decimal(15,2)"`
+
+	CreatedBy uint      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EmployeeLoan is money advanced to an employee. The outstanding balance is recovered
+// from the final settlement when the employee leaves.
+type EmployeeLoan struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	CompanyID   uint            `json:"company_id" gorm:"index"`
+	EmployeeID  uint            `json:"employee_id" gorm:"index"`
+	Employee    Employee        `json:"employee,omitempty" gorm:"foreignKey:EmployeeID"`
+	Description string          `json:"description"`
+	CurrencyID  uint            `json:"currency_id"`
+	Currency    Currency        `json:"currency" gorm:"foreignKey:CurrencyID"`
+	Principal   decimal.Decimal `json:"principal" gorm:"type:decimal(15,2)"`
+	Balance     decimal.Decimal `json:"balance" gorm:"type:decimal(15,2)"`
+	IssuedDate  time.Time       `json:"issued_date"`
+	Status      string          `json:"status" gorm:"default:'active'"` // active, settled
+	CreatedBy   uint            `json:"created_by"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
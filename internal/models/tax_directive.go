@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Tax directive types. A fixed percentage directive taxes the employee's earnings at Rate
+// instead of the PAYE brackets; a fixed amount directive sets the PAYE for every payslip it
+// covers; a lump sum directive sets the PAYE on a single payment and is used up by it.
+const (
+	DirectiveTypeFixedPercentage = "fixed_percentage"
+	DirectiveTypeFixedAmount     = "fixed_amount"
+	DirectiveTypeLumpSum         = "lump_sum"
+)
+
+// DirectiveScopeAll applies a directive to every kind of payroll period; otherwise Scope is
+// a period type and the directive only applies to payslips in periods of that type.
+const DirectiveScopeAll = "all"
+
+// TaxDirective is an instruction from the revenue authority that overrides the PAYE
+// brackets for an employee. Amounts are in the employee's currency.
+type TaxDirective struct {
+	ID              uint            `json:"id" gorm:"primaryKey"`
+	CompanyID       uint            `json:"company_id" gorm:"uniqueIndex:idx_tax_directive_number"`
+	EmployeeID      uint            `json:"employee_id" gorm:"index"`
+	Employee        Employee        `json:"employee,omitempty" gorm:"foreignKey:EmployeeID"`
+	DirectiveNumber string          `json:"directive_number" gorm:"not null;uniqueIndex:idx_tax_directive_number"`
+	Type            string          `json:"type"`                             // fixed_percentage, fixed_amount, lump_sum
+	Scope           string          `json:"scope" gorm:"default:'all'"`       // all, regular, bonus, correction, termination
+	Rate            float64         `json:"rate" gorm:"type:decimal(5,2)"`    // Percentage, for fixed_percentage
+	Amount          decimal.Decimal `json:"amount" gorm:"type:decimal(15,2)"` // PAYE, for fixed_amount and lump_sum
+	ValidFrom       time.Time       `json:"valid_from"`
+	ValidTo         *time.Time      `json:"valid_to"`                       // Open-ended when nil
+	Status          string          `json:"status" gorm:"default:'active'"` // active, cancelled
+	Notes           string          `json:"notes"`
+	CreatedBy       uint            `json:"created_by"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// Covers reports whether the directive applies to a payslip in period. Lump sum directives
+// never cover regular runs, whatever their scope.
+func (d *TaxDirective) Covers(period PayrollPeriod) bool {
+	if d.Status != "active" {
+		return false
+	}
+	periodType := period.PeriodType
+	if periodType == "" {
+		periodType = PeriodTypeRegular
+	}
+	if d.Scope != "" && d.Scope != DirectiveScopeAll && d.Scope != periodType {
+		return false
+	}
+	if d.Type == DirectiveTypeLumpSum && periodType == PeriodTypeRegular {
+		return false
+	}
+	if d.ValidFrom.After(period.EndDate) {
+		return false
+	}
+	return d.ValidTo == nil || !d.ValidTo.Before(period.StartDate)
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// TimeEntry is work captured for an employee who is not on a monthly salary: hours for
+// hourly employees, days for daily employees and units for piece-rate employees. Approved
+// entries are paid at the employee's PayRate by the first regular run whose period ends on
+// or after the work date, which records itself on the entry.
+type TimeEntry struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	CompanyID     uint       `json:"company_id" gorm:"index"`
+	EmployeeID    uint       `json:"employee_id" gorm:"index"`
+	Employee      Employee   `json:"employee,omitempty" gorm:"foreignKey:EmployeeID"`
+	WorkDate      time.Time  `json:"work_date" gorm:"index"`
+	Quantity      float64    `json:"quantity" gorm:"type:decimal(10,2)"` // Hours, days or units, by the employee's pay type
+	Description   string     `json:"description"`
+	Status        string     `json:"status" gorm:"default:'pending'"` // pending, approved, rejected
+	ReviewedBy    *uint      `json:"reviewed_by"`
+	ReviewedAt    *time.Time `json:"reviewed_at"`
+	PaidPeriodID  *uint      `json:"paid_period_id"`
+	PaidPayslipID *uint      `json:"paid_payslip_id" gorm:"index"`
+	CreatedBy     uint       `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
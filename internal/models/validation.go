@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// PayrollValidationAcknowledgement records that a pre-payroll warning was reviewed and
+// accepted for a period, so it no longer blocks processing.
+type PayrollValidationAcknowledgement struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	CompanyID       uint      `json:"company_id"`
+	PayrollPeriodID uint      `json:"payroll_period_id" gorm:"uniqueIndex:idx_validation_ack_period_issue"`
+	IssueKey        string    `json:"issue_key" gorm:"uniqueIndex:idx_validation_ack_period_issue"`
+	Comment         string    `json:"comment"`
+	AcknowledgedBy  uint      `json:"acknowledged_by"`
+	CreatedAt       time.Time `json:"created_at"`
+}
@@ -0,0 +1,108 @@
+// Package money holds the rules for payroll amounts, which are exact decimals rather than
+// floats so totals always agree with the decimal(15,2) columns they are stored in.
+package money
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	// Amounts stay JSON numbers in API responses rather than quoted strings
+	decimal.MarshalJSONWithoutQuotes = true
+}
+
+// Rounding modes
+const (
+	RoundHalfUp   = "half_up"
+	RoundHalfEven = "half_even" // Banker's rounding
+)
+
+// Rounding levels. Per line rounds each item, such as one allowance or one deduction,
+// before it is added to the payslip; per total adds items exactly and rounds their total.
+const (
+	RoundPerLine  = "line"
+	RoundPerTotal = "total"
+)
+
+// minorUnits lists currencies whose smallest unit is not the cent
+var minorUnits = map[string]int32{
+	"JPY": 0,
+	"KRW": 0,
+	"RWF": 0,
+	"UGX": 0,
+	"XAF": 0,
+	"XOF": 0,
+	"BHD": 3,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+}
+
+var hundred = decimal.NewFromInt(100)
+
+// Places returns the number of decimal places amounts in a currency are rounded to
+func Places(currency string) int32 {
+	if places, ok := minorUnits[currency]; ok {
+		return places
+	}
+	return 2
+}
+
+// Rounding is a company's rule for rounding payroll amounts. The zero value rounds half up,
+// per line.
+type Rounding struct {
+	Mode  string // half_up or half_even
+	Level string // line or total
+}
+
+// ValidMode reports whether mode is a rounding mode, empty meaning the default
+func ValidMode(mode string) bool {
+	return mode == "" || mode == RoundHalfUp || mode == RoundHalfEven
+}
+
+// ValidLevel reports whether level is a rounding level, empty meaning the default
+func ValidLevel(level string) bool {
+	return level == "" || level == RoundPerLine || level == RoundPerTotal
+}
+
+// Round rounds an amount to the smallest unit of its currency
+func (r Rounding) Round(amount decimal.Decimal, currency string) decimal.Decimal {
+	if r.Mode == RoundHalfEven {
+		return amount.RoundBank(Places(currency))
+	}
+	return amount.Round(Places(currency))
+}
+
+// Line rounds one item of a payslip line when the company rounds per line, and otherwise
+// leaves it exact so that only the line total is rounded.
+func (r Rounding) Line(amount decimal.Decimal, currency string) decimal.Decimal {
+	if r.Level == RoundPerTotal {
+		return amount
+	}
+	return r.Round(amount, currency)
+}
+
+// Sum adds amounts exactly
+func Sum(amounts ...decimal.Decimal) decimal.Decimal {
+	total := decimal.Zero
+	for _, amount := range amounts {
+		total = total.Add(amount)
+	}
+	return total
+}
+
+// FromFloat converts a float such as a salary, exchange rate or percentage held outside the
+// money paths. The float's shortest decimal representation is used, so 0.1 becomes 0.1.
+func FromFloat(value float64) decimal.Decimal {
+	return decimal.NewFromFloat(value)
+}
+
+// Percent returns rate percent of amount
+func Percent(amount decimal.Decimal, rate float64) decimal.Decimal {
+	return PercentOf(amount, FromFloat(rate))
+}
+
+// PercentOf returns rate percent of amount for a rate stored as a decimal
+func PercentOf(amount, rate decimal.Decimal) decimal.Decimal {
+	return amount.Mul(rate).Div(hundred)
+}
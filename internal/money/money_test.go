@@ -0,0 +1,74 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func amount(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+func TestRoundModes(t *testing.T) {
+	cases := []struct {
+		amount, halfUp, halfEven string
+	}{
+		{"2.345", "2.35", "2.34"},
+		{"2.355", "2.36", "2.36"},
+		{"-2.345", "-2.35", "-2.34"},
+		{"2.3449", "2.34", "2.34"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.halfUp, Rounding{Mode: RoundHalfUp}.Round(amount(c.amount), "USD").String(), c.amount)
+		assert.Equal(t, c.halfEven, Rounding{Mode: RoundHalfEven}.Round(amount(c.amount), "USD").String(), c.amount)
+	}
+	// The zero value rounds half up
+	assert.Equal(t, "2.35", Rounding{}.Round(amount("2.345"), "USD").String())
+}
+
+func TestPlaces(t *testing.T) {
+	assert.Equal(t, int32(2), Places("USD"))
+	assert.Equal(t, int32(2), Places("ZWG"))
+	assert.Equal(t, int32(0), Places("JPY"))
+	assert.Equal(t, int32(3), Places("KWD"))
+
+	assert.Equal(t, "1235", Rounding{}.Round(amount("1234.5"), "JPY").String())
+	assert.Equal(t, "1234", Rounding{Mode: RoundHalfEven}.Round(amount("1234.5"), "JPY").String())
+	assert.Equal(t, "1.235", Rounding{}.Round(amount("1.2345"), "KWD").String())
+}
+
+func TestLineRoundsOnlyPerLine(t *testing.T) {
+	items := []decimal.Decimal{amount("0.005"), amount("0.005"), amount("0.005")}
+
+	perLine := Rounding{Level: RoundPerLine}
+	lines := make([]decimal.Decimal, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, perLine.Line(item, "USD"))
+	}
+	assert.Equal(t, "0.03", perLine.Round(Sum(lines...), "USD").String())
+
+	perTotal := Rounding{Level: RoundPerTotal}
+	lines = lines[:0]
+	for _, item := range items {
+		lines = append(lines, perTotal.Line(item, "USD"))
+	}
+	assert.Equal(t, "0.015", Sum(lines...).String(), "items are left exact")
+	assert.Equal(t, "0.02", perTotal.Round(Sum(lines...), "USD").String())
+}
+
+func TestPercent(t *testing.T) {
+	assert.Equal(t, "45", Percent(amount("1000"), 4.5).String())
+	assert.Equal(t, "0.1", Percent(amount("1"), 10).String())
+	assert.Equal(t, "33.33", Percent(amount("100"), 33.33).String())
+	assert.True(t, Percent(amount("1000"), 0).IsZero())
+}
+
+func TestValidModeAndLevel(t *testing.T) {
+	assert.True(t, ValidMode(""))
+	assert.True(t, ValidMode(RoundHalfEven))
+	assert.False(t, ValidMode("half_down"))
+	assert.True(t, ValidLevel(RoundPerTotal))
+	assert.False(t, ValidLevel("payslip"))
+}
@@ -0,0 +1,152 @@
+package accounting
+
+import (
+	"encoding/csv"
+	"fmt"
+	"gm58-hr-backend/internal/money"
+	"io"
+
+	"github.com/shopspring/decimal"
+)
+
+// Supported journal export formats
+const (
+	FormatCSV        = "csv"
+	FormatXero       = "xero"
+	FormatQuickBooks = "quickbooks"
+	FormatSage       = "sage"
+)
+
+// ExportJournal writes the journal to w in the requested import format.
+func ExportJournal(w io.Writer, journal *Journal, format string) error {
+	writer := csv.NewWriter(w)
+
+	var err error
+	switch format {
+	case FormatCSV, "":
+		err = writeGenericCSV(writer, journal)
+	case FormatXero:
+		err = writeXero(writer, journal)
+	case FormatQuickBooks:
+		err = writeQuickBooks(writer, journal)
+	case FormatSage:
+		err = writeSage(writer, journal)
+	default:
+		return fmt.Errorf("unsupported journal format %s", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeGenericCSV(w *csv.Writer, journal *Journal) error {
+	if err := w.Write([]string{"Date", "Reference", "Account Code", "Account Name", "Cost Centre", "Description", "Debit", "Credit", "Currency"}); err != nil {
+		return err
+	}
+	for _, line := range journal.Lines {
+		if err := w.Write([]string{
+			journal.Date.Format("2006-01-02"),
+			journal.Reference,
+			line.AccountCode,
+			line.AccountName,
+			line.CostCentre,
+			line.Description,
+			formatAmount(line.Debit, journal.Currency),
+			formatAmount(line.Credit, journal.Currency),
+			journal.Currency,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeXero follows the Xero manual journal import template: debits are positive
+// amounts, credits negative, and the cost centre is a tracking category option.
+func writeXero(w *csv.Writer, journal *Journal) error {
+	if err := w.Write([]string{"*Narration", "*Date", "Description", "*AccountCode", "*TaxRate", "*Amount", "TrackingName1", "TrackingOption1"}); err != nil {
+		return err
+	}
+	for _, line := range journal.Lines {
+		amount := line.Debit.Sub(line.Credit)
+		if err := w.Write([]string{
+			"Payroll " + journal.Reference,
+			journal.Date.Format("02/01/2006"),
+			line.Description,
+			line.AccountCode,
+			"Tax Exempt",
+			formatAmount(amount, journal.Currency),
+			"Department",
+			line.CostCentre,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeQuickBooks follows the QuickBooks Online journal entry import layout,
+// with the cost centre carried as the QuickBooks class.
+func writeQuickBooks(w *csv.Writer, journal *Journal) error {
+	if err := w.Write([]string{"Journal No", "Journal Date", "Currency", "Account", "Debits", "Credits", "Description", "Class"}); err != nil {
+		return err
+	}
+	for _, line := range journal.Lines {
+		if err := w.Write([]string{
+			journal.Reference,
+			journal.Date.Format("01/02/2006"),
+			journal.Currency,
+			line.AccountCode,
+			formatOptionalAmount(line.Debit, journal.Currency),
+			formatOptionalAmount(line.Credit, journal.Currency),
+			line.Description,
+			line.CostCentre,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSage follows the Sage 50 audit trail import layout using JD/JC
+// (journal debit/credit) transactions with the non-vatable T9 tax code.
+func writeSage(w *csv.Writer, journal *Journal) error {
+	if err := w.Write([]string{"Type", "Nominal A/C Ref", "Department Code", "Date", "Reference", "Details", "Net Amount", "Tax Code", "Tax Amount"}); err != nil {
+		return err
+	}
+	for _, line := range journal.Lines {
+		transactionType, amount := "JD", line.Debit
+		if !line.Credit.IsZero() {
+			transactionType, amount = "JC", line.Credit
+		}
+		if err := w.Write([]string{
+			transactionType,
+			line.AccountCode,
+			line.CostCentre,
+			journal.Date.Format("02/01/2006"),
+			journal.Reference,
+			line.Description,
+			formatAmount(amount, journal.Currency),
+			"T9",
+			formatAmount(decimal.Zero, journal.Currency),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatAmount writes an amount to the currency's minor units
+func formatAmount(amount decimal.Decimal, currency string) string {
+	return amount.StringFixed(money.Places(currency))
+}
+
+func formatOptionalAmount(amount decimal.Decimal, currency string) string {
+	if amount.IsZero() {
+		return ""
+	}
+	return formatAmount(amount, currency)
+}
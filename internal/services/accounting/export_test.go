@@ -0,0 +1,61 @@
+package accounting
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden export files")
+
+func exportJournal() *Journal {
+	amount := func(value string) decimal.Decimal { return decimal.RequireFromString(value) }
+	return &Journal{
+		Reference: "PAY-2024-01",
+		Date:      time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		Currency:  "USD",
+		Lines: []JournalLine{
+			{AccountCode: "6000", AccountName: "Salaries", CostCentre: "Operations", Component: "basic_salary", Description: "basic_salary 2024-01", Debit: amount("1000")},
+			{AccountCode: "6100", AccountName: "Employer NSSA", CostCentre: "Operations", Component: "employer_nssa", Description: "employer_nssa 2024-01", Debit: amount("45")},
+			{AccountCode: "2210", AccountName: "PAYE payable", CostCentre: "Operations", Component: "paye_tax", Description: "paye_tax 2024-01", Credit: amount("150.5")},
+			{AccountCode: "2220", AccountName: "NSSA payable", CostCentre: "Operations", Component: "employer_nssa", Description: "employer_nssa 2024-01", Credit: amount("45")},
+			{AccountCode: "2300", AccountName: "Net wages payable", CostCentre: "Operations", Component: "net_pay", Description: "net_pay 2024-01", Credit: amount("849.5")},
+		},
+		TotalDebit:  amount("1045"),
+		TotalCredit: amount("1045"),
+	}
+}
+
+func TestExportJournalFormats(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatXero, FormatQuickBooks, FormatSage} {
+		t.Run(format, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, ExportJournal(&out, exportJournal(), format))
+
+			golden := filepath.Join("testdata", "journal_"+format+".golden")
+			if *update {
+				require.NoError(t, os.WriteFile(golden, out.Bytes(), 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), out.String())
+		})
+	}
+}
+
+func TestExportJournalWritesCurrencyMinorUnits(t *testing.T) {
+	journal := exportJournal()
+	journal.Currency = "JPY"
+	var out bytes.Buffer
+	require.NoError(t, ExportJournal(&out, journal, FormatCSV))
+	assert.Contains(t, out.String(), ",151,JPY\n", "150.5 rounds to whole yen")
+
+	assert.Error(t, ExportJournal(&out, journal, "pdf"))
+}
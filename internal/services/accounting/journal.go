@@ -0,0 +1,286 @@
+package accounting
+
+import (
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Component types used by GL account mappings
+const (
+	ComponentTypeEarning              = "earning"
+	ComponentTypeDeduction            = "deduction"
+	ComponentTypeEmployerContribution = "employer_contribution"
+	ComponentTypeNetPay               = "net_pay"
+)
+
+// Component describes a payslip amount that can be posted to the general ledger.
+type Component struct {
+	Name   string
+	Type   string
+	Amount func(p models.Payslip) decimal.Decimal
+}
+
+// Components lists every payslip amount that the journal posts, in posting order.
+// Earnings and employer contributions are debits; deductions and net pay are credits.
+var Components = []Component{
+	{"basic_salary", ComponentTypeEarning, func(p models.Payslip) decimal.Decimal { return p.BasicSalary }},
+	{"overtime", ComponentTypeEarning, func(p models.Payslip) decimal.Decimal { return p.Overtime }},
+	{"allowances", ComponentTypeEarning, func(p models.Payslip) decimal.Decimal { return p.Allowances }},
+	{"bonus", ComponentTypeEarning, func(p models.Payslip) decimal.Decimal { return p.Bonus }},
+	{"commission", ComponentTypeEarning, func(p models.Payslip) decimal.Decimal { return p.Commission }},
+	{"other_earnings", ComponentTypeEarning, func(p models.Payslip) decimal.Decimal { return p.OtherEarnings }},
+	{"back_pay", ComponentTypeEarning, func(p models.Payslip) decimal.Decimal { return p.BackPay }},
+	{"leave_pay", ComponentTypeEarning, func(p models.Payslip) decimal.Decimal { return p.LeavePay }},
+	{"notice_pay", ComponentTypeEarning, func(p models.Payslip) decimal.Decimal { return p.NoticePay }},
+	{"severance_pay", ComponentTypeEarning, func(p models.Payslip) decimal.Decimal { return p.SeverancePay }},
+	{"paye_tax", ComponentTypeDeduction, func(p models.Payslip) decimal.Decimal { return p.PayeeTax }},
+	{"aids_levy", ComponentTypeDeduction, func(p models.Payslip) decimal.Decimal { return p.AidsLevy }},
+	{"nssa_contribution", ComponentTypeDeduction, func(p models.Payslip) decimal.Decimal { return p.NSSAContribution }},
+	{"withholding_tax", ComponentTypeDeduction, func(p models.Payslip) decimal.Decimal { return p.WithholdingTax }},
+	{"pension_contribution", ComponentTypeDeduction, func(p models.Payslip) decimal.Decimal { return p.PensionContribution }},
+	{"medical_aid", ComponentTypeDeduction, func(p models.Payslip) decimal.Decimal { return p.MedicalAid }},
+	{"union_dues", ComponentTypeDeduction, func(p models.Payslip) decimal.Decimal { return p.UnionDues }},
+	{"loan_deductions", ComponentTypeDeduction, func(p models.Payslip) decimal.Decimal { return p.LoanDeductions }},
+	{"other_deductions", ComponentTypeDeduction, func(p models.Payslip) decimal.Decimal { return p.OtherDeductions }},
+	{"employer_nssa", ComponentTypeEmployerContribution, func(p models.Payslip) decimal.Decimal { return p.EmployerNSSA }},
+	{"net_pay", ComponentTypeNetPay, func(p models.Payslip) decimal.Decimal { return p.NetPay }},
+}
+
+// ComponentByName returns the journal component with the given name.
+func ComponentByName(name string) (Component, bool) {
+	for _, component := range Components {
+		if component.Name == name {
+			return component, true
+		}
+	}
+	return Component{}, false
+}
+
+type Journal struct {
+	CompanyID   uint            `json:"company_id"`
+	PeriodID    uint            `json:"period_id"`
+	Reference   string          `json:"reference"`
+	Date        time.Time       `json:"date"`
+	Currency    string          `json:"currency"`
+	Lines       []JournalLine   `json:"lines"`
+	TotalDebit  decimal.Decimal `json:"total_debit"`
+	TotalCredit decimal.Decimal `json:"total_credit"`
+}
+
+type JournalLine struct {
+	AccountCode string          `json:"account_code"`
+	AccountName string          `json:"account_name"`
+	CostCentre  string          `json:"cost_centre"`
+	Component   string          `json:"component"`
+	Description string          `json:"description"`
+	Debit       decimal.Decimal `json:"debit"`
+	Credit      decimal.Decimal `json:"credit"`
+}
+
+type JournalService struct {
+	db *gorm.DB
+}
+
+func NewJournalService(db *gorm.DB) *JournalService {
+	return &JournalService{
+		db: db,
+	}
+}
+
+// BuildPayrollJournal produces a balanced double-entry journal for an approved payroll period.
+// Amounts are posted in the company base currency and summarised per account and cost centre.
+func (js *JournalService) BuildPayrollJournal(periodID, companyID uint) (*Journal, error) {
+	var period models.PayrollPeriod
+	if err := js.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
+		return nil, fmt.Errorf("payroll period not found: %w", err)
+	}
+
+	if period.Status != "approved" && period.Status != "paid" {
+		return nil, fmt.Errorf("payroll period must be approved before exporting the journal")
+	}
+
+	var company models.Company
+	if err := js.db.Preload("BaseCurrency").First(&company, companyID).Error; err != nil {
+		return nil, fmt.Errorf("company not found: %w", err)
+	}
+	var settings models.CompanySettings
+	js.db.Where("company_id = ?", companyID).First(&settings)
+	rounding := settings.Rounding()
+	currency := company.BaseCurrency.Code
+
+	mappings, err := js.GetMappings(companyID)
+	if err != nil {
+		return nil, err
+	}
+	mappingByComponent := make(map[string]models.GLAccountMapping, len(mappings))
+	for _, mapping := range mappings {
+		mappingByComponent[mapping.Component] = mapping
+	}
+
+	var payslips []models.Payslip
+	if err := js.db.Where("payroll_period_id = ? AND company_id = ?", periodID, companyID).
+		Find(&payslips).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch payslips: %w", err)
+	}
+
+	type lineKey struct {
+		account    string
+		costCentre string
+		component  string
+		credit     bool
+	}
+	totals := make(map[lineKey]decimal.Decimal)
+	lines := make(map[lineKey]JournalLine)
+
+	post := func(code, name, costCentre, component, description string, amount decimal.Decimal, credit bool) {
+		key := lineKey{code, costCentre, component, credit}
+		totals[key] = totals[key].Add(amount)
+		if _, ok := lines[key]; !ok {
+			lines[key] = JournalLine{
+				AccountCode: code,
+				AccountName: name,
+				CostCentre:  costCentre,
+				Component:   component,
+				Description: description,
+			}
+		}
+	}
+
+	for _, payslip := range payslips {
+		costCentre := payslip.CostCentre
+		if costCentre == "" {
+			costCentre = payslip.DepartmentName
+		}
+
+		for _, component := range Components {
+			amount := component.Amount(payslip).Mul(payslip.ExchangeRate)
+			if amount.IsZero() {
+				continue
+			}
+
+			mapping, ok := mappingByComponent[component.Name]
+			if !ok {
+				return nil, fmt.Errorf("no GL account mapped for component %s", component.Name)
+			}
+
+			description := fmt.Sprintf("%s %04d-%02d", component.Name, period.Year, period.Month)
+			switch component.Type {
+			case ComponentTypeEarning:
+				post(mapping.AccountCode, mapping.AccountName, costCentre, component.Name, description, amount, false)
+			case ComponentTypeDeduction, ComponentTypeNetPay:
+				post(mapping.AccountCode, mapping.AccountName, costCentre, component.Name, description, amount, true)
+			case ComponentTypeEmployerContribution:
+				if mapping.ContraAccountCode == "" {
+					return nil, fmt.Errorf("no contra account mapped for employer contribution %s", component.Name)
+				}
+				post(mapping.AccountCode, mapping.AccountName, costCentre, component.Name, description, amount, false)
+				post(mapping.ContraAccountCode, mapping.ContraAccountName, costCentre, component.Name, description, amount, true)
+			}
+		}
+	}
+
+	journal := &Journal{
+		CompanyID: companyID,
+		PeriodID:  period.ID,
+		Reference: fmt.Sprintf("PAY-%04d-%02d", period.Year, period.Month),
+		Date:      period.EndDate,
+		Currency:  currency,
+	}
+
+	keys := make([]lineKey, 0, len(lines))
+	for key := range lines {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].credit != keys[j].credit {
+			return !keys[i].credit
+		}
+		if keys[i].account != keys[j].account {
+			return keys[i].account < keys[j].account
+		}
+		return keys[i].costCentre < keys[j].costCentre
+	})
+
+	for _, key := range keys {
+		line := lines[key]
+		amount := rounding.Round(totals[key], currency)
+		if key.credit {
+			line.Credit = amount
+			journal.TotalCredit = journal.TotalCredit.Add(amount)
+		} else {
+			line.Debit = amount
+			journal.TotalDebit = journal.TotalDebit.Add(amount)
+		}
+		journal.Lines = append(journal.Lines, line)
+	}
+
+	// Rounding each summarised line to the currency's smallest unit can leave a residual of
+	// a few units; absorb it in the largest net pay line so the journal always balances.
+	if diff := journal.TotalDebit.Sub(journal.TotalCredit); !diff.IsZero() {
+		largest := -1
+		for i, line := range journal.Lines {
+			if line.Component == "net_pay" && (largest == -1 || line.Credit.GreaterThan(journal.Lines[largest].Credit)) {
+				largest = i
+			}
+		}
+		if largest == -1 || diff.Abs().GreaterThan(decimal.New(int64(len(journal.Lines)), -money.Places(currency))) {
+			return nil, fmt.Errorf("payroll journal does not balance: difference %s", diff.StringFixed(money.Places(currency)))
+		}
+		journal.Lines[largest].Credit = journal.Lines[largest].Credit.Add(diff)
+		journal.TotalCredit = journal.TotalCredit.Add(diff)
+	}
+
+	return journal, nil
+}
+
+// GetMappings returns the chart-of-accounts mapping configured for a company.
+func (js *JournalService) GetMappings(companyID uint) ([]models.GLAccountMapping, error) {
+	var mappings []models.GLAccountMapping
+	if err := js.db.Where("company_id = ?", companyID).Order("component").Find(&mappings).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch GL mappings: %w", err)
+	}
+	return mappings, nil
+}
+
+// SaveMappings creates or replaces the mappings for the given components.
+func (js *JournalService) SaveMappings(companyID uint, mappings []models.GLAccountMapping) error {
+	return js.db.Transaction(func(tx *gorm.DB) error {
+		for _, mapping := range mappings {
+			component, ok := ComponentByName(mapping.Component)
+			if !ok {
+				return fmt.Errorf("unknown payroll component %s", mapping.Component)
+			}
+			if mapping.AccountCode == "" {
+				return fmt.Errorf("account code is required for component %s", mapping.Component)
+			}
+			if component.Type == ComponentTypeEmployerContribution && mapping.ContraAccountCode == "" {
+				return fmt.Errorf("contra account code is required for employer contribution %s", mapping.Component)
+			}
+
+			var existing models.GLAccountMapping
+			err := tx.Where("company_id = ? AND component = ?", companyID, mapping.Component).First(&existing).Error
+			if err != nil && err != gorm.ErrRecordNotFound {
+				return err
+			}
+
+			existing.CompanyID = companyID
+			existing.Component = component.Name
+			existing.ComponentType = component.Type
+			existing.AccountCode = mapping.AccountCode
+			existing.AccountName = mapping.AccountName
+			existing.ContraAccountCode = mapping.ContraAccountCode
+			existing.ContraAccountName = mapping.ContraAccountName
+
+			if err := tx.Save(&existing).Error; err != nil {
+				return fmt.Errorf("failed to save GL mapping for %s: %w", mapping.Component, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,105 @@
+package accounting
+
+import (
+	"testing"
+	"time"
+
+	"gm58-hr-backend/internal/database"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupJournalDB creates a USD company rounding in the given mode, with an approved
+// January 2024 period and GL accounts mapped for salary, PAYE, NSSA and net pay.
+func setupJournalDB(t *testing.T, roundingMode string) (*gorm.DB, models.PayrollPeriod) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, database.AutoMigrate(db))
+
+	usd := models.Currency{Code: "USD", Name: "US Dollar", Symbol: "$", IsActive: true, IsBaseCurrency: true}
+	require.NoError(t, db.Create(&usd).Error)
+	company := models.Company{Name: "Ledger Co", Code: "LEDGER", Email: "accounts@ledger.test", BaseCurrencyID: usd.ID}
+	require.NoError(t, db.Create(&company).Error)
+	require.NoError(t, db.Create(&models.CompanySettings{CompanyID: company.ID, RoundingMode: roundingMode}).Error)
+
+	require.NoError(t, NewJournalService(db).SaveMappings(company.ID, []models.GLAccountMapping{
+		{Component: "basic_salary", AccountCode: "6000", AccountName: "Salaries"},
+		{Component: "paye_tax", AccountCode: "2210", AccountName: "PAYE payable"},
+		{Component: "nssa_contribution", AccountCode: "2220", AccountName: "NSSA payable"},
+		{Component: "employer_nssa", AccountCode: "6100", AccountName: "Employer NSSA", ContraAccountCode: "2220", ContraAccountName: "NSSA payable"},
+		{Component: "net_pay", AccountCode: "2300", AccountName: "Net wages payable"},
+	}))
+
+	january := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	period := models.PayrollPeriod{
+		CompanyID: company.ID, Year: 2024, Month: 1, StartDate: january, EndDate: january.AddDate(0, 1, -1), Status: "approved",
+	}
+	require.NoError(t, db.Create(&period).Error)
+	return db, period
+}
+
+func addPayslip(t *testing.T, db *gorm.DB, period models.PayrollPeriod, costCentre string, exchangeRate float64, basic, paye, nssa float64) {
+	var payslips int64
+	require.NoError(t, db.Model(&models.Payslip{}).Count(&payslips).Error)
+	payslip := models.Payslip{
+		CompanyID: period.CompanyID, PayrollPeriodID: period.ID, EmployeeID: uint(payslips) + 1, CostCentre: costCentre, ExchangeRate: decimal.NewFromFloat(exchangeRate),
+		BasicSalary: decimal.NewFromFloat(basic), PayeeTax: decimal.NewFromFloat(paye), NSSAContribution: decimal.NewFromFloat(nssa),
+		EmployerNSSA: decimal.NewFromFloat(nssa), NetPay: decimal.NewFromFloat(basic - paye - nssa),
+	}
+	require.NoError(t, db.Create(&payslip).Error)
+}
+
+func TestBuildPayrollJournalBalances(t *testing.T) {
+	db, period := setupJournalDB(t, "")
+	addPayslip(t, db, period, "Operations", 1, 1000, 150, 45)
+	addPayslip(t, db, period, "Sales", 1, 800, 100, 36)
+
+	journal, err := NewJournalService(db).BuildPayrollJournal(period.ID, period.CompanyID)
+	require.NoError(t, err)
+	assert.Equal(t, "PAY-2024-01", journal.Reference)
+	assert.True(t, journal.TotalDebit.Equal(journal.TotalCredit))
+	// Salaries and employer NSSA on the debit side
+	assert.Equal(t, "1881", journal.TotalDebit.String())
+
+	_, err = NewJournalService(db).BuildPayrollJournal(period.ID, period.CompanyID+1)
+	assert.Error(t, err)
+}
+
+func TestBuildPayrollJournalAbsorbsResidualInNetPay(t *testing.T) {
+	// Converted at 0.5 the salary is 50.01, but PAYE and net pay come to 5.005 and 45.005
+	for mode, netPay := range map[string]string{money.RoundHalfUp: "45", money.RoundHalfEven: "45.01"} {
+		t.Run(mode, func(t *testing.T) {
+			db, period := setupJournalDB(t, mode)
+			addPayslip(t, db, period, "Operations", 0.5, 100.02, 10.01, 0)
+
+			journal, err := NewJournalService(db).BuildPayrollJournal(period.ID, period.CompanyID)
+			require.NoError(t, err)
+			assert.Equal(t, "50.01", journal.TotalDebit.String())
+			assert.Equal(t, "50.01", journal.TotalCredit.String())
+
+			amounts := make(map[string]string)
+			for _, line := range journal.Lines {
+				amounts[line.Component] = line.Debit.Add(line.Credit).String()
+			}
+			assert.Equal(t, netPay, amounts["net_pay"])
+		})
+	}
+}
+
+func TestBuildPayrollJournalNeedsApprovedPeriod(t *testing.T) {
+	db, period := setupJournalDB(t, "")
+	require.NoError(t, db.Model(&period).Update("status", "processed").Error)
+
+	_, err := NewJournalService(db).BuildPayrollJournal(period.ID, period.CompanyID)
+	assert.Error(t, err)
+}
@@ -2,11 +2,13 @@ package currency
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 	"gm58-hr-backend/internal/models"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -17,9 +19,9 @@ type CurrencyService struct {
 }
 
 type ExchangeRateResponse struct {
-	Base  string             `json:"base"`
-	Date  string             `json:"date"`
-	Rates map[string]float64 `json:"rates"`
+	Base  string                     `json:"base"`
+	Date  string                     `json:"date"`
+	Rates map[string]decimal.Decimal `json:"rates"`
 }
 
 func NewCurrencyService(db *gorm.DB, apiKey, apiURL string) *CurrencyService {
@@ -30,7 +32,7 @@ func NewCurrencyService(db *gorm.DB, apiKey, apiURL string) *CurrencyService {
 	}
 }
 
-func (cs *CurrencyService) GetExchangeRate(fromCurrency, toCurrency string) (float64, error) {
+func (cs *CurrencyService) GetExchangeRate(fromCurrency, toCurrency string) (decimal.Decimal, error) {
 	// First try to get from database (cached rates)
 	var exchangeRate models.ExchangeRate
 	err := cs.db.Joins("FromCurrency").Joins("ToCurrency").
@@ -46,7 +48,7 @@ func (cs *CurrencyService) GetExchangeRate(fromCurrency, toCurrency string) (flo
 	// If not found or outdated, fetch from API
 	rate, err := cs.fetchExchangeRateFromAPI(fromCurrency, toCurrency)
 	if err != nil {
-		return 0, err
+		return decimal.Zero, err
 	}
 
 	// Save to database for caching
@@ -55,29 +57,57 @@ func (cs *CurrencyService) GetExchangeRate(fromCurrency, toCurrency string) (flo
 	return rate, nil
 }
 
-func (cs *CurrencyService) fetchExchangeRateFromAPI(fromCurrency, toCurrency string) (float64, error) {
+// GetExchangeRateAt returns the latest stored rate effective on or before the given date.
+// Only a date that has not yet passed falls back to the current rate; a past date with no
+// recorded rate is an error rather than being converted at today's rate.
+func (cs *CurrencyService) GetExchangeRateAt(fromCurrency, toCurrency string, date time.Time) (decimal.Decimal, error) {
+	if fromCurrency == toCurrency {
+		return decimal.NewFromInt(1), nil
+	}
+
+	var exchangeRate models.ExchangeRate
+	err := cs.db.Joins("FromCurrency").Joins("ToCurrency").
+		Where("FromCurrency.code = ? AND ToCurrency.code = ? AND effective_date <= ?",
+			fromCurrency, toCurrency, date).
+		Order("effective_date DESC").
+		First(&exchangeRate).Error
+
+	if err == nil {
+		return exchangeRate.Rate, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return decimal.Zero, err
+	}
+	if date.Before(time.Now().Truncate(24 * time.Hour)) {
+		return decimal.Zero, fmt.Errorf("no %s to %s exchange rate recorded on or before %s", fromCurrency, toCurrency, date.Format("2006-01-02"))
+	}
+
+	return cs.GetExchangeRate(fromCurrency, toCurrency)
+}
+
+func (cs *CurrencyService) fetchExchangeRateFromAPI(fromCurrency, toCurrency string) (decimal.Decimal, error) {
 	url := fmt.Sprintf("%s%s", cs.apiURL, fromCurrency)
 	
 	resp, err := http.Get(url)
 	if err != nil {
-		return 0, err
+		return decimal.Zero, err
 	}
 	defer resp.Body.Close()
 
 	var response ExchangeRateResponse
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return 0, err
+		return decimal.Zero, err
 	}
 
 	rate, exists := response.Rates[toCurrency]
 	if !exists {
-		return 0, fmt.Errorf("exchange rate not found for %s to %s", fromCurrency, toCurrency)
+		return decimal.Zero, fmt.Errorf("exchange rate not found for %s to %s", fromCurrency, toCurrency)
 	}
 
 	return rate, nil
 }
 
-func (cs *CurrencyService) saveExchangeRate(fromCurrency, toCurrency string, rate float64) {
+func (cs *CurrencyService) saveExchangeRate(fromCurrency, toCurrency string, rate decimal.Decimal) {
 	var fromCurr, toCurr models.Currency
 	
 	cs.db.Where("code = ?", fromCurrency).First(&fromCurr)
@@ -94,17 +124,19 @@ func (cs *CurrencyService) saveExchangeRate(fromCurrency, toCurrency string, rat
 	cs.db.Create(&exchangeRate)
 }
 
-func (cs *CurrencyService) ConvertAmount(amount float64, fromCurrency, toCurrency string) (float64, error) {
+// ConvertMoney converts an exact amount at the current rate. The rate is the decimal it is
+// stored as, so the conversion adds no float error of its own.
+func (cs *CurrencyService) ConvertMoney(amount decimal.Decimal, fromCurrency, toCurrency string) (decimal.Decimal, error) {
 	if fromCurrency == toCurrency {
 		return amount, nil
 	}
 
 	rate, err := cs.GetExchangeRate(fromCurrency, toCurrency)
 	if err != nil {
-		return 0, err
+		return decimal.Zero, err
 	}
 
-	return amount * rate, nil
+	return amount.Mul(rate), nil
 }
 
 func (cs *CurrencyService) GetSupportedCurrencies() ([]models.Currency, error) {
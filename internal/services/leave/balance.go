@@ -0,0 +1,92 @@
+package leave
+
+import (
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LeaveService struct {
+	db *gorm.DB
+}
+
+func NewLeaveService(db *gorm.DB) *LeaveService {
+	return &LeaveService{db: db}
+}
+
+// Balance is an employee's leave position for one leave type in the leave year
+// containing AsOf. Days are working days.
+type Balance struct {
+	LeaveTypeID   uint      `json:"leave_type_id"`
+	LeaveTypeName string    `json:"leave_type_name"`
+	YearStart     time.Time `json:"year_start"`
+	AsOf          time.Time `json:"as_of"`
+	Entitlement   float64   `json:"entitlement"` // Days for the full leave year
+	Accrued       float64   `json:"accrued"`     // Days earned by AsOf
+	Taken         float64   `json:"taken"`       // Approved days starting in the leave year up to AsOf
+	Available     float64   `json:"available"`
+}
+
+// GetBalance accrues the leave type's yearly days evenly across the leave year, from the
+// hire date for employees who joined during it. Carry-forward from earlier years is not included.
+func (ls *LeaveService) GetBalance(employee models.Employee, leaveType models.LeaveType, asOf time.Time) (*Balance, error) {
+	var settings models.CompanySettings
+	ls.db.Where("company_id = ?", employee.CompanyID).First(&settings)
+
+	yearStart := LeaveYearStart(settings, asOf)
+	yearEnd := yearStart.AddDate(1, 0, 0)
+
+	accrualStart := yearStart
+	if hired, ok := employee.HiredOn(); ok && hired.After(accrualStart) {
+		accrualStart = hired
+	}
+
+	balance := &Balance{
+		LeaveTypeID:   leaveType.ID,
+		LeaveTypeName: leaveType.Name,
+		YearStart:     yearStart,
+		AsOf:          asOf,
+		Entitlement:   float64(leaveType.DaysPerYear),
+	}
+
+	if !accrualStart.After(asOf) {
+		elapsedDays := asOf.Sub(accrualStart).Hours()/24 + 1
+		yearDays := yearEnd.Sub(yearStart).Hours() / 24
+		balance.Accrued = roundDays(balance.Entitlement * elapsedDays / yearDays)
+	}
+
+	var taken float64
+	if err := ls.db.Model(&models.LeaveRequest{}).
+		Where("employee_id = ? AND leave_type_id = ? AND status = ?", employee.ID, leaveType.ID, "approved").
+		Where("start_date >= ? AND start_date <= ?", yearStart, asOf).
+		Select("COALESCE(SUM(days_requested), 0)").
+		Scan(&taken).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch leave taken: %w", err)
+	}
+	balance.Taken = taken
+	balance.Available = roundDays(balance.Accrued - balance.Taken)
+
+	return balance, nil
+}
+
+// LeaveYearStart returns the start of the company leave year containing date. Companies
+// without a configured start use the calendar year.
+func LeaveYearStart(settings models.CompanySettings, date time.Time) time.Time {
+	month, day := time.January, 1
+	if !settings.LeaveYearStart.IsZero() {
+		month, day = settings.LeaveYearStart.Month(), settings.LeaveYearStart.Day()
+	}
+
+	start := time.Date(date.Year(), month, day, 0, 0, 0, 0, date.Location())
+	if start.After(date) {
+		start = start.AddDate(-1, 0, 0)
+	}
+	return start
+}
+
+func roundDays(days float64) float64 {
+	return math.Round(days*100) / 100
+}
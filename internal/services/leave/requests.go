@@ -0,0 +1,250 @@
+package leave
+
+import (
+	"errors"
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"time"
+)
+
+var (
+	// ErrLeaveOverlap is returned when a request overlaps one of the employee's pending or
+	// approved requests.
+	ErrLeaveOverlap = errors.New("leave request overlaps an existing request")
+
+	// ErrInsufficientLeave is returned when a request would take the employee's balance below
+	// zero and the company does not allow negative leave.
+	ErrInsufficientLeave = errors.New("insufficient leave balance")
+
+	// ErrLeaveState is returned when a request is not in a status that allows the action.
+	ErrLeaveState = errors.New("leave request is not in a state that allows this")
+)
+
+// RequestFilter narrows the requests listed; zero values match everything.
+type RequestFilter struct {
+	EmployeeID uint
+	Status     string
+	From       time.Time // Requests ending on or after
+	To         time.Time // Requests starting on or before
+}
+
+// WorkingDays counts the days from start to end inclusive that fall in a work week of
+// workWeekDays days: Monday to Friday for five, Monday to Saturday for six, every day for seven.
+func WorkingDays(start, end time.Time, workWeekDays int) int {
+	if workWeekDays == 0 {
+		workWeekDays = 5
+	}
+
+	days := 0
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		switch {
+		case workWeekDays >= 7:
+			days++
+		case workWeekDays == 6:
+			if d.Weekday() != time.Sunday {
+				days++
+			}
+		default:
+			if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+				days++
+			}
+		}
+	}
+	return days
+}
+
+// CreateRequest applies for leave on an employee's behalf. The days requested are the working
+// days in the company's work week between the start and end dates. Leave types that do not
+// require approval are approved at once.
+func (ls *LeaveService) CreateRequest(companyID uint, request models.LeaveRequest) (*models.LeaveRequest, error) {
+	if request.StartDate.IsZero() || request.EndDate.IsZero() {
+		return nil, fmt.Errorf("start and end dates are required")
+	}
+	if request.EndDate.Before(request.StartDate) {
+		return nil, fmt.Errorf("end date cannot be before start date")
+	}
+
+	var employee models.Employee
+	if err := ls.db.Where("id = ? AND company_id = ?", request.EmployeeID, companyID).First(&employee).Error; err != nil {
+		return nil, err
+	}
+	if employee.EmploymentStatus == "terminated" {
+		return nil, fmt.Errorf("employee %s has been terminated", employee.EmployeeNumber)
+	}
+	var leaveType models.LeaveType
+	if err := ls.db.Where("id = ? AND company_id = ? AND is_active = ?", request.LeaveTypeID, companyID, true).First(&leaveType).Error; err != nil {
+		return nil, fmt.Errorf("leave type %d not found", request.LeaveTypeID)
+	}
+	var company models.Company
+	if err := ls.db.First(&company, companyID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get company: %w", err)
+	}
+
+	request.DaysRequested = WorkingDays(request.StartDate, request.EndDate, company.WorkWeekDays)
+	if request.DaysRequested == 0 {
+		return nil, fmt.Errorf("the request covers no working days")
+	}
+
+	var overlapping models.LeaveRequest
+	if err := ls.db.Where("employee_id = ? AND status IN ? AND start_date <= ? AND end_date >= ?",
+		employee.ID, []string{"pending", "approved"}, request.EndDate, request.StartDate).
+		First(&overlapping).Error; err == nil {
+		return nil, fmt.Errorf("%w: %s to %s", ErrLeaveOverlap,
+			overlapping.StartDate.Format("2006-01-02"), overlapping.EndDate.Format("2006-01-02"))
+	}
+
+	if err := ls.checkBalance(employee, leaveType, request, 0); err != nil {
+		return nil, err
+	}
+
+	request.ID = 0
+	request.CompanyID = companyID
+	request.Status = "pending"
+	request.ApprovedBy = nil
+	request.ApprovedAt = nil
+	request.RejectionReason = ""
+	if !leaveType.RequiresApproval {
+		now := time.Now()
+		request.Status = "approved"
+		request.ApprovedAt = &now
+	}
+	if err := ls.db.Omit("Employee", "LeaveType", "Company", "Approver").Create(&request).Error; err != nil {
+		return nil, fmt.Errorf("failed to save leave request: %w", err)
+	}
+	return ls.GetRequest(companyID, request.ID)
+}
+
+// GetRequests lists the company's leave requests, latest start first.
+func (ls *LeaveService) GetRequests(companyID uint, filter RequestFilter) ([]models.LeaveRequest, error) {
+	query := ls.db.Preload("Employee").Preload("LeaveType").Where("company_id = ?", companyID)
+	if filter.EmployeeID != 0 {
+		query = query.Where("employee_id = ?", filter.EmployeeID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("end_date >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("start_date <= ?", filter.To)
+	}
+
+	var requests []models.LeaveRequest
+	err := query.Order("start_date DESC, id DESC").Find(&requests).Error
+	return requests, err
+}
+
+// GetRequest returns one of the company's leave requests.
+func (ls *LeaveService) GetRequest(companyID, requestID uint) (*models.LeaveRequest, error) {
+	var request models.LeaveRequest
+	if err := ls.db.Preload("Employee").Preload("LeaveType").Preload("Approver").
+		Where("id = ? AND company_id = ?", requestID, companyID).
+		First(&request).Error; err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// CancelRequest withdraws a pending request, or an approved one that has not started.
+func (ls *LeaveService) CancelRequest(companyID, requestID uint) (*models.LeaveRequest, error) {
+	request, err := ls.GetRequest(companyID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case request.Status == "pending":
+	case request.Status == "approved" && request.StartDate.After(time.Now()):
+	default:
+		return nil, fmt.Errorf("%w: only pending requests and approved leave that has not started can be cancelled", ErrLeaveState)
+	}
+
+	request.Status = "cancelled"
+	if err := ls.db.Model(request).Update("status", request.Status).Error; err != nil {
+		return nil, fmt.Errorf("failed to cancel leave request: %w", err)
+	}
+	return request, nil
+}
+
+// ApproveRequest approves a pending request, checking the balance again in case other leave
+// was approved since it was made. approverID is the approving user's employee record, if any.
+func (ls *LeaveService) ApproveRequest(companyID, requestID uint, approverID *uint) (*models.LeaveRequest, error) {
+	request, err := ls.GetRequest(companyID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != "pending" {
+		return nil, fmt.Errorf("%w: only pending requests can be approved", ErrLeaveState)
+	}
+	if err := ls.checkBalance(request.Employee, request.LeaveType, *request, request.ID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := ls.db.Model(&models.LeaveRequest{}).Where("id = ?", request.ID).
+		Updates(map[string]interface{}{"status": "approved", "approved_by": approverID, "approved_at": now}).Error; err != nil {
+		return nil, fmt.Errorf("failed to approve leave request: %w", err)
+	}
+	return ls.GetRequest(companyID, request.ID)
+}
+
+// RejectRequest turns down a pending request with a reason.
+func (ls *LeaveService) RejectRequest(companyID, requestID uint, reason string, approverID *uint) (*models.LeaveRequest, error) {
+	if reason == "" {
+		return nil, fmt.Errorf("a reason is required when rejecting leave")
+	}
+	request, err := ls.GetRequest(companyID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != "pending" {
+		return nil, fmt.Errorf("%w: only pending requests can be rejected", ErrLeaveState)
+	}
+
+	now := time.Now()
+	if err := ls.db.Model(&models.LeaveRequest{}).Where("id = ?", request.ID).
+		Updates(map[string]interface{}{"status": "rejected", "rejection_reason": reason, "approved_by": approverID, "approved_at": now}).Error; err != nil {
+		return nil, fmt.Errorf("failed to reject leave request: %w", err)
+	}
+	return ls.GetRequest(companyID, request.ID)
+}
+
+// checkBalance verifies the request fits in the balance accrued by its start date, after the
+// other pending and approved leave booked in the same leave year. Leave types without a
+// yearly entitlement are not limited, and neither are companies that allow negative leave.
+func (ls *LeaveService) checkBalance(employee models.Employee, leaveType models.LeaveType, request models.LeaveRequest, excludeID uint) error {
+	if leaveType.DaysPerYear == 0 {
+		return nil
+	}
+	var settings models.CompanySettings
+	ls.db.Where("company_id = ?", employee.CompanyID).First(&settings)
+	if settings.AllowNegativeLeave {
+		return nil
+	}
+
+	balance, err := ls.GetBalance(employee, leaveType, request.StartDate)
+	if err != nil {
+		return err
+	}
+
+	// The balance counts approved leave up to the start date; anything else booked in the
+	// year is set aside as well
+	var booked float64
+	yearEnd := balance.YearStart.AddDate(1, 0, 0)
+	if err := ls.db.Model(&models.LeaveRequest{}).
+		Where("employee_id = ? AND leave_type_id = ? AND id <> ?", employee.ID, leaveType.ID, excludeID).
+		Where("start_date >= ? AND start_date < ?", balance.YearStart, yearEnd).
+		Where(ls.db.Where("status = ?", "pending").
+			Or("status = ? AND start_date > ?", "approved", request.StartDate)).
+		Select("COALESCE(SUM(days_requested), 0)").
+		Scan(&booked).Error; err != nil {
+		return fmt.Errorf("failed to fetch booked leave: %w", err)
+	}
+
+	remaining := roundDays(balance.Available - booked)
+	if remaining < float64(request.DaysRequested) {
+		return fmt.Errorf("%w: %d days requested, %.2f %s days available", ErrInsufficientLeave,
+			request.DaysRequested, remaining, leaveType.Name)
+	}
+	return nil
+}
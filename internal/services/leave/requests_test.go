@@ -0,0 +1,87 @@
+package leave
+
+import (
+	"testing"
+	"time"
+
+	"gm58-hr-backend/internal/database"
+	"gm58-hr-backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCreateRequestCountsWorkingDaysAndChecksBalance(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, database.AutoMigrate(db))
+
+	usd := models.Currency{Code: "USD", Name: "US Dollar", Symbol: "$", IsActive: true, IsBaseCurrency: true}
+	require.NoError(t, db.Create(&usd).Error)
+	company := models.Company{Name: "Leave Co", Code: "LEAVE", Email: "hr@leave.test", BaseCurrencyID: usd.ID, WorkWeekDays: 5}
+	require.NoError(t, db.Create(&company).Error)
+	settings := models.CompanySettings{CompanyID: company.ID}
+	require.NoError(t, db.Create(&settings).Error)
+	employee := models.Employee{
+		CompanyID: company.ID, EmployeeNumber: "EMP00001", FirstName: "Tariro", LastName: "Moyo", NationalID: "63-000001A00",
+		HireDate: "2024-01-01", BasicSalary: decimal.NewFromInt(1000), CurrencyID: usd.ID, IsActive: true, EmploymentStatus: "active",
+	}
+	require.NoError(t, db.Create(&employee).Error)
+	annual := models.LeaveType{CompanyID: company.ID, Name: "Annual", DaysPerYear: 24, IsPaid: true, RequiresApproval: true, IsActive: true}
+	require.NoError(t, db.Create(&annual).Error)
+
+	service := NewLeaveService(db)
+	request := func(start, end time.Time) (*models.LeaveRequest, error) {
+		return service.CreateRequest(company.ID, models.LeaveRequest{
+			EmployeeID: employee.ID, LeaveTypeID: annual.ID, StartDate: start, EndDate: end,
+		})
+	}
+
+	// A Monday to Friday week needs five days, but only 4.2 have accrued by 4 March
+	_, err = request(date(2024, 3, 4), date(2024, 3, 8))
+	assert.ErrorIs(t, err, ErrInsufficientLeave)
+
+	first, err := request(date(2024, 3, 11), date(2024, 3, 12))
+	require.NoError(t, err)
+	assert.Equal(t, 2, first.DaysRequested)
+	assert.Equal(t, "pending", first.Status)
+
+	_, err = request(date(2024, 3, 12), date(2024, 3, 13))
+	assert.ErrorIs(t, err, ErrLeaveOverlap)
+	_, err = request(date(2024, 3, 16), date(2024, 3, 17))
+	assert.Error(t, err, "a weekend has no working days")
+
+	// 4.79 days accrued by 13 March less the 2 pending leaves room for 2 more, but not 3
+	_, err = request(date(2024, 3, 13), date(2024, 3, 15))
+	assert.ErrorIs(t, err, ErrInsufficientLeave)
+	second, err := request(date(2024, 3, 13), date(2024, 3, 14))
+	require.NoError(t, err)
+
+	approved, err := service.ApproveRequest(company.ID, first.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "approved", approved.Status)
+	_, err = service.ApproveRequest(company.ID, first.ID, nil)
+	assert.ErrorIs(t, err, ErrLeaveState)
+
+	cancelled, err := service.CancelRequest(company.ID, second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", cancelled.Status)
+
+	// Negative leave lets the full week through
+	require.NoError(t, db.Model(&settings).Update("allow_negative_leave", true).Error)
+	_, err = request(date(2024, 3, 18), date(2024, 3, 22))
+	require.NoError(t, err)
+
+	assert.Equal(t, 6, WorkingDays(date(2024, 3, 4), date(2024, 3, 10), 6))
+}
@@ -0,0 +1,307 @@
+package payroll
+
+import (
+	"errors"
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrApprovalNotAllowed is returned when the user may not act on the current approval level.
+	ErrApprovalNotAllowed = errors.New("user is not allowed to approve this payroll level")
+	// ErrApprovalState is returned when the period is not awaiting approval.
+	ErrApprovalState = errors.New("payroll period is not awaiting approval")
+)
+
+// defaultApproverRoles may approve any level when the company has not configured approval steps
+var defaultApproverRoles = map[string]bool{"company_admin": true, "hr": true}
+
+// GetApprovalSteps returns the company's ordered approval steps. Companies without configured
+// steps get one default step per CompanySettings.PayrollApprovalLevels.
+func (pp *PayrollProcessor) GetApprovalSteps(companyID uint) ([]models.PayrollApprovalStep, error) {
+	return approvalSteps(pp.db, companyID)
+}
+
+func approvalSteps(db *gorm.DB, companyID uint) ([]models.PayrollApprovalStep, error) {
+	var steps []models.PayrollApprovalStep
+	if err := db.Where("company_id = ?", companyID).Order("level").Find(&steps).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch approval steps: %w", err)
+	}
+	if len(steps) > 0 {
+		return steps, nil
+	}
+
+	var settings models.CompanySettings
+	db.Where("company_id = ?", companyID).First(&settings)
+
+	levels := settings.PayrollApprovalLevels
+	if levels < 1 {
+		levels = 1
+	}
+	for level := 1; level <= levels; level++ {
+		steps = append(steps, models.PayrollApprovalStep{
+			CompanyID: companyID,
+			Level:     level,
+			Name:      fmt.Sprintf("Level %d approval", level),
+		})
+	}
+	return steps, nil
+}
+
+// SaveApprovalSteps replaces the company's approval steps and keeps
+// CompanySettings.PayrollApprovalLevels in line with the number of steps.
+func (pp *PayrollProcessor) SaveApprovalSteps(companyID uint, steps []models.PayrollApprovalStep) error {
+	for i, step := range steps {
+		if step.ApproverRole == "" && step.ApproverUserID == nil {
+			return fmt.Errorf("approval level %d needs an approver role or user", i+1)
+		}
+	}
+
+	return pp.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("company_id = ?", companyID).Delete(&models.PayrollApprovalStep{}).Error; err != nil {
+			return err
+		}
+
+		for i, step := range steps {
+			step.ID = 0
+			step.CompanyID = companyID
+			step.Level = i + 1
+			if step.Name == "" {
+				step.Name = fmt.Sprintf("Level %d approval", step.Level)
+			}
+			if err := tx.Create(&step).Error; err != nil {
+				return fmt.Errorf("failed to save approval step: %w", err)
+			}
+		}
+
+		levels := len(steps)
+		if levels == 0 {
+			levels = 1
+		}
+		return tx.Model(&models.CompanySettings{}).Where("company_id = ?", companyID).
+			Update("payroll_approval_levels", levels).Error
+	})
+}
+
+// NextApprovalStep returns the step the period is waiting on, or nil once fully approved.
+func (pp *PayrollProcessor) NextApprovalStep(period models.PayrollPeriod) (*models.PayrollApprovalStep, error) {
+	return nextApprovalStep(pp.db, period)
+}
+
+func nextApprovalStep(db *gorm.DB, period models.PayrollPeriod) (*models.PayrollApprovalStep, error) {
+	if period.Status != "processed" {
+		return nil, nil
+	}
+
+	steps, err := approvalSteps(db, period.CompanyID)
+	if err != nil {
+		return nil, err
+	}
+	if period.ApprovalLevel >= len(steps) {
+		return nil, nil
+	}
+	return &steps[period.ApprovalLevel], nil
+}
+
+// ApprovePayrollForCompany records the approval of the current level by the given user.
+// The period becomes approved once every level has signed off. The user who processed the
+// run, or who approved an earlier level, cannot approve it.
+func (pp *PayrollProcessor) ApprovePayrollForCompany(periodID, companyID, userID uint, companyRole, comment string) (*models.PayrollPeriod, error) {
+	var period models.PayrollPeriod
+
+	err := pp.db.Transaction(func(tx *gorm.DB) error {
+		step, err := pp.checkApprover(tx, &period, periodID, companyID, userID, companyRole)
+		if err != nil {
+			return err
+		}
+
+		steps, err := approvalSteps(tx, companyID)
+		if err != nil {
+			return err
+		}
+
+		approval := models.PayrollApproval{
+			CompanyID:       companyID,
+			PayrollPeriodID: period.ID,
+			Level:           step.Level,
+			Action:          "approved",
+			UserID:          userID,
+			Role:            companyRole,
+			Comment:         comment,
+		}
+		if err := tx.Create(&approval).Error; err != nil {
+			return fmt.Errorf("failed to record approval: %w", err)
+		}
+
+		period.ApprovalLevel = step.Level
+		if period.ApprovalLevel >= len(steps) {
+			now := time.Now()
+			period.Status = "approved"
+			period.ApprovedAt = &now
+			period.ApprovedBy = &userID
+
+			if period.PeriodType == models.PeriodTypeTermination {
+				if err := completeFinalSettlement(tx, period); err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.Save(&period).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &period, nil
+}
+
+// RejectPayrollForCompany records a rejection and returns the period to draft so it can be
+// corrected and processed again. The generated payslips are discarded; the approval history is kept.
+func (pp *PayrollProcessor) RejectPayrollForCompany(periodID, companyID, userID uint, companyRole, comment string) (*models.PayrollPeriod, error) {
+	if comment == "" {
+		return nil, fmt.Errorf("a comment is required when rejecting payroll")
+	}
+
+	var period models.PayrollPeriod
+
+	err := pp.db.Transaction(func(tx *gorm.DB) error {
+		step, err := pp.checkApprover(tx, &period, periodID, companyID, userID, companyRole)
+		if err != nil {
+			return err
+		}
+
+		rejection := models.PayrollApproval{
+			CompanyID:       companyID,
+			PayrollPeriodID: period.ID,
+			Level:           step.Level,
+			Action:          "rejected",
+			UserID:          userID,
+			Role:            companyRole,
+			Comment:         comment,
+		}
+		if err := tx.Create(&rejection).Error; err != nil {
+			return fmt.Errorf("failed to record rejection: %w", err)
+		}
+
+		payslipIDs := tx.Model(&models.Payslip{}).Select("id").
+			Where("payroll_period_id = ? AND company_id = ?", period.ID, companyID)
+		if err := tx.Where("payslip_id IN (?)", payslipIDs).Delete(&models.PayslipNetPay{}).Error; err != nil {
+			return fmt.Errorf("failed to discard payslips: %w", err)
+		}
+		if err := tx.Where("payslip_id IN (?)", payslipIDs).Delete(&models.PayslipCalculationStep{}).Error; err != nil {
+			return fmt.Errorf("failed to discard payslips: %w", err)
+		}
+		// Back pay paid through the discarded payslips is owed again on the next run
+		if err := tx.Model(&models.SalaryAdjustment{}).
+			Where("applied_period_id = ? AND company_id = ?", period.ID, companyID).
+			Updates(map[string]interface{}{
+				"status":             "pending",
+				"applied_period_id":  nil,
+				"applied_payslip_id": nil,
+			}).Error; err != nil {
+			return fmt.Errorf("failed to release back pay: %w", err)
+		}
+		// So is approved time
+		if err := tx.Model(&models.TimeEntry{}).
+			Where("paid_period_id = ? AND company_id = ?", period.ID, companyID).
+			Updates(map[string]interface{}{"paid_period_id": nil, "paid_payslip_id": nil}).Error; err != nil {
+			return fmt.Errorf("failed to release time entries: %w", err)
+		}
+		// A rejected final settlement is recalculated when the period is processed again
+		if err := tx.Model(&models.FinalSettlement{}).
+			Where("payroll_period_id = ? AND company_id = ?", period.ID, companyID).
+			Updates(map[string]interface{}{"status": "pending", "payslip_id": nil}).Error; err != nil {
+			return fmt.Errorf("failed to release final settlement: %w", err)
+		}
+		if err := tx.Where("payroll_period_id = ? AND company_id = ?", period.ID, companyID).
+			Delete(&models.Payslip{}).Error; err != nil {
+			return fmt.Errorf("failed to discard payslips: %w", err)
+		}
+
+		period.Status = "draft"
+		period.ApprovalLevel = 0
+		period.ProcessedAt = nil
+		period.ProcessedBy = nil
+		return tx.Save(&period).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &period, nil
+}
+
+// GetApprovalHistory returns every approval and rejection recorded for a period, oldest first.
+func (pp *PayrollProcessor) GetApprovalHistory(periodID, companyID uint) ([]models.PayrollApproval, error) {
+	var approvals []models.PayrollApproval
+	err := pp.db.Preload("User").
+		Where("payroll_period_id = ? AND company_id = ?", periodID, companyID).
+		Order("created_at, id").
+		Find(&approvals).Error
+	return approvals, err
+}
+
+// checkApprover loads the period into period and verifies the user may act on its current level.
+// The period row stays locked until the transaction ends, so two approvers acting at once
+// cannot both sign off the same level.
+func (pp *PayrollProcessor) checkApprover(tx *gorm.DB, period *models.PayrollPeriod, periodID, companyID, userID uint, companyRole string) (*models.PayrollApprovalStep, error) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND company_id = ?", periodID, companyID).First(period).Error; err != nil {
+		return nil, fmt.Errorf("payroll period not found: %w", err)
+	}
+
+	step, err := nextApprovalStep(tx, *period)
+	if err != nil {
+		return nil, err
+	}
+	if step == nil {
+		return nil, ErrApprovalState
+	}
+
+	// Segregation of duties: the preparer never approves their own run
+	if period.ProcessedBy != nil && *period.ProcessedBy == userID {
+		return nil, fmt.Errorf("%w: the user who processed the payroll cannot approve it", ErrApprovalNotAllowed)
+	}
+
+	// Each level must be signed off by a different person, counting only the current cycle
+	var priorApprovals int64
+	if err := tx.Model(&models.PayrollApproval{}).
+		Where("payroll_period_id = ? AND user_id = ? AND action = ? AND created_at >= ?",
+			period.ID, userID, "approved", period.ProcessedAt).
+		Count(&priorApprovals).Error; err != nil {
+		return nil, fmt.Errorf("failed to check earlier approvals: %w", err)
+	}
+	if priorApprovals > 0 {
+		return nil, fmt.Errorf("%w: user has already approved an earlier level", ErrApprovalNotAllowed)
+	}
+
+	if err := checkStepApprover(*step, userID, companyRole); err != nil {
+		return nil, err
+	}
+
+	return step, nil
+}
+
+// checkStepApprover verifies the user is the step's designated approver or holds its role.
+func checkStepApprover(step models.PayrollApprovalStep, userID uint, companyRole string) error {
+	switch {
+	case step.ApproverUserID != nil:
+		if *step.ApproverUserID != userID {
+			return fmt.Errorf("%w: level %d must be approved by its designated approver", ErrApprovalNotAllowed, step.Level)
+		}
+	case step.ApproverRole != "":
+		if step.ApproverRole != companyRole {
+			return fmt.Errorf("%w: level %d requires the %s role", ErrApprovalNotAllowed, step.Level, step.ApproverRole)
+		}
+	default:
+		if !defaultApproverRoles[companyRole] {
+			return fmt.Errorf("%w: level %d requires a company admin or HR user", ErrApprovalNotAllowed, step.Level)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package payroll
+
+import (
+	"testing"
+	"time"
+
+	"gm58-hr-backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// processedPeriod processes January 2024 for a one-employee company, run by user 1, with
+// approval steps for the given roles.
+func processedPeriod(t *testing.T, roles ...string) (*gorm.DB, *PayrollProcessor, models.PayrollPeriod) {
+	db, company := setupPayrollDB(t, 1)
+	processor := newProcessor(db)
+	if len(roles) > 0 {
+		steps := make([]models.PayrollApprovalStep, 0, len(roles))
+		for _, role := range roles {
+			steps = append(steps, models.PayrollApprovalStep{ApproverRole: role})
+		}
+		require.NoError(t, processor.SaveApprovalSteps(company.ID, steps))
+	}
+	period := createDraftPeriod(t, db, company.ID, 0)
+	require.NoError(t, processor.ProcessPayrollForCompany(period.ID, company.ID, 1))
+	return db, processor, period
+}
+
+func TestPreparerCannotApproveOwnRun(t *testing.T) {
+	_, processor, period := processedPeriod(t)
+
+	_, err := processor.ApprovePayrollForCompany(period.ID, period.CompanyID, 1, "company_admin", "")
+	assert.ErrorIs(t, err, ErrApprovalNotAllowed)
+	_, err = processor.RejectPayrollForCompany(period.ID, period.CompanyID, 1, "company_admin", "Wrong rates")
+	assert.ErrorIs(t, err, ErrApprovalNotAllowed)
+
+	approved, err := processor.ApprovePayrollForCompany(period.ID, period.CompanyID, 2, "company_admin", "")
+	require.NoError(t, err)
+	assert.Equal(t, "approved", approved.Status)
+}
+
+func TestEachLevelNeedsADifferentApprover(t *testing.T) {
+	_, processor, period := processedPeriod(t, "company_admin", "company_admin")
+
+	first, err := processor.ApprovePayrollForCompany(period.ID, period.CompanyID, 2, "company_admin", "")
+	require.NoError(t, err)
+	assert.Equal(t, "processed", first.Status)
+	assert.Equal(t, 1, first.ApprovalLevel)
+
+	_, err = processor.ApprovePayrollForCompany(period.ID, period.CompanyID, 2, "company_admin", "")
+	assert.ErrorIs(t, err, ErrApprovalNotAllowed)
+
+	second, err := processor.ApprovePayrollForCompany(period.ID, period.CompanyID, 3, "company_admin", "")
+	require.NoError(t, err)
+	assert.Equal(t, "approved", second.Status)
+	_, err = processor.ApprovePayrollForCompany(period.ID, period.CompanyID, 4, "company_admin", "")
+	assert.ErrorIs(t, err, ErrApprovalState)
+}
+
+func TestApprovalsCountOnlyInTheCurrentCycle(t *testing.T) {
+	_, processor, period := processedPeriod(t, "company_admin", "company_admin")
+
+	_, err := processor.ApprovePayrollForCompany(period.ID, period.CompanyID, 2, "company_admin", "")
+	require.NoError(t, err)
+	_, err = processor.RejectPayrollForCompany(period.ID, period.CompanyID, 3, "company_admin", "Overtime missing")
+	require.NoError(t, err)
+
+	// Reprocessed after the rejection, the run is approved afresh by the same people
+	require.NoError(t, processor.ProcessPayrollForCompany(period.ID, period.CompanyID, 1))
+	_, err = processor.ApprovePayrollForCompany(period.ID, period.CompanyID, 2, "company_admin", "")
+	require.NoError(t, err)
+	approved, err := processor.ApprovePayrollForCompany(period.ID, period.CompanyID, 3, "company_admin", "")
+	require.NoError(t, err)
+	assert.Equal(t, "approved", approved.Status)
+
+	history, err := processor.GetApprovalHistory(period.ID, period.CompanyID)
+	require.NoError(t, err)
+	assert.Len(t, history, 4)
+}
+
+func TestApprovalStepApprover(t *testing.T) {
+	t.Run("role", func(t *testing.T) {
+		_, processor, period := processedPeriod(t, "hr")
+
+		_, err := processor.ApprovePayrollForCompany(period.ID, period.CompanyID, 2, "company_admin", "")
+		assert.ErrorIs(t, err, ErrApprovalNotAllowed)
+		_, err = processor.ApprovePayrollForCompany(period.ID, period.CompanyID, 2, "hr", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("designated user", func(t *testing.T) {
+		db, processor, period := processedPeriod(t)
+		approver := uint(7)
+		require.NoError(t, processor.SaveApprovalSteps(period.CompanyID, []models.PayrollApprovalStep{{ApproverUserID: &approver}}))
+
+		_, err := processor.ApprovePayrollForCompany(period.ID, period.CompanyID, 2, "company_admin", "")
+		assert.ErrorIs(t, err, ErrApprovalNotAllowed)
+		_, err = processor.ApprovePayrollForCompany(period.ID, period.CompanyID, approver, "employee", "")
+		require.NoError(t, err)
+
+		var approval models.PayrollApproval
+		require.NoError(t, db.Where("payroll_period_id = ?", period.ID).First(&approval).Error)
+		assert.Equal(t, approver, approval.UserID)
+	})
+
+	t.Run("default roles", func(t *testing.T) {
+		_, processor, period := processedPeriod(t)
+
+		_, err := processor.ApprovePayrollForCompany(period.ID, period.CompanyID, 2, "manager", "")
+		assert.ErrorIs(t, err, ErrApprovalNotAllowed)
+		_, err = processor.ApprovePayrollForCompany(period.ID, period.CompanyID, 2, "hr", "")
+		assert.NoError(t, err)
+	})
+}
+
+func TestRejectionNeedsAComment(t *testing.T) {
+	db, processor, period := processedPeriod(t)
+
+	_, err := processor.RejectPayrollForCompany(period.ID, period.CompanyID, 2, "company_admin", "")
+	assert.Error(t, err)
+
+	var reloaded models.PayrollPeriod
+	require.NoError(t, db.First(&reloaded, period.ID).Error)
+	assert.Equal(t, "processed", reloaded.Status)
+	var payslips int64
+	require.NoError(t, db.Model(&models.Payslip{}).Where("payroll_period_id = ?", period.ID).Count(&payslips).Error)
+	assert.EqualValues(t, 1, payslips)
+}
+
+func TestRejectionReleasesBackPayAndTime(t *testing.T) {
+	db, company := setupPayrollDB(t, 2)
+	processor := newProcessor(db)
+	approvedJanuary(t, db, processor, company.ID)
+
+	// February pays the first employee's January arrears and the second's approved hours
+	employees := employeesByNumber(t, db)
+	adjustment, err := processor.CreateSalaryAdjustment(company.ID, employees[0].ID, decimal.NewFromInt(700),
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "Backdated increase", 1)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&employees[1]).Updates(map[string]interface{}{
+		"pay_type": models.PayTypeHourly, "pay_rate": 5, "basic_salary": 0,
+	}).Error)
+	february := createDraftPeriod(t, db, company.ID, 1)
+	entry, err := processor.CreateTimeEntry(company.ID, employees[1].ID, february.StartDate, 8, "", 1)
+	require.NoError(t, err)
+	_, err = processor.ReviewTimeEntry(company.ID, entry.ID, true, 1)
+	require.NoError(t, err)
+	require.NoError(t, processor.ProcessPayrollForCompany(february.ID, company.ID, 1))
+
+	var applied models.SalaryAdjustment
+	require.NoError(t, db.First(&applied, adjustment.ID).Error)
+	require.Equal(t, "applied", applied.Status)
+
+	rejected, err := processor.RejectPayrollForCompany(february.ID, company.ID, 2, "company_admin", "Hours not signed off")
+	require.NoError(t, err)
+	assert.Equal(t, "draft", rejected.Status)
+	assert.Nil(t, rejected.ProcessedAt)
+
+	var released models.SalaryAdjustment
+	require.NoError(t, db.First(&released, adjustment.ID).Error)
+	assert.Equal(t, "pending", released.Status)
+	assert.Nil(t, released.AppliedPeriodID)
+	assert.Nil(t, released.AppliedPayslipID)
+	var unpaid models.TimeEntry
+	require.NoError(t, db.First(&unpaid, entry.ID).Error)
+	assert.Nil(t, unpaid.PaidPeriodID)
+	assert.Nil(t, unpaid.PaidPayslipID)
+	var payslips int64
+	require.NoError(t, db.Model(&models.Payslip{}).Where("payroll_period_id = ?", february.ID).Count(&payslips).Error)
+	assert.Zero(t, payslips)
+
+	// Processed again, February pays both once more
+	require.NoError(t, processor.ProcessPayrollForCompany(february.ID, company.ID, 1))
+	var arrears models.Payslip
+	require.NoError(t, db.Where("payroll_period_id = ? AND employee_id = ?", february.ID, employees[0].ID).First(&arrears).Error)
+	assertAmount(t, 200, arrears.BackPay)
+	var hours models.Payslip
+	require.NoError(t, db.Where("payroll_period_id = ? AND employee_id = ?", february.ID, employees[1].ID).First(&hours).Error)
+	assert.Equal(t, 8.0, hours.PayQuantity)
+}
+
+func TestRejectionReleasesFinalSettlement(t *testing.T) {
+	db, company := setupPayrollDB(t, 1)
+	processor := newProcessor(db)
+	employee := firstEmployee(t, db)
+
+	settlement, err := processor.CreateFinalSettlement(company.ID, employee.ID, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), "resignation", 5, 1)
+	require.NoError(t, err)
+	require.Equal(t, "processed", settlement.Status)
+	require.NotNil(t, settlement.PayslipID)
+
+	_, err = processor.RejectPayrollForCompany(settlement.PayrollPeriodID, company.ID, 2, "company_admin", "Leave days disputed")
+	require.NoError(t, err)
+
+	var released models.FinalSettlement
+	require.NoError(t, db.First(&released, settlement.ID).Error)
+	assert.Equal(t, "pending", released.Status)
+	assert.Nil(t, released.PayslipID)
+	var payslips int64
+	require.NoError(t, db.Model(&models.Payslip{}).Where("payroll_period_id = ?", settlement.PayrollPeriodID).Count(&payslips).Error)
+	assert.Zero(t, payslips)
+
+	require.NoError(t, processor.ProcessPayrollForCompany(settlement.PayrollPeriodID, company.ID, 1))
+	require.NoError(t, db.First(&released, settlement.ID).Error)
+	assert.Equal(t, "processed", released.Status)
+}
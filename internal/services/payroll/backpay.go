@@ -0,0 +1,235 @@
+package payroll
+
+import (
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// CreateSalaryAdjustment changes an employee's basic salary from effectiveDate. Periods
+// already approved or paid since that date are recalculated under the new salary, and the
+// arrears with the extra tax they attract are held until the employee's next payroll run.
+// The change is recorded in the employee's compensation history.
+func (pp *PayrollProcessor) CreateSalaryAdjustment(companyID, employeeID uint, newSalary decimal.Decimal, effectiveDate time.Time, reason string, createdBy uint) (*models.SalaryAdjustment, error) {
+	adjustment, _, err := pp.createSalaryAdjustment(companyID, employeeID, models.CompensationChange{
+		NewSalary:     newSalary,
+		EffectiveDate: effectiveDate,
+		Reason:        models.CompensationReasonOther,
+		Notes:         reason,
+		ApprovedBy:    &createdBy,
+	}, createdBy)
+	return adjustment, err
+}
+
+// createSalaryAdjustment applies a salary change dated today or earlier, raising back pay
+// for approved periods since the effective date and recording the change in the history.
+func (pp *PayrollProcessor) createSalaryAdjustment(companyID, employeeID uint, change models.CompensationChange, createdBy uint) (*models.SalaryAdjustment, *models.CompensationChange, error) {
+	newSalary := change.NewSalary
+	effectiveDate := change.EffectiveDate
+	if !newSalary.IsPositive() {
+		return nil, nil, fmt.Errorf("new salary must be greater than zero")
+	}
+	if effectiveDate.After(time.Now()) {
+		return nil, nil, fmt.Errorf("effective date cannot be in the future")
+	}
+
+	var employee models.Employee
+	if err := pp.db.Preload("Currency").
+		Where("id = ? AND company_id = ?", employeeID, companyID).
+		First(&employee).Error; err != nil {
+		return nil, nil, fmt.Errorf("employee not found: %w", err)
+	}
+	if newSalary.Equal(employee.BasicSalary) {
+		return nil, nil, fmt.Errorf("new salary is the same as the current salary")
+	}
+
+	var settings models.CompanySettings
+	pp.db.Where("company_id = ?", companyID).First(&settings)
+
+	adjustment := models.SalaryAdjustment{
+		CompanyID:      companyID,
+		EmployeeID:     employee.ID,
+		CurrencyID:     employee.CurrencyID,
+		PreviousSalary: employee.BasicSalary,
+		NewSalary:      newSalary,
+		EffectiveDate:  effectiveDate,
+		Reason:         change.Notes,
+		Status:         "pending",
+		CreatedBy:      createdBy,
+	}
+
+	lines, err := pp.calculateBackPay(employee, adjustment, settings)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, line := range lines {
+		adjustment.TotalArrears = adjustment.TotalArrears.Add(line.SalaryDifference)
+		adjustment.TotalTax = money.Sum(adjustment.TotalTax, line.ExtraPAYE, line.ExtraAidsLevy)
+		adjustment.TotalNSSA = adjustment.TotalNSSA.Add(line.ExtraNSSA)
+		adjustment.NetArrears = adjustment.NetArrears.Add(line.NetArrears)
+	}
+	adjustment.Lines = lines
+
+	// Nothing is owed when no approved period falls after the effective date
+	if len(lines) == 0 {
+		adjustment.Status = "applied"
+	}
+
+	appliedAt := time.Now()
+	change.CompanyID = companyID
+	change.EmployeeID = employee.ID
+	change.PreviousSalary = employee.BasicSalary
+	change.PreviousCurrencyID = employee.CurrencyID
+	change.CurrencyID = employee.CurrencyID
+	change.AppliedAt = &appliedAt
+	change.CreatedBy = createdBy
+
+	err = pp.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&adjustment).Error; err != nil {
+			return fmt.Errorf("failed to save salary adjustment: %w", err)
+		}
+		change.SalaryAdjustmentID = &adjustment.ID
+		if err := tx.Create(&change).Error; err != nil {
+			return fmt.Errorf("failed to save compensation change: %w", err)
+		}
+		return tx.Model(&models.Employee{}).Where("id = ?", employee.ID).
+			Update("basic_salary", newSalary).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &adjustment, &change, nil
+}
+
+// GetSalaryAdjustments returns an employee's backdated salary adjustments with their per-period arrears.
+func (pp *PayrollProcessor) GetSalaryAdjustments(companyID, employeeID uint) ([]models.SalaryAdjustment, error) {
+	var adjustments []models.SalaryAdjustment
+	err := pp.db.Preload("Currency").Preload("Lines.PayrollPeriod").
+		Where("company_id = ? AND employee_id = ?", companyID, employeeID).
+		Order("effective_date DESC, id DESC").
+		Find(&adjustments).Error
+	return adjustments, err
+}
+
+// calculateBackPay recalculates each approved regular payslip from the effective date onwards
+// with the salary difference added, so the arrears are taxed at the rates of the month they
+// relate to. Off-cycle payslips did not pay the salary and are left alone. The extra PAYE is
+// the tax on the difference on top of everything taxed that month, at the exchange rate of
+// the period; a payslip taxed under a directive is taxed under the same directive again.
+func (pp *PayrollProcessor) calculateBackPay(employee models.Employee, adjustment models.SalaryAdjustment, settings models.CompanySettings) ([]models.BackPayLine, error) {
+	var payslips []models.Payslip
+	if err := pp.db.Preload("PayrollPeriod").Preload("Currency").
+		Joins("JOIN payroll_periods ON payroll_periods.id = payslips.payroll_period_id").
+		Where("payslips.employee_id = ? AND payslips.company_id = ?", employee.ID, employee.CompanyID).
+		Where("payroll_periods.status IN ? AND payroll_periods.end_date >= ?",
+			[]string{"approved", "paid"}, adjustment.EffectiveDate).
+		Where("payroll_periods.period_type = ?", models.PeriodTypeRegular).
+		Order("payroll_periods.year, payroll_periods.month").
+		Find(&payslips).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch past payslips: %w", err)
+	}
+
+	rounding := settings.Rounding()
+	lines := make([]models.BackPayLine, 0, len(payslips))
+	for _, payslip := range payslips {
+		if payslip.CurrencyID != employee.CurrencyID {
+			return nil, fmt.Errorf("payslip for %04d-%02d was paid in %s; back pay across a currency change must be calculated manually",
+				payslip.PayrollPeriod.Year, payslip.PayrollPeriod.Month, payslip.Currency.Code)
+		}
+
+		period := payslip.PayrollPeriod
+		currencyCode := payslip.Currency.Code
+		factor := prorationFactor(period, adjustment.EffectiveDate)
+		difference := rounding.Round(adjustment.NewSalary.Sub(adjustment.PreviousSalary).Mul(money.FromFloat(factor)), currencyCode)
+		revisedEarnings := payslip.TotalEarnings.Add(difference)
+		// Earlier arrears and tax-free pay were not taxed as the month's earnings
+		taxable := payslip.TotalEarnings.Sub(payslip.BackPay).Sub(payslip.TaxFreeEarnings)
+
+		revisedPAYE, revisedAidsLevy, revisedNSSA := payslip.PayeeTax, payslip.AidsLevy, payslip.NSSAContribution
+		if settings.EnablePAYE && !payslip.IsContractor {
+			if payslip.TaxDirectiveID != nil {
+				var directive models.TaxDirective
+				if err := pp.db.First(&directive, *payslip.TaxDirectiveID).Error; err != nil {
+					return nil, fmt.Errorf("failed to fetch tax directive %s: %w", payslip.TaxDirectiveNumber, err)
+				}
+				revisedPAYE = rounding.Round(pp.taxCalculator.CalculateDirectivePAYE(directive, taxable.Add(difference)), currencyCode)
+			} else {
+				monthToDate, err := pp.monthToDateEarnings(period, []models.Employee{employee})
+				if err != nil {
+					return nil, err
+				}
+				toUSD, fromUSD, err := pp.usdRatesAt(currencyCode, period.EndDate)
+				if err != nil {
+					return nil, fmt.Errorf("failed to calculate PAYE for %04d-%02d: %w", period.Year, period.Month, err)
+				}
+				base := taxable.Add(monthToDate[employee.ID])
+				extra := pp.taxCalculator.CalculateMonthlyPAYEAtRates(base.Add(difference), toUSD, fromUSD).
+					Sub(pp.taxCalculator.CalculateMonthlyPAYEAtRates(base, toUSD, fromUSD))
+				revisedPAYE = payslip.PayeeTax.Add(rounding.Round(extra, currencyCode))
+			}
+		}
+		if settings.EnableAidsLevy {
+			revisedAidsLevy = rounding.Round(pp.taxCalculator.CalculateAidsLevy(revisedPAYE), currencyCode)
+		}
+		if settings.EnableNSSA && !payslip.IsContractor {
+			nssa, err := pp.taxCalculator.CalculateNSSAContribution(taxable.Add(difference), currencyCode)
+			if err != nil {
+				return nil, fmt.Errorf("failed to calculate NSSA: %w", err)
+			}
+			revisedNSSA = rounding.Round(nssa, currencyCode)
+		}
+
+		line := models.BackPayLine{
+			PayrollPeriodID:   period.ID,
+			OriginalPayslipID: payslip.ID,
+			ProrationFactor:   factor,
+			SalaryDifference:  difference,
+			OriginalEarnings:  payslip.TotalEarnings,
+			RevisedEarnings:   revisedEarnings,
+			ExtraPAYE:         revisedPAYE.Sub(payslip.PayeeTax),
+			ExtraAidsLevy:     revisedAidsLevy.Sub(payslip.AidsLevy),
+			ExtraNSSA:         revisedNSSA.Sub(payslip.NSSAContribution),
+		}
+		line.NetArrears = line.SalaryDifference.Sub(money.Sum(line.ExtraPAYE, line.ExtraAidsLevy, line.ExtraNSSA))
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// prorationFactor is the share of the period's calendar days on or after the effective date
+func prorationFactor(period models.PayrollPeriod, effectiveDate time.Time) float64 {
+	if !effectiveDate.After(period.StartDate) {
+		return 1
+	}
+	periodDays := period.EndDate.Sub(period.StartDate).Hours()/24 + 1
+	coveredDays := period.EndDate.Sub(effectiveDate).Hours()/24 + 1
+	if coveredDays <= 0 {
+		return 0
+	}
+	return coveredDays / periodDays
+}
+
+// pendingBackPay totals the arrears awaiting payment for an employee
+type pendingBackPay struct {
+	adjustmentIDs []uint
+	arrears       decimal.Decimal
+	paye          decimal.Decimal
+	aidsLevy      decimal.Decimal
+	nssa          decimal.Decimal
+}
+
+func (pending *pendingBackPay) add(adjustment models.SalaryAdjustment) {
+	pending.adjustmentIDs = append(pending.adjustmentIDs, adjustment.ID)
+	for _, line := range adjustment.Lines {
+		pending.arrears = pending.arrears.Add(line.SalaryDifference)
+		pending.paye = pending.paye.Add(line.ExtraPAYE)
+		pending.aidsLevy = pending.aidsLevy.Add(line.ExtraAidsLevy)
+		pending.nssa = pending.nssa.Add(line.ExtraNSSA)
+	}
+}
@@ -0,0 +1,62 @@
+package payroll
+
+import (
+	"testing"
+	"time"
+
+	"gm58-hr-backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSalaryAdjustmentRepricesOnlyRegularPayslips(t *testing.T) {
+	db, company := setupPayrollDB(t, 1)
+	processor := newProcessor(db)
+	regular, _ := approvedJanuary(t, db, processor, company.ID)
+
+	employee := firstEmployee(t, db)
+	adjustment, err := processor.CreateSalaryAdjustment(company.ID, employee.ID, decimal.NewFromInt(700),
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "Backdated increase", 1)
+	require.NoError(t, err)
+
+	// The bonus run did not pay the salary, so only January's regular payslip owes arrears
+	require.Len(t, adjustment.Lines, 1)
+	assert.Equal(t, regular.ID, adjustment.Lines[0].PayrollPeriodID)
+	assertAmount(t, 200, adjustment.TotalArrears)
+}
+
+func TestSalaryAdjustmentTaxesArrearsOnTopOfMonth(t *testing.T) {
+	db, company := setupPayrollDB(t, 2)
+	processor := newProcessor(db)
+
+	employees := employeesByNumber(t, db)
+	_, err := processor.CreateTaxDirective(company.ID, employees[0].ID, models.TaxDirective{
+		DirectiveNumber: "TD2024/0001", Type: models.DirectiveTypeFixedPercentage, Rate: 20,
+		ValidFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}, 1)
+	require.NoError(t, err)
+	regular, _ := approvedJanuary(t, db, processor, company.ID)
+
+	// Arrears paid with January's salary are not part of January's taxable earnings
+	require.NoError(t, db.Model(&models.Payslip{}).Where("payroll_period_id = ? AND employee_id = ?", regular.ID, employees[1].ID).
+		Updates(map[string]interface{}{"back_pay": 100, "total_earnings": 750}).Error)
+
+	effective := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// 650 taxable plus the 300 bonus puts the 200 raise across the 1000 bracket: tax on
+	// 1150 less tax on 950 is 260 - 202.50
+	adjustment, err := processor.CreateSalaryAdjustment(company.ID, employees[1].ID, decimal.NewFromInt(800), effective, "Regrade", 1)
+	require.NoError(t, err)
+	require.Len(t, adjustment.Lines, 1)
+	assertAmount(t, 57.5, adjustment.Lines[0].ExtraPAYE)
+	assertAmount(t, 1.72, adjustment.Lines[0].ExtraAidsLevy)
+	assertAmount(t, 6, adjustment.Lines[0].ExtraNSSA)
+
+	// The directed employee's arrears are taxed at the directive's 20%
+	directed, err := processor.CreateSalaryAdjustment(company.ID, employees[0].ID, decimal.NewFromInt(700), effective, "Regrade", 1)
+	require.NoError(t, err)
+	require.Len(t, directed.Lines, 1)
+	assertAmount(t, 40, directed.Lines[0].ExtraPAYE)
+	assertAmount(t, 1.2, directed.Lines[0].ExtraAidsLevy)
+}
@@ -0,0 +1,177 @@
+package payroll
+
+import (
+	"errors"
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ErrNoBonusPolicy is returned when a bonus payout is requested for a company without an active policy.
+var ErrNoBonusPolicy = errors.New("company has no active bonus policy")
+
+// GetBonusPolicy returns the company's bonus policy.
+func (pp *PayrollProcessor) GetBonusPolicy(companyID uint) (*models.BonusPolicy, error) {
+	var policy models.BonusPolicy
+	if err := pp.db.Where("company_id = ?", companyID).First(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// SaveBonusPolicy creates or replaces the company's bonus policy.
+func (pp *PayrollProcessor) SaveBonusPolicy(companyID uint, policy models.BonusPolicy) (*models.BonusPolicy, error) {
+	if policy.Multiple <= 0 {
+		return nil, fmt.Errorf("bonus multiple must be greater than zero")
+	}
+	if policy.PayoutMonth < 1 || policy.PayoutMonth > 12 {
+		return nil, fmt.Errorf("payout month must be between 1 and 12")
+	}
+	if policy.MinServiceMonths < 0 || policy.TaxFreeThreshold.IsNegative() {
+		return nil, fmt.Errorf("service months and tax-free threshold cannot be negative")
+	}
+
+	var existing models.BonusPolicy
+	err := pp.db.Where("company_id = ?", companyID).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to fetch bonus policy: %w", err)
+	}
+
+	policy.ID = existing.ID
+	policy.CompanyID = companyID
+	policy.CreatedAt = existing.CreatedAt
+	if policy.Name == "" {
+		policy.Name = "13th cheque"
+	}
+	// Save writes zero values, so switching proration off or deactivating the policy sticks
+	if err := pp.db.Save(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to save bonus policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// GenerateBonusPayout creates a draft bonus period for the given month paying the annual
+// bonus to every eligible active employee. The bonus year is the twelve months ending with
+// that month. Each employee's bonus is tax free up to the policy threshold, less any
+// tax-free bonus already paid to them in the calendar year.
+func (pp *PayrollProcessor) GenerateBonusPayout(companyID uint, year, month int) (*models.PayrollPeriod, error) {
+	var policy models.BonusPolicy
+	if err := pp.db.Where("company_id = ? AND is_active = ?", companyID, true).First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoBonusPolicy
+		}
+		return nil, fmt.Errorf("failed to fetch bonus policy: %w", err)
+	}
+	if month < 1 || month > 12 {
+		return nil, fmt.Errorf("invalid month: %d", month)
+	}
+
+	description := fmt.Sprintf("%s %d", policy.Name, year)
+	var generated int64
+	pp.db.Model(&models.PayrollPeriod{}).
+		Where("company_id = ? AND period_type = ? AND year = ? AND description = ?",
+			companyID, models.PeriodTypeBonus, year, description).
+		Count(&generated)
+	if generated > 0 {
+		return nil, fmt.Errorf("the %s payout has already been generated", description)
+	}
+
+	var employees []models.Employee
+	if err := pp.db.Preload("Currency").
+		Where("company_id = ? AND is_active = ? AND employment_status = ?", companyID, true, "active").
+		Where("employment_type <> ?", models.EmploymentTypeContractor).
+		Order("employee_number").
+		Find(&employees).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch employees: %w", err)
+	}
+
+	// Tax-free bonus already paid this year counts against the annual threshold
+	var used []struct {
+		EmployeeID uint
+		Amount     decimal.Decimal
+	}
+	if err := pp.db.Model(&models.PayrollPeriodEmployee{}).
+		Select("payroll_period_employees.employee_id, SUM(payroll_period_employees.tax_free_amount) AS amount").
+		Joins("JOIN payroll_periods ON payroll_periods.id = payroll_period_employees.payroll_period_id").
+		Where("payroll_periods.company_id = ? AND payroll_periods.year = ?", companyID, year).
+		Group("payroll_period_employees.employee_id").
+		Scan(&used).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch tax-free bonus paid: %w", err)
+	}
+	usedByEmployee := make(map[uint]decimal.Decimal, len(used))
+	for _, row := range used {
+		usedByEmployee[row.EmployeeID] = row.Amount
+	}
+
+	var settings models.CompanySettings
+	pp.db.Where("company_id = ?", companyID).First(&settings)
+	rounding := settings.Rounding()
+
+	payoutEnd := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, -1)
+	lines := make([]models.PayrollPeriodEmployee, 0, len(employees))
+	for _, employee := range employees {
+		currencyCode := employee.Currency.Code
+		bonus := rounding.Round(bonusEntitlement(policy, employee, payoutEnd), currencyCode)
+		if !bonus.IsPositive() {
+			continue
+		}
+
+		threshold := policy.TaxFreeThreshold
+		if threshold.IsPositive() {
+			converted, err := pp.currencyService.ConvertMoney(threshold, "USD", currencyCode)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert tax-free threshold for employee %s: %w", employee.EmployeeNumber, err)
+			}
+			threshold = rounding.Round(converted, currencyCode)
+		}
+		taxFree := decimal.Max(decimal.Zero, decimal.Min(bonus, threshold.Sub(usedByEmployee[employee.ID])))
+
+		lines = append(lines, models.PayrollPeriodEmployee{
+			EmployeeID:    employee.ID,
+			Bonus:         bonus,
+			TaxFreeAmount: taxFree,
+			Description:   description,
+		})
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no employees qualify for the %s", description)
+	}
+
+	return pp.CreateOffCyclePeriod(companyID, models.PeriodTypeBonus, year, month, description, lines)
+}
+
+// bonusEntitlement is the employee's annual bonus for the bonus year ending payoutEnd, or
+// zero when they do not have the service to qualify.
+func bonusEntitlement(policy models.BonusPolicy, employee models.Employee, payoutEnd time.Time) decimal.Decimal {
+	factor := 1.0
+	if hired, ok := employee.HiredOn(); ok {
+		if hired.After(payoutEnd) {
+			return decimal.Zero
+		}
+		serviceMonths := (payoutEnd.Year()-hired.Year())*12 + int(payoutEnd.Month()) - int(hired.Month())
+		if payoutEnd.Day() < hired.Day() {
+			serviceMonths--
+		}
+		if serviceMonths < policy.MinServiceMonths {
+			return decimal.Zero
+		}
+
+		yearStart := payoutEnd.AddDate(0, 0, 1).AddDate(-1, 0, 0)
+		if policy.ProrateService && hired.After(yearStart) {
+			yearDays := payoutEnd.Sub(yearStart).Hours()/24 + 1
+			servedDays := payoutEnd.Sub(hired).Hours()/24 + 1
+			factor = servedDays / yearDays
+		}
+	}
+
+	return employee.BasicSalary.Mul(money.FromFloat(policy.Multiple)).Mul(money.FromFloat(factor))
+}
+
+// monthlyBonusAccrual is the share of the annual bonus earned by a month's basic salary
+func monthlyBonusAccrual(policy models.BonusPolicy, basicSalary decimal.Decimal, currency string) decimal.Decimal {
+	return basicSalary.Mul(money.FromFloat(policy.Multiple)).Div(decimal.NewFromInt(12)).Round(money.Places(currency))
+}
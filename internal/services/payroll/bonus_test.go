@@ -0,0 +1,60 @@
+package payroll
+
+import (
+	"testing"
+	"time"
+
+	"gm58-hr-backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateBonusPayoutProratesAndCapsTaxFree(t *testing.T) {
+	db, company := setupPayrollDB(t, 3)
+	processor := newProcessor(db)
+	_, err := processor.SaveBonusPolicy(company.ID, models.BonusPolicy{
+		Multiple: 1, MinServiceMonths: 3, ProrateService: true, PayoutMonth: 12,
+		TaxFreeThreshold: decimal.NewFromInt(400), IsActive: true,
+	})
+	require.NoError(t, err)
+
+	// Paid 500, 600 and 700: a long-serving employee, one hired too recently to qualify and
+	// one hired half way through the bonus year
+	employees := employeesByNumber(t, db)
+	for i, hired := range []string{"2020-01-01", "2024-11-01", "2024-07-01"} {
+		require.NoError(t, db.Model(&employees[i]).Update("hire_date", hired).Error)
+	}
+
+	// 100 of the first employee's tax-free allowance went on an earlier bonus this year
+	june := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	earlier := models.PayrollPeriod{
+		CompanyID: company.ID, PeriodType: models.PeriodTypeBonus, Year: 2024, Month: 6,
+		StartDate: june, EndDate: june.AddDate(0, 1, -1), Status: "paid", Description: "Mid-year bonus",
+	}
+	require.NoError(t, db.Create(&earlier).Error)
+	require.NoError(t, db.Create(&models.PayrollPeriodEmployee{
+		PayrollPeriodID: earlier.ID, EmployeeID: employees[0].ID,
+		Bonus: decimal.NewFromInt(100), TaxFreeAmount: decimal.NewFromInt(100),
+	}).Error)
+
+	period, err := processor.GenerateBonusPayout(company.ID, 2024, 12)
+	require.NoError(t, err)
+	assert.Equal(t, models.PeriodTypeBonus, period.PeriodType)
+	require.Len(t, period.Employees, 2)
+
+	full := period.Employees[0]
+	assert.Equal(t, employees[0].ID, full.EmployeeID)
+	assertAmount(t, 500, full.Bonus)
+	assertAmount(t, 300, full.TaxFreeAmount)
+
+	// 184 of the year's 366 days
+	prorated := period.Employees[1]
+	assert.Equal(t, employees[2].ID, prorated.EmployeeID)
+	assertAmount(t, 351.91, prorated.Bonus)
+	assertAmount(t, 351.91, prorated.TaxFreeAmount)
+
+	_, err = processor.GenerateBonusPayout(company.ID, 2024, 12)
+	assert.Error(t, err, "the year's payout is generated once")
+}
@@ -0,0 +1,104 @@
+package payroll
+
+import (
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GenerateTaxCertificates issues the year's tax certificates from approved and paid payslips,
+// one per employee and currency. Certificates already issued for the year are refreshed so
+// late corrections are picked up. Contractors are excluded because their tax is withheld
+// rather than deducted as PAYE.
+func (pp *PayrollProcessor) GenerateTaxCertificates(companyID uint, year int) ([]models.TaxCertificate, error) {
+	var payslips []models.Payslip
+	if err := pp.db.Joins("JOIN payroll_periods ON payroll_periods.id = payslips.payroll_period_id").
+		Where("payslips.company_id = ? AND payroll_periods.year = ?", companyID, year).
+		Where("payslips.status IN ? AND payslips.is_contractor = ?", []string{"approved", "paid"}, false).
+		Find(&payslips).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch payslips: %w", err)
+	}
+	if len(payslips) == 0 {
+		return nil, fmt.Errorf("no approved payslips found for %d", year)
+	}
+
+	type certificateKey struct {
+		employeeID uint
+		currencyID uint
+	}
+	totals := make(map[certificateKey]*models.TaxCertificate)
+	directives := make(map[certificateKey]map[string]bool)
+	numbers := make(map[uint]string)
+	for _, payslip := range payslips {
+		key := certificateKey{payslip.EmployeeID, payslip.CurrencyID}
+		certificate, ok := totals[key]
+		if !ok {
+			certificate = &models.TaxCertificate{
+				CompanyID:  companyID,
+				EmployeeID: payslip.EmployeeID,
+				Year:       year,
+				CurrencyID: payslip.CurrencyID,
+			}
+			totals[key] = certificate
+			directives[key] = make(map[string]bool)
+		}
+		certificate.TotalEarnings = certificate.TotalEarnings.Add(payslip.TotalEarnings)
+		certificate.TotalTax = money.Sum(certificate.TotalTax, payslip.PayeeTax, payslip.AidsLevy)
+		if payslip.TaxDirectiveNumber != "" {
+			directives[key][payslip.TaxDirectiveNumber] = true
+		}
+		numbers[payslip.EmployeeID] = payslip.EmployeeNumber
+	}
+
+	var currencies []models.Currency
+	if err := pp.db.Find(&currencies).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch currencies: %w", err)
+	}
+	codes := make(map[uint]string, len(currencies))
+	for _, currency := range currencies {
+		codes[currency.ID] = currency.Code
+	}
+
+	now := time.Now()
+	certificates := make([]models.TaxCertificate, 0, len(totals))
+	for key, certificate := range totals {
+		applied := make([]string, 0, len(directives[key]))
+		for number := range directives[key] {
+			applied = append(applied, number)
+		}
+		sort.Strings(applied)
+
+		var existing models.TaxCertificate
+		pp.db.Where("company_id = ? AND employee_id = ? AND year = ? AND currency_id = ?",
+			companyID, key.employeeID, year, key.currencyID).
+			Limit(1).Find(&existing)
+
+		certificate.ID = existing.ID
+		certificate.CreatedAt = existing.CreatedAt
+		certificate.CertificateNumber = fmt.Sprintf("%d-%s-%s", year, numbers[key.employeeID], codes[key.currencyID])
+		certificate.TaxDirectives = strings.Join(applied, ",")
+		certificate.IssuedAt = now
+		if err := pp.db.Save(certificate).Error; err != nil {
+			return nil, fmt.Errorf("failed to save tax certificate: %w", err)
+		}
+		certificates = append(certificates, *certificate)
+	}
+	sort.Slice(certificates, func(i, j int) bool {
+		return certificates[i].CertificateNumber < certificates[j].CertificateNumber
+	})
+
+	return certificates, nil
+}
+
+// GetTaxCertificates returns the tax certificates issued to an employee, newest year first.
+func (pp *PayrollProcessor) GetTaxCertificates(companyID, employeeID uint) ([]models.TaxCertificate, error) {
+	var certificates []models.TaxCertificate
+	err := pp.db.Preload("Currency").
+		Where("company_id = ? AND employee_id = ?", companyID, employeeID).
+		Order("year DESC, currency_id").
+		Find(&certificates).Error
+	return certificates, err
+}
@@ -0,0 +1,220 @@
+package payroll
+
+import (
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// RecordCompensationChange changes an employee's basic salary or pay currency from the
+// change's effective date. A change dated today or earlier takes effect at once, with back
+// pay for approved periods it reaches back into; a later change is held until it falls due.
+// The new salary is held to the company's salary band policy, and an accepted salary outside
+// the band is returned with its warning.
+func (pp *PayrollProcessor) RecordCompensationChange(companyID, employeeID uint, change models.CompensationChange, createdBy uint) (*models.CompensationChange, error) {
+	if !models.ValidCompensationReason(change.Reason) {
+		return nil, fmt.Errorf("reason must be promotion, annual_increase, correction or other")
+	}
+	if !change.NewSalary.IsPositive() {
+		return nil, fmt.Errorf("new salary must be greater than zero")
+	}
+	if change.EffectiveDate.IsZero() {
+		return nil, fmt.Errorf("effective date is required")
+	}
+
+	var employee models.Employee
+	if err := pp.db.Where("id = ? AND company_id = ?", employeeID, companyID).First(&employee).Error; err != nil {
+		return nil, err
+	}
+	if !employee.IsSalaried() {
+		return nil, fmt.Errorf("employee is paid by %s rate, not a basic salary", employee.PayType)
+	}
+	if change.CurrencyID == 0 {
+		change.CurrencyID = employee.CurrencyID
+	}
+	if change.CurrencyID != employee.CurrencyID {
+		var count int64
+		pp.db.Model(&models.Currency{}).Where("id = ? AND is_active = ?", change.CurrencyID, true).Count(&count)
+		if count == 0 {
+			return nil, fmt.Errorf("currency %d not found", change.CurrencyID)
+		}
+	}
+
+	// Future changes follow any already scheduled before them
+	previousSalary, previousCurrencyID := pp.salaryAt(employee, change.EffectiveDate)
+	if change.NewSalary.Equal(previousSalary) && change.CurrencyID == previousCurrencyID {
+		return nil, fmt.Errorf("new salary is the same as the current salary")
+	}
+
+	positionID := change.PositionID
+	if positionID == 0 {
+		positionID = employee.PositionID
+	}
+	check, err := pp.EnforceSalaryBand(companyID, positionID, change.NewSalary, change.CurrencyID, change.SalaryBandOverride)
+	if err != nil {
+		return nil, err
+	}
+	change.SalaryBandWarning = ""
+	if check != nil && check.OutOfBand() {
+		change.SalaryBandWarning = check.Message()
+	} else {
+		change.SalaryBandOverride = ""
+	}
+
+	if !change.EffectiveDate.After(time.Now()) {
+		if change.CurrencyID == employee.CurrencyID {
+			_, recorded, err := pp.createSalaryAdjustment(companyID, employee.ID, change, createdBy)
+			return recorded, err
+		}
+		return pp.changePayCurrency(employee, change, createdBy)
+	}
+
+	change.ID = 0
+	change.CompanyID = companyID
+	change.EmployeeID = employee.ID
+	change.PreviousSalary = previousSalary
+	change.PreviousCurrencyID = previousCurrencyID
+	change.AppliedAt = nil
+	change.CreatedBy = createdBy
+	if err := pp.db.Create(&change).Error; err != nil {
+		return nil, fmt.Errorf("failed to save compensation change: %w", err)
+	}
+	return &change, nil
+}
+
+// salaryAt is the salary and pay currency an employee will have on date: their current
+// salary, or the last change scheduled on or before that date.
+func (pp *PayrollProcessor) salaryAt(employee models.Employee, date time.Time) (decimal.Decimal, uint) {
+	var scheduled models.CompensationChange
+	if err := pp.db.Where("employee_id = ? AND applied_at IS NULL AND effective_date <= ?", employee.ID, date).
+		Order("effective_date DESC, id DESC").
+		First(&scheduled).Error; err == nil {
+		return scheduled.NewSalary, scheduled.CurrencyID
+	}
+	return employee.BasicSalary, employee.CurrencyID
+}
+
+// changePayCurrency moves an employee to a new pay currency from a date no later than
+// today. Back pay cannot be worked out across currencies, so the change may not reach
+// back into periods that have already been approved.
+func (pp *PayrollProcessor) changePayCurrency(employee models.Employee, change models.CompensationChange, createdBy uint) (*models.CompensationChange, error) {
+	var approved int64
+	pp.db.Model(&models.Payslip{}).
+		Joins("JOIN payroll_periods ON payroll_periods.id = payslips.payroll_period_id").
+		Where("payslips.employee_id = ? AND payroll_periods.status IN ? AND payroll_periods.end_date >= ?",
+			employee.ID, []string{"approved", "paid"}, change.EffectiveDate).
+		Count(&approved)
+	if approved > 0 {
+		return nil, fmt.Errorf("a currency change cannot be backdated into approved periods; back pay across a currency change must be calculated manually")
+	}
+
+	appliedAt := time.Now()
+	change.ID = 0
+	change.CompanyID = employee.CompanyID
+	change.EmployeeID = employee.ID
+	change.PreviousSalary = employee.BasicSalary
+	change.PreviousCurrencyID = employee.CurrencyID
+	change.AppliedAt = &appliedAt
+	change.CreatedBy = createdBy
+
+	err := pp.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&change).Error; err != nil {
+			return fmt.Errorf("failed to save compensation change: %w", err)
+		}
+		return tx.Model(&models.Employee{}).Where("id = ?", employee.ID).
+			Updates(map[string]interface{}{"basic_salary": change.NewSalary, "currency_id": change.CurrencyID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// GetCompensationHistory returns an employee's compensation changes, latest first.
+func (pp *PayrollProcessor) GetCompensationHistory(companyID, employeeID uint) ([]models.CompensationChange, error) {
+	var changes []models.CompensationChange
+	if err := pp.db.Preload("Currency").
+		Where("company_id = ? AND employee_id = ?", companyID, employeeID).
+		Order("effective_date DESC, id DESC").
+		Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// ApplyDueCompensationChanges brings employee records up to date with scheduled changes
+// whose effective date has arrived. It is run when a payroll period is processed; reads
+// work from the compensation history instead.
+func (pp *PayrollProcessor) ApplyDueCompensationChanges(companyID uint) error {
+	var due []models.CompensationChange
+	if err := pp.db.Where("company_id = ? AND applied_at IS NULL AND effective_date <= ?", companyID, time.Now()).
+		Order("effective_date, id").
+		Find(&due).Error; err != nil {
+		return fmt.Errorf("failed to fetch scheduled compensation changes: %w", err)
+	}
+	if len(due) == 0 {
+		return nil
+	}
+
+	return pp.db.Transaction(func(tx *gorm.DB) error {
+		appliedAt := time.Now()
+		for _, change := range due {
+			if err := tx.Model(&models.Employee{}).Where("id = ?", change.EmployeeID).
+				Updates(map[string]interface{}{"basic_salary": change.NewSalary, "currency_id": change.CurrencyID}).Error; err != nil {
+				return fmt.Errorf("failed to apply compensation change %d: %w", change.ID, err)
+			}
+			if err := tx.Model(&models.CompensationChange{}).Where("id = ?", change.ID).
+				Update("applied_at", appliedAt).Error; err != nil {
+				return fmt.Errorf("failed to apply compensation change %d: %w", change.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// salaryInForce is an employee's basic salary for a period from their compensation history,
+// which is sorted by effective date. A change part way through the period is prorated by
+// calendar days. The employee's current salary is used when there is no history, or when
+// the period spans a change of pay currency.
+func salaryInForce(employee models.Employee, changes []models.CompensationChange, period models.PayrollPeriod) (decimal.Decimal, []models.CompensationChange) {
+	current := employee.BasicSalary
+	if len(changes) == 0 {
+		return current, nil
+	}
+
+	// The salary at the start of the period is set by the last change before it, or is the
+	// one the first later change replaced
+	salary := changes[0].PreviousSalary
+	currencyID := changes[0].PreviousCurrencyID
+	var during []models.CompensationChange
+	for _, change := range changes {
+		if change.EffectiveDate.After(period.EndDate) {
+			break
+		}
+		if change.EffectiveDate.After(period.StartDate) {
+			during = append(during, change)
+			continue
+		}
+		salary = change.NewSalary
+		currencyID = change.CurrencyID
+	}
+	if currencyID != employee.CurrencyID {
+		return current, nil
+	}
+
+	// Each change adds its difference for the share of the period it covers
+	prorated := salary
+	for _, change := range during {
+		if change.CurrencyID != employee.CurrencyID {
+			return current, nil
+		}
+		factor := money.FromFloat(prorationFactor(period, change.EffectiveDate))
+		prorated = prorated.Add(change.NewSalary.Sub(salary).Mul(factor))
+		salary = change.NewSalary
+	}
+	return prorated, during
+}
@@ -0,0 +1,157 @@
+package payroll
+
+import (
+	"fmt"
+	"gm58-hr-backend/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// directiveScopes lists the payroll period types a directive can be limited to
+var directiveScopes = map[string]bool{
+	models.DirectiveScopeAll:     true,
+	models.PeriodTypeRegular:     true,
+	models.PeriodTypeBonus:       true,
+	models.PeriodTypeCorrection:  true,
+	models.PeriodTypeTermination: true,
+}
+
+// CreateTaxDirective records a tax directive for an employee. Directive numbers are unique
+// within the company.
+func (pp *PayrollProcessor) CreateTaxDirective(companyID, employeeID uint, directive models.TaxDirective, createdBy uint) (*models.TaxDirective, error) {
+	var employee models.Employee
+	if err := pp.db.Where("id = ? AND company_id = ?", employeeID, companyID).First(&employee).Error; err != nil {
+		return nil, fmt.Errorf("employee not found: %w", err)
+	}
+	if employee.IsContractor() {
+		return nil, fmt.Errorf("contractors have tax withheld and cannot hold a PAYE directive")
+	}
+
+	if directive.DirectiveNumber == "" {
+		return nil, fmt.Errorf("directive number is required")
+	}
+	switch directive.Type {
+	case models.DirectiveTypeFixedPercentage:
+		if directive.Rate < 0 || directive.Rate > 100 {
+			return nil, fmt.Errorf("directive rate must be between 0 and 100")
+		}
+		directive.Amount = decimal.Zero
+	case models.DirectiveTypeFixedAmount, models.DirectiveTypeLumpSum:
+		if directive.Amount.IsNegative() {
+			return nil, fmt.Errorf("directive amount cannot be negative")
+		}
+		directive.Rate = 0
+	default:
+		return nil, fmt.Errorf("invalid directive type: %s", directive.Type)
+	}
+	if directive.Scope == "" {
+		directive.Scope = models.DirectiveScopeAll
+	}
+	if !directiveScopes[directive.Scope] {
+		return nil, fmt.Errorf("invalid directive scope: %s", directive.Scope)
+	}
+	// A lump sum directive taxes one payout; on a regular run it would replace the PAYE on
+	// the month's salary and be used up before the lump sum is paid
+	if directive.Type == models.DirectiveTypeLumpSum &&
+		(directive.Scope == models.DirectiveScopeAll || directive.Scope == models.PeriodTypeRegular) {
+		return nil, fmt.Errorf("a lump sum directive must be scoped to bonus, correction or termination runs")
+	}
+	if directive.ValidFrom.IsZero() {
+		return nil, fmt.Errorf("valid from date is required")
+	}
+	if directive.ValidTo != nil && directive.ValidTo.Before(directive.ValidFrom) {
+		return nil, fmt.Errorf("valid to date is before the valid from date")
+	}
+
+	var taken int64
+	pp.db.Model(&models.TaxDirective{}).
+		Where("company_id = ? AND directive_number = ?", companyID, directive.DirectiveNumber).
+		Count(&taken)
+	if taken > 0 {
+		return nil, fmt.Errorf("directive %s has already been recorded", directive.DirectiveNumber)
+	}
+
+	directive.ID = 0
+	directive.CompanyID = companyID
+	directive.EmployeeID = employee.ID
+	directive.Status = "active"
+	directive.CreatedBy = createdBy
+	if err := pp.db.Create(&directive).Error; err != nil {
+		return nil, fmt.Errorf("failed to save tax directive: %w", err)
+	}
+	return &directive, nil
+}
+
+// GetTaxDirectives returns an employee's tax directives, newest first.
+func (pp *PayrollProcessor) GetTaxDirectives(companyID, employeeID uint) ([]models.TaxDirective, error) {
+	var directives []models.TaxDirective
+	err := pp.db.Where("company_id = ? AND employee_id = ?", companyID, employeeID).
+		Order("valid_from DESC, id DESC").
+		Find(&directives).Error
+	return directives, err
+}
+
+// CancelTaxDirective stops a directive applying to payslips calculated from now on.
+// Payslips already calculated keep the tax worked out under it.
+func (pp *PayrollProcessor) CancelTaxDirective(companyID, directiveID uint) (*models.TaxDirective, error) {
+	var directive models.TaxDirective
+	if err := pp.db.Where("id = ? AND company_id = ?", directiveID, companyID).First(&directive).Error; err != nil {
+		return nil, err
+	}
+	if directive.Status == "cancelled" {
+		return nil, fmt.Errorf("tax directive is already cancelled")
+	}
+
+	directive.Status = "cancelled"
+	if err := pp.db.Save(&directive).Error; err != nil {
+		return nil, fmt.Errorf("failed to cancel tax directive: %w", err)
+	}
+	return &directive, nil
+}
+
+// directivesFor returns the directive applying to each employee's payslip in the period.
+// A lump sum directive applies to one payment only, so it is skipped once a payslip in
+// another period has used it. When several directives cover the period the most recent wins.
+func (pp *PayrollProcessor) directivesFor(period models.PayrollPeriod, employeeIDs []uint) (map[uint]models.TaxDirective, error) {
+	directives := make(map[uint]models.TaxDirective)
+	if len(employeeIDs) == 0 {
+		return directives, nil
+	}
+
+	var candidates []models.TaxDirective
+	if err := pp.db.Where("company_id = ? AND employee_id IN ? AND status = ?", period.CompanyID, employeeIDs, "active").
+		Order("valid_from, id").
+		Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch tax directives: %w", err)
+	}
+
+	var used []uint
+	if err := pp.db.Model(&models.Payslip{}).
+		Where("company_id = ? AND payroll_period_id <> ? AND tax_directive_id IS NOT NULL", period.CompanyID, period.ID).
+		Distinct().
+		Pluck("tax_directive_id", &used).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch used tax directives: %w", err)
+	}
+	isUsed := make(map[uint]bool, len(used))
+	for _, id := range used {
+		isUsed[id] = true
+	}
+
+	for _, directive := range candidates {
+		if !directive.Covers(period) {
+			continue
+		}
+		if directive.Type == models.DirectiveTypeLumpSum && isUsed[directive.ID] {
+			continue
+		}
+		directives[directive.EmployeeID] = directive
+	}
+	return directives, nil
+}
+
+// applyDirective records the directive used on a payslip
+func applyDirective(payslip *models.Payslip, directive models.TaxDirective) {
+	id := directive.ID
+	payslip.TaxDirectiveID = &id
+	payslip.TaxDirectiveNumber = directive.DirectiveNumber
+}
@@ -0,0 +1,98 @@
+package payroll
+
+import (
+	"fmt"
+	"gm58-hr-backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// traceSections is the order the sections of a calculation trace are explained in
+var traceSections = []string{
+	models.TraceSectionEarnings,
+	models.TraceSectionTaxable,
+	models.TraceSectionTax,
+	models.TraceSectionCredits,
+	models.TraceSectionDeductions,
+	models.TraceSectionNetPay,
+}
+
+// PayslipExplanationSection is one part of a payslip calculation, with its steps in the
+// order they were worked out.
+type PayslipExplanationSection struct {
+	Section string                          `json:"section"`
+	Steps   []models.PayslipCalculationStep `json:"steps"`
+}
+
+// PayslipExplanation is the calculation trace recorded for a payslip, for answering
+// employee queries about their pay and tax.
+type PayslipExplanation struct {
+	PayslipID          uint                        `json:"payslip_id"`
+	EmployeeID         uint                        `json:"employee_id"`
+	EmployeeNumber     string                      `json:"employee_number"`
+	EmployeeName       string                      `json:"employee_name"`
+	PayrollPeriodID    uint                        `json:"payroll_period_id"`
+	PeriodType         string                      `json:"period_type"`
+	Year               int                         `json:"year"`
+	Month              int                         `json:"month"`
+	Currency           string                      `json:"currency"`
+	ExchangeRate       decimal.Decimal             `json:"exchange_rate"` // To the company base currency
+	TaxTableVersion    string                      `json:"tax_table_version"`
+	TaxDirectiveNumber string                      `json:"tax_directive_number,omitempty"`
+	TotalEarnings      decimal.Decimal             `json:"total_earnings"`
+	TotalDeductions    decimal.Decimal             `json:"total_deductions"`
+	NetPay             decimal.Decimal             `json:"net_pay"`
+	Sections           []PayslipExplanationSection `json:"sections"`
+	Note               string                      `json:"note,omitempty"`
+}
+
+// ExplainPayslip returns the calculation trace recorded when the payslip was calculated.
+func (pp *PayrollProcessor) ExplainPayslip(companyID, payslipID uint) (*PayslipExplanation, error) {
+	var payslip models.Payslip
+	if err := pp.db.Preload("Currency").Preload("PayrollPeriod").
+		Preload("CalculationSteps", func(db *gorm.DB) *gorm.DB { return db.Order("sequence") }).
+		Where("id = ? AND company_id = ?", payslipID, companyID).
+		First(&payslip).Error; err != nil {
+		return nil, err
+	}
+
+	periodType := payslip.PayrollPeriod.PeriodType
+	if periodType == "" {
+		periodType = models.PeriodTypeRegular
+	}
+	explanation := &PayslipExplanation{
+		PayslipID:          payslip.ID,
+		EmployeeID:         payslip.EmployeeID,
+		EmployeeNumber:     payslip.EmployeeNumber,
+		EmployeeName:       payslip.EmployeeName,
+		PayrollPeriodID:    payslip.PayrollPeriodID,
+		PeriodType:         periodType,
+		Year:               payslip.PayrollPeriod.Year,
+		Month:              payslip.PayrollPeriod.Month,
+		Currency:           payslip.Currency.Code,
+		ExchangeRate:       payslip.ExchangeRate,
+		TaxTableVersion:    payslip.TaxTableVersion,
+		TaxDirectiveNumber: payslip.TaxDirectiveNumber,
+		TotalEarnings:      payslip.TotalEarnings,
+		TotalDeductions:    payslip.TotalDeductions,
+		NetPay:             payslip.NetPay,
+		Sections:           make([]PayslipExplanationSection, 0, len(traceSections)),
+	}
+	if len(payslip.CalculationSteps) == 0 {
+		explanation.Note = fmt.Sprintf("payslip %d was calculated before calculation traces were recorded", payslip.ID)
+		return explanation, nil
+	}
+
+	bySection := make(map[string][]models.PayslipCalculationStep)
+	for _, step := range payslip.CalculationSteps {
+		bySection[step.Section] = append(bySection[step.Section], step)
+	}
+	for _, section := range traceSections {
+		if steps := bySection[section]; len(steps) > 0 {
+			explanation.Sections = append(explanation.Sections, PayslipExplanationSection{Section: section, Steps: steps})
+		}
+	}
+
+	return explanation, nil
+}
@@ -0,0 +1,38 @@
+package payroll
+
+import (
+	"testing"
+
+	"gm58-hr-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainPayslipTracesCalculation(t *testing.T) {
+	db, company := setupPayrollDB(t, 1)
+	processor := newProcessor(db)
+
+	period := createDraftPeriod(t, db, company.ID, 0)
+	require.NoError(t, processor.ProcessPayrollForCompany(period.ID, company.ID, 1))
+
+	var payslip models.Payslip
+	require.NoError(t, db.Where("payroll_period_id = ?", period.ID).First(&payslip).Error)
+	explanation, err := processor.ExplainPayslip(company.ID, payslip.ID)
+	require.NoError(t, err)
+	assert.Empty(t, explanation.Note)
+
+	steps := make(map[string]models.PayslipCalculationStep)
+	for _, section := range explanation.Sections {
+		for _, step := range section.Steps {
+			assert.Equal(t, section.Section, step.Section)
+			steps[step.Component] = step
+		}
+	}
+	assert.Equal(t, "Transport", steps["allowance"].Description)
+	assertAmount(t, 550, steps["taxable_earnings"].Amount)
+	assert.Equal(t, 25.0, steps["paye_bracket"].Rate)
+	assert.True(t, payslip.PayeeTax.Equal(steps["paye"].Amount))
+	assert.Equal(t, "Canteen", steps["deduction"].Description)
+	assert.True(t, payslip.NetPay.Equal(steps["net_pay"].Amount))
+}
@@ -0,0 +1,184 @@
+package payroll
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gm58-hr-backend/internal/database"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/services/currency"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Shared fixtures for the payroll tests. setupPayrollDB builds a whole company ready to
+// run; tests that need less can start from newPayrollDB and add only what they use.
+
+// newPayrollDB returns an empty, migrated in-memory database
+func newPayrollDB(tb testing.TB) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(tb, err)
+	sqlDB, err := db.DB()
+	require.NoError(tb, err)
+	// Every connection to :memory: is a separate database
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(tb, database.AutoMigrate(db))
+	return db
+}
+
+func newProcessor(db *gorm.DB) *PayrollProcessor {
+	return NewPayrollProcessor(db, currency.NewCurrencyService(db, "", ""))
+}
+
+var testCurrencies = map[string]models.Currency{
+	"USD": {Code: "USD", Name: "US Dollar", Symbol: "$", IsBaseCurrency: true},
+	"ZWG": {Code: "ZWG", Name: "Zimbabwe Gold", Symbol: "ZiG"},
+}
+
+// createCurrency adds an active USD or ZWG currency; USD is the base currency
+func createCurrency(tb testing.TB, db *gorm.DB, code string) models.Currency {
+	currency, ok := testCurrencies[code]
+	require.True(tb, ok, "no test currency %s", code)
+	currency.IsActive = true
+	require.NoError(tb, db.Create(&currency).Error)
+	return currency
+}
+
+func findCurrency(tb testing.TB, db *gorm.DB, code string) models.Currency {
+	var currency models.Currency
+	require.NoError(tb, db.Where("code = ?", code).First(&currency).Error)
+	return currency
+}
+
+// createRate records a manual exchange rate from one currency to another
+func createRate(tb testing.TB, db *gorm.DB, from, to models.Currency, rate float64, effective time.Time) {
+	require.NoError(tb, db.Create(&models.ExchangeRate{
+		FromCurrencyID: from.ID, ToCurrencyID: to.ID, Rate: decimal.NewFromFloat(rate), EffectiveDate: effective, Source: "manual",
+	}).Error)
+}
+
+// createCompany adds a company with PAYE, AIDS levy and NSSA switched on
+func createCompany(tb testing.TB, db *gorm.DB, base models.Currency) models.Company {
+	company := models.Company{Name: "Bench Co", Code: "BENCH", Email: "payroll@bench.test", BaseCurrencyID: base.ID, WorkWeekDays: 5}
+	require.NoError(tb, db.Create(&company).Error)
+	require.NoError(tb, db.Create(&models.CompanySettings{
+		CompanyID: company.ID, EnablePAYE: true, EnableAidsLevy: true, EnableNSSA: true,
+	}).Error)
+	return company
+}
+
+// createEmployee adds the i-th employee of the position, paid a monthly salary with full
+// tax, identity and bank details
+func createEmployee(tb testing.TB, db *gorm.DB, position models.Position, i int, salary int64) models.Employee {
+	employee := models.Employee{
+		CompanyID:        position.CompanyID,
+		EmployeeNumber:   fmt.Sprintf("EMP%05d", i),
+		FirstName:        "Employee",
+		LastName:         fmt.Sprint(i),
+		NationalID:       fmt.Sprintf("63-%06dA00", i),
+		TaxNumber:        fmt.Sprintf("TIN%06d", i),
+		PaymentMethod:    "bank_transfer",
+		BankName:         "CBZ",
+		BankAccount:      fmt.Sprintf("0112%08d", i),
+		PositionID:       position.ID,
+		DepartmentID:     position.DepartmentID,
+		BasicSalary:      decimal.NewFromInt(salary),
+		CurrencyID:       position.CurrencyID,
+		IsActive:         true,
+		EmploymentStatus: "active",
+	}
+	require.NoError(tb, db.Create(&employee).Error)
+	return employee
+}
+
+// setupPayrollDB creates an in-memory company with the given number of USD employees,
+// each with one allowance and one deduction.
+func setupPayrollDB(tb testing.TB, employees int) (*gorm.DB, models.Company) {
+	db := newPayrollDB(tb)
+	usd := createCurrency(tb, db, "USD")
+	company := createCompany(tb, db, usd)
+
+	department := models.Department{CompanyID: company.ID, Name: "Operations", IsActive: true}
+	require.NoError(tb, db.Create(&department).Error)
+	position := models.Position{CompanyID: company.ID, Title: "Operator", DepartmentID: department.ID, CurrencyID: usd.ID, IsActive: true}
+	require.NoError(tb, db.Create(&position).Error)
+
+	for i := 0; i < employees; i++ {
+		employee := createEmployee(tb, db, position, i, int64(500+(i%40)*100))
+		require.NoError(tb, db.Create(&models.Allowance{
+			CompanyID: company.ID, EmployeeID: employee.ID, Name: "Transport", Amount: decimal.NewFromInt(50),
+			CurrencyID: usd.ID, IsActive: true, IsRecurring: true, StartDate: time.Now(),
+		}).Error)
+		require.NoError(tb, db.Create(&models.Deduction{
+			CompanyID: company.ID, EmployeeID: employee.ID, Name: "Canteen", Amount: decimal.NewFromInt(10),
+			CurrencyID: usd.ID, IsActive: true, IsRecurring: true, StartDate: time.Now(),
+		}).Error)
+	}
+
+	return db, company
+}
+
+// employeesByNumber loads every employee, in employee number order
+func employeesByNumber(tb testing.TB, db *gorm.DB) []models.Employee {
+	var employees []models.Employee
+	require.NoError(tb, db.Order("employee_number").Find(&employees).Error)
+	return employees
+}
+
+// firstEmployee loads the employee of a one-employee fixture
+func firstEmployee(tb testing.TB, db *gorm.DB) models.Employee {
+	var employee models.Employee
+	require.NoError(tb, db.Order("employee_number").First(&employee).Error)
+	return employee
+}
+
+// createDraftPeriod adds the regular period month months after January 2024
+func createDraftPeriod(tb testing.TB, db *gorm.DB, companyID uint, month int) models.PayrollPeriod {
+	start := time.Date(2024, time.Month(1), 1, 0, 0, 0, 0, time.UTC).AddDate(0, month, 0)
+	period := models.PayrollPeriod{
+		CompanyID: companyID,
+		Year:      start.Year(),
+		Month:     int(start.Month()),
+		StartDate: start,
+		EndDate:   start.AddDate(0, 1, -1),
+		Status:    "draft",
+	}
+	require.NoError(tb, db.Create(&period).Error)
+	return period
+}
+
+// approvedJanuary processes and approves January 2024 for the company, then adds an approved
+// bonus run in the same month paying each employee 300 on top, taxed on top of the salary.
+func approvedJanuary(t *testing.T, db *gorm.DB, processor *PayrollProcessor, companyID uint) (models.PayrollPeriod, models.PayrollPeriod) {
+	regular := createDraftPeriod(t, db, companyID, 0)
+	require.NoError(t, processor.ProcessPayrollForCompany(regular.ID, companyID, 1))
+	require.NoError(t, db.Model(&regular).Update("status", "approved").Error)
+
+	bonus := models.PayrollPeriod{
+		CompanyID: companyID, PeriodType: models.PeriodTypeBonus, Year: 2024, Month: 1,
+		StartDate: regular.StartDate, EndDate: regular.EndDate, Status: "approved", Description: "Mid-year bonus",
+	}
+	require.NoError(t, db.Create(&bonus).Error)
+	var payslips []models.Payslip
+	require.NoError(t, db.Where("payroll_period_id = ?", regular.ID).Find(&payslips).Error)
+	for _, payslip := range payslips {
+		require.NoError(t, db.Create(&models.Payslip{
+			CompanyID: companyID, PayrollPeriodID: bonus.ID, EmployeeID: payslip.EmployeeID, CurrencyID: payslip.CurrencyID,
+			EmployeeNumber: payslip.EmployeeNumber, Bonus: decimal.NewFromInt(300), TotalEarnings: decimal.NewFromInt(300),
+			PayeeTax: decimal.NewFromInt(75), NetPay: decimal.NewFromInt(225), Status: "approved",
+		}).Error)
+	}
+	return regular, bonus
+}
+
+// assertAmount checks a money amount by value, whatever its number of decimal places
+func assertAmount(t *testing.T, expected float64, actual decimal.Decimal) {
+	t.Helper()
+	assert.True(t, decimal.NewFromFloat(expected).Equal(actual), "expected %v, got %s", expected, actual)
+}
@@ -0,0 +1,534 @@
+package payroll
+
+import (
+	"errors"
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// maxForecastMonths caps a scenario's horizon
+const maxForecastMonths = 60
+
+// ForecastProjection is a scenario's projected payroll cost for each month of its horizon.
+// Amounts on a line are in the line's currency; the Base amounts are in the company's base
+// currency at the scenario's exchange rates.
+type ForecastProjection struct {
+	ScenarioID    uint            `json:"scenario_id"`
+	Name          string          `json:"name"`
+	BaseCurrency  string          `json:"base_currency"`
+	Months        []ForecastMonth `json:"months"`
+	TotalCostBase decimal.Decimal `json:"total_cost_base"`
+}
+
+type ForecastMonth struct {
+	Year          int             `json:"year"`
+	Month         int             `json:"month"`
+	Headcount     int             `json:"headcount"`
+	Lines         []ForecastLine  `json:"lines"`
+	TotalCostBase decimal.Decimal `json:"total_cost_base"`
+}
+
+// ForecastLine is one department's projected cost in one currency. TotalCost is what the
+// company pays: gross pay, employer NSSA and the bonus accrued for the month. PAYE and
+// AIDS levy are withheld from gross pay and shown for remittance planning.
+type ForecastLine struct {
+	DepartmentID  uint            `json:"department_id"`
+	Department    string          `json:"department"`
+	Currency      string          `json:"currency"`
+	Headcount     int             `json:"headcount"`
+	BasicSalary   decimal.Decimal `json:"basic_salary"`
+	Allowances    decimal.Decimal `json:"allowances"`
+	GrossPay      decimal.Decimal `json:"gross_pay"`
+	EmployerNSSA  decimal.Decimal `json:"employer_nssa"`
+	BonusAccrual  decimal.Decimal `json:"bonus_accrual"`
+	PAYE          decimal.Decimal `json:"paye"`
+	AidsLevy      decimal.Decimal `json:"aids_levy"`
+	TotalCost     decimal.Decimal `json:"total_cost"`
+	TotalCostBase decimal.Decimal `json:"total_cost_base"`
+}
+
+// ForecastComparison sets scenarios side by side. Difference is each scenario's total cost
+// less that of the first scenario.
+type ForecastComparison struct {
+	BaseCurrency string                    `json:"base_currency"`
+	Scenarios    []ForecastScenarioSummary `json:"scenarios"`
+}
+
+type ForecastScenarioSummary struct {
+	ScenarioID    uint                 `json:"scenario_id"`
+	Name          string               `json:"name"`
+	TotalCostBase decimal.Decimal      `json:"total_cost_base"`
+	Difference    decimal.Decimal      `json:"difference"`
+	Months        []ForecastMonthTotal `json:"months"`
+}
+
+type ForecastMonthTotal struct {
+	Year          int             `json:"year"`
+	Month         int             `json:"month"`
+	Headcount     int             `json:"headcount"`
+	TotalCostBase decimal.Decimal `json:"total_cost_base"`
+}
+
+// CreateForecastScenario validates and saves a scenario with its assumptions.
+func (pp *PayrollProcessor) CreateForecastScenario(companyID, createdBy uint, scenario models.ForecastScenario) (*models.ForecastScenario, error) {
+	if scenario.Name == "" {
+		return nil, fmt.Errorf("scenario name is required")
+	}
+	if scenario.Months == 0 {
+		scenario.Months = 12
+	}
+	if scenario.Months < 1 || scenario.Months > maxForecastMonths {
+		return nil, fmt.Errorf("months must be between 1 and %d", maxForecastMonths)
+	}
+	if scenario.StartYear < 1 || scenario.StartMonth < 1 || scenario.StartMonth > 12 {
+		return nil, fmt.Errorf("a valid start year and month are required")
+	}
+	for _, increase := range scenario.Increases {
+		if increase.Year < 1 || increase.Month < 1 || increase.Month > 12 {
+			return nil, fmt.Errorf("each increase needs a valid year and month")
+		}
+		if increase.Percentage <= -100 {
+			return nil, fmt.Errorf("an increase cannot cut salaries by 100%% or more")
+		}
+		if increase.DepartmentID != nil {
+			var count int64
+			pp.db.Model(&models.Department{}).Where("id = ? AND company_id = ?", *increase.DepartmentID, companyID).Count(&count)
+			if count == 0 {
+				return nil, fmt.Errorf("department %d not found", *increase.DepartmentID)
+			}
+		}
+	}
+	for _, hire := range scenario.Hires {
+		if hire.Headcount < 1 {
+			return nil, fmt.Errorf("each hire needs a headcount of at least one")
+		}
+		if hire.Year < 1 || hire.Month < 1 || hire.Month > 12 {
+			return nil, fmt.Errorf("each hire needs a valid year and month")
+		}
+		if hire.BasicSalary.IsNegative() {
+			return nil, fmt.Errorf("basic salary cannot be negative")
+		}
+		var count int64
+		pp.db.Model(&models.Position{}).Where("id = ? AND company_id = ?", hire.PositionID, companyID).Count(&count)
+		if count == 0 {
+			return nil, fmt.Errorf("position %d not found", hire.PositionID)
+		}
+	}
+	for _, rate := range scenario.ExchangeRates {
+		if rate.CurrencyCode == "" || !rate.Rate.IsPositive() {
+			return nil, fmt.Errorf("each exchange rate needs a currency code and a positive rate")
+		}
+		if rate.Year != 0 && (rate.Month < 1 || rate.Month > 12) {
+			return nil, fmt.Errorf("exchange rate for %s has an invalid month", rate.CurrencyCode)
+		}
+	}
+
+	scenario.ID = 0
+	scenario.CompanyID = companyID
+	scenario.CreatedBy = createdBy
+	if err := pp.db.Create(&scenario).Error; err != nil {
+		return nil, fmt.Errorf("failed to save forecast scenario: %w", err)
+	}
+	return &scenario, nil
+}
+
+// GetForecastScenarios lists the company's saved scenarios, newest first.
+func (pp *PayrollProcessor) GetForecastScenarios(companyID uint) ([]models.ForecastScenario, error) {
+	var scenarios []models.ForecastScenario
+	if err := pp.db.Where("company_id = ?", companyID).Order("created_at DESC").Find(&scenarios).Error; err != nil {
+		return nil, err
+	}
+	return scenarios, nil
+}
+
+// GetForecastScenario returns a scenario with its assumptions.
+func (pp *PayrollProcessor) GetForecastScenario(companyID, scenarioID uint) (*models.ForecastScenario, error) {
+	var scenario models.ForecastScenario
+	if err := pp.db.Preload("Increases").Preload("Hires").Preload("Hires.Position.Currency").Preload("Hires.Position.Department").
+		Preload("ExchangeRates").
+		Where("id = ? AND company_id = ?", scenarioID, companyID).
+		First(&scenario).Error; err != nil {
+		return nil, err
+	}
+	return &scenario, nil
+}
+
+// DeleteForecastScenario removes a scenario and its assumptions.
+func (pp *PayrollProcessor) DeleteForecastScenario(companyID, scenarioID uint) error {
+	scenario, err := pp.GetForecastScenario(companyID, scenarioID)
+	if err != nil {
+		return err
+	}
+	return pp.db.Transaction(func(tx *gorm.DB) error {
+		for _, child := range []interface{}{&models.ForecastIncrease{}, &models.ForecastHire{}, &models.ForecastExchangeRate{}} {
+			if err := tx.Where("scenario_id = ?", scenario.ID).Delete(child).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(scenario).Error
+	})
+}
+
+// forecastInputs is the current workforce a projection starts from
+type forecastInputs struct {
+	baseCurrency string
+	settings     models.CompanySettings
+	bonusPolicy  *models.BonusPolicy
+	employees    []models.Employee
+	allowances   map[uint][]models.Allowance
+	lastPay      map[uint]decimal.Decimal
+	currentRates map[string]decimal.Decimal
+}
+
+// ProjectForecast projects a scenario's monthly payroll cost by department and currency.
+func (pp *PayrollProcessor) ProjectForecast(companyID, scenarioID uint) (*ForecastProjection, error) {
+	scenario, err := pp.GetForecastScenario(companyID, scenarioID)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := pp.loadForecastInputs(companyID)
+	if err != nil {
+		return nil, err
+	}
+	return pp.projectForecast(*scenario, inputs)
+}
+
+// CompareForecasts projects several scenarios against the same workforce.
+func (pp *PayrollProcessor) CompareForecasts(companyID uint, scenarioIDs []uint) (*ForecastComparison, error) {
+	if len(scenarioIDs) < 2 {
+		return nil, fmt.Errorf("at least two scenarios are needed for a comparison")
+	}
+	inputs, err := pp.loadForecastInputs(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &ForecastComparison{BaseCurrency: inputs.baseCurrency}
+	for _, scenarioID := range scenarioIDs {
+		scenario, err := pp.GetForecastScenario(companyID, scenarioID)
+		if err != nil {
+			return nil, err
+		}
+		projection, err := pp.projectForecast(*scenario, inputs)
+		if err != nil {
+			return nil, err
+		}
+
+		summary := ForecastScenarioSummary{
+			ScenarioID:    scenario.ID,
+			Name:          scenario.Name,
+			TotalCostBase: projection.TotalCostBase,
+		}
+		if len(comparison.Scenarios) > 0 {
+			summary.Difference = projection.TotalCostBase.Sub(comparison.Scenarios[0].TotalCostBase)
+		}
+		for _, month := range projection.Months {
+			summary.Months = append(summary.Months, ForecastMonthTotal{
+				Year:          month.Year,
+				Month:         month.Month,
+				Headcount:     month.Headcount,
+				TotalCostBase: month.TotalCostBase,
+			})
+		}
+		comparison.Scenarios = append(comparison.Scenarios, summary)
+	}
+	return comparison, nil
+}
+
+func (pp *PayrollProcessor) loadForecastInputs(companyID uint) (*forecastInputs, error) {
+	var company models.Company
+	if err := pp.db.Preload("BaseCurrency").First(&company, companyID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get company base currency: %w", err)
+	}
+	inputs := &forecastInputs{
+		baseCurrency: company.BaseCurrency.Code,
+		allowances:   make(map[uint][]models.Allowance),
+		lastPay:      make(map[uint]decimal.Decimal),
+		currentRates: make(map[string]decimal.Decimal),
+	}
+	pp.db.Where("company_id = ?", companyID).First(&inputs.settings)
+
+	var policy models.BonusPolicy
+	if err := pp.db.Where("company_id = ? AND is_active = ?", companyID, true).First(&policy).Error; err == nil {
+		inputs.bonusPolicy = &policy
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to fetch bonus policy: %w", err)
+	}
+
+	if err := pp.db.Preload("Currency").Preload("Department").
+		Where("company_id = ? AND is_active = ? AND employment_status = ?", companyID, true, "active").
+		Order("employee_number").
+		Find(&inputs.employees).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch employees: %w", err)
+	}
+
+	var allowances []models.Allowance
+	if err := pp.db.Preload("Currency").
+		Where("company_id = ? AND is_active = ? AND is_recurring = ?", companyID, true, true).
+		Find(&allowances).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch allowances: %w", err)
+	}
+	for _, allowance := range allowances {
+		inputs.allowances[allowance.EmployeeID] = append(inputs.allowances[allowance.EmployeeID], allowance)
+	}
+
+	// Employees paid for captured time are projected at their latest payslip's pay
+	var unsalaried []uint
+	for _, employee := range inputs.employees {
+		if !employee.IsSalaried() {
+			unsalaried = append(unsalaried, employee.ID)
+		}
+	}
+	if len(unsalaried) > 0 {
+		var payslips []models.Payslip
+		if err := pp.db.Select("employee_id", "basic_salary").
+			Where("company_id = ? AND employee_id IN ?", companyID, unsalaried).
+			Order("id").
+			Find(&payslips).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch latest pay: %w", err)
+		}
+		for _, payslip := range payslips {
+			inputs.lastPay[payslip.EmployeeID] = payslip.BasicSalary
+		}
+	}
+
+	return inputs, nil
+}
+
+// currentRate is the units of a currency to one unit of base currency today
+func (pp *PayrollProcessor) currentRate(inputs *forecastInputs, currencyCode string) (decimal.Decimal, error) {
+	if currencyCode == inputs.baseCurrency {
+		return decimal.NewFromInt(1), nil
+	}
+	if rate, ok := inputs.currentRates[currencyCode]; ok {
+		return rate, nil
+	}
+	rate, err := pp.currencyService.GetExchangeRate(inputs.baseCurrency, currencyCode)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("no exchange rate for %s: %w", currencyCode, err)
+	}
+	inputs.currentRates[currencyCode] = rate
+	return inputs.currentRates[currencyCode], nil
+}
+
+// forecastPerson is one current or planned employee in a projection
+type forecastPerson struct {
+	departmentID uint
+	department   string
+	currency     string
+	basicSalary  decimal.Decimal
+	allowances   []models.Allowance
+	contractor   bool
+	startMonth   int // Months since year zero the person is first paid in
+	endMonth     int // Last month paid, or zero when open-ended
+	headcount    int
+}
+
+func (pp *PayrollProcessor) projectForecast(scenario models.ForecastScenario, inputs *forecastInputs) (*ForecastProjection, error) {
+	rounding := inputs.settings.Rounding()
+	start := monthIndex(scenario.StartYear, scenario.StartMonth)
+
+	// Assumed exchange rates in the order they take effect
+	assumed := append([]models.ForecastExchangeRate(nil), scenario.ExchangeRates...)
+	sort.SliceStable(assumed, func(i, j int) bool {
+		return monthIndex(assumed[i].Year, assumed[i].Month) < monthIndex(assumed[j].Year, assumed[j].Month)
+	})
+	rateAt := func(currencyCode string, month int) (decimal.Decimal, error) {
+		if currencyCode == inputs.baseCurrency {
+			return decimal.NewFromInt(1), nil
+		}
+		var rate decimal.Decimal
+		for _, assumption := range assumed {
+			if assumption.CurrencyCode == currencyCode && (assumption.Year == 0 || monthIndex(assumption.Year, assumption.Month) <= month) {
+				rate = assumption.Rate
+			}
+		}
+		if rate.IsPositive() {
+			return rate, nil
+		}
+		return pp.currentRate(inputs, currencyCode)
+	}
+	convert := func(amount decimal.Decimal, from, to string, month int) (decimal.Decimal, error) {
+		if from == to {
+			return amount, nil
+		}
+		fromRate, err := rateAt(from, month)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		toRate, err := rateAt(to, month)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		return amount.Div(fromRate).Mul(toRate), nil
+	}
+
+	people := make([]forecastPerson, 0, len(inputs.employees)+len(scenario.Hires))
+	for _, employee := range inputs.employees {
+		person := forecastPerson{
+			departmentID: employee.DepartmentID,
+			department:   employee.Department.Name,
+			currency:     employee.Currency.Code,
+			basicSalary:  employee.BasicSalary,
+			allowances:   inputs.allowances[employee.ID],
+			contractor:   employee.IsContractor(),
+			headcount:    1,
+		}
+		if !employee.IsSalaried() {
+			person.basicSalary = inputs.lastPay[employee.ID]
+		}
+		if hired, ok := employee.HiredOn(); ok {
+			person.startMonth = monthIndex(hired.Year(), int(hired.Month()))
+		}
+		for _, date := range []string{employee.ContractEndDate, employee.TerminationDate} {
+			if leaves, err := time.Parse("2006-01-02", date); err == nil {
+				last := monthIndex(leaves.Year(), int(leaves.Month()))
+				if person.endMonth == 0 || last < person.endMonth {
+					person.endMonth = last
+				}
+			}
+		}
+		people = append(people, person)
+	}
+	for _, hire := range scenario.Hires {
+		salary := hire.BasicSalary
+		if !salary.IsPositive() {
+			salary = hire.Position.MinSalary
+			if hire.Position.MaxSalary.GreaterThan(hire.Position.MinSalary) {
+				salary = hire.Position.MinSalary.Add(hire.Position.MaxSalary).Div(decimal.NewFromInt(2))
+			}
+		}
+		position := hire.Position
+		people = append(people, forecastPerson{
+			departmentID: position.DepartmentID,
+			department:   position.Department.Name,
+			currency:     position.Currency.Code,
+			basicSalary:  salary,
+			startMonth:   monthIndex(hire.Year, hire.Month),
+			headcount:    hire.Headcount,
+		})
+	}
+
+	projection := &ForecastProjection{
+		ScenarioID:   scenario.ID,
+		Name:         scenario.Name,
+		BaseCurrency: inputs.baseCurrency,
+	}
+	for m := start; m < start+scenario.Months; m++ {
+		lines := make(map[string]*ForecastLine)
+		month := ForecastMonth{Year: m / 12, Month: m%12 + 1}
+
+		for _, person := range people {
+			if person.startMonth > m || (person.endMonth != 0 && person.endMonth < m) {
+				continue
+			}
+			currencyCode := person.currency
+			round := func(amount decimal.Decimal) decimal.Decimal {
+				return rounding.Round(amount, currencyCode)
+			}
+
+			// Increases compound; people hired later start on the salary already raised
+			basic := person.basicSalary
+			for _, increase := range scenario.Increases {
+				effective := monthIndex(increase.Year, increase.Month)
+				if effective > m || effective <= person.startMonth {
+					continue
+				}
+				if increase.DepartmentID != nil && *increase.DepartmentID != person.departmentID {
+					continue
+				}
+				basic = basic.Mul(decimal.NewFromInt(1).Add(money.FromFloat(increase.Percentage).Div(decimal.NewFromInt(100))))
+			}
+			basic = round(basic)
+
+			allowances := decimal.Zero
+			for _, allowance := range person.allowances {
+				amount, err := convert(allowance.Amount, allowance.Currency.Code, currencyCode, m)
+				if err != nil {
+					return nil, err
+				}
+				allowances = allowances.Add(rounding.Line(amount, currencyCode))
+			}
+			allowances = round(allowances)
+			gross := basic.Add(allowances)
+
+			var employerNSSA, bonusAccrual, paye, aidsLevy decimal.Decimal
+			if !person.contractor {
+				if inputs.settings.EnableNSSA {
+					nssa, err := pp.taxCalculator.CalculateNSSAContribution(gross, currencyCode)
+					if err != nil {
+						return nil, err
+					}
+					employerNSSA = round(nssa)
+				}
+				if inputs.settings.EnablePAYE {
+					toUSD, err := convert(decimal.NewFromInt(1), currencyCode, "USD", m)
+					if err != nil {
+						return nil, err
+					}
+					fromUSD, err := convert(decimal.NewFromInt(1), "USD", currencyCode, m)
+					if err != nil {
+						return nil, err
+					}
+					paye = round(pp.taxCalculator.CalculateMonthlyPAYEAtRates(gross, toUSD, fromUSD))
+					if inputs.settings.EnableAidsLevy {
+						aidsLevy = round(pp.taxCalculator.CalculateAidsLevy(paye))
+					}
+				}
+				if inputs.bonusPolicy != nil {
+					bonusAccrual = monthlyBonusAccrual(*inputs.bonusPolicy, basic, currencyCode)
+				}
+			}
+
+			key := fmt.Sprintf("%d/%s", person.departmentID, currencyCode)
+			line, ok := lines[key]
+			if !ok {
+				line = &ForecastLine{DepartmentID: person.departmentID, Department: person.department, Currency: currencyCode}
+				lines[key] = line
+			}
+			heads := decimal.NewFromInt(int64(person.headcount))
+			line.Headcount += person.headcount
+			line.BasicSalary = line.BasicSalary.Add(basic.Mul(heads))
+			line.Allowances = line.Allowances.Add(allowances.Mul(heads))
+			line.GrossPay = line.GrossPay.Add(gross.Mul(heads))
+			line.EmployerNSSA = line.EmployerNSSA.Add(employerNSSA.Mul(heads))
+			line.BonusAccrual = line.BonusAccrual.Add(bonusAccrual.Mul(heads))
+			line.PAYE = line.PAYE.Add(paye.Mul(heads))
+			line.AidsLevy = line.AidsLevy.Add(aidsLevy.Mul(heads))
+			month.Headcount += person.headcount
+		}
+
+		for _, line := range lines {
+			line.TotalCost = money.Sum(line.GrossPay, line.EmployerNSSA, line.BonusAccrual)
+			costBase, err := convert(line.TotalCost, line.Currency, inputs.baseCurrency, m)
+			if err != nil {
+				return nil, err
+			}
+			line.TotalCostBase = rounding.Round(costBase, inputs.baseCurrency)
+			month.TotalCostBase = month.TotalCostBase.Add(line.TotalCostBase)
+			month.Lines = append(month.Lines, *line)
+		}
+		sort.Slice(month.Lines, func(i, j int) bool {
+			if month.Lines[i].Department != month.Lines[j].Department {
+				return month.Lines[i].Department < month.Lines[j].Department
+			}
+			return month.Lines[i].Currency < month.Lines[j].Currency
+		})
+
+		projection.TotalCostBase = projection.TotalCostBase.Add(month.TotalCostBase)
+		projection.Months = append(projection.Months, month)
+	}
+
+	return projection, nil
+}
+
+// monthIndex numbers months consecutively so that they can be compared and stepped through
+func monthIndex(year, month int) int {
+	return year*12 + month - 1
+}
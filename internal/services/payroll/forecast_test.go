@@ -0,0 +1,56 @@
+package payroll
+
+import (
+	"testing"
+
+	"gm58-hr-backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectForecastAppliesIncreasesAndHires(t *testing.T) {
+	db, company := setupPayrollDB(t, 2)
+	processor := newProcessor(db)
+
+	var position models.Position
+	require.NoError(t, db.Where("company_id = ?", company.ID).First(&position).Error)
+
+	baseline, err := processor.CreateForecastScenario(company.ID, 1, models.ForecastScenario{
+		Name: "Baseline", StartYear: 2025, StartMonth: 1, Months: 6,
+	})
+	require.NoError(t, err)
+	growth, err := processor.CreateForecastScenario(company.ID, 1, models.ForecastScenario{
+		Name: "Raise and hire", StartYear: 2025, StartMonth: 1, Months: 6,
+		Increases: []models.ForecastIncrease{{Year: 2025, Month: 3, Percentage: 8}},
+		Hires:     []models.ForecastHire{{PositionID: position.ID, Headcount: 2, Year: 2025, Month: 4, BasicSalary: decimal.NewFromInt(1000)}},
+	})
+	require.NoError(t, err)
+
+	projection, err := processor.ProjectForecast(company.ID, growth.ID)
+	require.NoError(t, err)
+	require.Len(t, projection.Months, 6)
+
+	// Salaries of 500 and 600 plus a 50 allowance each, with 3% employer NSSA
+	january := projection.Months[0]
+	assert.Equal(t, 2, january.Headcount)
+	require.Len(t, january.Lines, 1)
+	assertAmount(t, 1200, january.Lines[0].GrossPay)
+	assertAmount(t, 36, january.Lines[0].EmployerNSSA)
+	assertAmount(t, 1236, january.TotalCostBase)
+
+	// 8% from March on basic salaries of 540 and 648
+	assertAmount(t, 1326.64, projection.Months[2].TotalCostBase)
+
+	// Two hires at 1000 from April, not raised by the earlier increase
+	april := projection.Months[3]
+	assert.Equal(t, 4, april.Headcount)
+	assertAmount(t, 3386.64, april.TotalCostBase)
+
+	comparison, err := processor.CompareForecasts(company.ID, []uint{baseline.ID, growth.ID})
+	require.NoError(t, err)
+	require.Len(t, comparison.Scenarios, 2)
+	assertAmount(t, 1236*6, comparison.Scenarios[0].TotalCostBase)
+	assert.True(t, comparison.Scenarios[1].Difference.Equal(projection.TotalCostBase.Sub(comparison.Scenarios[0].TotalCostBase)))
+}
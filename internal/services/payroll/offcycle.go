@@ -0,0 +1,334 @@
+package payroll
+
+import (
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"gm58-hr-backend/internal/services/tax"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// CreateOffCyclePeriod creates a draft bonus or correction period for the given month that
+// pays only the listed employees. Final settlements create their own termination periods.
+func (pp *PayrollProcessor) CreateOffCyclePeriod(companyID uint, periodType string, year, month int, description string, lines []models.PayrollPeriodEmployee) (*models.PayrollPeriod, error) {
+	switch periodType {
+	case models.PeriodTypeBonus, models.PeriodTypeCorrection:
+	case models.PeriodTypeTermination:
+		return nil, fmt.Errorf("termination periods are created from the employee's final settlement")
+	default:
+		return nil, fmt.Errorf("invalid off-cycle period type: %s", periodType)
+	}
+	if month < 1 || month > 12 {
+		return nil, fmt.Errorf("invalid month: %d", month)
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("select at least one employee")
+	}
+
+	seen := make(map[uint]bool, len(lines))
+	employeeIDs := make([]uint, 0, len(lines))
+	for _, line := range lines {
+		if seen[line.EmployeeID] {
+			return nil, fmt.Errorf("employee %d is listed more than once", line.EmployeeID)
+		}
+		seen[line.EmployeeID] = true
+		employeeIDs = append(employeeIDs, line.EmployeeID)
+
+		if line.Bonus.IsNegative() || line.OtherEarnings.IsNegative() || line.OtherDeductions.IsNegative() {
+			return nil, fmt.Errorf("amounts for employee %d cannot be negative", line.EmployeeID)
+		}
+		if line.Bonus.IsZero() && line.OtherEarnings.IsZero() && line.OtherDeductions.IsZero() {
+			return nil, fmt.Errorf("no amounts given for employee %d", line.EmployeeID)
+		}
+		if line.TaxFreeAmount.IsNegative() || line.TaxFreeAmount.GreaterThan(line.Bonus) {
+			return nil, fmt.Errorf("tax-free amount for employee %d must be between zero and the bonus", line.EmployeeID)
+		}
+	}
+
+	var found int64
+	if err := pp.db.Model(&models.Employee{}).
+		Where("company_id = ? AND id IN ? AND is_active = ? AND employment_status = ?",
+			companyID, employeeIDs, true, "active").
+		Count(&found).Error; err != nil {
+		return nil, fmt.Errorf("failed to check employees: %w", err)
+	}
+	if int(found) != len(employeeIDs) {
+		return nil, fmt.Errorf("every selected employee must be an active employee of the company")
+	}
+
+	startDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	period := models.PayrollPeriod{
+		CompanyID:   companyID,
+		PeriodType:  periodType,
+		Year:        year,
+		Month:       month,
+		StartDate:   startDate,
+		EndDate:     startDate.AddDate(0, 1, -1),
+		Status:      "draft",
+		Description: description,
+	}
+
+	err := pp.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&period).Error; err != nil {
+			return fmt.Errorf("failed to create payroll period: %w", err)
+		}
+		for i := range lines {
+			lines[i].ID = 0
+			lines[i].PayrollPeriodID = period.ID
+		}
+		if err := tx.Create(&lines).Error; err != nil {
+			return fmt.Errorf("failed to save period employees: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	period.Employees = lines
+	return &period, nil
+}
+
+// processOffCyclePeriod calculates the payslips for a bonus or correction period. Only the
+// amounts captured for the period are paid; recurring allowances and deductions stay with
+// the regular run. The period must already be claimed for processing.
+func (pp *PayrollProcessor) processOffCyclePeriod(period models.PayrollPeriod, processedBy uint) error {
+	var lines []models.PayrollPeriodEmployee
+	if err := pp.db.Preload("Employee.Currency").Preload("Employee.Position").Preload("Employee.Department").
+		Where("payroll_period_id = ?", period.ID).
+		Find(&lines).Error; err != nil {
+		return fmt.Errorf("failed to fetch period employees: %w", err)
+	}
+
+	var company models.Company
+	if err := pp.db.Preload("BaseCurrency").First(&company, period.CompanyID).Error; err != nil {
+		return fmt.Errorf("failed to get company base currency: %w", err)
+	}
+	var settings models.CompanySettings
+	pp.db.Where("company_id = ?", period.CompanyID).First(&settings)
+	rounding := settings.Rounding()
+	baseCode := company.BaseCurrency.Code
+
+	employees := make([]models.Employee, 0, len(lines))
+	for _, line := range lines {
+		employees = append(employees, line.Employee)
+	}
+	monthToDate, err := pp.monthToDateEarnings(period, employees)
+	if err != nil {
+		return err
+	}
+	employeeIDs := make([]uint, 0, len(employees))
+	for _, employee := range employees {
+		employeeIDs = append(employeeIDs, employee.ID)
+	}
+	directives, err := pp.directivesFor(period, employeeIDs)
+	if err != nil {
+		return err
+	}
+
+	payslips := make([]models.Payslip, 0, len(lines))
+	for _, line := range lines {
+		employee := line.Employee
+		currencyCode := employee.Currency.Code
+
+		exchangeRate, err := pp.currencyService.GetExchangeRateAt(currencyCode, baseCode, period.EndDate)
+		if err != nil {
+			return fmt.Errorf("failed to get exchange rate for employee %s: %w", employee.EmployeeNumber, err)
+		}
+
+		bonus := rounding.Round(line.Bonus, currencyCode)
+		otherEarnings := rounding.Round(line.OtherEarnings, currencyCode)
+		otherDeductions := rounding.Round(line.OtherDeductions, currencyCode)
+		taxFree := rounding.Round(line.TaxFreeAmount, currencyCode)
+
+		trace := newCalculationTrace(currencyCode, rounding)
+		if !bonus.IsZero() {
+			trace.total(models.TraceSectionEarnings, "bonus", "Bonus", bonus)
+		}
+		if !otherEarnings.IsZero() {
+			trace.total(models.TraceSectionEarnings, "other_earnings", "Other earnings", otherEarnings)
+		}
+		totalEarnings := bonus.Add(otherEarnings)
+		trace.total(models.TraceSectionEarnings, "total_earnings", "Total earnings", totalEarnings)
+
+		var payeeTax, aidsLevy, nssaContribution, withholdingTax decimal.Decimal
+		var withholdingRate decimal.Decimal
+		directive, directed := directives[employee.ID]
+		directed = directed && settings.EnablePAYE && !employee.IsContractor()
+		if employee.IsContractor() {
+			withholdingRate = contractorWithholdingRate(employee, settings)
+			withholdingTax = rounding.Round(money.PercentOf(totalEarnings, withholdingRate), currencyCode)
+			trace.withholding(totalEarnings, withholdingRate, withholdingTax)
+		} else {
+			// The tax-free part of a bonus is left out of PAYE but still counts for NSSA
+			if !taxFree.IsZero() {
+				trace.total(models.TraceSectionCredits, "tax_free_earnings", "Tax-free part of the bonus, exempt from PAYE", taxFree.Neg())
+			}
+			trace.total(models.TraceSectionTaxable, "taxable_earnings", "Earnings taxed in this period", totalEarnings.Sub(taxFree))
+			var applied *models.TaxDirective
+			if directed {
+				applied = &directive
+			}
+			payeeTax, aidsLevy, nssaContribution, err = pp.taxOnTop(totalEarnings.Sub(taxFree), totalEarnings,
+				monthToDate[employee.ID], currencyCode, period.EndDate, settings, applied, trace)
+			if err != nil {
+				return fmt.Errorf("failed to calculate tax for employee %s: %w", employee.EmployeeNumber, err)
+			}
+		}
+
+		if !otherDeductions.IsZero() {
+			trace.total(models.TraceSectionDeductions, "other_deductions", "Other deductions", otherDeductions)
+		}
+		totalDeductions := money.Sum(payeeTax, aidsLevy, nssaContribution, withholdingTax, otherDeductions)
+		netPay := totalEarnings.Sub(totalDeductions)
+		totalEarningsBase := rounding.Round(totalEarnings.Mul(exchangeRate), baseCode)
+		totalDeductionsBase := rounding.Round(totalDeductions.Mul(exchangeRate), baseCode)
+		netPayBase := totalEarningsBase.Sub(totalDeductionsBase)
+		trace.total(models.TraceSectionDeductions, "total_deductions", "Total deductions", totalDeductions)
+		trace.netPay(netPay, exchangeRate, baseCode, netPayBase)
+
+		payslip := models.Payslip{
+			CompanyID:           period.CompanyID,
+			EmployeeID:          employee.ID,
+			PayrollPeriodID:     period.ID,
+			CurrencyID:          employee.CurrencyID,
+			ExchangeRate:        exchangeRate,
+			TaxTableVersion:     tax.TaxTableVersion,
+			Bonus:               bonus,
+			OtherEarnings:       otherEarnings,
+			TotalEarnings:       totalEarnings,
+			TaxFreeEarnings:     taxFree,
+			PayeeTax:            payeeTax,
+			AidsLevy:            aidsLevy,
+			NSSAContribution:    nssaContribution,
+			WithholdingTax:      withholdingTax,
+			WithholdingTaxRate:  withholdingRate,
+			OtherDeductions:     otherDeductions,
+			TotalDeductions:     totalDeductions,
+			NetPay:              netPay,
+			EmployerNSSA:        nssaContribution,
+			TotalEarningsBase:   totalEarningsBase,
+			TotalDeductionsBase: totalDeductionsBase,
+			NetPayBase:          netPayBase,
+			Status:              "generated",
+			CalculationSteps:    trace.steps,
+		}
+		payslip.SnapshotEmployee(employee)
+		if directed {
+			applyDirective(&payslip, directive)
+		}
+		payslips = append(payslips, payslip)
+	}
+
+	now := time.Now()
+	return pp.db.Transaction(func(tx *gorm.DB) error {
+		if len(payslips) > 0 {
+			if err := tx.CreateInBatches(&payslips, payslipBatchSize).Error; err != nil {
+				return fmt.Errorf("failed to save payslips: %w", err)
+			}
+		}
+		return tx.Model(&period).Updates(map[string]interface{}{
+			"status":         "processed",
+			"processed_at":   now,
+			"processed_by":   processedBy,
+			"approval_level": 0,
+		}).Error
+	})
+}
+
+// monthToDateEarnings returns each employee's taxable earnings already paid in the period's
+// month through the company's other processed periods. Back pay is left out because it is
+// taxed at the rates of the months it relates to, and so are tax-free earnings and payslips
+// in another currency.
+func (pp *PayrollProcessor) monthToDateEarnings(period models.PayrollPeriod, employees []models.Employee) (map[uint]decimal.Decimal, error) {
+	earnings := make(map[uint]decimal.Decimal)
+	if len(employees) == 0 {
+		return earnings, nil
+	}
+
+	currencyByEmployee := make(map[uint]uint, len(employees))
+	employeeIDs := make([]uint, 0, len(employees))
+	for _, employee := range employees {
+		currencyByEmployee[employee.ID] = employee.CurrencyID
+		employeeIDs = append(employeeIDs, employee.ID)
+	}
+
+	var rows []struct {
+		EmployeeID uint
+		CurrencyID uint
+		Earnings   decimal.Decimal
+	}
+	if err := pp.db.Model(&models.Payslip{}).
+		Select("payslips.employee_id, payslips.currency_id, SUM(payslips.total_earnings - payslips.back_pay - payslips.tax_free_earnings) AS earnings").
+		Joins("JOIN payroll_periods ON payroll_periods.id = payslips.payroll_period_id").
+		Where("payroll_periods.company_id = ? AND payroll_periods.year = ? AND payroll_periods.month = ? AND payroll_periods.id <> ?",
+			period.CompanyID, period.Year, period.Month, period.ID).
+		Where("payroll_periods.status IN ?", []string{"processed", "approved", "paid"}).
+		Where("payslips.employee_id IN ?", employeeIDs).
+		Group("payslips.employee_id, payslips.currency_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch month-to-date earnings: %w", err)
+	}
+
+	for _, row := range rows {
+		if currencyByEmployee[row.EmployeeID] == row.CurrencyID {
+			earnings[row.EmployeeID] = earnings[row.EmployeeID].Add(row.Earnings)
+		}
+	}
+	return earnings, nil
+}
+
+// taxOnTop works out PAYE and AIDS levy on taxable earnings paid on top of earnings already
+// taxed earlier in the month: the tax on the combined total less the tax already due on
+// the earlier earnings, so the month is taxed as if paid at once. A tax directive, when
+// given, sets the PAYE instead of the brackets. NSSA is charged on insurable earnings the
+// same way. The brackets are applied at the rates in force on the given date, the end of the
+// period being paid. Each step is recorded on the trace.
+func (pp *PayrollProcessor) taxOnTop(taxable, insurable, earlier decimal.Decimal, currencyCode string, at time.Time, settings models.CompanySettings,
+	directive *models.TaxDirective, trace *calculationTrace) (paye, aidsLevy, nssa decimal.Decimal, err error) {
+	rounding := settings.Rounding()
+	switch {
+	case directive != nil:
+		paye = rounding.Round(pp.taxCalculator.CalculateDirectivePAYE(*directive, taxable), currencyCode)
+		trace.directive(*directive, taxable, paye)
+	case settings.EnablePAYE:
+		toUSD, fromUSD, err := pp.usdRatesAt(currencyCode, at)
+		if err != nil {
+			return paye, aidsLevy, nssa, fmt.Errorf("failed to calculate PAYE: %w", err)
+		}
+		paye = rounding.Round(pp.taxCalculator.CalculateMonthlyPAYEAtRates(earlier.Add(taxable), toUSD, fromUSD).
+			Sub(pp.taxCalculator.CalculateMonthlyPAYEAtRates(earlier, toUSD, fromUSD)), currencyCode)
+		trace.paye(pp.taxCalculator, taxable, earlier, toUSD, fromUSD, paye)
+	}
+	if settings.EnableAidsLevy {
+		aidsLevy = rounding.Round(pp.taxCalculator.CalculateAidsLevy(paye), currencyCode)
+	}
+	if settings.EnableNSSA {
+		combined, err := pp.taxCalculator.CalculateNSSAContribution(earlier.Add(insurable), currencyCode)
+		if err != nil {
+			return paye, aidsLevy, nssa, fmt.Errorf("failed to calculate NSSA: %w", err)
+		}
+		already, err := pp.taxCalculator.CalculateNSSAContribution(earlier, currencyCode)
+		if err != nil {
+			return paye, aidsLevy, nssa, fmt.Errorf("failed to calculate NSSA: %w", err)
+		}
+		nssa = rounding.Round(combined.Sub(already), currencyCode)
+	}
+	trace.levies(paye, aidsLevy, insurable, nssa, settings)
+	return paye, aidsLevy, nssa, nil
+}
+
+// usdRatesAt returns the rates between a currency and USD, the currency of the tax brackets,
+// in force on a date
+func (pp *PayrollProcessor) usdRatesAt(currencyCode string, date time.Time) (toUSD, fromUSD decimal.Decimal, err error) {
+	if toUSD, err = pp.currencyService.GetExchangeRateAt(currencyCode, "USD", date); err != nil {
+		return toUSD, fromUSD, err
+	}
+	if fromUSD, err = pp.currencyService.GetExchangeRateAt("USD", currencyCode, date); err != nil {
+		return toUSD, fromUSD, err
+	}
+	return toUSD, fromUSD, nil
+}
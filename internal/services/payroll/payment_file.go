@@ -0,0 +1,128 @@
+package payroll
+
+import (
+	"encoding/csv"
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"io"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// PaymentBatch groups the payments for a payroll period that are made in one currency.
+type PaymentBatch struct {
+	Currency   string          `json:"currency"`
+	CurrencyID uint            `json:"currency_id"`
+	Items      []PaymentItem   `json:"items"`
+	Total      decimal.Decimal `json:"total"`
+}
+
+type PaymentItem struct {
+	PayslipID      uint            `json:"payslip_id"`
+	EmployeeID     uint            `json:"employee_id"`
+	EmployeeNumber string          `json:"employee_number"`
+	EmployeeName   string          `json:"employee_name"`
+	PaymentMethod  string          `json:"payment_method"`
+	BankName       string          `json:"bank_name"`
+	BankAccount    string          `json:"bank_account"`
+	BankBranch     string          `json:"bank_branch"`
+	BankCode       string          `json:"bank_code"`
+	SwiftCode      string          `json:"swift_code"`
+	Amount         decimal.Decimal `json:"amount"`
+	Reference      string          `json:"reference"`
+}
+
+// BuildPaymentBatches groups the net pay for a period by payout currency. Split-currency
+// payslips contribute one payment per currency; all others pay their net pay in the payslip currency.
+func (pp *PayrollProcessor) BuildPaymentBatches(periodID, companyID uint) ([]PaymentBatch, error) {
+	var period models.PayrollPeriod
+	if err := pp.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
+		return nil, fmt.Errorf("payroll period not found: %w", err)
+	}
+
+	var payslips []models.Payslip
+	if err := pp.db.Preload("Currency").
+		Preload("NetPaySplits").Preload("NetPaySplits.Currency").
+		Where("payroll_period_id = ? AND company_id = ?", periodID, companyID).
+		Find(&payslips).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch payslips: %w", err)
+	}
+
+	batches := make(map[string]*PaymentBatch)
+	add := func(currency models.Currency, payslip models.Payslip, amount decimal.Decimal) {
+		if !amount.IsPositive() {
+			return
+		}
+		batch, ok := batches[currency.Code]
+		if !ok {
+			batch = &PaymentBatch{Currency: currency.Code, CurrencyID: currency.ID}
+			batches[currency.Code] = batch
+		}
+
+		batch.Items = append(batch.Items, PaymentItem{
+			PayslipID:      payslip.ID,
+			EmployeeID:     payslip.EmployeeID,
+			EmployeeNumber: payslip.EmployeeNumber,
+			EmployeeName:   payslip.EmployeeName,
+			PaymentMethod:  payslip.PaymentMethod,
+			BankName:       payslip.BankName,
+			BankAccount:    payslip.BankAccount,
+			BankBranch:     payslip.BankBranch,
+			BankCode:       payslip.BankCode,
+			SwiftCode:      payslip.SwiftCode,
+			Amount:         amount,
+			Reference:      fmt.Sprintf("SAL %04d-%02d %s", period.Year, period.Month, payslip.EmployeeNumber),
+		})
+		batch.Total = batch.Total.Add(amount)
+	}
+
+	for _, payslip := range payslips {
+		if len(payslip.NetPaySplits) == 0 {
+			add(payslip.Currency, payslip, payslip.NetPay)
+			continue
+		}
+		for _, split := range payslip.NetPaySplits {
+			add(split.Currency, payslip, split.NetAmount)
+		}
+	}
+
+	result := make([]PaymentBatch, 0, len(batches))
+	for _, batch := range batches {
+		result = append(result, *batch)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Currency < result[j].Currency })
+
+	return result, nil
+}
+
+// WritePaymentFile writes a bank transfer file for a single currency batch.
+func WritePaymentFile(w io.Writer, batch PaymentBatch) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write([]string{"Employee Number", "Employee Name", "Payment Method", "Bank Name", "Bank Code", "Branch", "Account Number", "Swift Code", "Currency", "Amount", "Reference"}); err != nil {
+		return err
+	}
+
+	for _, item := range batch.Items {
+		if err := writer.Write([]string{
+			item.EmployeeNumber,
+			item.EmployeeName,
+			item.PaymentMethod,
+			item.BankName,
+			item.BankCode,
+			item.BankBranch,
+			item.BankAccount,
+			item.SwiftCode,
+			batch.Currency,
+			item.Amount.StringFixed(money.Places(batch.Currency)),
+			item.Reference,
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -1,19 +1,30 @@
 package payroll
 
 import (
+	"errors"
 	"fmt"
 	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
 	"gm58-hr-backend/internal/services/currency"
+	"gm58-hr-backend/internal/services/leave"
 	"gm58-hr-backend/internal/services/tax"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrPeriodNotDraft is returned when a payroll run is requested for a period that is
+// already being processed or has been processed.
+var ErrPeriodNotDraft = errors.New("payroll period is not in draft status")
+
 type PayrollProcessor struct {
 	db              *gorm.DB
 	taxCalculator   *tax.TaxCalculator
 	currencyService *currency.CurrencyService
+	leaveService    *leave.LeaveService
+	workers         int
 }
 
 func NewPayrollProcessor(db *gorm.DB, currencyService *currency.CurrencyService) *PayrollProcessor {
@@ -21,228 +32,135 @@ func NewPayrollProcessor(db *gorm.DB, currencyService *currency.CurrencyService)
 		db:              db,
 		taxCalculator:   tax.NewTaxCalculator(currencyService),
 		currencyService: currencyService,
+		leaveService:    leave.NewLeaveService(db),
+		workers:         defaultPayrollWorkers,
 	}
 }
 
+// withDB returns a copy of the processor that works through db, so its methods can run
+// inside a caller's transaction.
+func (pp *PayrollProcessor) withDB(db *gorm.DB) *PayrollProcessor {
+	clone := *pp
+	clone.db = db
+	return &clone
+}
+
 // Add these methods to the PayrollProcessor for multi-company support
 
-func (pp *PayrollProcessor) ProcessPayrollForCompany(periodID uint, companyID uint) error {
+func (pp *PayrollProcessor) ProcessPayrollForCompany(periodID uint, companyID uint, processedBy uint) error {
+	// Move the period to processing under a row lock, so only one of several
+	// simultaneous requests gets to run it. Scheduled salary changes that have
+	// fallen due are written to the employee records in the same transaction.
 	var period models.PayrollPeriod
-	if err := pp.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
-		return fmt.Errorf("payroll period not found: %w", err)
-	}
-
-	if period.Status != "draft" {
-		return fmt.Errorf("payroll period is not in draft status")
-	}
-
-	// Update status to processing
-	period.Status = "processing"
-	pp.db.Save(&period)
-
-	var employees []models.Employee
-	if err := pp.db.Preload("Currency").Preload("Position").Preload("Department").
-		Where("company_id = ? AND is_active = ? AND employment_status = ?",
-			companyID, true, "active").
-		Find(&employees).Error; err != nil {
-		return fmt.Errorf("failed to fetch employees: %w", err)
-	}
-
-	// Get company settings for tax configuration
-	var companySettings models.CompanySettings
-	pp.db.Where("company_id = ?", companyID).First(&companySettings)
-
-	for _, employee := range employees {
-		if err := pp.processEmployeePayrollWithCompany(employee, period, companySettings); err != nil {
-			fmt.Printf("Failed to process payroll for employee %s: %v\n", employee.EmployeeNumber, err)
-			continue
+	err := pp.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
+			return fmt.Errorf("payroll period not found: %w", err)
 		}
-	}
-
-	// Update period status
-	now := time.Now()
-	period.Status = "processed"
-	period.ProcessedAt = &now
-	if err := pp.db.Save(&period).Error; err != nil {
-		return fmt.Errorf("failed to update period status: %w", err)
-	}
-
-	return nil
-}
-
-func (pp *PayrollProcessor) processEmployeePayrollWithCompany(employee models.Employee, period models.PayrollPeriod, settings models.CompanySettings) error {
-	// Check if payslip already exists
-	var existingPayslip models.Payslip
-	if err := pp.db.Where("employee_id = ? AND payroll_period_id = ? AND company_id = ?",
-		employee.ID, period.ID, employee.CompanyID).First(&existingPayslip).Error; err == nil {
-		return fmt.Errorf("payslip already exists for employee %s", employee.EmployeeNumber)
-	}
-
-	// Get exchange rate for employee currency to base currency
-	baseCurrency, err := pp.getCompanyBaseCurrency(employee.CompanyID)
-	if err != nil {
-		return fmt.Errorf("failed to get company base currency: %w", err)
-	}
 
-	exchangeRate := 1.0
-	if employee.Currency.Code != baseCurrency.Code {
-		rate, err := pp.currencyService.GetExchangeRate(employee.Currency.Code, baseCurrency.Code)
-		if err != nil {
-			return fmt.Errorf("failed to get exchange rate: %w", err)
+		if period.Status != "draft" {
+			return fmt.Errorf("%w: it is %s", ErrPeriodNotDraft, period.Status)
 		}
-		exchangeRate = rate
-	}
-
-	// Calculate earnings
-	basicSalary := employee.BasicSalary
 
-	// Get allowances for the employee
-	allowances, err := pp.calculateAllowancesForCompany(employee.ID, employee.CompanyID, employee.Currency.Code)
+		period.Status = "processing"
+		if err := tx.Model(&period).Update("status", period.Status).Error; err != nil {
+			return err
+		}
+		return pp.withDB(tx).ApplyDueCompensationChanges(companyID)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to calculate allowances: %w", err)
+		return err
 	}
 
-	// Calculate overtime (company-specific rates)
-	overtime := pp.calculateOvertimeForCompany(employee.ID, period, employee.CompanyID)
-
-	// Calculate bonus
-	bonus := pp.calculateBonusForCompany(employee.ID, period, employee.CompanyID)
-
-	totalEarnings := basicSalary + allowances + overtime + bonus
-
-	// Calculate deductions based on company settings
-	var payeeTax, aidsLevy, nssaContribution float64
-
-	if settings.EnablePAYE {
-		payeeTax, err = pp.taxCalculator.CalculateMonthlyPAYE(totalEarnings, employee.Currency.Code)
+	if period.IsOffCycle() {
+		var report *ValidationReport
+		report, err = pp.validateOffCyclePeriod(period)
+		if err == nil && !report.CanProcess {
+			err = &ValidationError{Report: report}
+		}
+		if err == nil {
+			if period.PeriodType == models.PeriodTypeTermination {
+				err = pp.processFinalSettlement(period, processedBy)
+			} else {
+				err = pp.processOffCyclePeriod(period, processedBy)
+			}
+		}
 		if err != nil {
-			return fmt.Errorf("failed to calculate PAYE: %w", err)
+			pp.db.Model(&period).Update("status", "draft")
+			return err
 		}
+		return nil
 	}
 
-	if settings.EnableAidsLevy {
-		aidsLevy = pp.taxCalculator.CalculateAidsLevy(payeeTax)
-	}
-
-	if settings.EnableNSSA {
-		nssaContribution, err = pp.taxCalculator.CalculateNSSAContribution(totalEarnings, employee.Currency.Code)
-		if err != nil {
-			return fmt.Errorf("failed to calculate NSSA: %w", err)
+	run, err := pp.loadPayrollRun(period)
+	if err == nil {
+		var report *ValidationReport
+		report, err = pp.validatePayrollRun(run)
+		if err == nil && !report.CanProcess {
+			err = &ValidationError{Report: report}
 		}
 	}
-
-	// Get other deductions
-	otherDeductions, err := pp.calculateDeductionsForCompany(employee.ID, employee.CompanyID, employee.Currency.Code)
 	if err != nil {
-		return fmt.Errorf("failed to calculate deductions: %w", err)
-	}
-
-	totalDeductions := payeeTax + aidsLevy + nssaContribution + otherDeductions
-	netPay := totalEarnings - totalDeductions
-
-	// Convert to base currency for reporting
-	totalEarningsBase := totalEarnings * exchangeRate
-	totalDeductionsBase := totalDeductions * exchangeRate
-	netPayBase := netPay * exchangeRate
-
-	// Get working days based on company settings
-	var company models.Company
-	pp.db.First(&company, employee.CompanyID)
-	workingDays := pp.calculateWorkingDaysForCompany(period.StartDate, period.EndDate, company.WorkWeekDays)
-	daysWorked := pp.getDaysWorkedForCompany(employee.ID, period, employee.CompanyID)
-
-	// Create payslip
-	payslip := models.Payslip{
-		CompanyID:           employee.CompanyID,
-		EmployeeID:          employee.ID,
-		PayrollPeriodID:     period.ID,
-		CurrencyID:          employee.CurrencyID,
-		ExchangeRate:        exchangeRate,
-		BasicSalary:         basicSalary,
-		Overtime:            overtime,
-		Allowances:          allowances,
-		Bonus:               bonus,
-		TotalEarnings:       totalEarnings,
-		PayeeTax:            payeeTax,
-		AidsLevy:            aidsLevy,
-		NSSAContribution:    nssaContribution,
-		OtherDeductions:     otherDeductions,
-		TotalDeductions:     totalDeductions,
-		NetPay:              netPay,
-		TotalEarningsBase:   totalEarningsBase,
-		TotalDeductionsBase: totalDeductionsBase,
-		NetPayBase:          netPayBase,
-		WorkingDays:         workingDays,
-		DaysWorked:          daysWorked,
-		DaysAbsent:          workingDays - daysWorked,
-		Status:              "generated",
+		// Release the period so the run can be retried
+		pp.db.Model(&period).Update("status", "draft")
+		return err
 	}
 
-	return pp.db.Create(&payslip).Error
-}
+	results := pp.calculatePayslips(run)
 
-func (pp *PayrollProcessor) getCompanyBaseCurrency(companyID uint) (*models.Currency, error) {
-	var company models.Company
-	if err := pp.db.Preload("BaseCurrency").First(&company, companyID).Error; err != nil {
-		return nil, err
-	}
-	return &company.BaseCurrency, nil
-}
-
-func (pp *PayrollProcessor) calculateAllowancesForCompany(employeeID, companyID uint, currency string) (float64, error) {
-	var allowances []models.Allowance
-	err := pp.db.Preload("Currency").
-		Where("employee_id = ? AND company_id = ? AND is_active = ? AND is_recurring = ?",
-			employeeID, companyID, true, true).Find(&allowances).Error
-	if err != nil {
-		return 0, err
+	payslips := make([]models.Payslip, 0, len(results))
+	for _, result := range results {
+		if result.err != nil {
+			fmt.Printf("Failed to process payroll for employee %s: %v\n", result.employeeNumber, result.err)
+			continue
+		}
+		payslips = append(payslips, result.payslip)
 	}
 
-	total := 0.0
-	for _, allowance := range allowances {
-		amount := allowance.Amount
-
-		// Convert to employee currency if different
-		if allowance.Currency.Code != currency {
-			convertedAmount, err := pp.currencyService.ConvertAmount(amount, allowance.Currency.Code, currency)
-			if err != nil {
-				continue
+	// Payslips, applied back pay and the period status are saved together, so a
+	// failed run leaves the period in draft with nothing written
+	now := time.Now()
+	err = pp.db.Transaction(func(tx *gorm.DB) error {
+		if len(payslips) > 0 {
+			if err := tx.CreateInBatches(&payslips, payslipBatchSize).Error; err != nil {
+				return fmt.Errorf("failed to save payslips: %w", err)
 			}
-			amount = convertedAmount
 		}
 
-		total += amount
-	}
-
-	return total, nil
-}
-
-func (pp *PayrollProcessor) calculateDeductionsForCompany(employeeID, companyID uint, currency string) (float64, error) {
-	var deductions []models.Deduction
-	err := pp.db.Preload("Currency").
-		Where("employee_id = ? AND company_id = ? AND is_active = ? AND is_recurring = ?",
-			employeeID, companyID, true, true).Find(&deductions).Error
-	if err != nil {
-		return 0, err
-	}
-
-	total := 0.0
-	for _, deduction := range deductions {
-		amount := deduction.Amount
+		for _, payslip := range payslips {
+			if err := markTimePaid(tx, run.work[payslip.EmployeeID].entryIDs, payslip); err != nil {
+				return err
+			}
 
-		// Convert to employee currency if different
-		if deduction.Currency.Code != currency {
-			convertedAmount, err := pp.currencyService.ConvertAmount(amount, deduction.Currency.Code, currency)
-			if err != nil {
+			adjustmentIDs := run.backPay[payslip.EmployeeID].adjustmentIDs
+			if len(adjustmentIDs) == 0 {
 				continue
 			}
-			amount = convertedAmount
+			if err := tx.Model(&models.SalaryAdjustment{}).
+				Where("id IN ?", adjustmentIDs).
+				Updates(map[string]interface{}{
+					"status":             "applied",
+					"applied_period_id":  period.ID,
+					"applied_payslip_id": payslip.ID,
+				}).Error; err != nil {
+				return fmt.Errorf("failed to apply back pay: %w", err)
+			}
 		}
 
-		total += amount
+		// The preparer is recorded for segregation of duties at approval
+		return tx.Model(&period).Updates(map[string]interface{}{
+			"status":         "processed",
+			"processed_at":   now,
+			"processed_by":   processedBy,
+			"approval_level": 0,
+		}).Error
+	})
+	if err != nil {
+		pp.db.Model(&period).Update("status", "draft")
+		return err
 	}
 
-	return total, nil
+	return nil
 }
 
 func (pp *PayrollProcessor) calculateWorkingDaysForCompany(startDate, endDate time.Time, workWeekDays int) int {
@@ -270,23 +188,15 @@ func (pp *PayrollProcessor) calculateWorkingDaysForCompany(startDate, endDate ti
 	return days
 }
 
-func (pp *PayrollProcessor) calculateOvertimeForCompany(employeeID uint, period models.PayrollPeriod, companyID uint) float64 {
+func (pp *PayrollProcessor) calculateOvertimeForCompany(employeeID uint, period models.PayrollPeriod, companyID uint) decimal.Decimal {
 	// TODO: Implement company-specific overtime calculation
 	// This would involve checking timesheets, overtime rates, etc.
-	return 0
+	return decimal.Zero
 }
 
-func (pp *PayrollProcessor) calculateBonusForCompany(employeeID uint, period models.PayrollPeriod, companyID uint) float64 {
+func (pp *PayrollProcessor) calculateBonusForCompany(employeeID uint, period models.PayrollPeriod, companyID uint) decimal.Decimal {
 	// TODO: Implement company-specific bonus calculation
-	return 0
-}
-
-func (pp *PayrollProcessor) getDaysWorkedForCompany(employeeID uint, period models.PayrollPeriod, companyID uint) int {
-	// TODO: Implement actual attendance tracking
-	// For now, assume full attendance
-	var company models.Company
-	pp.db.First(&company, companyID)
-	return pp.calculateWorkingDaysForCompany(period.StartDate, period.EndDate, company.WorkWeekDays)
+	return decimal.Zero
 }
 
 func (pp *PayrollProcessor) GetPayrollSummaryForCompany(periodID, companyID uint) (map[string]interface{}, error) {
@@ -296,314 +206,65 @@ func (pp *PayrollProcessor) GetPayrollSummaryForCompany(periodID, companyID uint
 		return nil, err
 	}
 
-	summary := map[string]interface{}{
-		"total_employees":    len(payslips),
-		"total_earnings":     0.0,
-		"total_deductions":   0.0,
-		"total_net_pay":      0.0,
-		"total_paye_tax":     0.0,
-		"total_nssa":         0.0,
-		"currency_breakdown": make(map[string]interface{}),
+	// Regular months accrue their share of the annual bonus for the company's books
+	var period models.PayrollPeriod
+	pp.db.Select("id", "period_type").First(&period, periodID)
+	var bonusPolicy *models.BonusPolicy
+	if period.PeriodType == models.PeriodTypeRegular {
+		var policy models.BonusPolicy
+		if err := pp.db.Where("company_id = ? AND is_active = ?", companyID, true).First(&policy).Error; err == nil {
+			bonusPolicy = &policy
+		}
 	}
 
-	currencyBreakdown := make(map[string]map[string]float64)
+	// Amounts converted to the base currency are totalled exactly and rounded once
+	var company models.Company
+	pp.db.Preload("BaseCurrency").First(&company, companyID)
+	basePlaces := money.Places(company.BaseCurrency.Code)
+
+	var totalEarnings, totalDeductions, totalNetPay, totalPAYE, totalNSSA, totalWithholding, bonusAccrual decimal.Decimal
+	currencyBreakdown := make(map[string]map[string]decimal.Decimal)
 
 	for _, payslip := range payslips {
-		summary["total_earnings"] = summary["total_earnings"].(float64) + payslip.TotalEarningsBase
-		summary["total_deductions"] = summary["total_deductions"].(float64) + payslip.TotalDeductionsBase
-		summary["total_net_pay"] = summary["total_net_pay"].(float64) + payslip.NetPayBase
-		summary["total_paye_tax"] = summary["total_paye_tax"].(float64) + payslip.PayeeTax*payslip.ExchangeRate
-		summary["total_nssa"] = summary["total_nssa"].(float64) + payslip.NSSAContribution*payslip.ExchangeRate
+		rate := payslip.ExchangeRate
+		totalEarnings = totalEarnings.Add(payslip.TotalEarningsBase)
+		totalDeductions = totalDeductions.Add(payslip.TotalDeductionsBase)
+		totalNetPay = totalNetPay.Add(payslip.NetPayBase)
+		totalPAYE = totalPAYE.Add(payslip.PayeeTax.Mul(rate))
+		totalNSSA = totalNSSA.Add(payslip.NSSAContribution.Mul(rate))
+		totalWithholding = totalWithholding.Add(payslip.WithholdingTax.Mul(rate))
 
 		// Track by currency
 		var currency models.Currency
 		pp.db.First(&currency, payslip.CurrencyID)
 
-		if currencyBreakdown[currency.Code] == nil {
-			currencyBreakdown[currency.Code] = make(map[string]float64)
+		breakdown := currencyBreakdown[currency.Code]
+		if breakdown == nil {
+			breakdown = make(map[string]decimal.Decimal)
+			currencyBreakdown[currency.Code] = breakdown
 		}
 
-		currencyBreakdown[currency.Code]["total_earnings"] += payslip.TotalEarnings
-		currencyBreakdown[currency.Code]["total_net_pay"] += payslip.NetPay
-		currencyBreakdown[currency.Code]["employee_count"] += 1
-	}
-
-	summary["currency_breakdown"] = currencyBreakdown
-	return summary, nil
-}
-
-func (pp *PayrollProcessor) ProcessPayroll(periodID uint) error {
-	var period models.PayrollPeriod
-	if err := pp.db.First(&period, periodID).Error; err != nil {
-		return fmt.Errorf("payroll period not found: %w", err)
-	}
+		breakdown["total_earnings"] = breakdown["total_earnings"].Add(payslip.TotalEarnings)
+		breakdown["total_net_pay"] = breakdown["total_net_pay"].Add(payslip.NetPay)
+		breakdown["employee_count"] = breakdown["employee_count"].Add(decimal.NewFromInt(1))
 
-	if period.Status != "draft" {
-		return fmt.Errorf("payroll period is not in draft status")
-	}
-
-	// Update status to processing
-	period.Status = "processing"
-	pp.db.Save(&period)
-
-	var employees []models.Employee
-	if err := pp.db.Preload("Currency").Preload("Position").Preload("Department").
-		Where("is_active = ? AND employment_status = ?", true, "active").
-		Find(&employees).Error; err != nil {
-		return fmt.Errorf("failed to fetch employees: %w", err)
-	}
-
-	for _, employee := range employees {
-		if err := pp.processEmployeePayroll(employee, period); err != nil {
-			fmt.Printf("Failed to process payroll for employee %s: %v\n", employee.EmployeeNumber, err)
-			continue
+		if bonusPolicy != nil && !payslip.IsContractor {
+			accrual := monthlyBonusAccrual(*bonusPolicy, payslip.BasicSalary, currency.Code)
+			bonusAccrual = bonusAccrual.Add(accrual.Mul(rate))
+			breakdown["bonus_accrual"] = breakdown["bonus_accrual"].Add(accrual)
 		}
 	}
 
-	// Update period status
-	now := time.Now()
-	period.Status = "processed"
-	period.ProcessedAt = &now
-	if err := pp.db.Save(&period).Error; err != nil {
-		return fmt.Errorf("failed to update period status: %w", err)
-	}
-
-	return nil
-}
-
-func (pp *PayrollProcessor) processEmployeePayroll(employee models.Employee, period models.PayrollPeriod) error {
-	// Check if payslip already exists
-	var existingPayslip models.Payslip
-	if err := pp.db.Where("employee_id = ? AND payroll_period_id = ?",
-		employee.ID, period.ID).First(&existingPayslip).Error; err == nil {
-		return fmt.Errorf("payslip already exists for employee %s", employee.EmployeeNumber)
-	}
-
-	// Get exchange rate for employee currency to base currency
-	baseCurrency, err := pp.currencyService.GetBaseCurrency()
-	if err != nil {
-		return fmt.Errorf("failed to get base currency: %w", err)
-	}
-
-	exchangeRate := 1.0
-	if employee.Currency.Code != baseCurrency.Code {
-		rate, err := pp.currencyService.GetExchangeRate(employee.Currency.Code, baseCurrency.Code)
-		if err != nil {
-			return fmt.Errorf("failed to get exchange rate: %w", err)
-		}
-		exchangeRate = rate
-	}
-
-	// Calculate earnings
-	basicSalary := employee.BasicSalary
-
-	// Get allowances for the employee
-	allowances, err := pp.calculateAllowances(employee.ID, employee.Currency.Code)
-	if err != nil {
-		return fmt.Errorf("failed to calculate allowances: %w", err)
-	}
-
-	// Calculate overtime
-	overtime := pp.calculateOvertime(employee.ID, period)
-
-	// Calculate bonus
-	bonus := pp.calculateBonus(employee.ID, period)
-
-	totalEarnings := basicSalary + allowances + overtime + bonus
-
-	// Calculate deductions
-	payeeTax, err := pp.taxCalculator.CalculateMonthlyPAYE(totalEarnings, employee.Currency.Code)
-	if err != nil {
-		return fmt.Errorf("failed to calculate PAYE: %w", err)
-	}
-
-	aidsLevy := pp.taxCalculator.CalculateAidsLevy(payeeTax)
-
-	nssaContribution, err := pp.taxCalculator.CalculateNSSAContribution(totalEarnings, employee.Currency.Code)
-	if err != nil {
-		return fmt.Errorf("failed to calculate NSSA: %w", err)
-	}
-
-	// Get other deductions
-	otherDeductions, err := pp.calculateDeductions(employee.ID, employee.Currency.Code)
-	if err != nil {
-		return fmt.Errorf("failed to calculate deductions: %w", err)
-	}
-
-	totalDeductions := payeeTax + aidsLevy + nssaContribution + otherDeductions
-	netPay := totalEarnings - totalDeductions
-
-	// Convert to base currency for reporting
-	totalEarningsBase := totalEarnings * exchangeRate
-	totalDeductionsBase := totalDeductions * exchangeRate
-	netPayBase := netPay * exchangeRate
-
-	// Get working days
-	workingDays := pp.calculateWorkingDays(period.StartDate, period.EndDate)
-	daysWorked := pp.getDaysWorked(employee.ID, period)
-
-	// Create payslip
-	payslip := models.Payslip{
-		EmployeeID:          employee.ID,
-		PayrollPeriodID:     period.ID,
-		CurrencyID:          employee.CurrencyID,
-		ExchangeRate:        exchangeRate,
-		BasicSalary:         basicSalary,
-		Overtime:            overtime,
-		Allowances:          allowances,
-		Bonus:               bonus,
-		TotalEarnings:       totalEarnings,
-		PayeeTax:            payeeTax,
-		AidsLevy:            aidsLevy,
-		NSSAContribution:    nssaContribution,
-		OtherDeductions:     otherDeductions,
-		TotalDeductions:     totalDeductions,
-		NetPay:              netPay,
-		TotalEarningsBase:   totalEarningsBase,
-		TotalDeductionsBase: totalDeductionsBase,
-		NetPayBase:          netPayBase,
-		WorkingDays:         workingDays,
-		DaysWorked:          daysWorked,
-		DaysAbsent:          workingDays - daysWorked,
-		Status:              "generated",
-	}
-
-	return pp.db.Create(&payslip).Error
-}
-
-func (pp *PayrollProcessor) calculateAllowances(employeeID uint, currency string) (float64, error) {
-	var allowances []models.Allowance
-	err := pp.db.Preload("Currency").Where("employee_id = ? AND is_active = ? AND is_recurring = ?",
-		employeeID, true, true).Find(&allowances).Error
-	if err != nil {
-		return 0, err
-	}
-
-	total := 0.0
-	for _, allowance := range allowances {
-		amount := allowance.Amount
-
-		// Convert to employee currency if different
-		if allowance.Currency.Code != currency {
-			convertedAmount, err := pp.currencyService.ConvertAmount(amount, allowance.Currency.Code, currency)
-			if err != nil {
-				continue
-			}
-			amount = convertedAmount
-		}
-
-		total += amount
-	}
-
-	return total, nil
-}
-
-func (pp *PayrollProcessor) calculateDeductions(employeeID uint, currency string) (float64, error) {
-	var deductions []models.Deduction
-	err := pp.db.Preload("Currency").Where("employee_id = ? AND is_active = ? AND is_recurring = ?",
-		employeeID, true, true).Find(&deductions).Error
-	if err != nil {
-		return 0, err
-	}
-
-	total := 0.0
-	for _, deduction := range deductions {
-		amount := deduction.Amount
-
-		// Convert to employee currency if different
-		if deduction.Currency.Code != currency {
-			convertedAmount, err := pp.currencyService.ConvertAmount(amount, deduction.Currency.Code, currency)
-			if err != nil {
-				continue
-			}
-			amount = convertedAmount
-		}
-
-		total += amount
-	}
-
-	return total, nil
-}
-
-func (pp *PayrollProcessor) calculateOvertime(employeeID uint, period models.PayrollPeriod) float64 {
-	return 0
-}
-
-func (pp *PayrollProcessor) calculateBonus(employeeID uint, period models.PayrollPeriod) float64 {
-	return 0
-}
-
-func (pp *PayrollProcessor) calculateWorkingDays(startDate, endDate time.Time) int {
-	days := 0
-	for d := startDate; d.Before(endDate) || d.Equal(endDate); d = d.AddDate(0, 0, 1) {
-		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
-			days++
-		}
-	}
-	return days
-}
-
-func (pp *PayrollProcessor) getDaysWorked(employeeID uint, period models.PayrollPeriod) int {
-	return pp.calculateWorkingDays(period.StartDate, period.EndDate)
-}
-
-func (pp *PayrollProcessor) ApprovePayroll(periodID uint, approverID uint) error {
-	var period models.PayrollPeriod
-	if err := pp.db.First(&period, periodID).Error; err != nil {
-		return fmt.Errorf("payroll period not found: %w", err)
-	}
-
-	if period.Status != "processed" {
-		return fmt.Errorf("payroll period must be processed before approval")
-	}
-
-	now := time.Now()
-	period.Status = "approved"
-	period.ApprovedAt = &now
-	period.ApprovedBy = &approverID
-
-	return pp.db.Save(&period).Error
-}
-
-func (pp *PayrollProcessor) GetPayrollSummary(periodID uint) (map[string]interface{}, error) {
-	var payslips []models.Payslip
-	err := pp.db.Where("payroll_period_id = ?", periodID).Find(&payslips).Error
-	if err != nil {
-		return nil, err
-	}
-
 	summary := map[string]interface{}{
 		"total_employees":    len(payslips),
-		"total_earnings":     0.0,
-		"total_deductions":   0.0,
-		"total_net_pay":      0.0,
-		"total_paye_tax":     0.0,
-		"total_nssa":         0.0,
-		"currency_breakdown": make(map[string]interface{}),
+		"total_earnings":     totalEarnings,
+		"total_deductions":   totalDeductions,
+		"total_net_pay":      totalNetPay,
+		"total_paye_tax":     totalPAYE.Round(basePlaces),
+		"total_nssa":         totalNSSA.Round(basePlaces),
+		"total_withholding":  totalWithholding.Round(basePlaces),
+		"bonus_accrual":      bonusAccrual.Round(basePlaces),
+		"currency_breakdown": currencyBreakdown,
 	}
-
-	currencyBreakdown := make(map[string]map[string]float64)
-
-	for _, payslip := range payslips {
-		summary["total_earnings"] = summary["total_earnings"].(float64) + payslip.TotalEarningsBase
-		summary["total_deductions"] = summary["total_deductions"].(float64) + payslip.TotalDeductionsBase
-		summary["total_net_pay"] = summary["total_net_pay"].(float64) + payslip.NetPayBase
-		summary["total_paye_tax"] = summary["total_paye_tax"].(float64) + payslip.PayeeTax*payslip.ExchangeRate
-		summary["total_nssa"] = summary["total_nssa"].(float64) + payslip.NSSAContribution*payslip.ExchangeRate
-
-		// Track by currency
-		var currency models.Currency
-		pp.db.First(&currency, payslip.CurrencyID)
-
-		if currencyBreakdown[currency.Code] == nil {
-			currencyBreakdown[currency.Code] = make(map[string]float64)
-		}
-
-		currencyBreakdown[currency.Code]["total_earnings"] += payslip.TotalEarnings
-		currencyBreakdown[currency.Code]["total_net_pay"] += payslip.NetPay
-		currencyBreakdown[currency.Code]["employee_count"] += 1
-	}
-
-	summary["currency_breakdown"] = currencyBreakdown
 	return summary, nil
 }
@@ -0,0 +1,551 @@
+package payroll
+
+import (
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"gm58-hr-backend/internal/services/currency"
+	"gm58-hr-backend/internal/services/tax"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// defaultPayrollWorkers is the number of employees calculated in parallel during a run
+	defaultPayrollWorkers = 4
+	// payslipBatchSize is the number of payslips written per insert statement
+	payslipBatchSize = 100
+)
+
+// payrollRun holds everything needed to calculate a period's payslips, loaded up front
+// so that employees can be calculated in parallel without touching the database.
+type payrollRun struct {
+	period       models.PayrollPeriod
+	settings     models.CompanySettings
+	baseCurrency models.Currency
+	workingDays  int
+	employees    []models.Employee
+	existing     map[uint]bool
+	allowances   map[uint][]models.Allowance
+	deductions   map[uint][]models.Deduction
+	backPay      map[uint]pendingBackPay
+	monthToDate  map[uint]decimal.Decimal
+	work         map[uint]approvedTime
+	pendingWork  map[uint]int
+	directives   map[uint]models.TaxDirective
+	compensation map[uint][]models.CompensationChange
+	rates        *rateTable
+	bandRates    *rateTable
+}
+
+// payslipResult is the outcome of calculating one employee's payslip
+type payslipResult struct {
+	employeeNumber string
+	payslip        models.Payslip
+	err            error
+}
+
+// SetWorkers sets how many employees are calculated in parallel during a payroll run.
+func (pp *PayrollProcessor) SetWorkers(workers int) {
+	if workers > 0 {
+		pp.workers = workers
+	}
+}
+
+// loadPayrollRun fetches the company, its active employees and their allowances, deductions,
+// pending back pay and exchange rates in a fixed number of queries.
+func (pp *PayrollProcessor) loadPayrollRun(period models.PayrollPeriod) (*payrollRun, error) {
+	companyID := period.CompanyID
+	run := &payrollRun{
+		period:     period,
+		existing:   make(map[uint]bool),
+		allowances: make(map[uint][]models.Allowance),
+		deductions: make(map[uint][]models.Deduction),
+		backPay:    make(map[uint]pendingBackPay),
+	}
+
+	var company models.Company
+	if err := pp.db.Preload("BaseCurrency").First(&company, companyID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get company base currency: %w", err)
+	}
+	run.baseCurrency = company.BaseCurrency
+	run.workingDays = pp.calculateWorkingDaysForCompany(period.StartDate, period.EndDate, company.WorkWeekDays)
+
+	// Get company settings for tax configuration
+	pp.db.Where("company_id = ?", companyID).First(&run.settings)
+
+	if err := pp.db.Preload("Currency").Preload("Position.Currency").Preload("Department").
+		Preload("SalaryComponents", "is_active = ?", true).Preload("SalaryComponents.Currency").
+		Where("company_id = ? AND is_active = ? AND employment_status = ?",
+			companyID, true, "active").
+		Order("employee_number").
+		Find(&run.employees).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch employees: %w", err)
+	}
+
+	var existingIDs []uint
+	if err := pp.db.Model(&models.Payslip{}).
+		Where("payroll_period_id = ? AND company_id = ?", period.ID, companyID).
+		Pluck("employee_id", &existingIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch existing payslips: %w", err)
+	}
+	for _, id := range existingIDs {
+		run.existing[id] = true
+	}
+
+	var allowances []models.Allowance
+	if err := pp.db.Preload("Currency").
+		Where("company_id = ? AND is_active = ? AND is_recurring = ?", companyID, true, true).
+		Find(&allowances).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch allowances: %w", err)
+	}
+	for _, allowance := range allowances {
+		run.allowances[allowance.EmployeeID] = append(run.allowances[allowance.EmployeeID], allowance)
+	}
+
+	var deductions []models.Deduction
+	if err := pp.db.Preload("Currency").
+		Where("company_id = ? AND is_active = ? AND is_recurring = ?", companyID, true, true).
+		Find(&deductions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch deductions: %w", err)
+	}
+	for _, deduction := range deductions {
+		run.deductions[deduction.EmployeeID] = append(run.deductions[deduction.EmployeeID], deduction)
+	}
+
+	// Arrears from backdated salary adjustments not yet paid
+	var adjustments []models.SalaryAdjustment
+	if err := pp.db.Preload("Lines").
+		Where("company_id = ? AND status = ?", companyID, "pending").
+		Find(&adjustments).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch pending back pay: %w", err)
+	}
+	for _, adjustment := range adjustments {
+		pending := run.backPay[adjustment.EmployeeID]
+		pending.add(adjustment)
+		run.backPay[adjustment.EmployeeID] = pending
+	}
+
+	// Earnings already paid this month through off-cycle periods
+	monthToDate, err := pp.monthToDateEarnings(period, run.employees)
+	if err != nil {
+		return nil, err
+	}
+	run.monthToDate = monthToDate
+
+	// Approved hours, days and units for employees not on a monthly salary
+	if run.work, err = pp.approvedWork(companyID, period.EndDate); err != nil {
+		return nil, err
+	}
+	if run.pendingWork, err = pp.pendingTimeEntries(period); err != nil {
+		return nil, err
+	}
+
+	employeeIDs := make([]uint, 0, len(run.employees))
+	for _, employee := range run.employees {
+		employeeIDs = append(employeeIDs, employee.ID)
+	}
+	if run.directives, err = pp.directivesFor(period, employeeIDs); err != nil {
+		return nil, err
+	}
+
+	// Compensation history, to pay the salary in force for the period
+	var changes []models.CompensationChange
+	if err := pp.db.Where("employee_id IN ?", employeeIDs).
+		Order("effective_date, id").
+		Find(&changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch compensation history: %w", err)
+	}
+	run.compensation = make(map[uint][]models.CompensationChange)
+	for _, change := range changes {
+		run.compensation[change.EmployeeID] = append(run.compensation[change.EmployeeID], change)
+	}
+
+	run.rates = pp.loadRates(run)
+	run.bandRates = pp.loadBandRates(run)
+	return run, nil
+}
+
+// loadBandRates looks up the period-end rates that convert salaries into the currency of
+// their position's band. They are kept apart from the run's rates because a missing one
+// only stops the band check, not the run.
+func (pp *PayrollProcessor) loadBandRates(run *payrollRun) *rateTable {
+	rates := newRateTable()
+	for _, employee := range run.employees {
+		position := employee.Position
+		if position.MinSalary.IsZero() && position.MaxSalary.IsZero() {
+			continue
+		}
+		rates.load(pp.currencyService, rateKey{from: employee.Currency.Code, to: position.Currency.Code, atPeriodEnd: true}, run.period.EndDate)
+	}
+	return rates
+}
+
+// loadRates looks up every exchange rate the run needs once
+func (pp *PayrollProcessor) loadRates(run *payrollRun) *rateTable {
+	rates := newRateTable()
+	for _, employee := range run.employees {
+		currencyCode := employee.Currency.Code
+		rates.load(pp.currencyService, rateKey{from: currencyCode, to: run.baseCurrency.Code}, run.period.EndDate)
+		if run.settings.EnablePAYE {
+			rates.load(pp.currencyService, rateKey{from: currencyCode, to: "USD"}, run.period.EndDate)
+			rates.load(pp.currencyService, rateKey{from: "USD", to: currencyCode}, run.period.EndDate)
+		}
+		for _, component := range employee.SalaryComponents {
+			rates.load(pp.currencyService, rateKey{from: currencyCode, to: component.Currency.Code, atPeriodEnd: true}, run.period.EndDate)
+		}
+		for _, allowance := range run.allowances[employee.ID] {
+			rates.load(pp.currencyService, rateKey{from: allowance.Currency.Code, to: currencyCode}, run.period.EndDate)
+		}
+		for _, deduction := range run.deductions[employee.ID] {
+			rates.load(pp.currencyService, rateKey{from: deduction.Currency.Code, to: currencyCode}, run.period.EndDate)
+		}
+	}
+	return rates
+}
+
+// calculatePayslips calculates every employee's payslip across the worker pool. Results
+// keep the order of run.employees.
+func (pp *PayrollProcessor) calculatePayslips(run *payrollRun) []payslipResult {
+	results := make([]payslipResult, len(run.employees))
+
+	workers := pp.workers
+	if workers < 1 {
+		workers = defaultPayrollWorkers
+	}
+	if workers > len(run.employees) {
+		workers = len(run.employees)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				employee := run.employees[i]
+				payslip, err := pp.calculatePayslip(employee, run)
+				results[i] = payslipResult{employeeNumber: employee.EmployeeNumber, payslip: payslip, err: err}
+			}
+		}()
+	}
+	for i := range run.employees {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// calculatePayslip works out one employee's payslip from the preloaded run data. Each
+// payslip line is rounded once to the currency's smallest unit and the totals are the exact
+// sums of the rounded lines, so a payslip always adds up.
+func (pp *PayrollProcessor) calculatePayslip(employee models.Employee, run *payrollRun) (models.Payslip, error) {
+	period := run.period
+	settings := run.settings
+	rounding := settings.Rounding()
+	currencyCode := employee.Currency.Code
+	round := func(amount decimal.Decimal) decimal.Decimal {
+		return rounding.Round(amount, currencyCode)
+	}
+
+	if run.existing[employee.ID] {
+		return models.Payslip{}, fmt.Errorf("payslip already exists for employee %s", employee.EmployeeNumber)
+	}
+
+	// Get exchange rate for employee currency to base currency
+	exchangeRate, err := run.rates.rate(rateKey{from: currencyCode, to: run.baseCurrency.Code})
+	if err != nil {
+		return models.Payslip{}, fmt.Errorf("failed to get exchange rate: %w", err)
+	}
+
+	trace := newCalculationTrace(currencyCode, rounding)
+
+	// Salaried employees are paid the salary in force for the period, prorated around changes
+	if employee.IsSalaried() {
+		salary, changes := salaryInForce(employee, run.compensation[employee.ID], period)
+		employee.BasicSalary = round(salary)
+		for _, change := range changes {
+			trace.add(models.PayslipCalculationStep{
+				Section:   models.TraceSectionEarnings,
+				Component: "salary_change",
+				Description: fmt.Sprintf("Salary changed from %s to %s on %s", change.PreviousSalary.StringFixed(money.Places(currencyCode)),
+					change.NewSalary.StringFixed(money.Places(currencyCode)), change.EffectiveDate.Format("2006-01-02")),
+				Rate:   prorationFactor(period, change.EffectiveDate) * 100,
+				Amount: change.NewSalary,
+			})
+		}
+	}
+
+	// Non-salaried employees are paid for their approved time in place of a monthly salary
+	var payQuantity float64
+	var payRate decimal.Decimal
+	if !employee.IsSalaried() {
+		payQuantity = run.work[employee.ID].quantity
+		payRate = employee.PayRate
+		employee.BasicSalary = round(workedPay(employee, payQuantity))
+		trace.add(models.PayslipCalculationStep{
+			Section:     models.TraceSectionEarnings,
+			Component:   "worked_pay",
+			Description: fmt.Sprintf("%g approved %s at %s", payQuantity, workUnits[employee.PayType], payRate),
+			Basis:       payQuantity,
+			Rate:        payRate.InexactFloat64(),
+			Amount:      employee.BasicSalary,
+		})
+	}
+
+	// Calculate earnings, combining salary parts paid in other currencies
+	basicSalary, salarySplits, err := calculateSplitSalary(employee, run.rates, rounding)
+	if err != nil {
+		return models.Payslip{}, fmt.Errorf("failed to calculate split salary: %w", err)
+	}
+	for _, split := range salarySplits {
+		trace.converted(models.TraceSectionEarnings, "salary_component",
+			fmt.Sprintf("Salary part paid in %s", split.currency),
+			split.netPay.GrossAmount, split.currency, decimal.NewFromInt(1).Div(split.rate), split.value)
+	}
+	trace.total(models.TraceSectionEarnings, "basic_salary", "Basic salary", basicSalary)
+
+	allowances := decimal.Zero
+	for _, allowance := range run.allowances[employee.ID] {
+		rate, err := run.rates.rate(rateKey{from: allowance.Currency.Code, to: currencyCode})
+		if err != nil {
+			trace.converted(models.TraceSectionEarnings, "allowance", allowance.Name+" (not paid: no exchange rate)",
+				allowance.Amount, allowance.Currency.Code, decimal.Zero, decimal.Zero)
+			continue
+		}
+		amount := rounding.Line(allowance.Amount.Mul(rate), currencyCode)
+		trace.converted(models.TraceSectionEarnings, "allowance", allowance.Name,
+			allowance.Amount, allowance.Currency.Code, rate, amount)
+		allowances = allowances.Add(amount)
+	}
+	allowances = round(allowances)
+
+	// Calculate overtime (company-specific rates)
+	overtime := round(pp.calculateOvertimeForCompany(employee.ID, period, employee.CompanyID))
+
+	// Calculate bonus
+	bonus := round(pp.calculateBonusForCompany(employee.ID, period, employee.CompanyID))
+
+	backPay := run.backPay[employee.ID]
+
+	taxableEarnings := money.Sum(basicSalary, allowances, overtime, bonus)
+	if !overtime.IsZero() {
+		trace.total(models.TraceSectionEarnings, "overtime", "Overtime", overtime)
+	}
+	if !bonus.IsZero() {
+		trace.total(models.TraceSectionEarnings, "bonus", "Bonus", bonus)
+	}
+	trace.total(models.TraceSectionTaxable, "taxable_earnings", "Earnings taxed this month", taxableEarnings)
+
+	// Calculate deductions based on company settings. Contractors are outside PAYE and NSSA.
+	contractor := employee.IsContractor()
+	var payeeTax, aidsLevy, nssaContribution decimal.Decimal
+
+	directive, directed := run.directives[employee.ID]
+	directed = directed && settings.EnablePAYE && !contractor
+	if directed {
+		// A tax directive replaces the brackets for this payslip
+		payeeTax = round(pp.taxCalculator.CalculateDirectivePAYE(directive, taxableEarnings))
+		trace.directive(directive, taxableEarnings, payeeTax)
+	} else if settings.EnablePAYE && !contractor {
+		toUSD, err := run.rates.rate(rateKey{from: currencyCode, to: "USD"})
+		if err != nil {
+			return models.Payslip{}, fmt.Errorf("failed to calculate PAYE: %w", err)
+		}
+		fromUSD, err := run.rates.rate(rateKey{from: "USD", to: currencyCode})
+		if err != nil {
+			return models.Payslip{}, fmt.Errorf("failed to calculate PAYE: %w", err)
+		}
+		// Tax on top of anything already paid this month, so the month is taxed as a whole
+		earlier := run.monthToDate[employee.ID]
+		payeeTax = round(pp.taxCalculator.CalculateMonthlyPAYEAtRates(earlier.Add(taxableEarnings), toUSD, fromUSD).
+			Sub(pp.taxCalculator.CalculateMonthlyPAYEAtRates(earlier, toUSD, fromUSD)))
+		trace.paye(pp.taxCalculator, taxableEarnings, earlier, toUSD, fromUSD, payeeTax)
+	}
+
+	if settings.EnableAidsLevy {
+		aidsLevy = round(pp.taxCalculator.CalculateAidsLevy(payeeTax))
+	}
+
+	if settings.EnableNSSA && !contractor {
+		nssa, err := pp.taxCalculator.CalculateNSSAContribution(taxableEarnings, currencyCode)
+		if err != nil {
+			return models.Payslip{}, fmt.Errorf("failed to calculate NSSA: %w", err)
+		}
+		nssaContribution = round(nssa)
+	}
+	if !contractor {
+		trace.levies(payeeTax, aidsLevy, taxableEarnings, nssaContribution, settings)
+	}
+
+	// Back pay is taxed at the rates of the periods it relates to, not on top of this month's earnings
+	if contractor {
+		backPay.paye, backPay.aidsLevy, backPay.nssa = decimal.Zero, decimal.Zero, decimal.Zero
+	}
+	if !backPay.arrears.IsZero() {
+		trace.total(models.TraceSectionEarnings, "back_pay", "Arrears from backdated salary adjustments", backPay.arrears)
+		if !contractor {
+			trace.total(models.TraceSectionTax, "back_pay_tax",
+				"PAYE, AIDS levy and NSSA on arrears, at the rates of the months they relate to",
+				money.Sum(backPay.paye, backPay.aidsLevy, backPay.nssa))
+		}
+	}
+	payeeTax = payeeTax.Add(backPay.paye)
+	aidsLevy = aidsLevy.Add(backPay.aidsLevy)
+	nssaContribution = nssaContribution.Add(backPay.nssa)
+	// Employer matches the employee contribution
+	employerNSSA := nssaContribution
+	totalEarnings := taxableEarnings.Add(backPay.arrears)
+	trace.total(models.TraceSectionEarnings, "total_earnings", "Total earnings", totalEarnings)
+
+	// Contractors have tax withheld from the whole payment, arrears included
+	var withholdingTax decimal.Decimal
+	var withholdingRate decimal.Decimal
+	if contractor {
+		withholdingRate = contractorWithholdingRate(employee, settings)
+		withholdingTax = round(money.PercentOf(totalEarnings, withholdingRate))
+		trace.withholding(totalEarnings, withholdingRate, withholdingTax)
+	}
+
+	otherDeductions := decimal.Zero
+	for _, deduction := range run.deductions[employee.ID] {
+		rate, err := run.rates.rate(rateKey{from: deduction.Currency.Code, to: currencyCode})
+		if err != nil {
+			trace.converted(models.TraceSectionDeductions, "deduction", deduction.Name+" (not deducted: no exchange rate)",
+				deduction.Amount, deduction.Currency.Code, decimal.Zero, decimal.Zero)
+			continue
+		}
+		amount := rounding.Line(deduction.Amount.Mul(rate), currencyCode)
+		trace.converted(models.TraceSectionDeductions, "deduction", deduction.Name,
+			deduction.Amount, deduction.Currency.Code, rate, amount)
+		otherDeductions = otherDeductions.Add(amount)
+	}
+	otherDeductions = round(otherDeductions)
+
+	totalDeductions := money.Sum(payeeTax, aidsLevy, nssaContribution, withholdingTax, otherDeductions)
+	netPay := totalEarnings.Sub(totalDeductions)
+
+	// Base currency totals are rounded in the base currency and net pay is their difference,
+	// so reports in the base currency add up as well
+	baseCode := run.baseCurrency.Code
+	totalEarningsBase := rounding.Round(totalEarnings.Mul(exchangeRate), baseCode)
+	totalDeductionsBase := rounding.Round(totalDeductions.Mul(exchangeRate), baseCode)
+	netPayBase := totalEarningsBase.Sub(totalDeductionsBase)
+
+	trace.total(models.TraceSectionDeductions, "total_deductions", "Total deductions", totalDeductions)
+	trace.netPay(netPay, exchangeRate, baseCode, netPayBase)
+
+	// TODO: Use attendance once it is tracked; for now assume full attendance
+	daysWorked := run.workingDays
+	if employee.PayType == models.PayTypeDaily {
+		daysWorked = int(math.Min(math.Round(payQuantity), float64(run.workingDays)))
+	}
+
+	payslip := models.Payslip{
+		CompanyID:           employee.CompanyID,
+		EmployeeID:          employee.ID,
+		PayrollPeriodID:     period.ID,
+		CurrencyID:          employee.CurrencyID,
+		ExchangeRate:        exchangeRate,
+		TaxTableVersion:     tax.TaxTableVersion,
+		BasicSalary:         basicSalary,
+		PayQuantity:         payQuantity,
+		PayRate:             payRate,
+		Overtime:            overtime,
+		Allowances:          allowances,
+		Bonus:               bonus,
+		BackPay:             backPay.arrears,
+		TotalEarnings:       totalEarnings,
+		PayeeTax:            payeeTax,
+		AidsLevy:            aidsLevy,
+		NSSAContribution:    nssaContribution,
+		WithholdingTax:      withholdingTax,
+		WithholdingTaxRate:  withholdingRate,
+		OtherDeductions:     otherDeductions,
+		TotalDeductions:     totalDeductions,
+		NetPay:              netPay,
+		EmployerNSSA:        employerNSSA,
+		TotalEarningsBase:   totalEarningsBase,
+		TotalDeductionsBase: totalDeductionsBase,
+		NetPayBase:          netPayBase,
+		WorkingDays:         run.workingDays,
+		DaysWorked:          daysWorked,
+		DaysAbsent:          run.workingDays - daysWorked,
+		Status:              "generated",
+		NetPaySplits:        allocateNetPay(salarySplits, basicSalary, netPay, currencyCode, rounding),
+		CalculationSteps:    trace.steps,
+	}
+	payslip.SnapshotEmployee(employee)
+	if directed {
+		applyDirective(&payslip, directive)
+	}
+
+	return payslip, nil
+}
+
+// rateKey identifies an exchange rate lookup. atPeriodEnd selects the rate in force at the
+// end of the period rather than the latest rate.
+type rateKey struct {
+	from        string
+	to          string
+	atPeriodEnd bool
+}
+
+// rateTable caches the exchange rates used by a payroll run. It is filled before the
+// workers start and only read afterwards.
+type rateTable struct {
+	rates map[rateKey]decimal.Decimal
+	errs  map[rateKey]error
+}
+
+func newRateTable() *rateTable {
+	return &rateTable{
+		rates: make(map[rateKey]decimal.Decimal),
+		errs:  make(map[rateKey]error),
+	}
+}
+
+func (rt *rateTable) load(source *currency.CurrencyService, key rateKey, periodEnd time.Time) {
+	if key.from == key.to {
+		return
+	}
+	if _, ok := rt.rates[key]; ok {
+		return
+	}
+	if _, ok := rt.errs[key]; ok {
+		return
+	}
+
+	var rate decimal.Decimal
+	var err error
+	if key.atPeriodEnd {
+		rate, err = source.GetExchangeRateAt(key.from, key.to, periodEnd)
+	} else {
+		rate, err = source.GetExchangeRate(key.from, key.to)
+	}
+	if err != nil {
+		rt.errs[key] = err
+		return
+	}
+	rt.rates[key] = rate
+}
+
+func (rt *rateTable) rate(key rateKey) (decimal.Decimal, error) {
+	if key.from == key.to {
+		return decimal.NewFromInt(1), nil
+	}
+	if err, ok := rt.errs[key]; ok {
+		return decimal.Zero, err
+	}
+	rate, ok := rt.rates[key]
+	if !ok {
+		return decimal.Zero, fmt.Errorf("no exchange rate loaded for %s to %s", key.from, key.to)
+	}
+	return rate, nil
+}
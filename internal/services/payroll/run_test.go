@@ -0,0 +1,337 @@
+package payroll
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gm58-hr-backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessPayrollForCompanyMatchesAcrossWorkerCounts(t *testing.T) {
+	db, company := setupPayrollDB(t, 25)
+	processor := newProcessor(db)
+
+	netPayByWorkers := make(map[int]map[uint]string)
+	for i, workers := range []int{1, 8} {
+		processor.SetWorkers(workers)
+		period := createDraftPeriod(t, db, company.ID, i)
+		require.NoError(t, processor.ProcessPayrollForCompany(period.ID, company.ID, 1))
+
+		var payslips []models.Payslip
+		require.NoError(t, db.Where("payroll_period_id = ?", period.ID).Find(&payslips).Error)
+		require.Len(t, payslips, 25)
+
+		netPayByWorkers[workers] = make(map[uint]string)
+		for _, payslip := range payslips {
+			assertAmount(t, 50, payslip.Allowances)
+			assertAmount(t, 10, payslip.OtherDeductions)
+			netPayByWorkers[workers][payslip.EmployeeID] = payslip.NetPay.String()
+		}
+
+		var processed models.PayrollPeriod
+		require.NoError(t, db.First(&processed, period.ID).Error)
+		assert.Equal(t, "processed", processed.Status)
+	}
+
+	assert.Equal(t, netPayByWorkers[1], netPayByWorkers[8])
+}
+
+func TestProcessPayrollForCompanyRunsAPeriodOnce(t *testing.T) {
+	db, company := setupPayrollDB(t, 3)
+	processor := newProcessor(db)
+	countPayslips := func(periodID uint) int64 {
+		var count int64
+		require.NoError(t, db.Model(&models.Payslip{}).Where("payroll_period_id = ?", periodID).Count(&count).Error)
+		return count
+	}
+
+	t.Run("again after processing", func(t *testing.T) {
+		period := createDraftPeriod(t, db, company.ID, 0)
+		require.NoError(t, processor.ProcessPayrollForCompany(period.ID, company.ID, 1))
+
+		err := processor.ProcessPayrollForCompany(period.ID, company.ID, 1)
+		assert.ErrorIs(t, err, ErrPeriodNotDraft)
+		assert.EqualValues(t, 3, countPayslips(period.ID))
+
+		var processed models.PayrollPeriod
+		require.NoError(t, db.First(&processed, period.ID).Error)
+		assert.Equal(t, "processed", processed.Status)
+	})
+
+	t.Run("two requests at once", func(t *testing.T) {
+		period := createDraftPeriod(t, db, company.ID, 1)
+		errs := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() { errs <- processor.ProcessPayrollForCompany(period.ID, company.ID, 1) }()
+		}
+
+		var succeeded int
+		for i := 0; i < 2; i++ {
+			if err := <-errs; err == nil {
+				succeeded++
+			} else {
+				assert.ErrorIs(t, err, ErrPeriodNotDraft)
+			}
+		}
+		assert.Equal(t, 1, succeeded)
+		assert.EqualValues(t, 3, countPayslips(period.ID))
+	})
+}
+
+func TestProcessPayrollForCompanyPaysApprovedTime(t *testing.T) {
+	db, company := setupPayrollDB(t, 1)
+	processor := newProcessor(db)
+
+	employee := firstEmployee(t, db)
+	require.NoError(t, db.Model(&employee).Updates(map[string]interface{}{
+		"pay_type": models.PayTypeHourly, "pay_rate": 5, "basic_salary": 0,
+	}).Error)
+
+	period := createDraftPeriod(t, db, company.ID, 0)
+	capture := func(day time.Time, hours float64, approve bool) {
+		entry, err := processor.CreateTimeEntry(company.ID, employee.ID, day, hours, "", 1)
+		require.NoError(t, err)
+		_, err = processor.ReviewTimeEntry(company.ID, entry.ID, approve, 1)
+		require.NoError(t, err)
+	}
+	capture(period.StartDate, 10, true)
+	capture(period.StartDate.AddDate(0, 0, 1), 8, true)
+	capture(period.StartDate.AddDate(0, 0, 2), 6, false)
+	capture(period.EndDate.AddDate(0, 0, 1), 4, true)
+
+	_, err := processor.CreateTimeEntry(company.ID, employee.ID, period.StartDate, 25, "", 1)
+	assert.Error(t, err, "an hourly entry cannot exceed a day")
+
+	require.NoError(t, processor.ProcessPayrollForCompany(period.ID, company.ID, 1))
+
+	var payslip models.Payslip
+	require.NoError(t, db.Where("payroll_period_id = ? AND employee_id = ?", period.ID, employee.ID).First(&payslip).Error)
+	assert.Equal(t, 18.0, payslip.PayQuantity)
+	assertAmount(t, 5, payslip.PayRate)
+	assertAmount(t, 90, payslip.BasicSalary)
+	assertAmount(t, 140, payslip.TotalEarnings)
+
+	_, err = processor.CreateTimeEntry(company.ID, employee.ID, period.StartDate.AddDate(0, 0, 3), 8, "", 1)
+	assert.ErrorIs(t, err, ErrPeriodClosed)
+}
+
+func TestProcessPayrollForCompanyPaysTimeApprovedLate(t *testing.T) {
+	db, company := setupPayrollDB(t, 1)
+	processor := newProcessor(db)
+
+	employee := firstEmployee(t, db)
+	require.NoError(t, db.Model(&employee).Updates(map[string]interface{}{
+		"pay_type": models.PayTypeHourly, "pay_rate": 5, "basic_salary": 0,
+	}).Error)
+
+	january := createDraftPeriod(t, db, company.ID, 0)
+	late, err := processor.CreateTimeEntry(company.ID, employee.ID, january.StartDate, 6, "", 1)
+	require.NoError(t, err)
+	paid, err := processor.CreateTimeEntry(company.ID, employee.ID, january.StartDate.AddDate(0, 0, 1), 8, "", 1)
+	require.NoError(t, err)
+	_, err = processor.ReviewTimeEntry(company.ID, paid.ID, true, 1)
+	require.NoError(t, err)
+	_, err = processor.AcknowledgeValidationWarnings(january.ID, company.ID, 1,
+		[]string{fmt.Sprintf("pending_time_entries:%d", employee.ID)}, "Awaiting the supervisor")
+	require.NoError(t, err)
+	require.NoError(t, processor.ProcessPayrollForCompany(january.ID, company.ID, 1))
+
+	// January's time approved after its run is paid in February, once
+	_, err = processor.ReviewTimeEntry(company.ID, late.ID, true, 1)
+	require.NoError(t, err)
+	february := createDraftPeriod(t, db, company.ID, 1)
+	require.NoError(t, processor.ProcessPayrollForCompany(february.ID, company.ID, 1))
+
+	var payslip models.Payslip
+	require.NoError(t, db.Where("payroll_period_id = ? AND employee_id = ?", february.ID, employee.ID).First(&payslip).Error)
+	assert.Equal(t, 6.0, payslip.PayQuantity)
+	assertAmount(t, 30, payslip.BasicSalary)
+
+	require.NoError(t, db.First(late, late.ID).Error)
+	require.NotNil(t, late.PaidPayslipID)
+	assert.Equal(t, payslip.ID, *late.PaidPayslipID)
+	require.NoError(t, db.First(paid, paid.ID).Error)
+	require.NotNil(t, paid.PaidPeriodID)
+	assert.Equal(t, january.ID, *paid.PaidPeriodID)
+}
+
+func TestProcessPayrollForCompanyWithholdsContractorTax(t *testing.T) {
+	db, company := setupPayrollDB(t, 2)
+	processor := newProcessor(db)
+
+	employees := employeesByNumber(t, db)
+	contractor := employees[0]
+	require.NoError(t, db.Model(&contractor).Update("employment_type", models.EmploymentTypeContractor).Error)
+
+	period := createDraftPeriod(t, db, company.ID, 0)
+	require.NoError(t, processor.ProcessPayrollForCompany(period.ID, company.ID, 1))
+
+	var payslip models.Payslip
+	require.NoError(t, db.Where("payroll_period_id = ? AND employee_id = ?", period.ID, contractor.ID).First(&payslip).Error)
+	assert.True(t, payslip.IsContractor)
+	assertAmount(t, 550, payslip.TotalEarnings)
+	assertAmount(t, 0, payslip.PayeeTax)
+	assertAmount(t, 0, payslip.NSSAContribution)
+	assertAmount(t, 0, payslip.EmployerNSSA)
+	assertAmount(t, 30, payslip.WithholdingTaxRate)
+	assertAmount(t, 165, payslip.WithholdingTax)
+	assertAmount(t, 375, payslip.NetPay)
+
+	report, err := processor.GetWithholdingTaxReport(period.ID, company.ID)
+	require.NoError(t, err)
+	require.Len(t, report.Lines, 1)
+	assert.Equal(t, contractor.EmployeeNumber, report.Lines[0].EmployeeNumber)
+	assertAmount(t, 165, report.Totals["USD"].WithholdingTax)
+	assertAmount(t, 165, report.WithholdingTaxBase)
+}
+
+func TestProcessPayrollForCompanyAppliesTaxDirective(t *testing.T) {
+	db, company := setupPayrollDB(t, 2)
+	processor := newProcessor(db)
+
+	employees := employeesByNumber(t, db)
+	directed := employees[0]
+	directive, err := processor.CreateTaxDirective(company.ID, directed.ID, models.TaxDirective{
+		DirectiveNumber: "TD2024/0001",
+		Type:            models.DirectiveTypeFixedPercentage,
+		Rate:            20,
+		ValidFrom:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}, 1)
+	require.NoError(t, err)
+
+	// A lump sum directive cannot cover the regular salary run
+	_, err = processor.CreateTaxDirective(company.ID, employees[1].ID, models.TaxDirective{
+		DirectiveNumber: "TD2024/0002",
+		Type:            models.DirectiveTypeLumpSum,
+		Amount:          decimal.NewFromInt(100),
+		ValidFrom:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}, 1)
+	assert.Error(t, err)
+
+	period := createDraftPeriod(t, db, company.ID, 0)
+	require.NoError(t, processor.ProcessPayrollForCompany(period.ID, company.ID, 1))
+
+	var payslip models.Payslip
+	require.NoError(t, db.Where("payroll_period_id = ? AND employee_id = ?", period.ID, directed.ID).First(&payslip).Error)
+	assertAmount(t, 550, payslip.TotalEarnings)
+	assertAmount(t, 110, payslip.PayeeTax)
+	assertAmount(t, 3.3, payslip.AidsLevy)
+	require.NotNil(t, payslip.TaxDirectiveID)
+	assert.Equal(t, directive.ID, *payslip.TaxDirectiveID)
+	assert.Equal(t, "TD2024/0001", payslip.TaxDirectiveNumber)
+
+	var other models.Payslip
+	require.NoError(t, db.Where("payroll_period_id = ? AND employee_id = ?", period.ID, employees[1].ID).First(&other).Error)
+	assert.Nil(t, other.TaxDirectiveID)
+
+	require.NoError(t, db.Model(&models.Payslip{}).Where("payroll_period_id = ?", period.ID).Update("status", "approved").Error)
+	certificates, err := processor.GenerateTaxCertificates(company.ID, 2024)
+	require.NoError(t, err)
+	require.Len(t, certificates, 2)
+	assert.Equal(t, directed.ID, certificates[0].EmployeeID)
+	assertAmount(t, 113.3, certificates[0].TotalTax)
+	assert.Equal(t, "TD2024/0001", certificates[0].TaxDirectives)
+	assert.Empty(t, certificates[1].TaxDirectives)
+}
+
+func TestProcessPayrollForCompanyRoundsPayslipLines(t *testing.T) {
+	db, company := setupPayrollDB(t, 1)
+	processor := newProcessor(db)
+
+	employee := firstEmployee(t, db)
+	for _, name := range []string{"Airtime", "Data"} {
+		require.NoError(t, db.Create(&models.Allowance{
+			CompanyID: company.ID, EmployeeID: employee.ID, Name: name, Amount: decimal.RequireFromString("0.125"),
+			CurrencyID: employee.CurrencyID, IsActive: true, IsRecurring: true, StartDate: time.Now(),
+		}).Error)
+	}
+
+	cases := []struct {
+		mode       string
+		level      string
+		allowances float64
+	}{
+		{"half_up", "line", 50.26},
+		{"half_even", "line", 50.24},
+		{"half_even", "total", 50.25},
+	}
+	for i, tc := range cases {
+		require.NoError(t, db.Model(&models.CompanySettings{}).Where("company_id = ?", company.ID).
+			Updates(map[string]interface{}{"rounding_mode": tc.mode, "rounding_level": tc.level}).Error)
+		period := createDraftPeriod(t, db, company.ID, i)
+		require.NoError(t, processor.ProcessPayrollForCompany(period.ID, company.ID, 1))
+
+		var payslip models.Payslip
+		require.NoError(t, db.Where("payroll_period_id = ?", period.ID).First(&payslip).Error)
+		assertAmount(t, tc.allowances, payslip.Allowances)
+		assert.True(t, payslip.TotalEarnings.Equal(payslip.BasicSalary.Add(payslip.Allowances)), "%s/%s", tc.mode, tc.level)
+		assert.True(t, payslip.NetPay.Equal(payslip.TotalEarnings.Sub(payslip.TotalDeductions)), "%s/%s", tc.mode, tc.level)
+		assert.True(t, payslip.NetPay.Equal(payslip.NetPay.Round(2)), "%s/%s", tc.mode, tc.level)
+	}
+}
+
+func TestProcessPayrollForCompanyPaysSalaryInForce(t *testing.T) {
+	db, company := setupPayrollDB(t, 1)
+	processor := newProcessor(db)
+
+	employee := firstEmployee(t, db)
+
+	// A raise from 500 to 1000 on 17 January 2024, and another to 1200 not yet due
+	raise, err := processor.RecordCompensationChange(company.ID, employee.ID, models.CompensationChange{
+		NewSalary: decimal.NewFromInt(1000), EffectiveDate: time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC),
+		Reason: models.CompensationReasonAnnualIncrease,
+	}, 1)
+	require.NoError(t, err)
+	assert.NotNil(t, raise.AppliedAt)
+	scheduled, err := processor.RecordCompensationChange(company.ID, employee.ID, models.CompensationChange{
+		NewSalary: decimal.NewFromInt(1200), EffectiveDate: time.Now().AddDate(1, 0, 0),
+		Reason: models.CompensationReasonPromotion,
+	}, 1)
+	require.NoError(t, err)
+	assert.Nil(t, scheduled.AppliedAt)
+	assertAmount(t, 1000, scheduled.PreviousSalary)
+
+	require.NoError(t, db.First(&employee, employee.ID).Error)
+	assertAmount(t, 1000, employee.BasicSalary)
+
+	// January pays 16 days at 500 and 15 days at 1000; February the full new salary
+	expected := map[int]float64{0: 741.94, 1: 1000}
+	for month, salary := range expected {
+		period := createDraftPeriod(t, db, company.ID, month)
+		require.NoError(t, processor.ProcessPayrollForCompany(period.ID, company.ID, 1))
+
+		var payslip models.Payslip
+		require.NoError(t, db.Where("payroll_period_id = ?", period.ID).First(&payslip).Error)
+		assertAmount(t, salary, payslip.BasicSalary)
+	}
+
+	history, err := processor.GetCompensationHistory(company.ID, employee.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, scheduled.ID, history[0].ID)
+}
+
+func BenchmarkProcessPayrollForCompany(b *testing.B) {
+	db, company := setupPayrollDB(b, 500)
+	processor := newProcessor(db)
+
+	for _, workers := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			processor.SetWorkers(workers)
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				period := createDraftPeriod(b, db, company.ID, i)
+				b.StartTimer()
+
+				if err := processor.ProcessPayrollForCompany(period.ID, company.ID, 1); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
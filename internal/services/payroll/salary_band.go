@@ -0,0 +1,264 @@
+package payroll
+
+import (
+	"errors"
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrSalaryOutOfBand is returned when a salary falls outside its position's band, the
+// company enforces bands and no override reason was given.
+var ErrSalaryOutOfBand = errors.New("salary is outside the position's salary band")
+
+// SalaryBandError carries the band check that rejected a salary.
+type SalaryBandError struct {
+	Check *SalaryBandCheck
+}
+
+func (e *SalaryBandError) Error() string {
+	return fmt.Sprintf("%s: %s; give an override reason to accept it", ErrSalaryOutOfBand, e.Check.Message())
+}
+
+func (e *SalaryBandError) Unwrap() error {
+	return ErrSalaryOutOfBand
+}
+
+// Where a salary sits against its position's band
+const (
+	BandBelow  = "below"
+	BandWithin = "within"
+	BandAbove  = "above"
+)
+
+// SalaryBandCheck places a salary in its position's band. Amounts are in the position's
+// currency. CompaRatio is the salary over the band midpoint and Penetration how far the
+// salary is from the minimum to the maximum, as a percentage; both are zero when the band
+// has no maximum.
+type SalaryBandCheck struct {
+	PositionID  uint            `json:"position_id"`
+	Position    string          `json:"position"`
+	Currency    string          `json:"currency"`
+	MinSalary   decimal.Decimal `json:"min_salary"`
+	MaxSalary   decimal.Decimal `json:"max_salary"`
+	Salary      decimal.Decimal `json:"salary"`
+	CompaRatio  float64         `json:"compa_ratio"`
+	Penetration float64         `json:"penetration"`
+	Status      string          `json:"status"` // below, within, above
+}
+
+// OutOfBand reports whether the salary is below the minimum or above the maximum.
+func (c *SalaryBandCheck) OutOfBand() bool {
+	return c.Status != BandWithin
+}
+
+// Message describes an out-of-band salary for warnings and errors.
+func (c *SalaryBandCheck) Message() string {
+	places := money.Places(c.Currency)
+	switch c.Status {
+	case BandBelow:
+		return fmt.Sprintf("salary of %s %s is below the %s minimum of %s", c.Currency, c.Salary.StringFixed(places), c.Position, c.MinSalary.StringFixed(places))
+	case BandAbove:
+		return fmt.Sprintf("salary of %s %s is above the %s maximum of %s", c.Currency, c.Salary.StringFixed(places), c.Position, c.MaxSalary.StringFixed(places))
+	}
+	return ""
+}
+
+// SalaryBandReport summarises how salaried employees sit in their position bands, by
+// department. Employees whose position has no band are left out.
+type SalaryBandReport struct {
+	Headcount          int                     `json:"headcount"`
+	OutOfBand          int                     `json:"out_of_band"`
+	AverageCompaRatio  float64                 `json:"average_compa_ratio"`
+	AveragePenetration float64                 `json:"average_penetration"`
+	Departments        []DepartmentBandSummary `json:"departments"`
+}
+
+type DepartmentBandSummary struct {
+	DepartmentID       uint                `json:"department_id"`
+	Department         string              `json:"department"`
+	Headcount          int                 `json:"headcount"`
+	BelowBand          int                 `json:"below_band"`
+	AboveBand          int                 `json:"above_band"`
+	AverageCompaRatio  float64             `json:"average_compa_ratio"`
+	AveragePenetration float64             `json:"average_penetration"`
+	OutOfBand          []EmployeeBandCheck `json:"out_of_band"`
+}
+
+type EmployeeBandCheck struct {
+	EmployeeID     uint   `json:"employee_id"`
+	EmployeeNumber string `json:"employee_number"`
+	Name           string `json:"name"`
+	Override       string `json:"override"`
+	SalaryBandCheck
+}
+
+// CheckSalaryBand places a salary paid in the given currency in a position's band, converting
+// it to the position's currency at the current rate. It returns nil when the position has no
+// band.
+func (pp *PayrollProcessor) CheckSalaryBand(companyID, positionID uint, salary decimal.Decimal, currencyID uint) (*SalaryBandCheck, error) {
+	if positionID == 0 {
+		return nil, nil
+	}
+	var position models.Position
+	if err := pp.db.Preload("Currency").Where("id = ? AND company_id = ?", positionID, companyID).First(&position).Error; err != nil {
+		return nil, fmt.Errorf("position %d not found", positionID)
+	}
+	if position.MinSalary.IsZero() && position.MaxSalary.IsZero() {
+		return nil, nil
+	}
+
+	var salaryCurrency models.Currency
+	if err := pp.db.First(&salaryCurrency, currencyID).Error; err != nil {
+		return nil, fmt.Errorf("currency %d not found", currencyID)
+	}
+	converted, err := pp.currencyService.ConvertMoney(salary, salaryCurrency.Code, position.Currency.Code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s to %s: %w", salaryCurrency.Code, position.Currency.Code, err)
+	}
+	return placeInBand(position, converted.Round(money.Places(position.Currency.Code))), nil
+}
+
+// EnforceSalaryBand checks a salary against its position's band under the company's band
+// policy. An out-of-band salary is returned with a SalaryBandError when the company enforces
+// bands and there is no override reason; otherwise it is accepted and the caller warns.
+func (pp *PayrollProcessor) EnforceSalaryBand(companyID, positionID uint, salary decimal.Decimal, currencyID uint, overrideReason string) (*SalaryBandCheck, error) {
+	check, err := pp.CheckSalaryBand(companyID, positionID, salary, currencyID)
+	if err != nil || check == nil || !check.OutOfBand() {
+		return check, err
+	}
+
+	var settings models.CompanySettings
+	pp.db.Where("company_id = ?", companyID).First(&settings)
+	if settings.SalaryBandPolicy == models.SalaryBandPolicyEnforce && overrideReason == "" {
+		return check, &SalaryBandError{Check: check}
+	}
+	return check, nil
+}
+
+// SalaryBandReport reports compa-ratio, band penetration and out-of-band employees for each
+// department, converting salaries to position currencies at current rates.
+func (pp *PayrollProcessor) SalaryBandReport(companyID uint) (*SalaryBandReport, error) {
+	var employees []models.Employee
+	if err := pp.db.Preload("Currency").Preload("Position.Currency").Preload("Department").
+		Where("company_id = ? AND is_active = ? AND employment_status = ?", companyID, true, "active").
+		Order("employee_number").
+		Find(&employees).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch employees: %w", err)
+	}
+
+	report := &SalaryBandReport{}
+	byDepartment := make(map[uint]*DepartmentBandSummary)
+	// Averages cover the employees whose band has a maximum, and so a midpoint
+	rated := make(map[uint]int)
+	compaTotals := make(map[uint]float64)
+	penetrationTotals := make(map[uint]float64)
+	var ratedTotal int
+	var compaTotal, penetrationTotal float64
+	rates := make(map[string]decimal.Decimal)
+	for _, employee := range employees {
+		position := employee.Position
+		if !employee.IsSalaried() || position.ID == 0 || (position.MinSalary.IsZero() && position.MaxSalary.IsZero()) {
+			continue
+		}
+
+		pair := employee.Currency.Code + "/" + position.Currency.Code
+		rate, ok := rates[pair]
+		if !ok {
+			var err error
+			if rate, err = pp.currencyService.ConvertMoney(decimal.NewFromInt(1), employee.Currency.Code, position.Currency.Code); err != nil {
+				return nil, fmt.Errorf("failed to convert %s to %s: %w", employee.Currency.Code, position.Currency.Code, err)
+			}
+			rates[pair] = rate
+		}
+		salary := employee.BasicSalary.Mul(rate).Round(money.Places(position.Currency.Code))
+		check := placeInBand(position, salary)
+
+		department, ok := byDepartment[employee.DepartmentID]
+		if !ok {
+			department = &DepartmentBandSummary{DepartmentID: employee.DepartmentID, Department: employee.Department.Name}
+			byDepartment[employee.DepartmentID] = department
+		}
+		department.Headcount++
+		report.Headcount++
+		if check.MaxSalary.IsPositive() {
+			rated[employee.DepartmentID]++
+			compaTotals[employee.DepartmentID] += check.CompaRatio
+			penetrationTotals[employee.DepartmentID] += check.Penetration
+			ratedTotal++
+			compaTotal += check.CompaRatio
+			penetrationTotal += check.Penetration
+		}
+
+		if check.OutOfBand() {
+			if check.Status == BandBelow {
+				department.BelowBand++
+			} else {
+				department.AboveBand++
+			}
+			report.OutOfBand++
+			department.OutOfBand = append(department.OutOfBand, EmployeeBandCheck{
+				EmployeeID:      employee.ID,
+				EmployeeNumber:  employee.EmployeeNumber,
+				Name:            employee.FullName(),
+				Override:        employee.SalaryBandOverride,
+				SalaryBandCheck: *check,
+			})
+		}
+	}
+
+	for id, department := range byDepartment {
+		if rated[id] > 0 {
+			department.AverageCompaRatio = roundRatio(compaTotals[id]/float64(rated[id]), 3)
+			department.AveragePenetration = roundRatio(penetrationTotals[id]/float64(rated[id]), 2)
+		}
+		report.Departments = append(report.Departments, *department)
+	}
+	sort.Slice(report.Departments, func(i, j int) bool {
+		return report.Departments[i].Department < report.Departments[j].Department
+	})
+	if ratedTotal > 0 {
+		report.AverageCompaRatio = roundRatio(compaTotal/float64(ratedTotal), 3)
+		report.AveragePenetration = roundRatio(penetrationTotal/float64(ratedTotal), 2)
+	}
+	return report, nil
+}
+
+// placeInBand works out where a salary in the position's currency sits in its band. A zero
+// minimum or maximum leaves that side of the band open.
+func placeInBand(position models.Position, salary decimal.Decimal) *SalaryBandCheck {
+	minSalary, maxSalary := position.MinSalary, position.MaxSalary
+	check := &SalaryBandCheck{
+		PositionID: position.ID,
+		Position:   position.Title,
+		Currency:   position.Currency.Code,
+		MinSalary:  minSalary,
+		MaxSalary:  maxSalary,
+		Salary:     salary,
+		Status:     BandWithin,
+	}
+
+	switch {
+	case minSalary.IsPositive() && salary.LessThan(minSalary):
+		check.Status = BandBelow
+	case maxSalary.IsPositive() && salary.GreaterThan(maxSalary):
+		check.Status = BandAbove
+	}
+
+	if maxSalary.IsPositive() {
+		midpoint := minSalary.Add(maxSalary).Div(decimal.NewFromInt(2))
+		check.CompaRatio = salary.Div(midpoint).Round(3).InexactFloat64()
+		if spread := maxSalary.Sub(minSalary); spread.IsPositive() {
+			check.Penetration = salary.Sub(minSalary).Mul(decimal.NewFromInt(100)).Div(spread).Round(2).InexactFloat64()
+		}
+	}
+	return check
+}
+
+// roundRatio rounds an average ratio for reporting
+func roundRatio(value float64, places int32) float64 {
+	return decimal.NewFromFloat(value).Round(places).InexactFloat64()
+}
@@ -0,0 +1,89 @@
+package payroll
+
+import (
+	"testing"
+	"time"
+
+	"gm58-hr-backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSalaryBandReportConvertsAndFlagsOutOfBand(t *testing.T) {
+	db, company := setupPayrollDB(t, 3)
+	processor := newProcessor(db)
+
+	// A USD band of 550 to 1050, and the third employee paid 30000 ZWG at 0.04 to the dollar
+	var position models.Position
+	require.NoError(t, db.Where("company_id = ?", company.ID).First(&position).Error)
+	require.NoError(t, db.Model(&position).Updates(map[string]interface{}{"min_salary": 550, "max_salary": 1050}).Error)
+	zwg := createCurrency(t, db, "ZWG")
+	createRate(t, db, zwg, findCurrency(t, db, "USD"), 0.04, time.Now())
+	employees := employeesByNumber(t, db)
+	require.NoError(t, db.Model(&employees[2]).Updates(map[string]interface{}{"basic_salary": 30000, "currency_id": zwg.ID}).Error)
+
+	report, err := processor.SalaryBandReport(company.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Headcount)
+	assert.Equal(t, 2, report.OutOfBand)
+	require.Len(t, report.Departments, 1)
+	department := report.Departments[0]
+	assert.Equal(t, 1, department.BelowBand)
+	assert.Equal(t, 1, department.AboveBand)
+	// Compa-ratios of 0.625, 0.75 and 1.5 against the 800 midpoint; penetration -10, 10 and 130
+	assert.Equal(t, 0.958, department.AverageCompaRatio)
+	assert.Equal(t, 43.33, department.AveragePenetration)
+	require.Len(t, department.OutOfBand, 2)
+	assert.Equal(t, BandAbove, department.OutOfBand[1].Status)
+	assertAmount(t, 1200, department.OutOfBand[1].Salary)
+
+	// Enforced bands need an override reason
+	require.NoError(t, db.Model(&models.CompanySettings{}).Where("company_id = ?", company.ID).
+		Update("salary_band_policy", models.SalaryBandPolicyEnforce).Error)
+	_, err = processor.EnforceSalaryBand(company.ID, position.ID, decimal.NewFromInt(500), position.CurrencyID, "")
+	assert.ErrorIs(t, err, ErrSalaryOutOfBand)
+	check, err := processor.EnforceSalaryBand(company.ID, position.ID, decimal.NewFromInt(500), position.CurrencyID, "Trainee")
+	require.NoError(t, err)
+	assert.Equal(t, BandBelow, check.Status)
+}
+
+func TestCompensationChangeHeldToEnforcedBand(t *testing.T) {
+	db, company := setupPayrollDB(t, 1)
+	processor := newProcessor(db)
+	require.NoError(t, db.Model(&models.CompanySettings{}).Where("company_id = ?", company.ID).
+		Update("salary_band_policy", models.SalaryBandPolicyEnforce).Error)
+
+	var employee models.Employee
+	require.NoError(t, db.Preload("Position").Where("company_id = ?", company.ID).First(&employee).Error)
+	require.NoError(t, db.Model(&employee.Position).Updates(map[string]interface{}{"min_salary": 400, "max_salary": 600}).Error)
+	manager := models.Position{CompanyID: company.ID, Title: "Manager", DepartmentID: employee.Position.DepartmentID,
+		CurrencyID: employee.Position.CurrencyID, MinSalary: decimal.NewFromInt(900), MaxSalary: decimal.NewFromInt(1400), IsActive: true}
+	require.NoError(t, db.Create(&manager).Error)
+
+	raise := models.CompensationChange{
+		NewSalary: decimal.NewFromInt(1000), EffectiveDate: time.Now(), Reason: models.CompensationReasonPromotion,
+	}
+	_, err := processor.RecordCompensationChange(company.ID, employee.ID, raise, 1)
+	var bandErr *SalaryBandError
+	require.ErrorAs(t, err, &bandErr)
+	assert.Equal(t, BandAbove, bandErr.Check.Status)
+
+	// The salary fits the band of the position the employee is promoted to
+	raise.PositionID = manager.ID
+	change, err := processor.RecordCompensationChange(company.ID, employee.ID, raise, 1)
+	require.NoError(t, err)
+	assert.Empty(t, change.SalaryBandWarning)
+
+	// An override accepts an out-of-band salary and is kept with the change
+	change, err = processor.RecordCompensationChange(company.ID, employee.ID, models.CompensationChange{
+		NewSalary: decimal.NewFromInt(700), EffectiveDate: time.Now().AddDate(0, 1, 0), Reason: models.CompensationReasonOther,
+		SalaryBandOverride: "Retained on transfer",
+	}, 1)
+	require.NoError(t, err)
+	assert.NotEmpty(t, change.SalaryBandWarning)
+	var saved models.CompensationChange
+	require.NoError(t, db.First(&saved, change.ID).Error)
+	assert.Equal(t, "Retained on transfer", saved.SalaryBandOverride)
+}
@@ -0,0 +1,596 @@
+package payroll
+
+import (
+	"errors"
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrReviewState is returned when a salary review is not in a status that allows the action.
+var ErrReviewState = errors.New("salary review is not in a state that allows this")
+
+// reviewScopeRank orders rule scopes so that the most specific rule wins
+var reviewScopeRank = map[string]int{
+	models.ReviewScopeCompany:    1,
+	models.ReviewScopeDepartment: 2,
+	models.ReviewScopePosition:   3,
+	models.ReviewScopeEmployee:   4,
+}
+
+// SalaryReviewImpact is the cost of a review's proposals in the company's base currency at
+// current exchange rates. The annual increase is twelve months of the monthly increase.
+type SalaryReviewImpact struct {
+	BaseCurrency        string                   `json:"base_currency"`
+	Headcount           int                      `json:"headcount"`
+	CurrentMonthlyBase  decimal.Decimal          `json:"current_monthly_base"`
+	ProposedMonthlyBase decimal.Decimal          `json:"proposed_monthly_base"`
+	MonthlyIncreaseBase decimal.Decimal          `json:"monthly_increase_base"`
+	AnnualIncreaseBase  decimal.Decimal          `json:"annual_increase_base"`
+	AverageIncreasePct  float64                  `json:"average_increase_pct"`
+	Budget              decimal.Decimal          `json:"budget"`
+	BudgetRemaining     decimal.Decimal          `json:"budget_remaining"`
+	OverBudget          bool                     `json:"over_budget"`
+	Departments         []ReviewDepartmentImpact `json:"departments"`
+}
+
+type ReviewDepartmentImpact struct {
+	DepartmentID        uint            `json:"department_id"`
+	Department          string          `json:"department"`
+	Headcount           int             `json:"headcount"`
+	CurrentMonthlyBase  decimal.Decimal `json:"current_monthly_base"`
+	MonthlyIncreaseBase decimal.Decimal `json:"monthly_increase_base"`
+	AnnualIncreaseBase  decimal.Decimal `json:"annual_increase_base"`
+	AverageIncreasePct  float64         `json:"average_increase_pct"`
+}
+
+// CreateSalaryReview saves a draft review with its rules and generates a proposal for every
+// employee a rule covers.
+func (pp *PayrollProcessor) CreateSalaryReview(companyID, createdBy uint, review models.SalaryReview) (*models.SalaryReview, error) {
+	if review.Name == "" {
+		return nil, fmt.Errorf("review name is required")
+	}
+	if review.EffectiveDate.IsZero() {
+		return nil, fmt.Errorf("effective date is required")
+	}
+	if review.Reason == "" {
+		review.Reason = models.CompensationReasonAnnualIncrease
+	}
+	if !models.ValidCompensationReason(review.Reason) {
+		return nil, fmt.Errorf("reason must be promotion, annual_increase, correction or other")
+	}
+	if review.Budget.IsNegative() {
+		return nil, fmt.Errorf("budget cannot be negative")
+	}
+	if err := pp.validateReviewRules(companyID, review.Rules); err != nil {
+		return nil, err
+	}
+
+	review.ID = 0
+	review.CompanyID = companyID
+	review.Status = "draft"
+	review.ApprovalLevel = 0
+	review.CreatedBy = createdBy
+	review.Proposals = nil
+	review.Approvals = nil
+
+	err := pp.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&review).Error; err != nil {
+			return fmt.Errorf("failed to save salary review: %w", err)
+		}
+		return pp.generateReviewProposals(tx, review)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pp.GetSalaryReview(companyID, review.ID)
+}
+
+// UpdateSalaryReviewRules replaces a draft review's rules and regenerates its proposals.
+// Proposals edited by hand keep their salary.
+func (pp *PayrollProcessor) UpdateSalaryReviewRules(companyID, reviewID uint, rules []models.SalaryReviewRule) (*models.SalaryReview, error) {
+	if err := pp.validateReviewRules(companyID, rules); err != nil {
+		return nil, err
+	}
+
+	err := pp.db.Transaction(func(tx *gorm.DB) error {
+		var review models.SalaryReview
+		if err := tx.Where("id = ? AND company_id = ?", reviewID, companyID).First(&review).Error; err != nil {
+			return err
+		}
+		if review.Status != "draft" {
+			return fmt.Errorf("%w: only draft reviews can be changed", ErrReviewState)
+		}
+
+		if err := tx.Where("review_id = ?", review.ID).Delete(&models.SalaryReviewRule{}).Error; err != nil {
+			return fmt.Errorf("failed to replace review rules: %w", err)
+		}
+		for _, rule := range rules {
+			rule.ID = 0
+			rule.ReviewID = review.ID
+			if err := tx.Create(&rule).Error; err != nil {
+				return fmt.Errorf("failed to save review rule: %w", err)
+			}
+			review.Rules = append(review.Rules, rule)
+		}
+		return pp.generateReviewProposals(tx, review)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pp.GetSalaryReview(companyID, reviewID)
+}
+
+// UpdateSalaryReviewProposal sets one employee's proposed salary by hand on a draft review.
+func (pp *PayrollProcessor) UpdateSalaryReviewProposal(companyID, reviewID, proposalID uint, proposedSalary decimal.Decimal, notes string) (*models.SalaryReviewProposal, error) {
+	if !proposedSalary.IsPositive() {
+		return nil, fmt.Errorf("proposed salary must be greater than zero")
+	}
+
+	var review models.SalaryReview
+	if err := pp.db.Where("id = ? AND company_id = ?", reviewID, companyID).First(&review).Error; err != nil {
+		return nil, err
+	}
+	if review.Status != "draft" {
+		return nil, fmt.Errorf("%w: only draft reviews can be changed", ErrReviewState)
+	}
+
+	var proposal models.SalaryReviewProposal
+	if err := pp.db.Preload("Currency").
+		Where("id = ? AND review_id = ?", proposalID, review.ID).
+		First(&proposal).Error; err != nil {
+		return nil, err
+	}
+
+	var settings models.CompanySettings
+	pp.db.Where("company_id = ?", companyID).First(&settings)
+	proposal.ProposedSalary = settings.Rounding().Round(proposedSalary, proposal.Currency.Code)
+	proposal.Increase = proposal.ProposedSalary.Sub(proposal.CurrentSalary)
+	proposal.IncreasePercent = increasePercent(proposal.Increase, proposal.CurrentSalary)
+	proposal.RuleScope = ""
+	proposal.Overridden = true
+	proposal.Notes = notes
+	if err := pp.db.Save(&proposal).Error; err != nil {
+		return nil, fmt.Errorf("failed to save proposal: %w", err)
+	}
+	return &proposal, nil
+}
+
+// GetSalaryReviews lists the company's salary reviews, newest first.
+func (pp *PayrollProcessor) GetSalaryReviews(companyID uint) ([]models.SalaryReview, error) {
+	var reviews []models.SalaryReview
+	if err := pp.db.Where("company_id = ?", companyID).Order("created_at DESC").Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// GetSalaryReview returns a review with its rules, proposals and approval history.
+func (pp *PayrollProcessor) GetSalaryReview(companyID, reviewID uint) (*models.SalaryReview, error) {
+	var review models.SalaryReview
+	if err := pp.db.Preload("Rules").
+		Preload("Proposals", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Proposals.Employee").Preload("Proposals.Currency").
+		Preload("Approvals", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		Where("id = ? AND company_id = ?", reviewID, companyID).
+		First(&review).Error; err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// SalaryReviewImpact totals a review's proposals in base currency, per department and
+// against its budget.
+func (pp *PayrollProcessor) SalaryReviewImpact(review models.SalaryReview) (*SalaryReviewImpact, error) {
+	var company models.Company
+	if err := pp.db.Preload("BaseCurrency").First(&company, review.CompanyID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get company base currency: %w", err)
+	}
+	baseCurrency := company.BaseCurrency.Code
+
+	var departments []models.Department
+	pp.db.Where("company_id = ?", review.CompanyID).Find(&departments)
+	departmentNames := make(map[uint]string, len(departments))
+	for _, department := range departments {
+		departmentNames[department.ID] = department.Name
+	}
+
+	impact := &SalaryReviewImpact{BaseCurrency: baseCurrency, Budget: review.Budget}
+	byDepartment := make(map[uint]*ReviewDepartmentImpact)
+	rates := make(map[string]decimal.Decimal)
+	for _, proposal := range review.Proposals {
+		currencyCode := proposal.Currency.Code
+		rate, ok := rates[currencyCode]
+		if !ok {
+			var err error
+			if rate, err = pp.currencyService.ConvertMoney(decimal.NewFromInt(1), currencyCode, baseCurrency); err != nil {
+				return nil, fmt.Errorf("failed to convert %s to %s: %w", currencyCode, baseCurrency, err)
+			}
+			rates[currencyCode] = rate
+		}
+		current := proposal.CurrentSalary.Mul(rate)
+		increase := proposal.Increase.Mul(rate)
+
+		department, ok := byDepartment[proposal.DepartmentID]
+		if !ok {
+			department = &ReviewDepartmentImpact{DepartmentID: proposal.DepartmentID, Department: departmentNames[proposal.DepartmentID]}
+			byDepartment[proposal.DepartmentID] = department
+		}
+		department.Headcount++
+		department.CurrentMonthlyBase = department.CurrentMonthlyBase.Add(current)
+		department.MonthlyIncreaseBase = department.MonthlyIncreaseBase.Add(increase)
+		impact.Headcount++
+		impact.CurrentMonthlyBase = impact.CurrentMonthlyBase.Add(current)
+		impact.MonthlyIncreaseBase = impact.MonthlyIncreaseBase.Add(increase)
+	}
+
+	places := money.Places(baseCurrency)
+	for _, department := range byDepartment {
+		department.CurrentMonthlyBase = department.CurrentMonthlyBase.Round(places)
+		department.MonthlyIncreaseBase = department.MonthlyIncreaseBase.Round(places)
+		department.AnnualIncreaseBase = department.MonthlyIncreaseBase.Mul(decimal.NewFromInt(12))
+		department.AverageIncreasePct = increasePercent(department.MonthlyIncreaseBase, department.CurrentMonthlyBase)
+		impact.Departments = append(impact.Departments, *department)
+	}
+	sort.Slice(impact.Departments, func(i, j int) bool {
+		return impact.Departments[i].Department < impact.Departments[j].Department
+	})
+
+	impact.CurrentMonthlyBase = impact.CurrentMonthlyBase.Round(places)
+	impact.MonthlyIncreaseBase = impact.MonthlyIncreaseBase.Round(places)
+	impact.ProposedMonthlyBase = impact.CurrentMonthlyBase.Add(impact.MonthlyIncreaseBase)
+	impact.AnnualIncreaseBase = impact.MonthlyIncreaseBase.Mul(decimal.NewFromInt(12))
+	impact.AverageIncreasePct = increasePercent(impact.MonthlyIncreaseBase, impact.CurrentMonthlyBase)
+	if review.Budget.IsPositive() {
+		impact.BudgetRemaining = review.Budget.Sub(impact.AnnualIncreaseBase)
+		impact.OverBudget = impact.BudgetRemaining.IsNegative()
+	}
+	return impact, nil
+}
+
+// SubmitSalaryReview sends a draft review for approval.
+func (pp *PayrollProcessor) SubmitSalaryReview(companyID, reviewID, userID uint) (*models.SalaryReview, error) {
+	var review models.SalaryReview
+	if err := pp.db.Where("id = ? AND company_id = ?", reviewID, companyID).First(&review).Error; err != nil {
+		return nil, err
+	}
+	if review.Status != "draft" {
+		return nil, fmt.Errorf("%w: only draft reviews can be submitted", ErrReviewState)
+	}
+	var proposals int64
+	pp.db.Model(&models.SalaryReviewProposal{}).Where("review_id = ?", review.ID).Count(&proposals)
+	if proposals == 0 {
+		return nil, fmt.Errorf("the review has no proposals to approve")
+	}
+
+	now := time.Now()
+	review.Status = "submitted"
+	review.ApprovalLevel = 0
+	review.SubmittedBy = &userID
+	review.SubmittedAt = &now
+	if err := pp.db.Save(&review).Error; err != nil {
+		return nil, fmt.Errorf("failed to submit salary review: %w", err)
+	}
+	return &review, nil
+}
+
+// ApproveSalaryReview records the approval of the review's current level. Reviews follow the
+// company's payroll approval steps, and the user who submitted a review cannot approve it.
+// The final approval records an effective-dated compensation change for each proposal.
+func (pp *PayrollProcessor) ApproveSalaryReview(companyID, reviewID, userID uint, companyRole, comment string) (*models.SalaryReview, error) {
+	var review models.SalaryReview
+	err := pp.db.Transaction(func(tx *gorm.DB) error {
+		step, steps, err := checkReviewApprover(tx, &review, reviewID, companyID, userID, companyRole)
+		if err != nil {
+			return err
+		}
+
+		approval := models.SalaryReviewApproval{
+			CompanyID: companyID,
+			ReviewID:  review.ID,
+			Level:     step.Level,
+			Action:    "approved",
+			UserID:    userID,
+			Role:      companyRole,
+			Comment:   comment,
+		}
+		if err := tx.Create(&approval).Error; err != nil {
+			return fmt.Errorf("failed to record approval: %w", err)
+		}
+
+		review.ApprovalLevel = step.Level
+		if review.ApprovalLevel >= len(steps) {
+			now := time.Now()
+			review.Status = "approved"
+			review.ApprovedAt = &now
+			review.ApprovedBy = &userID
+		}
+		if err := tx.Save(&review).Error; err != nil {
+			return err
+		}
+
+		// The increases are recorded with the final approval, or not at all
+		if review.Status == "approved" {
+			return pp.withDB(tx).applySalaryReview(review, userID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pp.GetSalaryReview(companyID, review.ID)
+}
+
+// RejectSalaryReview records a rejection and returns the review to draft for rework.
+func (pp *PayrollProcessor) RejectSalaryReview(companyID, reviewID, userID uint, companyRole, comment string) (*models.SalaryReview, error) {
+	if comment == "" {
+		return nil, fmt.Errorf("a comment is required when rejecting a salary review")
+	}
+
+	var review models.SalaryReview
+	err := pp.db.Transaction(func(tx *gorm.DB) error {
+		step, _, err := checkReviewApprover(tx, &review, reviewID, companyID, userID, companyRole)
+		if err != nil {
+			return err
+		}
+
+		rejection := models.SalaryReviewApproval{
+			CompanyID: companyID,
+			ReviewID:  review.ID,
+			Level:     step.Level,
+			Action:    "rejected",
+			UserID:    userID,
+			Role:      companyRole,
+			Comment:   comment,
+		}
+		if err := tx.Create(&rejection).Error; err != nil {
+			return fmt.Errorf("failed to record rejection: %w", err)
+		}
+
+		review.Status = "draft"
+		review.ApprovalLevel = 0
+		review.SubmittedAt = nil
+		review.SubmittedBy = nil
+		return tx.Save(&review).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// checkReviewApprover loads the review and verifies the user may act on its current level.
+// The review row stays locked until the transaction ends.
+func checkReviewApprover(tx *gorm.DB, review *models.SalaryReview, reviewID, companyID, userID uint, companyRole string) (*models.PayrollApprovalStep, []models.PayrollApprovalStep, error) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND company_id = ?", reviewID, companyID).First(review).Error; err != nil {
+		return nil, nil, err
+	}
+	if review.Status != "submitted" {
+		return nil, nil, fmt.Errorf("%w: the review is not awaiting approval", ErrReviewState)
+	}
+
+	steps, err := approvalSteps(tx, companyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if review.ApprovalLevel >= len(steps) {
+		return nil, nil, fmt.Errorf("%w: the review is not awaiting approval", ErrReviewState)
+	}
+	step := steps[review.ApprovalLevel]
+
+	if review.SubmittedBy != nil && *review.SubmittedBy == userID {
+		return nil, nil, fmt.Errorf("%w: the user who submitted the review cannot approve it", ErrApprovalNotAllowed)
+	}
+
+	var priorApprovals int64
+	if err := tx.Model(&models.SalaryReviewApproval{}).
+		Where("review_id = ? AND user_id = ? AND action = ? AND created_at >= ?",
+			review.ID, userID, "approved", review.SubmittedAt).
+		Count(&priorApprovals).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to check earlier approvals: %w", err)
+	}
+	if priorApprovals > 0 {
+		return nil, nil, fmt.Errorf("%w: user has already approved an earlier level", ErrApprovalNotAllowed)
+	}
+
+	if err := checkStepApprover(step, userID, companyRole); err != nil {
+		return nil, nil, err
+	}
+	return &step, steps, nil
+}
+
+// applySalaryReview records a compensation change for each proposal of an approved review.
+// A proposal that cannot be applied keeps the error so it can be handled individually. That
+// includes a proposal drafted against a salary that a promotion or other change has since
+// replaced: its increase would otherwise overwrite the newer salary.
+func (pp *PayrollProcessor) applySalaryReview(review models.SalaryReview, approvedBy uint) error {
+	var proposals []models.SalaryReviewProposal
+	if err := pp.db.Where("review_id = ? AND compensation_change_id IS NULL", review.ID).
+		Order("id").
+		Find(&proposals).Error; err != nil {
+		return fmt.Errorf("failed to fetch review proposals: %w", err)
+	}
+
+	for _, proposal := range proposals {
+		if proposal.Increase.IsZero() {
+			continue
+		}
+
+		var change *models.CompensationChange
+		var employee models.Employee
+		err := pp.db.Where("id = ? AND company_id = ?", proposal.EmployeeID, review.CompanyID).First(&employee).Error
+		if err == nil {
+			salary, currencyID := pp.salaryAt(employee, review.EffectiveDate)
+			if !salary.Equal(proposal.CurrentSalary) || currencyID != proposal.CurrencyID {
+				err = fmt.Errorf("the salary in force on %s changed from %s to %s after the review was drafted; the increase was not applied",
+					review.EffectiveDate.Format("2006-01-02"), proposal.CurrentSalary.String(), salary.String())
+			}
+		}
+		if err == nil {
+			change, err = pp.RecordCompensationChange(review.CompanyID, proposal.EmployeeID, models.CompensationChange{
+				NewSalary:     proposal.ProposedSalary,
+				CurrencyID:    proposal.CurrencyID,
+				EffectiveDate: review.EffectiveDate,
+				Reason:        review.Reason,
+				Notes:         "Salary review: " + review.Name,
+				ApprovedBy:    &approvedBy,
+			}, review.CreatedBy)
+		}
+
+		updates := map[string]interface{}{"error": ""}
+		if err != nil {
+			updates["error"] = err.Error()
+		} else {
+			updates["compensation_change_id"] = change.ID
+		}
+		if err := pp.db.Model(&models.SalaryReviewProposal{}).Where("id = ?", proposal.ID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update proposal %d: %w", proposal.ID, err)
+		}
+	}
+	return nil
+}
+
+// validateReviewRules checks each rule's scope, type and value, and that scoped rules name a
+// department, position or employee of the company.
+func (pp *PayrollProcessor) validateReviewRules(companyID uint, rules []models.SalaryReviewRule) error {
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if _, ok := reviewScopeRank[rule.Scope]; !ok {
+			return fmt.Errorf("rule scope must be company, department, position or employee")
+		}
+		if rule.Type != models.ReviewIncreasePercentage && rule.Type != models.ReviewIncreaseAmount {
+			return fmt.Errorf("rule type must be percentage or amount")
+		}
+		if !rule.Value.IsPositive() {
+			return fmt.Errorf("rule value must be greater than zero")
+		}
+
+		key := rule.Scope
+		if rule.Scope != models.ReviewScopeCompany {
+			if rule.ScopeID == nil {
+				return fmt.Errorf("a %s rule needs a scope_id", rule.Scope)
+			}
+			var target interface{}
+			switch rule.Scope {
+			case models.ReviewScopeDepartment:
+				target = &models.Department{}
+			case models.ReviewScopePosition:
+				target = &models.Position{}
+			default:
+				target = &models.Employee{}
+			}
+			var count int64
+			pp.db.Model(target).Where("id = ? AND company_id = ?", *rule.ScopeID, companyID).Count(&count)
+			if count == 0 {
+				return fmt.Errorf("%s %d not found", rule.Scope, *rule.ScopeID)
+			}
+			key = fmt.Sprintf("%s/%d", rule.Scope, *rule.ScopeID)
+		}
+		if seen[key] {
+			return fmt.Errorf("more than one rule for %s", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// generateReviewProposals replaces the review's rule-based proposals with one for every active
+// salaried employee a rule covers, using the most specific rule. Proposals edited by hand stay.
+func (pp *PayrollProcessor) generateReviewProposals(tx *gorm.DB, review models.SalaryReview) error {
+	if err := tx.Where("review_id = ? AND overridden = ?", review.ID, false).Delete(&models.SalaryReviewProposal{}).Error; err != nil {
+		return fmt.Errorf("failed to replace proposals: %w", err)
+	}
+	var overridden []uint
+	if err := tx.Model(&models.SalaryReviewProposal{}).Where("review_id = ?", review.ID).
+		Pluck("employee_id", &overridden).Error; err != nil {
+		return fmt.Errorf("failed to fetch proposals: %w", err)
+	}
+	kept := make(map[uint]bool, len(overridden))
+	for _, employeeID := range overridden {
+		kept[employeeID] = true
+	}
+
+	var employees []models.Employee
+	if err := tx.Preload("Currency").
+		Where("company_id = ? AND is_active = ? AND employment_status = ?", review.CompanyID, true, "active").
+		Where("employment_type <> ?", models.EmploymentTypeContractor).
+		Order("employee_number").
+		Find(&employees).Error; err != nil {
+		return fmt.Errorf("failed to fetch employees: %w", err)
+	}
+
+	var settings models.CompanySettings
+	tx.Where("company_id = ?", review.CompanyID).First(&settings)
+	rounding := settings.Rounding()
+
+	for _, employee := range employees {
+		if kept[employee.ID] || !employee.IsSalaried() {
+			continue
+		}
+		rule := matchReviewRule(review.Rules, employee)
+		if rule == nil {
+			continue
+		}
+
+		current := employee.BasicSalary
+		increase := rule.Value
+		if rule.Type == models.ReviewIncreasePercentage {
+			increase = current.Mul(rule.Value).Div(decimal.NewFromInt(100))
+		}
+		proposed := rounding.Round(current.Add(increase), employee.Currency.Code)
+
+		proposal := models.SalaryReviewProposal{
+			ReviewID:        review.ID,
+			EmployeeID:      employee.ID,
+			DepartmentID:    employee.DepartmentID,
+			CurrencyID:      employee.CurrencyID,
+			CurrentSalary:   current,
+			ProposedSalary:  proposed,
+			Increase:        proposed.Sub(current),
+			IncreasePercent: increasePercent(proposed.Sub(current), current),
+			RuleScope:       rule.Scope,
+		}
+		if err := tx.Create(&proposal).Error; err != nil {
+			return fmt.Errorf("failed to save proposal for employee %s: %w", employee.EmployeeNumber, err)
+		}
+	}
+	return nil
+}
+
+// matchReviewRule returns the most specific rule covering the employee, or nil.
+func matchReviewRule(rules []models.SalaryReviewRule, employee models.Employee) *models.SalaryReviewRule {
+	var match *models.SalaryReviewRule
+	for i, rule := range rules {
+		covers := false
+		switch rule.Scope {
+		case models.ReviewScopeCompany:
+			covers = true
+		case models.ReviewScopeDepartment:
+			covers = rule.ScopeID != nil && *rule.ScopeID == employee.DepartmentID
+		case models.ReviewScopePosition:
+			covers = rule.ScopeID != nil && *rule.ScopeID == employee.PositionID
+		case models.ReviewScopeEmployee:
+			covers = rule.ScopeID != nil && *rule.ScopeID == employee.ID
+		}
+		if covers && (match == nil || reviewScopeRank[rule.Scope] > reviewScopeRank[match.Scope]) {
+			match = &rules[i]
+		}
+	}
+	return match
+}
+
+// increasePercent is increase as a percentage of current, to two decimal places
+func increasePercent(increase, current decimal.Decimal) float64 {
+	if !current.IsPositive() {
+		return 0
+	}
+	return increase.Mul(decimal.NewFromInt(100)).Div(current).Round(2).InexactFloat64()
+}
@@ -0,0 +1,81 @@
+package payroll
+
+import (
+	"testing"
+	"time"
+
+	"gm58-hr-backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSalaryReviewRecordsApprovedIncreases(t *testing.T) {
+	db, company := setupPayrollDB(t, 3)
+	processor := newProcessor(db)
+
+	employees := employeesByNumber(t, db)
+
+	// 5% for everyone, and a flat 100 for the third employee instead
+	effectiveDate := time.Now().AddDate(0, 2, 0).Truncate(24 * time.Hour)
+	review, err := processor.CreateSalaryReview(company.ID, 1, models.SalaryReview{
+		Name: "Annual review", EffectiveDate: effectiveDate, Budget: decimal.NewFromInt(1500),
+		Rules: []models.SalaryReviewRule{
+			{Scope: models.ReviewScopeCompany, Type: models.ReviewIncreasePercentage, Value: decimal.NewFromInt(5)},
+			{Scope: models.ReviewScopeEmployee, ScopeID: &employees[2].ID, Type: models.ReviewIncreaseAmount, Value: decimal.NewFromInt(100)},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, review.Proposals, 3)
+	assertAmount(t, 525, review.Proposals[0].ProposedSalary)
+	assertAmount(t, 630, review.Proposals[1].ProposedSalary)
+	assertAmount(t, 800, review.Proposals[2].ProposedSalary)
+	assert.Equal(t, models.ReviewScopeEmployee, review.Proposals[2].RuleScope)
+
+	// Increases of 155 a month are 1860 a year, 360 over the budget
+	impact, err := processor.SalaryReviewImpact(*review)
+	require.NoError(t, err)
+	assertAmount(t, 155, impact.MonthlyIncreaseBase)
+	assertAmount(t, 1860, impact.AnnualIncreaseBase)
+	assertAmount(t, -360, impact.BudgetRemaining)
+	assert.True(t, impact.OverBudget)
+
+	_, err = processor.SubmitSalaryReview(company.ID, review.ID, 1)
+	require.NoError(t, err)
+	_, err = processor.ApproveSalaryReview(company.ID, review.ID, 1, "hr", "")
+	assert.ErrorIs(t, err, ErrApprovalNotAllowed)
+
+	// A promotion while the review awaits approval is not overwritten by the older proposal
+	_, err = processor.RecordCompensationChange(company.ID, employees[1].ID, models.CompensationChange{
+		NewSalary: decimal.NewFromInt(900), EffectiveDate: effectiveDate.AddDate(0, -1, 0), Reason: models.CompensationReasonPromotion,
+	}, 1)
+	require.NoError(t, err)
+
+	approved, err := processor.ApproveSalaryReview(company.ID, review.ID, 2, "hr", "")
+	require.NoError(t, err)
+	assert.Equal(t, "approved", approved.Status)
+	for i, proposal := range approved.Proposals {
+		if i == 1 {
+			assert.NotEmpty(t, proposal.Error)
+			assert.Nil(t, proposal.CompensationChangeID)
+			continue
+		}
+		assert.Empty(t, proposal.Error)
+		require.NotNil(t, proposal.CompensationChangeID)
+	}
+	promoted, err := processor.GetCompensationHistory(company.ID, employees[1].ID)
+	require.NoError(t, err)
+	require.Len(t, promoted, 1)
+	assertAmount(t, 900, promoted[0].NewSalary)
+
+	// The changes are scheduled for the effective date and leave current salaries alone
+	history, err := processor.GetCompensationHistory(company.ID, employees[2].ID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assertAmount(t, 800, history[0].NewSalary)
+	assert.Equal(t, models.CompensationReasonAnnualIncrease, history[0].Reason)
+	assert.Nil(t, history[0].AppliedAt)
+	require.NoError(t, db.First(&employees[2], employees[2].ID).Error)
+	assertAmount(t, 700, employees[2].BasicSalary)
+}
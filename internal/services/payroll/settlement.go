@@ -0,0 +1,478 @@
+package payroll
+
+import (
+	"errors"
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"gm58-hr-backend/internal/services/tax"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ErrAlreadyTerminated is returned when a final settlement is requested for an employee who has left.
+var ErrAlreadyTerminated = errors.New("employee has already been terminated")
+
+// terminationReasons lists the accepted reasons and whether each one attracts severance pay
+var terminationReasons = map[string]bool{
+	"resignation":     false,
+	"dismissal":       false,
+	"retrenchment":    true,
+	"end_of_contract": false,
+	"retirement":      false,
+	"death":           false,
+}
+
+// CreateFinalSettlement terminates an employee and calculates their final pay in a new
+// termination period. The period is processed straight away and then goes through the
+// company's approval steps; a rejected settlement is recalculated when the period is
+// processed again.
+func (pp *PayrollProcessor) CreateFinalSettlement(companyID, employeeID uint, terminationDate time.Time, reason string, noticeDays int, createdBy uint) (*models.FinalSettlement, error) {
+	if _, ok := terminationReasons[reason]; !ok {
+		return nil, fmt.Errorf("invalid termination reason: %s", reason)
+	}
+	if noticeDays < 0 {
+		return nil, fmt.Errorf("notice days cannot be negative")
+	}
+	if terminationDate.After(time.Now()) {
+		return nil, fmt.Errorf("termination date cannot be in the future")
+	}
+
+	var employee models.Employee
+	if err := pp.db.Where("id = ? AND company_id = ?", employeeID, companyID).First(&employee).Error; err != nil {
+		return nil, fmt.Errorf("employee not found: %w", err)
+	}
+	if employee.EmploymentStatus == "terminated" {
+		return nil, ErrAlreadyTerminated
+	}
+	if employee.IsContractor() {
+		return nil, fmt.Errorf("contractors are not paid a final settlement; pay any amount due through a correction period")
+	}
+	if hired, ok := employee.HiredOn(); ok && terminationDate.Before(hired) {
+		return nil, fmt.Errorf("termination date is before the hire date")
+	}
+
+	monthStart := time.Date(terminationDate.Year(), terminationDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+	period := models.PayrollPeriod{
+		CompanyID:   companyID,
+		PeriodType:  models.PeriodTypeTermination,
+		Year:        terminationDate.Year(),
+		Month:       int(terminationDate.Month()),
+		StartDate:   monthStart,
+		EndDate:     terminationDate,
+		Status:      "draft",
+		Description: fmt.Sprintf("Final settlement for %s", employee.FullName()),
+	}
+	settlement := models.FinalSettlement{
+		CompanyID:       companyID,
+		EmployeeID:      employee.ID,
+		TerminationDate: terminationDate,
+		Reason:          reason,
+		NoticeDays:      noticeDays,
+		CurrencyID:      employee.CurrencyID,
+		Status:          "pending",
+		CreatedBy:       createdBy,
+	}
+
+	err := pp.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&period).Error; err != nil {
+			return fmt.Errorf("failed to create termination period: %w", err)
+		}
+		settlement.PayrollPeriodID = period.ID
+		if err := tx.Create(&settlement).Error; err != nil {
+			return fmt.Errorf("failed to save final settlement: %w", err)
+		}
+		// Terminated employees drop out of regular payroll runs
+		return tx.Model(&models.Employee{}).Where("id = ?", employee.ID).Updates(map[string]interface{}{
+			"employment_status": "terminated",
+			"is_active":         false,
+			"termination_date":  terminationDate.Format("2006-01-02"),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pp.ProcessPayrollForCompany(period.ID, companyID, createdBy); err != nil {
+		return nil, fmt.Errorf("final settlement saved but could not be processed: %w", err)
+	}
+
+	return pp.GetFinalSettlement(companyID, employee.ID)
+}
+
+// GetFinalSettlement returns the employee's final settlement with its period and payslip.
+func (pp *PayrollProcessor) GetFinalSettlement(companyID, employeeID uint) (*models.FinalSettlement, error) {
+	var settlement models.FinalSettlement
+	err := pp.db.Preload("Currency").Preload("PayrollPeriod").Preload("Payslip").
+		Where("company_id = ? AND employee_id = ?", companyID, employeeID).
+		Order("id DESC").
+		First(&settlement).Error
+	if err != nil {
+		return nil, err
+	}
+	return &settlement, nil
+}
+
+// processFinalSettlement calculates the settlement paid through a termination period and
+// saves its payslip. The period must already be claimed for processing.
+func (pp *PayrollProcessor) processFinalSettlement(period models.PayrollPeriod, processedBy uint) error {
+	var settlement models.FinalSettlement
+	if err := pp.db.Where("payroll_period_id = ? AND company_id = ?", period.ID, period.CompanyID).
+		First(&settlement).Error; err != nil {
+		return fmt.Errorf("final settlement not found: %w", err)
+	}
+
+	var employee models.Employee
+	if err := pp.db.Preload("Currency").Preload("Position").Preload("Department").
+		First(&employee, settlement.EmployeeID).Error; err != nil {
+		return fmt.Errorf("employee not found: %w", err)
+	}
+
+	// Approved time not yet paid by a regular run
+	work, err := pp.approvedWork(period.CompanyID, settlement.TerminationDate)
+	if err != nil {
+		return err
+	}
+	worked := work[employee.ID]
+
+	payslip, err := pp.calculateFinalSettlement(&settlement, employee, period, worked)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return pp.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&payslip).Error; err != nil {
+			return fmt.Errorf("failed to save payslip: %w", err)
+		}
+		if err := markTimePaid(tx, worked.entryIDs, payslip); err != nil {
+			return err
+		}
+
+		settlement.PayslipID = &payslip.ID
+		settlement.Status = "processed"
+		if err := tx.Save(&settlement).Error; err != nil {
+			return fmt.Errorf("failed to save final settlement: %w", err)
+		}
+
+		return tx.Model(&period).Updates(map[string]interface{}{
+			"status":         "processed",
+			"processed_at":   now,
+			"processed_by":   processedBy,
+			"approval_level": 0,
+		}).Error
+	})
+}
+
+// hoursPerDay is the working day used to turn an hourly rate into a daily rate
+const hoursPerDay = 8
+
+// settlementDailyRate is the employee's pay for a working day: the salary spread over the
+// working days of an average year, the daily rate, or a working day at the hourly rate.
+// Piece-rate employees are not paid by time, so they have none.
+func settlementDailyRate(employee models.Employee, workWeekDays int) (decimal.Decimal, bool) {
+	switch {
+	case employee.IsSalaried():
+		return employee.BasicSalary.Mul(decimal.NewFromInt(12)).Div(decimal.NewFromInt(int64(52 * workWeekDays))), true
+	case employee.PayType == models.PayTypeDaily:
+		return employee.PayRate, true
+	case employee.PayType == models.PayTypeHourly:
+		return employee.PayRate.Mul(decimal.NewFromInt(hoursPerDay)), true
+	}
+	return decimal.Zero, false
+}
+
+// calculateFinalSettlement fills in the settlement amounts and returns the payslip that pays them.
+// worked is the approved time still owed to an employee who is not on a salary.
+func (pp *PayrollProcessor) calculateFinalSettlement(settlement *models.FinalSettlement, employee models.Employee, period models.PayrollPeriod, worked approvedTime) (models.Payslip, error) {
+	var company models.Company
+	if err := pp.db.Preload("BaseCurrency").First(&company, period.CompanyID).Error; err != nil {
+		return models.Payslip{}, fmt.Errorf("failed to get company base currency: %w", err)
+	}
+	var settings models.CompanySettings
+	pp.db.Where("company_id = ?", period.CompanyID).First(&settings)
+	rounding := settings.Rounding()
+
+	currencyCode := employee.Currency.Code
+	round := func(amount decimal.Decimal) decimal.Decimal {
+		return rounding.Round(amount, currencyCode)
+	}
+	salary := employee.BasicSalary
+	terminationDate := settlement.TerminationDate
+	workWeekDays := company.WorkWeekDays
+	if workWeekDays == 0 {
+		workWeekDays = 5
+	}
+
+	// Salary for the final month up to the termination date, less anything a regular run already paid
+	monthStart := time.Date(terminationDate.Year(), terminationDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+	monthEnd := monthStart.AddDate(0, 1, -1)
+	payFrom := monthStart
+	if hired, ok := employee.HiredOn(); ok && hired.After(payFrom) {
+		payFrom = hired
+	}
+	var lastPaid models.PayrollPeriod
+	if err := pp.db.Joins("JOIN payslips ON payslips.payroll_period_id = payroll_periods.id").
+		Where("payslips.employee_id = ? AND payroll_periods.period_type = ? AND payroll_periods.status IN ?",
+			employee.ID, models.PeriodTypeRegular, []string{"processed", "approved", "paid"}).
+		Order("payroll_periods.end_date DESC").
+		First(&lastPaid).Error; err == nil && !lastPaid.EndDate.Before(payFrom) {
+		payFrom = lastPaid.EndDate.AddDate(0, 0, 1)
+	}
+
+	monthWorkingDays := pp.calculateWorkingDaysForCompany(monthStart, monthEnd, workWeekDays)
+	settlement.ProRataDays = 0
+	if !payFrom.After(terminationDate) {
+		settlement.ProRataDays = pp.calculateWorkingDaysForCompany(payFrom, terminationDate, workWeekDays)
+	}
+	settlement.ProRataSalary = decimal.Zero
+	if !employee.IsSalaried() {
+		settlement.ProRataSalary = round(workedPay(employee, worked.quantity))
+	} else if monthWorkingDays > 0 {
+		settlement.ProRataSalary = round(salary.Mul(decimal.NewFromInt(int64(settlement.ProRataDays))).Div(decimal.NewFromInt(int64(monthWorkingDays))))
+	}
+
+	// Leave and notice are paid at the daily rate of an average working year, and severance
+	// on the monthly pay that rate comes to for employees not on a salary
+	workingDaysPerYear := decimal.NewFromInt(int64(52 * workWeekDays))
+	dailyRate, hasDailyRate := settlementDailyRate(employee, workWeekDays)
+	if !employee.IsSalaried() {
+		salary = dailyRate.Mul(workingDaysPerYear).Div(decimal.NewFromInt(12))
+	}
+	settlement.DailyRate = round(dailyRate)
+
+	var leaveTypes []models.LeaveType
+	if err := pp.db.Where("company_id = ? AND is_active = ? AND is_paid = ? AND paid_on_exit = ?",
+		period.CompanyID, true, true, true).Find(&leaveTypes).Error; err != nil {
+		return models.Payslip{}, fmt.Errorf("failed to fetch leave types: %w", err)
+	}
+	settlement.LeaveDays = 0
+	for _, leaveType := range leaveTypes {
+		balance, err := pp.leaveService.GetBalance(employee, leaveType, terminationDate)
+		if err != nil {
+			return models.Payslip{}, err
+		}
+		if balance.Available > 0 {
+			settlement.LeaveDays += balance.Available
+		}
+	}
+	if !hasDailyRate && (settlement.LeaveDays > 0 || settlement.NoticeDays > 0 || terminationReasons[settlement.Reason]) {
+		return models.Payslip{}, fmt.Errorf("a %s employee has no daily rate for leave, notice or severance pay; pay these through a correction period", employee.PayType)
+	}
+	settlement.LeavePay = round(money.FromFloat(settlement.LeaveDays).Mul(settlement.DailyRate))
+	settlement.NoticePay = round(decimal.NewFromInt(int64(settlement.NoticeDays)).Mul(settlement.DailyRate))
+
+	settlement.ServiceYears = 0
+	if hired, ok := employee.HiredOn(); ok {
+		settlement.ServiceYears = math.Round(terminationDate.Sub(hired).Hours()/24/365.25*100) / 100
+	}
+	settlement.SeverancePay = decimal.Zero
+	if terminationReasons[settlement.Reason] {
+		settlement.SeverancePay = round(salary.Mul(money.FromFloat(settings.SeveranceMonthsPerYear)).Mul(money.FromFloat(settlement.ServiceYears)))
+	}
+
+	settlement.TotalEarnings = money.Sum(settlement.ProRataSalary, settlement.LeavePay, settlement.NoticePay, settlement.SeverancePay)
+
+	trace := newCalculationTrace(currencyCode, rounding)
+	if employee.IsSalaried() {
+		trace.add(models.PayslipCalculationStep{
+			Section:     models.TraceSectionEarnings,
+			Component:   "pro_rata_salary",
+			Description: fmt.Sprintf("Salary for %d of %d working days in the final month", settlement.ProRataDays, monthWorkingDays),
+			Basis:       employee.BasicSalary.InexactFloat64(),
+			Amount:      settlement.ProRataSalary,
+		})
+	} else {
+		trace.total(models.TraceSectionEarnings, "pro_rata_salary", "Approved time not yet paid", settlement.ProRataSalary)
+	}
+	trace.add(models.PayslipCalculationStep{
+		Section:     models.TraceSectionEarnings,
+		Component:   "leave_pay",
+		Description: fmt.Sprintf("%g days of accrued leave at the daily rate", settlement.LeaveDays),
+		Basis:       settlement.LeaveDays,
+		Rate:        settlement.DailyRate.InexactFloat64(),
+		Amount:      settlement.LeavePay,
+	})
+	trace.add(models.PayslipCalculationStep{
+		Section:     models.TraceSectionEarnings,
+		Component:   "notice_pay",
+		Description: fmt.Sprintf("%d days' pay in lieu of notice at the daily rate", settlement.NoticeDays),
+		Basis:       float64(settlement.NoticeDays),
+		Rate:        settlement.DailyRate.InexactFloat64(),
+		Amount:      settlement.NoticePay,
+	})
+	if !settlement.SeverancePay.IsZero() {
+		trace.add(models.PayslipCalculationStep{
+			Section:   models.TraceSectionEarnings,
+			Component: "severance_pay",
+			Description: fmt.Sprintf("%g months' salary per year of service for %g years",
+				settings.SeveranceMonthsPerYear, settlement.ServiceYears),
+			Basis:  salary.InexactFloat64(),
+			Amount: settlement.SeverancePay,
+		})
+	}
+	trace.total(models.TraceSectionEarnings, "total_earnings", "Total earnings", settlement.TotalEarnings)
+	trace.total(models.TraceSectionTaxable, "taxable_earnings", "Earnings taxed as income of the final month", settlement.TotalEarnings)
+
+	// Everything is taxed as income of the final month, together with anything already paid
+	// that month. A directive, typically a lump sum directive on the payout, replaces the brackets.
+	monthToDate, err := pp.monthToDateEarnings(period, []models.Employee{employee})
+	if err != nil {
+		return models.Payslip{}, err
+	}
+	directives, err := pp.directivesFor(period, []uint{employee.ID})
+	if err != nil {
+		return models.Payslip{}, err
+	}
+	directive, directed := directives[employee.ID]
+	directed = directed && settings.EnablePAYE
+	var applied *models.TaxDirective
+	if directed {
+		applied = &directive
+	}
+	settlement.PayeeTax, settlement.AidsLevy, settlement.NSSAContribution, err =
+		pp.taxOnTop(settlement.TotalEarnings, settlement.TotalEarnings, monthToDate[employee.ID], currencyCode, period.EndDate, settings, applied, trace)
+	if err != nil {
+		return models.Payslip{}, err
+	}
+	netBeforeLoans := settlement.TotalEarnings.Sub(money.Sum(settlement.PayeeTax, settlement.AidsLevy, settlement.NSSAContribution))
+
+	// Outstanding loans are recovered from whatever net pay there is
+	var outstanding decimal.Decimal
+	if err := pp.db.Model(&models.EmployeeLoan{}).
+		Where("employee_id = ? AND status = ?", employee.ID, "active").
+		Select("COALESCE(SUM(balance), 0)").
+		Scan(&outstanding).Error; err != nil {
+		return models.Payslip{}, fmt.Errorf("failed to fetch loans: %w", err)
+	}
+	settlement.LoanRecovery = decimal.Max(decimal.Zero, decimal.Min(outstanding, netBeforeLoans))
+	settlement.LoanBalance = outstanding.Sub(settlement.LoanRecovery)
+	settlement.NetPay = netBeforeLoans.Sub(settlement.LoanRecovery)
+
+	exchangeRate, err := pp.currencyService.GetExchangeRateAt(currencyCode, company.BaseCurrency.Code, period.EndDate)
+	if err != nil {
+		return models.Payslip{}, fmt.Errorf("failed to get exchange rate: %w", err)
+	}
+
+	totalDeductions := money.Sum(settlement.PayeeTax, settlement.AidsLevy, settlement.NSSAContribution, settlement.LoanRecovery)
+	if !settlement.LoanRecovery.IsZero() {
+		trace.total(models.TraceSectionDeductions, "loan_recovery",
+			fmt.Sprintf("Outstanding loans recovered from net pay, %s still owed", settlement.LoanBalance.StringFixed(money.Places(currencyCode))), settlement.LoanRecovery)
+	}
+	baseCode := company.BaseCurrency.Code
+	totalEarningsBase := rounding.Round(settlement.TotalEarnings.Mul(exchangeRate), baseCode)
+	totalDeductionsBase := rounding.Round(totalDeductions.Mul(exchangeRate), baseCode)
+	netPayBase := totalEarningsBase.Sub(totalDeductionsBase)
+	trace.total(models.TraceSectionDeductions, "total_deductions", "Total deductions", totalDeductions)
+	trace.netPay(settlement.NetPay, exchangeRate, baseCode, netPayBase)
+	payslip := models.Payslip{
+		CompanyID:           period.CompanyID,
+		EmployeeID:          employee.ID,
+		PayrollPeriodID:     period.ID,
+		CurrencyID:          employee.CurrencyID,
+		ExchangeRate:        exchangeRate,
+		TaxTableVersion:     tax.TaxTableVersion,
+		BasicSalary:         settlement.ProRataSalary,
+		LeavePay:            settlement.LeavePay,
+		NoticePay:           settlement.NoticePay,
+		SeverancePay:        settlement.SeverancePay,
+		TotalEarnings:       settlement.TotalEarnings,
+		PayeeTax:            settlement.PayeeTax,
+		AidsLevy:            settlement.AidsLevy,
+		NSSAContribution:    settlement.NSSAContribution,
+		LoanDeductions:      settlement.LoanRecovery,
+		TotalDeductions:     totalDeductions,
+		NetPay:              settlement.NetPay,
+		EmployerNSSA:        settlement.NSSAContribution,
+		TotalEarningsBase:   totalEarningsBase,
+		TotalDeductionsBase: totalDeductionsBase,
+		NetPayBase:          netPayBase,
+		WorkingDays:         monthWorkingDays,
+		DaysWorked:          settlement.ProRataDays,
+		Status:              "generated",
+		CalculationSteps:    trace.steps,
+	}
+	payslip.SnapshotEmployee(employee)
+	if directed {
+		applyDirective(&payslip, directive)
+	}
+
+	return payslip, nil
+}
+
+// completeFinalSettlement reduces the employee's loans by the amount recovered once the
+// termination period is fully approved. Older loans are settled first.
+func completeFinalSettlement(tx *gorm.DB, period models.PayrollPeriod) error {
+	var settlement models.FinalSettlement
+	if err := tx.Where("payroll_period_id = ? AND company_id = ?", period.ID, period.CompanyID).
+		First(&settlement).Error; err != nil {
+		return fmt.Errorf("final settlement not found: %w", err)
+	}
+
+	var loans []models.EmployeeLoan
+	if err := tx.Where("employee_id = ? AND status = ?", settlement.EmployeeID, "active").
+		Order("issued_date, id").Find(&loans).Error; err != nil {
+		return fmt.Errorf("failed to fetch loans: %w", err)
+	}
+
+	remaining := settlement.LoanRecovery
+	for _, loan := range loans {
+		if !remaining.IsPositive() {
+			break
+		}
+		recovered := decimal.Min(loan.Balance, remaining)
+		remaining = remaining.Sub(recovered)
+
+		balance := loan.Balance.Sub(recovered)
+		updates := map[string]interface{}{"balance": balance}
+		if balance.IsZero() {
+			updates["status"] = "settled"
+		}
+		if err := tx.Model(&loan).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update loan: %w", err)
+		}
+	}
+
+	return tx.Model(&settlement).Update("status", "approved").Error
+}
+
+// CreateEmployeeLoan records a loan in the employee's currency.
+func (pp *PayrollProcessor) CreateEmployeeLoan(companyID, employeeID uint, principal decimal.Decimal, description string, issuedDate time.Time, createdBy uint) (*models.EmployeeLoan, error) {
+	if !principal.IsPositive() {
+		return nil, fmt.Errorf("loan amount must be greater than zero")
+	}
+
+	var employee models.Employee
+	if err := pp.db.Where("id = ? AND company_id = ?", employeeID, companyID).First(&employee).Error; err != nil {
+		return nil, fmt.Errorf("employee not found: %w", err)
+	}
+
+	loan := models.EmployeeLoan{
+		CompanyID:   companyID,
+		EmployeeID:  employee.ID,
+		Description: description,
+		CurrencyID:  employee.CurrencyID,
+		Principal:   principal,
+		Balance:     principal,
+		IssuedDate:  issuedDate,
+		Status:      "active",
+		CreatedBy:   createdBy,
+	}
+	if err := pp.db.Create(&loan).Error; err != nil {
+		return nil, fmt.Errorf("failed to save loan: %w", err)
+	}
+	return &loan, nil
+}
+
+// GetEmployeeLoans returns an employee's loans, newest first.
+func (pp *PayrollProcessor) GetEmployeeLoans(companyID, employeeID uint) ([]models.EmployeeLoan, error) {
+	var loans []models.EmployeeLoan
+	err := pp.db.Preload("Currency").
+		Where("company_id = ? AND employee_id = ?", companyID, employeeID).
+		Order("issued_date DESC, id DESC").
+		Find(&loans).Error
+	return loans, err
+}
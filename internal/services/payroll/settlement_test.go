@@ -0,0 +1,67 @@
+package payroll
+
+import (
+	"testing"
+	"time"
+
+	"gm58-hr-backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinalSettlementPaysFromLastRegularRun(t *testing.T) {
+	db, company := setupPayrollDB(t, 1)
+	processor := newProcessor(db)
+	january := createDraftPeriod(t, db, company.ID, 0)
+	require.NoError(t, processor.ProcessPayrollForCompany(january.ID, company.ID, 1))
+
+	// A February bonus does not pay February's salary
+	employee := firstEmployee(t, db)
+	february := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	bonus := models.PayrollPeriod{
+		CompanyID: company.ID, PeriodType: models.PeriodTypeBonus, Year: 2024, Month: 2,
+		StartDate: february, EndDate: february.AddDate(0, 1, -1), Status: "approved",
+	}
+	require.NoError(t, db.Create(&bonus).Error)
+	require.NoError(t, db.Create(&models.Payslip{
+		CompanyID: company.ID, PayrollPeriodID: bonus.ID, EmployeeID: employee.ID, CurrencyID: employee.CurrencyID,
+		Bonus: decimal.NewFromInt(100), TotalEarnings: decimal.NewFromInt(100), NetPay: decimal.NewFromInt(100), Status: "approved",
+	}).Error)
+
+	settlement, err := processor.CreateFinalSettlement(company.ID, employee.ID, time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), "resignation", 5, 1)
+	require.NoError(t, err)
+	// 11 of February's 21 working days, and notice at 500 * 12 / 260 a day
+	assert.Equal(t, 11, settlement.ProRataDays)
+	assertAmount(t, 261.9, settlement.ProRataSalary)
+	assertAmount(t, 23.08, settlement.DailyRate)
+	assertAmount(t, 115.4, settlement.NoticePay)
+}
+
+func TestFinalSettlementDailyRateFromPayRate(t *testing.T) {
+	db, company := setupPayrollDB(t, 3)
+	processor := newProcessor(db)
+
+	employees := employeesByNumber(t, db)
+	for i, payType := range []string{models.PayTypeDaily, models.PayTypeHourly, models.PayTypePieceRate} {
+		require.NoError(t, db.Model(&employees[i]).Updates(map[string]interface{}{
+			"pay_type": payType, "pay_rate": 40, "basic_salary": 0,
+		}).Error)
+	}
+	terminated := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	daily, err := processor.CreateFinalSettlement(company.ID, employees[0].ID, terminated, "resignation", 5, 1)
+	require.NoError(t, err)
+	assertAmount(t, 40, daily.DailyRate)
+	assertAmount(t, 200, daily.NoticePay)
+
+	hourly, err := processor.CreateFinalSettlement(company.ID, employees[1].ID, terminated, "resignation", 5, 1)
+	require.NoError(t, err)
+	assertAmount(t, 320, hourly.DailyRate)
+	assertAmount(t, 1600, hourly.NoticePay)
+
+	// Notice cannot be priced for piece work
+	_, err = processor.CreateFinalSettlement(company.ID, employees[2].ID, terminated, "resignation", 5, 1)
+	assert.Error(t, err)
+}
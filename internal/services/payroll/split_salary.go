@@ -0,0 +1,133 @@
+package payroll
+
+import (
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+
+	"github.com/shopspring/decimal"
+)
+
+// salarySplit is one currency part of a split salary, with its value in the employee's currency.
+type salarySplit struct {
+	netPay   models.PayslipNetPay
+	rate     decimal.Decimal // Employee currency to the part's currency
+	value    decimal.Decimal
+	currency string
+}
+
+// calculateSplitSalary returns the basic salary in the employee's currency together with
+// the per-currency salary parts for employees paid in more than one currency. Fixed amount
+// components are converted at the rate in force at the end of the period. Components split
+// the salary rather than replace it: whatever they leave unallocated is paid in the
+// employee's own currency, and fixed amounts worth more than the salary are an error.
+func calculateSplitSalary(employee models.Employee, rates *rateTable, rounding money.Rounding) (decimal.Decimal, []salarySplit, error) {
+	currencyCode := employee.Currency.Code
+	salary := employee.BasicSalary
+
+	var components []models.SalaryComponent
+	for _, component := range employee.SalaryComponents {
+		if component.IsActive {
+			components = append(components, component)
+		}
+	}
+
+	if len(components) == 0 {
+		return rounding.Round(salary, currencyCode), nil, nil
+	}
+
+	basicSalary := decimal.Zero
+	hasFixed := false
+	splits := make([]salarySplit, 0, len(components)+1)
+	for _, component := range components {
+		rate, err := rates.rate(rateKey{from: currencyCode, to: component.Currency.Code, atPeriodEnd: true})
+		if err != nil {
+			return decimal.Zero, nil, fmt.Errorf("failed to get %s exchange rate: %w", component.Currency.Code, err)
+		}
+		if !rate.IsPositive() {
+			return decimal.Zero, nil, fmt.Errorf("invalid %s exchange rate", component.Currency.Code)
+		}
+
+		var valueInEmployeeCurrency, grossAmount decimal.Decimal
+		if component.IsPercentage {
+			valueInEmployeeCurrency = rounding.Line(money.Percent(salary, component.Percentage), currencyCode)
+			grossAmount = valueInEmployeeCurrency.Mul(rate)
+		} else {
+			hasFixed = true
+			grossAmount = component.Amount
+			valueInEmployeeCurrency = rounding.Line(grossAmount.Div(rate), currencyCode)
+		}
+
+		basicSalary = basicSalary.Add(valueInEmployeeCurrency)
+		splits = append(splits, salarySplit{
+			netPay: models.PayslipNetPay{
+				CurrencyID:   component.CurrencyID,
+				ExchangeRate: rate,
+				GrossAmount:  rounding.Round(grossAmount, component.Currency.Code),
+			},
+			rate:     rate,
+			value:    valueInEmployeeCurrency,
+			currency: component.Currency.Code,
+		})
+	}
+
+	salary = rounding.Round(salary, currencyCode)
+	remainder := salary.Sub(basicSalary)
+	switch {
+	case remainder.IsPositive():
+		splits = addRemainder(splits, employee, remainder)
+		basicSalary = salary
+	case remainder.IsNegative() && hasFixed:
+		return decimal.Zero, nil, fmt.Errorf("salary components are worth %s %s, more than the basic salary of %s",
+			currencyCode, basicSalary.StringFixed(money.Places(currencyCode)), salary.StringFixed(money.Places(currencyCode)))
+	}
+
+	return rounding.Round(basicSalary, currencyCode), splits, nil
+}
+
+// addRemainder pays the salary the components leave unallocated in the employee's own
+// currency, adding it to a component already paid in that currency if there is one.
+func addRemainder(splits []salarySplit, employee models.Employee, remainder decimal.Decimal) []salarySplit {
+	for i := range splits {
+		if splits[i].currency == employee.Currency.Code {
+			splits[i].value = splits[i].value.Add(remainder)
+			splits[i].netPay.GrossAmount = splits[i].netPay.GrossAmount.Add(remainder)
+			return splits
+		}
+	}
+	return append(splits, salarySplit{
+		netPay: models.PayslipNetPay{
+			CurrencyID:   employee.CurrencyID,
+			ExchangeRate: decimal.NewFromInt(1),
+			GrossAmount:  remainder,
+		},
+		rate:     decimal.NewFromInt(1),
+		value:    remainder,
+		currency: employee.Currency.Code,
+	})
+}
+
+// allocateNetPay distributes net pay across the salary currencies in proportion to each
+// component's share of basic salary. The last part takes the rounding remainder so the
+// parts always add up to the payslip net pay.
+func allocateNetPay(splits []salarySplit, basicSalary, netPay decimal.Decimal, currencyCode string, rounding money.Rounding) []models.PayslipNetPay {
+	if len(splits) == 0 || basicSalary.IsZero() {
+		return nil
+	}
+
+	netPays := make([]models.PayslipNetPay, len(splits))
+	allocated := decimal.Zero
+	for i, split := range splits {
+		share := rounding.Round(netPay.Mul(split.value).Div(basicSalary), currencyCode)
+		if i == len(splits)-1 {
+			share = netPay.Sub(allocated)
+		}
+		allocated = allocated.Add(share)
+
+		netPays[i] = split.netPay
+		netPays[i].NetAmountPayslipCurrency = share
+		netPays[i].NetAmount = rounding.Round(share.Mul(split.rate), split.currency)
+	}
+
+	return netPays
+}
@@ -0,0 +1,67 @@
+package payroll
+
+import (
+	"testing"
+	"time"
+
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/services/currency"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// splitEmployee is a 500 USD employee paid partly in ZWG at 25 to the dollar
+func splitEmployee(components ...models.SalaryComponent) (models.Employee, *rateTable) {
+	usd := models.Currency{ID: 1, Code: "USD"}
+	zwg := models.Currency{ID: 2, Code: "ZWG"}
+	for i := range components {
+		components[i].CurrencyID, components[i].Currency, components[i].IsActive = zwg.ID, zwg, true
+	}
+	employee := models.Employee{BasicSalary: decimal.NewFromInt(500), CurrencyID: usd.ID, Currency: usd, SalaryComponents: components}
+
+	rates := newRateTable()
+	rates.rates[rateKey{from: "USD", to: "ZWG", atPeriodEnd: true}] = decimal.NewFromInt(25)
+	return employee, rates
+}
+
+func TestSplitSalaryPaysUnallocatedPercentageInOwnCurrency(t *testing.T) {
+	employee, rates := splitEmployee(models.SalaryComponent{Name: "ZiG part", Percentage: 60, IsPercentage: true})
+
+	basicSalary, splits, err := calculateSplitSalary(employee, rates, models.CompanySettings{}.Rounding())
+	require.NoError(t, err)
+	assertAmount(t, 500, basicSalary)
+	require.Len(t, splits, 2)
+	assert.Equal(t, "ZWG", splits[0].currency)
+	assertAmount(t, 7500, splits[0].netPay.GrossAmount)
+	assert.Equal(t, "USD", splits[1].currency)
+	assertAmount(t, 200, splits[1].netPay.GrossAmount)
+}
+
+func TestSplitSalaryKeepsBasicSalaryBesideFixedComponent(t *testing.T) {
+	employee, rates := splitEmployee(models.SalaryComponent{Name: "ZiG allowance", Amount: decimal.NewFromInt(2500)})
+
+	basicSalary, splits, err := calculateSplitSalary(employee, rates, models.CompanySettings{}.Rounding())
+	require.NoError(t, err)
+	assertAmount(t, 500, basicSalary)
+	require.Len(t, splits, 2)
+	assertAmount(t, 100, splits[0].value)
+	assertAmount(t, 400, splits[1].value)
+
+	// Fixed amounts worth more than the salary are not paid on top of it
+	employee.SalaryComponents[0].Amount = decimal.NewFromInt(15000)
+	_, _, err = calculateSplitSalary(employee, rates, models.CompanySettings{}.Rounding())
+	assert.Error(t, err)
+}
+
+func TestRateTableDoesNotUseTodaysRateForPastPeriod(t *testing.T) {
+	db := newPayrollDB(t)
+	createRate(t, db, createCurrency(t, db, "USD"), createCurrency(t, db, "ZWG"), 26.5, time.Now())
+
+	rates := newRateTable()
+	key := rateKey{from: "USD", to: "ZWG", atPeriodEnd: true}
+	rates.load(currency.NewCurrencyService(db, "", ""), key, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC))
+	_, err := rates.rate(key)
+	assert.Error(t, err)
+}
@@ -0,0 +1,188 @@
+package payroll
+
+import (
+	"errors"
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ErrPeriodClosed is returned when time is captured for a date whose regular payroll period
+// has already been processed.
+var ErrPeriodClosed = errors.New("the payroll period for this date has already been processed")
+
+// maxQuantityPerEntry caps a single day's entry by pay type; piece-rate units are not capped
+var maxQuantityPerEntry = map[string]float64{
+	models.PayTypeHourly: 24,
+	models.PayTypeDaily:  1,
+}
+
+// CreateTimeEntry captures hours, days or units worked by a non-salaried employee. Entries
+// start pending and are only paid once approved.
+func (pp *PayrollProcessor) CreateTimeEntry(companyID, employeeID uint, workDate time.Time, quantity float64, description string, createdBy uint) (*models.TimeEntry, error) {
+	var employee models.Employee
+	if err := pp.db.Where("id = ? AND company_id = ?", employeeID, companyID).First(&employee).Error; err != nil {
+		return nil, fmt.Errorf("employee not found: %w", err)
+	}
+	if employee.IsSalaried() {
+		return nil, fmt.Errorf("employee %s is salaried; time is only captured for hourly, daily and piece-rate employees", employee.EmployeeNumber)
+	}
+	if quantity <= 0 {
+		return nil, fmt.Errorf("quantity must be greater than zero")
+	}
+	if max, ok := maxQuantityPerEntry[employee.PayType]; ok && quantity > max {
+		return nil, fmt.Errorf("a %s entry cannot exceed %g for one day", employee.PayType, max)
+	}
+	if err := pp.checkPeriodOpen(companyID, workDate); err != nil {
+		return nil, err
+	}
+
+	entry := models.TimeEntry{
+		CompanyID:   companyID,
+		EmployeeID:  employee.ID,
+		WorkDate:    workDate,
+		Quantity:    quantity,
+		Description: description,
+		Status:      "pending",
+		CreatedBy:   createdBy,
+	}
+	if err := pp.db.Create(&entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to save time entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// GetTimeEntries returns an employee's time entries between from and to inclusive,
+// optionally filtered by status, oldest first.
+func (pp *PayrollProcessor) GetTimeEntries(companyID, employeeID uint, from, to time.Time, status string) ([]models.TimeEntry, error) {
+	query := pp.db.Where("company_id = ? AND employee_id = ?", companyID, employeeID)
+	if !from.IsZero() {
+		query = query.Where("work_date >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("work_date <= ?", to)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var entries []models.TimeEntry
+	err := query.Order("work_date, id").Find(&entries).Error
+	return entries, err
+}
+
+// ReviewTimeEntry approves or rejects a pending time entry. An entry approved after its
+// period was processed is paid by the next regular run.
+func (pp *PayrollProcessor) ReviewTimeEntry(companyID, entryID uint, approve bool, reviewedBy uint) (*models.TimeEntry, error) {
+	var entry models.TimeEntry
+	if err := pp.db.Where("id = ? AND company_id = ?", entryID, companyID).First(&entry).Error; err != nil {
+		return nil, err
+	}
+	if entry.Status != "pending" {
+		return nil, fmt.Errorf("time entry is already %s", entry.Status)
+	}
+
+	now := time.Now()
+	entry.Status = "rejected"
+	if approve {
+		entry.Status = "approved"
+	}
+	entry.ReviewedBy = &reviewedBy
+	entry.ReviewedAt = &now
+	if err := pp.db.Save(&entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to update time entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// checkPeriodOpen returns ErrPeriodClosed when the regular period covering date has
+// moved past draft, so time is not captured against a period already run.
+func (pp *PayrollProcessor) checkPeriodOpen(companyID uint, date time.Time) error {
+	var closed int64
+	if err := pp.db.Model(&models.PayrollPeriod{}).
+		Where("company_id = ? AND period_type = ? AND status <> ?", companyID, models.PeriodTypeRegular, "draft").
+		Where("start_date <= ? AND end_date >= ?", date, date).
+		Count(&closed).Error; err != nil {
+		return fmt.Errorf("failed to check payroll period: %w", err)
+	}
+	if closed > 0 {
+		return ErrPeriodClosed
+	}
+	return nil
+}
+
+// approvedTime is an employee's approved time that no run has paid yet, with the entries
+// it comes from so the run that pays it can mark them paid.
+type approvedTime struct {
+	quantity float64
+	entryIDs []uint
+}
+
+// approvedWork totals each employee's approved, unpaid time entries dated on or before
+// through, including time approved after an earlier period was processed.
+func (pp *PayrollProcessor) approvedWork(companyID uint, through time.Time) (map[uint]approvedTime, error) {
+	var entries []models.TimeEntry
+	if err := pp.db.Select("id, employee_id, quantity").
+		Where("company_id = ? AND status = ? AND paid_period_id IS NULL AND work_date <= ?",
+			companyID, "approved", through).
+		Order("work_date, id").
+		Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch approved time: %w", err)
+	}
+
+	work := make(map[uint]approvedTime)
+	for _, entry := range entries {
+		worked := work[entry.EmployeeID]
+		worked.quantity += entry.Quantity
+		worked.entryIDs = append(worked.entryIDs, entry.ID)
+		work[entry.EmployeeID] = worked
+	}
+	return work, nil
+}
+
+// markTimePaid records the period and payslip that paid the entries.
+func markTimePaid(tx *gorm.DB, entryIDs []uint, payslip models.Payslip) error {
+	if len(entryIDs) == 0 {
+		return nil
+	}
+	if err := tx.Model(&models.TimeEntry{}).
+		Where("id IN ?", entryIDs).
+		Updates(map[string]interface{}{
+			"paid_period_id":  payslip.PayrollPeriodID,
+			"paid_payslip_id": payslip.ID,
+		}).Error; err != nil {
+		return fmt.Errorf("failed to mark time entries paid: %w", err)
+	}
+	return nil
+}
+
+// pendingTimeEntries counts each employee's time entries up to the period end still awaiting review.
+func (pp *PayrollProcessor) pendingTimeEntries(period models.PayrollPeriod) (map[uint]int, error) {
+	var rows []struct {
+		EmployeeID uint
+		Entries    int
+	}
+	if err := pp.db.Model(&models.TimeEntry{}).
+		Select("employee_id, COUNT(*) AS entries").
+		Where("company_id = ? AND status = ? AND work_date <= ?",
+			period.CompanyID, "pending", period.EndDate).
+		Group("employee_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch pending time: %w", err)
+	}
+
+	pending := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		pending[row.EmployeeID] = row.Entries
+	}
+	return pending, nil
+}
+
+// workedPay is what a non-salaried employee earns for the approved quantity, before rounding
+func workedPay(employee models.Employee, quantity float64) decimal.Decimal {
+	return employee.PayRate.Mul(money.FromFloat(quantity))
+}
@@ -0,0 +1,198 @@
+package payroll
+
+import (
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"gm58-hr-backend/internal/services/tax"
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+// calculationTrace collects the steps taken to work out one payslip. It is saved with the
+// payslip so HR can explain every figure when an employee queries it.
+type calculationTrace struct {
+	currency string
+	rounding money.Rounding
+	steps    []models.PayslipCalculationStep
+}
+
+func newCalculationTrace(currency string, rounding money.Rounding) *calculationTrace {
+	return &calculationTrace{currency: currency, rounding: rounding}
+}
+
+// add appends a step, numbering it and defaulting its currency to the payslip currency.
+// Amounts are shown rounded to their currency's smallest unit.
+func (t *calculationTrace) add(step models.PayslipCalculationStep) {
+	step.Sequence = len(t.steps) + 1
+	if step.Currency == "" {
+		step.Currency = t.currency
+	}
+	step.Amount = t.rounding.Round(step.Amount, step.Currency)
+	if step.SourceCurrency != "" {
+		step.SourceAmount = t.rounding.Round(step.SourceAmount, step.SourceCurrency)
+	}
+	t.steps = append(t.steps, step)
+}
+
+// converted records an amount captured in another currency and its value in the payslip
+// currency. Amounts already in the payslip currency are recorded as they are.
+func (t *calculationTrace) converted(section, component, description string, source decimal.Decimal, sourceCurrency string, rate, amount decimal.Decimal) {
+	step := models.PayslipCalculationStep{
+		Section:     section,
+		Component:   component,
+		Description: description,
+		Amount:      amount,
+	}
+	if sourceCurrency != t.currency {
+		step.SourceAmount = source
+		step.SourceCurrency = sourceCurrency
+		step.ExchangeRate = rate.InexactFloat64()
+	}
+	t.add(step)
+}
+
+// paye records how bracket PAYE on taxable earnings was worked out. The month's taxable
+// earnings are converted to USD and taxed in their bracket, and the PAYE already due on
+// earnings paid earlier in the month is credited, so the month is taxed as a whole.
+func (t *calculationTrace) paye(tc *tax.TaxCalculator, taxable, earlier, toUSD, fromUSD, paye decimal.Decimal) {
+	if !earlier.IsZero() {
+		t.add(models.PayslipCalculationStep{
+			Section:     models.TraceSectionTaxable,
+			Component:   "month_to_date_earnings",
+			Description: "Taxable earnings already paid this month",
+			Amount:      earlier,
+		})
+	}
+	t.bracket(tc, models.TraceSectionTax, "paye_bracket", "PAYE on the month's taxable earnings", earlier.Add(taxable), toUSD, fromUSD, false)
+	if earlier.IsPositive() {
+		t.bracket(tc, models.TraceSectionCredits, "paye_already_due", "PAYE already due on earlier earnings this month", earlier, toUSD, fromUSD, true)
+	}
+	t.add(models.PayslipCalculationStep{
+		Section:     models.TraceSectionTax,
+		Component:   "paye",
+		Description: fmt.Sprintf("PAYE under tax table %s", tax.TaxTableVersion),
+		Amount:      paye,
+	})
+}
+
+// bracket records the bracket matched by taxable earnings once converted to USD and the tax
+// it charges, converted back to the payslip currency, negated when it is a credit.
+func (t *calculationTrace) bracket(tc *tax.TaxCalculator, section, component, label string, taxable, toUSD, fromUSD decimal.Decimal, credit bool) {
+	grossUSD := taxable.Mul(toUSD)
+	bracket := tc.MonthlyTaxBracket(grossUSD)
+	ceiling := "and above"
+	if !math.IsInf(bracket.Max, 1) {
+		ceiling = fmt.Sprintf("to %.2f", bracket.Max)
+	}
+
+	step := models.PayslipCalculationStep{
+		Section:   section,
+		Component: component,
+		Description: fmt.Sprintf("%s: USD %s falls in the USD %.2f %s bracket, taxed at %g%% less %.2f",
+			label, grossUSD.StringFixed(2), bracket.Min, ceiling, bracket.Rate*100, bracket.Deduction),
+		Basis:  grossUSD.InexactFloat64(),
+		Rate:   bracket.Rate * 100,
+		Amount: bracket.Tax(grossUSD).Mul(fromUSD),
+	}
+	if credit {
+		step.Amount = step.Amount.Neg()
+	}
+	if t.currency != "USD" {
+		step.SourceAmount = taxable
+		step.SourceCurrency = t.currency
+		step.ExchangeRate = toUSD.InexactFloat64()
+	}
+	t.add(step)
+}
+
+// directive records PAYE set by a tax directive in place of the brackets
+func (t *calculationTrace) directive(directive models.TaxDirective, taxable, paye decimal.Decimal) {
+	step := models.PayslipCalculationStep{
+		Section:   models.TraceSectionTax,
+		Component: "paye",
+		Amount:    paye,
+		Basis:     taxable.InexactFloat64(),
+	}
+	switch directive.Type {
+	case models.DirectiveTypeFixedPercentage:
+		step.Description = fmt.Sprintf("PAYE at %g%% under tax directive %s", directive.Rate, directive.DirectiveNumber)
+		step.Rate = directive.Rate
+	case models.DirectiveTypeLumpSum:
+		step.Description = fmt.Sprintf("PAYE on lump sum set by tax directive %s", directive.DirectiveNumber)
+	default:
+		step.Description = fmt.Sprintf("PAYE fixed by tax directive %s", directive.DirectiveNumber)
+	}
+	t.add(step)
+}
+
+// levies records the AIDS levy on PAYE and the NSSA contribution on insurable earnings
+func (t *calculationTrace) levies(paye, aidsLevy, insurable, nssa decimal.Decimal, settings models.CompanySettings) {
+	if settings.EnableAidsLevy {
+		t.add(models.PayslipCalculationStep{
+			Section:     models.TraceSectionTax,
+			Component:   "aids_levy",
+			Description: fmt.Sprintf("AIDS levy at %g%% of PAYE", tax.AidsLevyRate*100),
+			Basis:       paye.InexactFloat64(),
+			Rate:        tax.AidsLevyRate * 100,
+			Amount:      aidsLevy,
+		})
+	}
+	if settings.EnableNSSA {
+		t.add(models.PayslipCalculationStep{
+			Section:     models.TraceSectionTax,
+			Component:   "nssa",
+			Description: fmt.Sprintf("NSSA at %g%% of insurable earnings", tax.NSSARate*100),
+			Basis:       insurable.InexactFloat64(),
+			Rate:        tax.NSSARate * 100,
+			Amount:      nssa,
+		})
+	}
+}
+
+// withholding records the tax withheld from a contractor in place of PAYE and NSSA
+func (t *calculationTrace) withholding(gross, rate, withheld decimal.Decimal) {
+	t.add(models.PayslipCalculationStep{
+		Section:     models.TraceSectionTax,
+		Component:   "withholding_tax",
+		Description: fmt.Sprintf("Contractor: no PAYE or NSSA, withholding tax at %s%% of the payment", rate),
+		Basis:       gross.InexactFloat64(),
+		Rate:        rate.InexactFloat64(),
+		Amount:      withheld,
+	})
+}
+
+// total records a subtotal or total
+func (t *calculationTrace) total(section, component, description string, amount decimal.Decimal) {
+	t.add(models.PayslipCalculationStep{
+		Section:     section,
+		Component:   component,
+		Description: description,
+		Amount:      amount,
+	})
+}
+
+// netPay records the net pay and its value in the company base currency
+func (t *calculationTrace) netPay(netPay, exchangeRate decimal.Decimal, baseCurrency string, netPayBase decimal.Decimal) {
+	t.total(models.TraceSectionNetPay, "net_pay", "Total earnings less total deductions", netPay)
+	if baseCurrency != t.currency {
+		t.add(models.PayslipCalculationStep{
+			Section:        models.TraceSectionNetPay,
+			Component:      "net_pay_base",
+			Description:    fmt.Sprintf("Net pay in the company base currency %s, for reporting", baseCurrency),
+			SourceAmount:   netPay,
+			SourceCurrency: t.currency,
+			ExchangeRate:   exchangeRate.InexactFloat64(),
+			Amount:         netPayBase,
+			Currency:       baseCurrency,
+		})
+	}
+}
+
+// workUnits names what a non-salaried employee's pay rate is paid for
+var workUnits = map[string]string{
+	models.PayTypeHourly:    "hours",
+	models.PayTypeDaily:     "days",
+	models.PayTypePieceRate: "units",
+}
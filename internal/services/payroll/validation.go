@@ -0,0 +1,329 @@
+package payroll
+
+import (
+	"errors"
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrValidationFailed is wrapped by ValidationError when a payroll run is blocked by
+// validation errors or unacknowledged warnings.
+var ErrValidationFailed = errors.New("payroll validation failed")
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationIssue is a data problem found before processing. Key identifies the issue
+// for acknowledgement and stays the same while the problem is unchanged.
+type ValidationIssue struct {
+	Key            string `json:"key"`
+	Code           string `json:"code"`
+	Severity       string `json:"severity"`
+	EmployeeID     *uint  `json:"employee_id,omitempty"`
+	EmployeeNumber string `json:"employee_number,omitempty"`
+	EmployeeName   string `json:"employee_name,omitempty"`
+	Message        string `json:"message"`
+	Acknowledged   bool   `json:"acknowledged"`
+}
+
+// ValidationReport lists the issues for a period. The run may proceed when there are
+// no errors and every warning has been acknowledged.
+type ValidationReport struct {
+	PayrollPeriodID  uint              `json:"payroll_period_id"`
+	EmployeesChecked int               `json:"employees_checked"`
+	Errors           []ValidationIssue `json:"errors"`
+	Warnings         []ValidationIssue `json:"warnings"`
+	CanProcess       bool              `json:"can_process"`
+}
+
+// ValidationError carries the report that blocked a payroll run.
+type ValidationError struct {
+	Report *ValidationReport
+}
+
+func (e *ValidationError) Error() string {
+	unacknowledged := 0
+	for _, warning := range e.Report.Warnings {
+		if !warning.Acknowledged {
+			unacknowledged++
+		}
+	}
+	return fmt.Sprintf("%s: %d errors, %d unacknowledged warnings",
+		ErrValidationFailed, len(e.Report.Errors), unacknowledged)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidationFailed
+}
+
+// ValidatePayrollForCompany checks the data a payroll run for the period would use.
+func (pp *PayrollProcessor) ValidatePayrollForCompany(periodID, companyID uint) (*ValidationReport, error) {
+	var period models.PayrollPeriod
+	if err := pp.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
+		return nil, fmt.Errorf("payroll period not found: %w", err)
+	}
+	if period.IsOffCycle() {
+		return pp.validateOffCyclePeriod(period)
+	}
+
+	run, err := pp.loadPayrollRun(period)
+	if err != nil {
+		return nil, err
+	}
+	return pp.validatePayrollRun(run)
+}
+
+// AcknowledgeValidationWarnings accepts the given warnings for a period. Errors cannot be
+// acknowledged and must be fixed.
+func (pp *PayrollProcessor) AcknowledgeValidationWarnings(periodID, companyID, userID uint, keys []string, comment string) (*ValidationReport, error) {
+	report, err := pp.ValidatePayrollForCompany(periodID, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := make(map[string]bool, len(report.Warnings))
+	for _, warning := range report.Warnings {
+		warnings[warning.Key] = true
+	}
+	for _, key := range keys {
+		if !warnings[key] {
+			return nil, fmt.Errorf("%s is not a current warning for this period", key)
+		}
+	}
+
+	err = pp.db.Transaction(func(tx *gorm.DB) error {
+		for _, key := range keys {
+			acknowledgement := models.PayrollValidationAcknowledgement{
+				CompanyID:       companyID,
+				PayrollPeriodID: periodID,
+				IssueKey:        key,
+				Comment:         comment,
+				AcknowledgedBy:  userID,
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&acknowledgement).Error; err != nil {
+				return fmt.Errorf("failed to acknowledge warning: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pp.ValidatePayrollForCompany(periodID, companyID)
+}
+
+// validation collects the issues found for a period, marking the warnings already
+// acknowledged for it
+type validation struct {
+	report       *ValidationReport
+	acknowledged map[string]bool
+}
+
+func (pp *PayrollProcessor) startValidation(period models.PayrollPeriod, employeesChecked int) (*validation, error) {
+	var acknowledged []string
+	if err := pp.db.Model(&models.PayrollValidationAcknowledgement{}).
+		Where("payroll_period_id = ? AND company_id = ?", period.ID, period.CompanyID).
+		Pluck("issue_key", &acknowledged).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch acknowledgements: %w", err)
+	}
+	v := &validation{
+		report: &ValidationReport{
+			PayrollPeriodID:  period.ID,
+			EmployeesChecked: employeesChecked,
+			Errors:           []ValidationIssue{},
+			Warnings:         []ValidationIssue{},
+		},
+		acknowledged: make(map[string]bool, len(acknowledged)),
+	}
+	for _, key := range acknowledged {
+		v.acknowledged[key] = true
+	}
+	return v, nil
+}
+
+func (v *validation) add(issue ValidationIssue) {
+	if issue.Severity == SeverityError {
+		v.report.Errors = append(v.report.Errors, issue)
+		return
+	}
+	issue.Acknowledged = v.acknowledged[issue.Key]
+	v.report.Warnings = append(v.report.Warnings, issue)
+}
+
+func (v *validation) employeeIssue(employee models.Employee, code, severity, message string) {
+	v.add(ValidationIssue{
+		Key:            fmt.Sprintf("%s:%d", code, employee.ID),
+		Code:           code,
+		Severity:       severity,
+		EmployeeID:     &employee.ID,
+		EmployeeNumber: employee.EmployeeNumber,
+		EmployeeName:   employee.FullName(),
+		Message:        message,
+	})
+}
+
+// employeeDetails checks the details every payslip needs, whatever is being paid
+func (v *validation) employeeDetails(employee models.Employee) {
+	if employee.PaymentMethod == "bank_transfer" && (employee.BankName == "" || employee.BankAccount == "") {
+		v.employeeIssue(employee, "missing_bank_details", SeverityError, "Paid by bank transfer but has no bank name or account number")
+	}
+	if employee.TaxNumber == "" {
+		v.employeeIssue(employee, "missing_tax_number", SeverityWarning, "No tax number recorded")
+	}
+	if employee.NationalID == "" {
+		v.employeeIssue(employee, "missing_national_id", SeverityWarning, "No national ID recorded")
+	}
+}
+
+// missingRates reports each currency pair the run could not look up a rate for
+func (v *validation) missingRates(rates *rateTable) {
+	seenPairs := make(map[string]bool)
+	for key, err := range rates.errs {
+		pair := key.from + ":" + key.to
+		if seenPairs[pair] {
+			continue
+		}
+		seenPairs[pair] = true
+		v.add(ValidationIssue{
+			Key:      "missing_exchange_rate:" + pair,
+			Code:     "missing_exchange_rate",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("No exchange rate from %s to %s: %v", key.from, key.to, err),
+		})
+	}
+}
+
+// finish orders the issues by employee and decides whether the run may proceed
+func (v *validation) finish() *ValidationReport {
+	report := v.report
+	for _, issues := range [][]ValidationIssue{report.Errors, report.Warnings} {
+		sort.SliceStable(issues, func(i, j int) bool {
+			if issues[i].EmployeeNumber != issues[j].EmployeeNumber {
+				return issues[i].EmployeeNumber < issues[j].EmployeeNumber
+			}
+			return issues[i].Key < issues[j].Key
+		})
+	}
+
+	report.CanProcess = len(report.Errors) == 0
+	for _, warning := range report.Warnings {
+		if !warning.Acknowledged {
+			report.CanProcess = false
+		}
+	}
+	return report
+}
+
+func (pp *PayrollProcessor) validatePayrollRun(run *payrollRun) (*ValidationReport, error) {
+	v, err := pp.startValidation(run.period, len(run.employees))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, employee := range run.employees {
+		v.employeeDetails(employee)
+		if employee.IsSalaried() && !employee.BasicSalary.IsPositive() {
+			v.employeeIssue(employee, "zero_basic_salary", SeverityError, "Basic salary is zero")
+		}
+		if !employee.IsSalaried() {
+			if !employee.PayRate.IsPositive() {
+				v.employeeIssue(employee, "zero_pay_rate", SeverityError, fmt.Sprintf("Paid %s but has no pay rate", employee.PayType))
+			}
+			if run.work[employee.ID].quantity <= 0 {
+				v.employeeIssue(employee, "no_approved_time", SeverityWarning, "No approved time in the period; nothing will be paid for work")
+			}
+			if pending := run.pendingWork[employee.ID]; pending > 0 {
+				v.employeeIssue(employee, "pending_time_entries", SeverityWarning, fmt.Sprintf("%d time entries up to the period end are awaiting approval and will not be paid in this run", pending))
+			}
+		}
+
+		// Salaries are placed in the band in the band's currency at the period-end rate
+		position := employee.Position
+		if employee.IsSalaried() && employee.BasicSalary.IsPositive() && (!position.MinSalary.IsZero() || !position.MaxSalary.IsZero()) {
+			rate, err := run.bandRates.rate(rateKey{from: employee.Currency.Code, to: position.Currency.Code, atPeriodEnd: true})
+			if err != nil {
+				v.employeeIssue(employee, "salary_band_unchecked", SeverityWarning, fmt.Sprintf("Basic salary could not be checked against the %s band: no %s to %s rate at the period end",
+					position.Title, employee.Currency.Code, position.Currency.Code))
+			} else {
+				salary := employee.BasicSalary.Mul(rate).Round(money.Places(position.Currency.Code))
+				switch check := placeInBand(position, salary); check.Status {
+				case BandBelow:
+					v.employeeIssue(employee, "below_salary_band", SeverityWarning, "Basic "+check.Message())
+				case BandAbove:
+					v.employeeIssue(employee, "above_salary_band", SeverityWarning, "Basic "+check.Message())
+				}
+			}
+		}
+
+		for _, allowance := range run.allowances[employee.ID] {
+			if !allowance.Currency.IsActive {
+				v.add(ValidationIssue{
+					Key:            fmt.Sprintf("inactive_allowance_currency:%d", allowance.ID),
+					Code:           "inactive_allowance_currency",
+					Severity:       SeverityWarning,
+					EmployeeID:     &employee.ID,
+					EmployeeNumber: employee.EmployeeNumber,
+					EmployeeName:   employee.FullName(),
+					Message:        fmt.Sprintf("Allowance %q is in %s, which is not an active currency", allowance.Name, allowance.Currency.Code),
+				})
+			}
+		}
+	}
+
+	// Rates the run could not look up
+	v.missingRates(run.rates)
+	return v.finish(), nil
+}
+
+// validateOffCyclePeriod checks the employees selected for a bonus, correction or
+// termination period. Only the amounts captured for the period are paid, so there are
+// no salaries or time to check, but the payslips still need the employee's details and
+// the rates in force at the period end.
+func (pp *PayrollProcessor) validateOffCyclePeriod(period models.PayrollPeriod) (*ValidationReport, error) {
+	selected := pp.db.Model(&models.PayrollPeriodEmployee{}).Select("employee_id").Where("payroll_period_id = ?", period.ID)
+	if period.PeriodType == models.PeriodTypeTermination {
+		selected = pp.db.Model(&models.FinalSettlement{}).Select("employee_id").Where("payroll_period_id = ?", period.ID)
+	}
+	var employees []models.Employee
+	if err := pp.db.Preload("Currency").
+		Where("company_id = ? AND id IN (?)", period.CompanyID, selected).
+		Order("employee_number").
+		Find(&employees).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch period employees: %w", err)
+	}
+
+	var company models.Company
+	if err := pp.db.Preload("BaseCurrency").First(&company, period.CompanyID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get company base currency: %w", err)
+	}
+	var settings models.CompanySettings
+	pp.db.Where("company_id = ?", period.CompanyID).First(&settings)
+
+	rates := newRateTable()
+	for _, employee := range employees {
+		currencyCode := employee.Currency.Code
+		rates.load(pp.currencyService, rateKey{from: currencyCode, to: company.BaseCurrency.Code, atPeriodEnd: true}, period.EndDate)
+		if settings.EnablePAYE && !employee.IsContractor() {
+			rates.load(pp.currencyService, rateKey{from: currencyCode, to: "USD", atPeriodEnd: true}, period.EndDate)
+			rates.load(pp.currencyService, rateKey{from: "USD", to: currencyCode, atPeriodEnd: true}, period.EndDate)
+		}
+	}
+
+	v, err := pp.startValidation(period, len(employees))
+	if err != nil {
+		return nil, err
+	}
+	for _, employee := range employees {
+		v.employeeDetails(employee)
+	}
+	v.missingRates(rates)
+	return v.finish(), nil
+}
@@ -0,0 +1,153 @@
+package payroll
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gm58-hr-backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// requireBlocked asserts that processing the period fails validation with the given issue
+// and leaves the period in draft with no payslips.
+func requireBlocked(t *testing.T, db *gorm.DB, processor *PayrollProcessor, period models.PayrollPeriod, severity, code string) {
+	err := processor.ProcessPayrollForCompany(period.ID, period.CompanyID, 1)
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.False(t, validationErr.Report.CanProcess)
+
+	issues := validationErr.Report.Errors
+	if severity == SeverityWarning {
+		issues = validationErr.Report.Warnings
+	}
+	codes := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		codes = append(codes, issue.Code)
+	}
+	assert.Contains(t, codes, code)
+
+	var reloaded models.PayrollPeriod
+	require.NoError(t, db.First(&reloaded, period.ID).Error)
+	assert.Equal(t, "draft", reloaded.Status)
+	var payslips int64
+	require.NoError(t, db.Model(&models.Payslip{}).Where("payroll_period_id = ?", period.ID).Count(&payslips).Error)
+	assert.Zero(t, payslips)
+}
+
+func TestValidationErrorsBlockProcessing(t *testing.T) {
+	cases := map[string]func(db *gorm.DB, employee models.Employee){
+		"missing_bank_details": func(db *gorm.DB, employee models.Employee) {
+			require.NoError(t, db.Model(&employee).Update("bank_account", "").Error)
+		},
+		"zero_basic_salary": func(db *gorm.DB, employee models.Employee) {
+			require.NoError(t, db.Model(&employee).Update("basic_salary", 0).Error)
+		},
+		"zero_pay_rate": func(db *gorm.DB, employee models.Employee) {
+			require.NoError(t, db.Model(&employee).Updates(map[string]interface{}{"pay_type": models.PayTypeHourly, "pay_rate": 0}).Error)
+		},
+		"missing_exchange_rate": func(db *gorm.DB, employee models.Employee) {
+			zwg := createCurrency(t, db, "ZWG")
+			require.NoError(t, db.Model(&employee).Update("currency_id", zwg.ID).Error)
+		},
+	}
+	for code, breakEmployee := range cases {
+		t.Run(code, func(t *testing.T) {
+			db, company := setupPayrollDB(t, 1)
+			processor := newProcessor(db)
+			employee := firstEmployee(t, db)
+			breakEmployee(db, employee)
+
+			requireBlocked(t, db, processor, createDraftPeriod(t, db, company.ID, 0), SeverityError, code)
+		})
+	}
+}
+
+func TestAcknowledgedWarningLetsRunProceed(t *testing.T) {
+	db, company := setupPayrollDB(t, 1)
+	processor := newProcessor(db)
+	employee := firstEmployee(t, db)
+	require.NoError(t, db.Model(&employee).Update("tax_number", "").Error)
+
+	period := createDraftPeriod(t, db, company.ID, 0)
+	requireBlocked(t, db, processor, period, SeverityWarning, "missing_tax_number")
+
+	_, err := processor.AcknowledgeValidationWarnings(period.ID, company.ID, 1, []string{"missing_national_id:999"}, "")
+	assert.Error(t, err, "only current warnings can be acknowledged")
+	report, err := processor.AcknowledgeValidationWarnings(period.ID, company.ID, 1,
+		[]string{fmt.Sprintf("missing_tax_number:%d", employee.ID)}, "Applied for with ZIMRA")
+	require.NoError(t, err)
+	assert.True(t, report.CanProcess)
+	require.NoError(t, processor.ProcessPayrollForCompany(period.ID, company.ID, 1))
+}
+
+func TestValidationChecksBandInItsCurrency(t *testing.T) {
+	db, company := setupPayrollDB(t, 1)
+	processor := newProcessor(db)
+
+	// 15000 ZWG against a USD band of 550 to 1050
+	employee := firstEmployee(t, db)
+	require.NoError(t, db.Model(&models.Position{}).Where("id = ?", employee.PositionID).
+		Updates(map[string]interface{}{"min_salary": 550, "max_salary": 1050}).Error)
+	zwg := createCurrency(t, db, "ZWG")
+	require.NoError(t, db.Model(&employee).Updates(map[string]interface{}{"currency_id": zwg.ID, "basic_salary": 15000}).Error)
+	usd := findCurrency(t, db, "USD")
+	createRate(t, db, usd, zwg, 25, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	period := createDraftPeriod(t, db, company.ID, 0)
+
+	// Without a rate into the band's currency the salary cannot be placed
+	report, err := processor.ValidatePayrollForCompany(period.ID, company.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "salary_band_unchecked", report.Warnings[0].Code)
+
+	createRate(t, db, zwg, usd, 0.02, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	report, err = processor.ValidatePayrollForCompany(period.ID, company.ID)
+	require.NoError(t, err)
+	require.Len(t, report.Warnings, 1)
+	assert.Equal(t, "below_salary_band", report.Warnings[0].Code)
+	assert.Contains(t, report.Warnings[0].Message, "USD 300.00")
+}
+
+func TestOffCycleRunIsValidatedAndUsesPeriodEndRates(t *testing.T) {
+	db, company := setupPayrollDB(t, 1)
+	processor := newProcessor(db)
+
+	employee := firstEmployee(t, db)
+	zwg := createCurrency(t, db, "ZWG")
+	require.NoError(t, db.Model(&employee).Update("currency_id", zwg.ID).Error)
+
+	january := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	period := models.PayrollPeriod{
+		CompanyID: company.ID, PeriodType: models.PeriodTypeBonus, Year: 2024, Month: 1,
+		StartDate: january, EndDate: january.AddDate(0, 1, -1), Status: "draft", Description: "January bonus",
+	}
+	require.NoError(t, db.Create(&period).Error)
+	require.NoError(t, db.Create(&models.PayrollPeriodEmployee{
+		PayrollPeriodID: period.ID, EmployeeID: employee.ID, Bonus: decimal.NewFromInt(10000),
+	}).Error)
+
+	requireBlocked(t, db, processor, period, SeverityError, "missing_exchange_rate")
+
+	// The rates in force in January, and the weaker rates that replaced them in June
+	usd := findCurrency(t, db, "USD")
+	june := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	createRate(t, db, zwg, usd, 0.04, january)
+	createRate(t, db, usd, zwg, 25, january)
+	createRate(t, db, zwg, usd, 0.02, june)
+	createRate(t, db, usd, zwg, 50, june)
+
+	require.NoError(t, db.Model(&employee).Update("bank_account", "").Error)
+	requireBlocked(t, db, processor, period, SeverityError, "missing_bank_details")
+
+	require.NoError(t, db.Model(&employee).Update("bank_account", "011200000001").Error)
+	require.NoError(t, processor.ProcessPayrollForCompany(period.ID, company.ID, 1))
+
+	var payslip models.Payslip
+	require.NoError(t, db.Where("payroll_period_id = ?", period.ID).First(&payslip).Error)
+	assertAmount(t, 0.04, payslip.ExchangeRate)
+	assertAmount(t, 400, payslip.TotalEarningsBase)
+}
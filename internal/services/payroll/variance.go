@@ -0,0 +1,328 @@
+package payroll
+
+import (
+	"errors"
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"math"
+	"sort"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ErrNoPreviousPeriod is returned when a period is compared with the one before it and the
+// company has no earlier regular period.
+var ErrNoPreviousPeriod = errors.New("no previous payroll period to compare with")
+
+// Default thresholds used to flag net pay swings between periods
+const (
+	DefaultVariancePercentThreshold = 10.0
+	DefaultVarianceAmountThreshold  = 0.0
+)
+
+// VarianceThresholds control which net pay changes are flagged as outliers.
+// A zero threshold disables that check.
+type VarianceThresholds struct {
+	NetPayPercent float64 `json:"net_pay_percent"`
+	NetPayAmount  float64 `json:"net_pay_amount"`
+}
+
+type VarianceReport struct {
+	CurrentPeriodID  uint               `json:"current_period_id"`
+	PreviousPeriodID uint               `json:"previous_period_id"`
+	Thresholds       VarianceThresholds `json:"thresholds"`
+	Summary          VarianceSummary    `json:"summary"`
+	Employees        []EmployeeVariance `json:"employees"`
+}
+
+type VarianceSummary struct {
+	NewJoiners         int             `json:"new_joiners"`
+	Leavers            int             `json:"leavers"`
+	SalaryChanges      int             `json:"salary_changes"`
+	NewDeductions      int             `json:"new_deductions"`
+	Outliers           int             `json:"outliers"`
+	PreviousNetPayBase decimal.Decimal `json:"previous_net_pay_base"`
+	CurrentNetPayBase  decimal.Decimal `json:"current_net_pay_base"`
+	NetPayBaseDelta    decimal.Decimal `json:"net_pay_base_delta"`
+}
+
+type EmployeeVariance struct {
+	EmployeeID      uint                `json:"employee_id"`
+	EmployeeNumber  string              `json:"employee_number"`
+	EmployeeName    string              `json:"employee_name"`
+	Status          string              `json:"status"` // new_joiner, leaver, changed, unchanged
+	Currency        string              `json:"currency"`
+	CurrencyChanged bool                `json:"currency_changed"`
+	SalaryChanged   bool                `json:"salary_changed"`
+	NewDeductions   []string            `json:"new_deductions,omitempty"`
+	NetPayDelta     decimal.Decimal     `json:"net_pay_delta"`
+	NetPayChangePct float64             `json:"net_pay_change_pct"`
+	IsOutlier       bool                `json:"is_outlier"`
+	Components      []ComponentVariance `json:"components"`
+}
+
+type ComponentVariance struct {
+	Component string          `json:"component"`
+	Previous  decimal.Decimal `json:"previous"`
+	Current   decimal.Decimal `json:"current"`
+	Delta     decimal.Decimal `json:"delta"`
+}
+
+// varianceComponents are the payslip amounts compared between periods
+var varianceComponents = []struct {
+	name   string
+	amount func(p models.Payslip) decimal.Decimal
+}{
+	{"basic_salary", func(p models.Payslip) decimal.Decimal { return p.BasicSalary }},
+	{"overtime", func(p models.Payslip) decimal.Decimal { return p.Overtime }},
+	{"allowances", func(p models.Payslip) decimal.Decimal { return p.Allowances }},
+	{"bonus", func(p models.Payslip) decimal.Decimal { return p.Bonus }},
+	{"commission", func(p models.Payslip) decimal.Decimal { return p.Commission }},
+	{"other_earnings", func(p models.Payslip) decimal.Decimal { return p.OtherEarnings }},
+	{"back_pay", func(p models.Payslip) decimal.Decimal { return p.BackPay }},
+	{"leave_pay", func(p models.Payslip) decimal.Decimal { return p.LeavePay }},
+	{"notice_pay", func(p models.Payslip) decimal.Decimal { return p.NoticePay }},
+	{"severance_pay", func(p models.Payslip) decimal.Decimal { return p.SeverancePay }},
+	{"total_earnings", func(p models.Payslip) decimal.Decimal { return p.TotalEarnings }},
+	{"paye_tax", func(p models.Payslip) decimal.Decimal { return p.PayeeTax }},
+	{"aids_levy", func(p models.Payslip) decimal.Decimal { return p.AidsLevy }},
+	{"nssa_contribution", func(p models.Payslip) decimal.Decimal { return p.NSSAContribution }},
+	{"withholding_tax", func(p models.Payslip) decimal.Decimal { return p.WithholdingTax }},
+	{"other_deductions", func(p models.Payslip) decimal.Decimal { return p.OtherDeductions }},
+	{"total_deductions", func(p models.Payslip) decimal.Decimal { return p.TotalDeductions }},
+	{"net_pay", func(p models.Payslip) decimal.Decimal { return p.NetPay }},
+}
+
+type VarianceService struct {
+	db *gorm.DB
+}
+
+func NewVarianceService(db *gorm.DB) *VarianceService {
+	return &VarianceService{
+		db: db,
+	}
+}
+
+// PreviousPeriod returns the latest processed period for the company that precedes the given one.
+// Draft and cancelled periods have no payslips worth comparing against.
+func (vs *VarianceService) PreviousPeriod(period models.PayrollPeriod) (*models.PayrollPeriod, error) {
+	var previous models.PayrollPeriod
+	err := vs.db.Where("company_id = ? AND id <> ? AND (year < ? OR (year = ? AND month < ?))",
+		period.CompanyID, period.ID, period.Year, period.Year, period.Month).
+		Where("period_type = ? AND status IN ?", models.PeriodTypeRegular, []string{"processed", "approved", "paid"}).
+		Order("year DESC, month DESC").
+		First(&previous).Error
+	if err != nil {
+		return nil, err
+	}
+	return &previous, nil
+}
+
+// ComparePeriods reports per-employee and per-component changes between two periods.
+// When previousPeriodID is zero the immediately preceding period is used.
+func (vs *VarianceService) ComparePeriods(currentPeriodID, previousPeriodID, companyID uint, thresholds VarianceThresholds) (*VarianceReport, error) {
+	var current models.PayrollPeriod
+	if err := vs.db.Where("id = ? AND company_id = ?", currentPeriodID, companyID).First(&current).Error; err != nil {
+		return nil, fmt.Errorf("payroll period not found: %w", err)
+	}
+
+	var previous *models.PayrollPeriod
+	if previousPeriodID != 0 {
+		previous = &models.PayrollPeriod{}
+		if err := vs.db.Where("id = ? AND company_id = ?", previousPeriodID, companyID).First(previous).Error; err != nil {
+			return nil, fmt.Errorf("comparison period not found: %w", err)
+		}
+	} else {
+		var err error
+		previous, err = vs.PreviousPeriod(current)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoPreviousPeriod
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch previous payroll period: %w", err)
+		}
+	}
+
+	var company models.Company
+	if err := vs.db.Preload("BaseCurrency").First(&company, companyID).Error; err != nil {
+		return nil, fmt.Errorf("company not found: %w", err)
+	}
+	var settings models.CompanySettings
+	vs.db.Where("company_id = ?", companyID).First(&settings)
+	rounding := settings.Rounding()
+
+	currentSlips, err := vs.payslipsByEmployee(current.ID, companyID)
+	if err != nil {
+		return nil, err
+	}
+	previousSlips, err := vs.payslipsByEmployee(previous.ID, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	newDeductions, err := vs.newDeductionsByEmployee(companyID, *previous, current)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VarianceReport{
+		CurrentPeriodID:  current.ID,
+		PreviousPeriodID: previous.ID,
+		Thresholds:       thresholds,
+		Employees:        []EmployeeVariance{},
+	}
+
+	employeeIDs := make(map[uint]bool)
+	for id := range currentSlips {
+		employeeIDs[id] = true
+	}
+	for id := range previousSlips {
+		employeeIDs[id] = true
+	}
+
+	for employeeID := range employeeIDs {
+		cur, inCurrent := currentSlips[employeeID]
+		prev, inPrevious := previousSlips[employeeID]
+
+		variance := EmployeeVariance{EmployeeID: employeeID}
+		switch {
+		case inCurrent && !inPrevious:
+			variance.Status = "new_joiner"
+			report.Summary.NewJoiners++
+		case !inCurrent && inPrevious:
+			variance.Status = "leaver"
+			report.Summary.Leavers++
+		default:
+			variance.Status = "unchanged"
+		}
+
+		reference := cur
+		if !inCurrent {
+			reference = prev
+		}
+		variance.EmployeeNumber = reference.EmployeeNumber
+		variance.EmployeeName = reference.EmployeeName
+		variance.Currency = reference.Currency.Code
+
+		// Compare in the payslip currency unless the employee changed currency,
+		// in which case both sides are compared in the base currency
+		curRate, prevRate := decimal.NewFromInt(1), decimal.NewFromInt(1)
+		currency := reference.Currency.Code
+		if inCurrent && inPrevious && cur.CurrencyID != prev.CurrencyID {
+			variance.CurrencyChanged = true
+			variance.Currency = ""
+			curRate, prevRate = cur.ExchangeRate, prev.ExchangeRate
+			currency = company.BaseCurrency.Code
+		}
+
+		for _, component := range varianceComponents {
+			var before, after decimal.Decimal
+			if inPrevious {
+				before = rounding.Round(component.amount(prev).Mul(prevRate), currency)
+			}
+			if inCurrent {
+				after = rounding.Round(component.amount(cur).Mul(curRate), currency)
+			}
+			delta := after.Sub(before)
+			variance.Components = append(variance.Components, ComponentVariance{
+				Component: component.name,
+				Previous:  before,
+				Current:   after,
+				Delta:     delta,
+			})
+
+			if component.name == "net_pay" {
+				variance.NetPayDelta = delta
+				if !before.IsZero() {
+					variance.NetPayChangePct = delta.Div(before.Abs()).Mul(decimal.NewFromInt(100)).Round(2).InexactFloat64()
+				}
+			}
+			if component.name == "basic_salary" && inCurrent && inPrevious && !delta.IsZero() {
+				variance.SalaryChanged = true
+			}
+		}
+
+		if names, ok := newDeductions[employeeID]; ok && inCurrent {
+			variance.NewDeductions = names
+			report.Summary.NewDeductions++
+		}
+
+		if variance.SalaryChanged {
+			report.Summary.SalaryChanges++
+		}
+		if variance.Status == "unchanged" && (!variance.NetPayDelta.IsZero() || variance.CurrencyChanged || len(variance.NewDeductions) > 0) {
+			variance.Status = "changed"
+		}
+
+		if inCurrent && inPrevious {
+			if thresholds.NetPayPercent > 0 && math.Abs(variance.NetPayChangePct) >= thresholds.NetPayPercent {
+				variance.IsOutlier = true
+			}
+			if thresholds.NetPayAmount > 0 && variance.NetPayDelta.Abs().GreaterThanOrEqual(money.FromFloat(thresholds.NetPayAmount)) {
+				variance.IsOutlier = true
+			}
+		}
+		if variance.IsOutlier {
+			report.Summary.Outliers++
+		}
+
+		if inCurrent {
+			report.Summary.CurrentNetPayBase = report.Summary.CurrentNetPayBase.Add(cur.NetPayBase)
+		}
+		if inPrevious {
+			report.Summary.PreviousNetPayBase = report.Summary.PreviousNetPayBase.Add(prev.NetPayBase)
+		}
+
+		report.Employees = append(report.Employees, variance)
+	}
+
+	report.Summary.NetPayBaseDelta = report.Summary.CurrentNetPayBase.Sub(report.Summary.PreviousNetPayBase)
+
+	// Outliers first, then largest swings
+	sort.Slice(report.Employees, func(i, j int) bool {
+		a, b := report.Employees[i], report.Employees[j]
+		if a.IsOutlier != b.IsOutlier {
+			return a.IsOutlier
+		}
+		if !a.NetPayDelta.Abs().Equal(b.NetPayDelta.Abs()) {
+			return a.NetPayDelta.Abs().GreaterThan(b.NetPayDelta.Abs())
+		}
+		return a.EmployeeNumber < b.EmployeeNumber
+	})
+
+	return report, nil
+}
+
+func (vs *VarianceService) payslipsByEmployee(periodID, companyID uint) (map[uint]models.Payslip, error) {
+	var payslips []models.Payslip
+	if err := vs.db.Preload("Currency").
+		Where("payroll_period_id = ? AND company_id = ?", periodID, companyID).
+		Find(&payslips).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch payslips: %w", err)
+	}
+
+	result := make(map[uint]models.Payslip, len(payslips))
+	for _, payslip := range payslips {
+		result[payslip.EmployeeID] = payslip
+	}
+	return result, nil
+}
+
+// newDeductionsByEmployee returns the names of recurring deductions that started,
+// or were captured, after the previous period closed.
+func (vs *VarianceService) newDeductionsByEmployee(companyID uint, previous, current models.PayrollPeriod) (map[uint][]string, error) {
+	var deductions []models.Deduction
+	if err := vs.db.Where("company_id = ? AND is_active = ? AND (start_date > ? OR created_at > ?) AND start_date <= ?",
+		companyID, true, previous.EndDate, previous.EndDate, current.EndDate).
+		Find(&deductions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch deductions: %w", err)
+	}
+
+	result := make(map[uint][]string)
+	for _, deduction := range deductions {
+		result[deduction.EmployeeID] = append(result[deduction.EmployeeID], deduction.Name)
+	}
+	return result, nil
+}
@@ -0,0 +1,149 @@
+package payroll
+
+import (
+	"testing"
+	"time"
+
+	"gm58-hr-backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComparePeriodsReportsJoinersLeaversAndChanges(t *testing.T) {
+	db, company := setupPayrollDB(t, 3)
+	processor := newProcessor(db)
+	variance := NewVarianceService(db)
+
+	january := createDraftPeriod(t, db, company.ID, 0)
+	require.NoError(t, processor.ProcessPayrollForCompany(january.ID, company.ID, 1))
+	// February is never run, so March is compared with January
+	createDraftPeriod(t, db, company.ID, 1)
+
+	employees := employeesByNumber(t, db)
+	require.NoError(t, db.Model(&employees[0]).Update("basic_salary", 800).Error)
+	require.NoError(t, db.Create(&models.Deduction{
+		CompanyID: company.ID, EmployeeID: employees[1].ID, Name: "Union dues", Amount: decimal.NewFromInt(30),
+		CurrencyID: employees[1].CurrencyID, IsActive: true, IsRecurring: true,
+		StartDate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	}).Error)
+	require.NoError(t, db.Model(&employees[2]).Updates(map[string]interface{}{
+		"is_active": false, "employment_status": "terminated",
+	}).Error)
+	joiner := employees[0]
+	joiner.ID = 0
+	joiner.EmployeeNumber = "EMP00003"
+	joiner.NationalID = "63-000003A00"
+	joiner.TaxNumber = "TIN000003"
+	joiner.BankAccount = "011200000003"
+	require.NoError(t, db.Create(&joiner).Error)
+
+	march := createDraftPeriod(t, db, company.ID, 2)
+	require.NoError(t, processor.ProcessPayrollForCompany(march.ID, company.ID, 1))
+
+	report, err := variance.ComparePeriods(march.ID, 0, company.ID, VarianceThresholds{NetPayPercent: DefaultVariancePercentThreshold})
+	require.NoError(t, err)
+	assert.Equal(t, january.ID, report.PreviousPeriodID)
+	assert.Equal(t, 1, report.Summary.NewJoiners)
+	assert.Equal(t, 1, report.Summary.Leavers)
+	assert.Equal(t, 1, report.Summary.SalaryChanges)
+	assert.Equal(t, 1, report.Summary.NewDeductions)
+	assert.Equal(t, 1, report.Summary.Outliers)
+	assert.True(t, report.Summary.NetPayBaseDelta.Equal(report.Summary.CurrentNetPayBase.Sub(report.Summary.PreviousNetPayBase)))
+
+	byNumber := make(map[string]EmployeeVariance)
+	for _, employee := range report.Employees {
+		byNumber[employee.EmployeeNumber] = employee
+	}
+	require.Len(t, byNumber, 4)
+	assert.Equal(t, "EMP00000", report.Employees[0].EmployeeNumber, "outliers are listed first")
+
+	raised := byNumber["EMP00000"]
+	assert.Equal(t, "changed", raised.Status)
+	assert.True(t, raised.SalaryChanged)
+	assert.True(t, raised.IsOutlier)
+	components := make(map[string]ComponentVariance)
+	for _, component := range raised.Components {
+		components[component.Component] = component
+	}
+	assertAmount(t, 500, components["basic_salary"].Previous)
+	assertAmount(t, 800, components["basic_salary"].Current)
+	assertAmount(t, 300, components["basic_salary"].Delta)
+	assertAmount(t, 0, components["allowances"].Delta)
+	assert.True(t, components["net_pay"].Delta.Equal(raised.NetPayDelta))
+
+	deducted := byNumber["EMP00001"]
+	assert.Equal(t, "changed", deducted.Status)
+	assert.False(t, deducted.SalaryChanged)
+	assert.Equal(t, []string{"Union dues"}, deducted.NewDeductions)
+	assertAmount(t, -30, deducted.NetPayDelta)
+	assert.False(t, deducted.IsOutlier)
+
+	leaver := byNumber["EMP00002"]
+	assert.Equal(t, "leaver", leaver.Status)
+	assert.False(t, leaver.IsOutlier)
+	for _, component := range leaver.Components {
+		assertAmount(t, 0, component.Current)
+	}
+
+	joined := byNumber["EMP00003"]
+	assert.Equal(t, "new_joiner", joined.Status)
+	assert.False(t, joined.IsOutlier)
+	for _, component := range joined.Components {
+		assertAmount(t, 0, component.Previous)
+	}
+}
+
+func TestComparePeriodsThresholds(t *testing.T) {
+	db, company := setupPayrollDB(t, 2)
+	processor := newProcessor(db)
+	variance := NewVarianceService(db)
+
+	january := createDraftPeriod(t, db, company.ID, 0)
+	require.NoError(t, processor.ProcessPayrollForCompany(january.ID, company.ID, 1))
+	employees := employeesByNumber(t, db)
+	require.NoError(t, db.Create(&models.Deduction{
+		CompanyID: company.ID, EmployeeID: employees[1].ID, Name: "Union dues", Amount: decimal.NewFromInt(30),
+		CurrencyID: employees[1].CurrencyID, IsActive: true, IsRecurring: true,
+		StartDate: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	}).Error)
+	february := createDraftPeriod(t, db, company.ID, 1)
+	require.NoError(t, processor.ProcessPayrollForCompany(february.ID, company.ID, 1))
+
+	cases := []struct {
+		name       string
+		thresholds VarianceThresholds
+		outliers   int
+	}{
+		{"percent above the change", VarianceThresholds{NetPayPercent: DefaultVariancePercentThreshold}, 0},
+		{"percent below the change", VarianceThresholds{NetPayPercent: 1}, 1},
+		{"amount at the change", VarianceThresholds{NetPayAmount: 30}, 1},
+		{"amount above the change", VarianceThresholds{NetPayAmount: 31}, 0},
+		{"disabled", VarianceThresholds{}, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			report, err := variance.ComparePeriods(february.ID, january.ID, company.ID, tc.thresholds)
+			require.NoError(t, err)
+			assert.Equal(t, tc.outliers, report.Summary.Outliers)
+			for _, employee := range report.Employees {
+				assert.Equal(t, tc.outliers > 0 && employee.EmployeeID == employees[1].ID, employee.IsOutlier, employee.EmployeeNumber)
+			}
+		})
+	}
+}
+
+func TestComparePeriodsWithoutPreviousRun(t *testing.T) {
+	db, company := setupPayrollDB(t, 1)
+	processor := newProcessor(db)
+	variance := NewVarianceService(db)
+
+	// January is still a draft, so February has nothing to compare with
+	createDraftPeriod(t, db, company.ID, 0)
+	february := createDraftPeriod(t, db, company.ID, 1)
+	require.NoError(t, processor.ProcessPayrollForCompany(february.ID, company.ID, 1))
+
+	_, err := variance.ComparePeriods(february.ID, 0, company.ID, VarianceThresholds{})
+	assert.ErrorIs(t, err, ErrNoPreviousPeriod)
+}
@@ -0,0 +1,110 @@
+package payroll
+
+import (
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// WithholdingTaxLine is one contractor's withholding in a period, in their own currency.
+type WithholdingTaxLine struct {
+	PayslipID      uint            `json:"payslip_id"`
+	EmployeeID     uint            `json:"employee_id"`
+	EmployeeNumber string          `json:"employee_number"`
+	Name           string          `json:"name"`
+	TaxNumber      string          `json:"tax_number"`
+	Currency       string          `json:"currency"`
+	GrossAmount    decimal.Decimal `json:"gross_amount"`
+	Rate           decimal.Decimal `json:"rate"`
+	WithholdingTax decimal.Decimal `json:"withholding_tax"`
+	NetPay         decimal.Decimal `json:"net_pay"`
+	ExchangeRate   decimal.Decimal `json:"exchange_rate"` // To the company base currency
+}
+
+// WithholdingTaxTotals sums the report lines in one currency.
+type WithholdingTaxTotals struct {
+	Contractors    int             `json:"contractors"`
+	GrossAmount    decimal.Decimal `json:"gross_amount"`
+	WithholdingTax decimal.Decimal `json:"withholding_tax"`
+	NetPay         decimal.Decimal `json:"net_pay"`
+}
+
+// WithholdingTaxReport lists the tax withheld from contractors in a period, kept apart from
+// PAYE because it is returned and remitted separately.
+type WithholdingTaxReport struct {
+	PayrollPeriodID    uint                            `json:"payroll_period_id"`
+	Year               int                             `json:"year"`
+	Month              int                             `json:"month"`
+	BaseCurrency       string                          `json:"base_currency"`
+	Lines              []WithholdingTaxLine            `json:"lines"`
+	Totals             map[string]WithholdingTaxTotals `json:"totals"` // By currency
+	WithholdingTaxBase decimal.Decimal                 `json:"withholding_tax_base"`
+}
+
+// contractorWithholdingRate is the percentage withheld from a contractor: their own rate,
+// for example zero with a tax clearance, otherwise the company rate.
+func contractorWithholdingRate(employee models.Employee, settings models.CompanySettings) decimal.Decimal {
+	if employee.WithholdingTaxRate != nil {
+		return *employee.WithholdingTaxRate
+	}
+	return settings.WithholdingTaxRate
+}
+
+// GetWithholdingTaxReport returns the withholding tax report for a processed period.
+func (pp *PayrollProcessor) GetWithholdingTaxReport(periodID, companyID uint) (*WithholdingTaxReport, error) {
+	var period models.PayrollPeriod
+	if err := pp.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
+		return nil, err
+	}
+	var company models.Company
+	if err := pp.db.Preload("BaseCurrency").First(&company, companyID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get company base currency: %w", err)
+	}
+
+	var payslips []models.Payslip
+	if err := pp.db.Preload("Currency").
+		Where("payroll_period_id = ? AND company_id = ? AND is_contractor = ?", periodID, companyID, true).
+		Find(&payslips).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch contractor payments: %w", err)
+	}
+	sort.Slice(payslips, func(i, j int) bool { return payslips[i].EmployeeNumber < payslips[j].EmployeeNumber })
+
+	report := &WithholdingTaxReport{
+		PayrollPeriodID: period.ID,
+		Year:            period.Year,
+		Month:           period.Month,
+		BaseCurrency:    company.BaseCurrency.Code,
+		Lines:           make([]WithholdingTaxLine, 0, len(payslips)),
+		Totals:          make(map[string]WithholdingTaxTotals),
+	}
+	for _, payslip := range payslips {
+		report.Lines = append(report.Lines, WithholdingTaxLine{
+			PayslipID:      payslip.ID,
+			EmployeeID:     payslip.EmployeeID,
+			EmployeeNumber: payslip.EmployeeNumber,
+			Name:           payslip.EmployeeName,
+			TaxNumber:      payslip.TaxNumber,
+			Currency:       payslip.Currency.Code,
+			GrossAmount:    payslip.TotalEarnings,
+			Rate:           payslip.WithholdingTaxRate,
+			WithholdingTax: payslip.WithholdingTax,
+			NetPay:         payslip.NetPay,
+			ExchangeRate:   payslip.ExchangeRate,
+		})
+
+		totals := report.Totals[payslip.Currency.Code]
+		totals.Contractors++
+		totals.GrossAmount = totals.GrossAmount.Add(payslip.TotalEarnings)
+		totals.WithholdingTax = totals.WithholdingTax.Add(payslip.WithholdingTax)
+		totals.NetPay = totals.NetPay.Add(payslip.NetPay)
+		report.Totals[payslip.Currency.Code] = totals
+
+		report.WithholdingTaxBase = report.WithholdingTaxBase.Add(payslip.WithholdingTax.Mul(payslip.ExchangeRate))
+	}
+	report.WithholdingTaxBase = report.WithholdingTaxBase.Round(money.Places(company.BaseCurrency.Code))
+
+	return report, nil
+}
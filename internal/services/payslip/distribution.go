@@ -0,0 +1,413 @@
+package payslip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/services/email"
+	"gm58-hr-backend/pkg/logger"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrEmailDisabled is returned when the company has switched off email notifications.
+	ErrEmailDisabled = errors.New("email notifications are disabled for this company")
+	// ErrPeriodNotApproved is returned when payslips are emailed before the payroll is approved.
+	ErrPeriodNotApproved = errors.New("payroll must be approved before payslips are emailed")
+)
+
+// stuckSendingAfter is how long a delivery may stay in "sending" before it is assumed
+// lost (for example the server restarted mid-send) and queued again
+const stuckSendingAfter = 10 * time.Minute
+
+type DistributionOptions struct {
+	RatePerMinute int           // Maximum messages sent per minute
+	MaxAttempts   int           // Attempts before a delivery is marked failed
+	PollInterval  time.Duration // How often the queue is checked for due deliveries
+}
+
+type QueueResult struct {
+	Queued        int                `json:"queued"`
+	AlreadyQueued int                `json:"already_queued"`
+	Skipped       []SkippedRecipient `json:"skipped"`
+}
+
+type SkippedRecipient struct {
+	EmployeeID     uint   `json:"employee_id"`
+	EmployeeNumber string `json:"employee_number"`
+	Reason         string `json:"reason"`
+}
+
+type DeliverySummary struct {
+	Total   int `json:"total"`
+	Queued  int `json:"queued"`
+	Sending int `json:"sending"`
+	Sent    int `json:"sent"`
+	Failed  int `json:"failed"`
+}
+
+// DistributionService queues payslip emails and delivers them in the background.
+type DistributionService struct {
+	db             *gorm.DB
+	payslipService *PayslipService
+	mailer         email.Mailer
+	logger         *logger.Logger
+	options        DistributionOptions
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewDistributionService(db *gorm.DB, payslipService *PayslipService, mailer email.Mailer, logger *logger.Logger, options DistributionOptions) *DistributionService {
+	if options.RatePerMinute <= 0 {
+		options.RatePerMinute = 30
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 5
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = 15 * time.Second
+	}
+
+	return &DistributionService{
+		db:             db,
+		payslipService: payslipService,
+		mailer:         mailer,
+		logger:         logger,
+		options:        options,
+		stop:           make(chan struct{}),
+	}
+}
+
+// QueuePeriod queues an email with the payslip PDF for every employee in an approved period.
+// employeeIDs limits the run to those employees; payslips that are already queued or sent are skipped.
+func (ds *DistributionService) QueuePeriod(periodID, companyID, requestedBy uint, protect bool, employeeIDs []uint) (*QueueResult, error) {
+	var period models.PayrollPeriod
+	if err := ds.db.Where("id = ? AND company_id = ?", periodID, companyID).First(&period).Error; err != nil {
+		return nil, fmt.Errorf("payroll period not found: %w", err)
+	}
+	if period.Status != "approved" && period.Status != "paid" {
+		return nil, ErrPeriodNotApproved
+	}
+	if err := ds.checkEnabled(companyID); err != nil {
+		return nil, err
+	}
+
+	query := ds.db.Preload("Employee").Where("payroll_period_id = ? AND company_id = ?", periodID, companyID)
+	if len(employeeIDs) > 0 {
+		query = query.Where("employee_id IN ?", employeeIDs)
+	}
+	var payslips []models.Payslip
+	if err := query.Find(&payslips).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch payslips: %w", err)
+	}
+
+	result := &QueueResult{Skipped: []SkippedRecipient{}}
+	now := time.Now()
+	for _, payslip := range payslips {
+		employee := payslip.Employee
+		skip := func(reason string) {
+			result.Skipped = append(result.Skipped, SkippedRecipient{
+				EmployeeID:     employee.ID,
+				EmployeeNumber: payslip.EmployeeNumber,
+				Reason:         reason,
+			})
+		}
+
+		if employee.Email == "" {
+			skip("employee has no email address")
+			continue
+		}
+		if protect && Password(payslip) == "" {
+			skip("employee has no national ID to protect the payslip with")
+			continue
+		}
+
+		var existing int64
+		if err := ds.db.Model(&models.PayslipEmail{}).
+			Where("payslip_id = ? AND status IN ?", payslip.ID, []string{"queued", "sending", "sent"}).
+			Count(&existing).Error; err != nil {
+			return nil, fmt.Errorf("failed to check queued payslip emails: %w", err)
+		}
+		if existing > 0 {
+			result.AlreadyQueued++
+			continue
+		}
+
+		delivery := models.PayslipEmail{
+			CompanyID:       companyID,
+			PayrollPeriodID: period.ID,
+			PayslipID:       payslip.ID,
+			EmployeeID:      employee.ID,
+			Recipient:       employee.Email,
+			Protect:         protect,
+			Status:          "queued",
+			NextAttemptAt:   now,
+			RequestedBy:     requestedBy,
+		}
+		if err := ds.db.Create(&delivery).Error; err != nil {
+			return nil, fmt.Errorf("failed to queue payslip email: %w", err)
+		}
+		result.Queued++
+	}
+
+	return result, nil
+}
+
+// GetPeriodDeliveries returns the delivery status of every payslip email in a period.
+func (ds *DistributionService) GetPeriodDeliveries(periodID, companyID uint) ([]models.PayslipEmail, DeliverySummary, error) {
+	var deliveries []models.PayslipEmail
+	if err := ds.db.Preload("Employee").
+		Where("payroll_period_id = ? AND company_id = ?", periodID, companyID).
+		Order("id").
+		Find(&deliveries).Error; err != nil {
+		return nil, DeliverySummary{}, fmt.Errorf("failed to fetch payslip emails: %w", err)
+	}
+
+	summary := DeliverySummary{Total: len(deliveries)}
+	for _, delivery := range deliveries {
+		switch delivery.Status {
+		case "queued":
+			summary.Queued++
+		case "sending":
+			summary.Sending++
+		case "sent":
+			summary.Sent++
+		case "failed":
+			summary.Failed++
+		}
+	}
+	return deliveries, summary, nil
+}
+
+// Resend queues a delivery again with a fresh set of attempts, using the employee's
+// current email address.
+func (ds *DistributionService) Resend(deliveryID, companyID uint) (*models.PayslipEmail, error) {
+	if err := ds.checkEnabled(companyID); err != nil {
+		return nil, err
+	}
+
+	var delivery models.PayslipEmail
+	if err := ds.db.Preload("Employee").
+		Where("id = ? AND company_id = ?", deliveryID, companyID).
+		First(&delivery).Error; err != nil {
+		return nil, fmt.Errorf("payslip email not found: %w", err)
+	}
+	if delivery.Status == "sending" {
+		return nil, fmt.Errorf("payslip email is being sent")
+	}
+	if delivery.Employee.Email == "" {
+		return nil, fmt.Errorf("employee has no email address")
+	}
+
+	delivery.Recipient = delivery.Employee.Email
+	delivery.Status = "queued"
+	delivery.Attempts = 0
+	delivery.LastError = ""
+	delivery.NextAttemptAt = time.Now()
+	if err := ds.db.Model(&models.PayslipEmail{}).Where("id = ?", delivery.ID).
+		Updates(map[string]interface{}{
+			"recipient":       delivery.Recipient,
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"last_error":      delivery.LastError,
+			"next_attempt_at": delivery.NextAttemptAt,
+		}).Error; err != nil {
+		return nil, fmt.Errorf("failed to queue payslip email: %w", err)
+	}
+	return &delivery, nil
+}
+
+// ResendFailed queues every failed delivery in a period again.
+func (ds *DistributionService) ResendFailed(periodID, companyID uint) (int64, error) {
+	if err := ds.checkEnabled(companyID); err != nil {
+		return 0, err
+	}
+
+	result := ds.db.Model(&models.PayslipEmail{}).
+		Where("payroll_period_id = ? AND company_id = ? AND status = ?", periodID, companyID, "failed").
+		Updates(map[string]interface{}{
+			"status":          "queued",
+			"attempts":        0,
+			"last_error":      "",
+			"next_attempt_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// Start runs the delivery worker in the background until Stop is called. Without a
+// configured mailer nothing can be delivered, so no worker is started.
+func (ds *DistributionService) Start() {
+	if !ds.mailer.Configured() {
+		ds.logger.Warn("SMTP is not configured; payslip emails will not be sent")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(ds.options.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			ds.dispatchDue()
+
+			select {
+			case <-ds.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (ds *DistributionService) Stop() {
+	ds.stopOnce.Do(func() { close(ds.stop) })
+}
+
+// dispatchDue sends up to one minute's worth of due deliveries, spaced to respect the rate limit.
+func (ds *DistributionService) dispatchDue() {
+	now := time.Now()
+
+	ds.db.Model(&models.PayslipEmail{}).
+		Where("status = ? AND updated_at < ?", "sending", now.Add(-stuckSendingAfter)).
+		Update("status", "queued")
+
+	var due []models.PayslipEmail
+	if err := ds.db.Where("status = ? AND next_attempt_at <= ?", "queued", now).
+		Order("next_attempt_at, id").
+		Limit(ds.options.RatePerMinute).
+		Find(&due).Error; err != nil {
+		ds.logger.Error("Failed to load payslip email queue: " + err.Error())
+		return
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	limiter := time.NewTicker(time.Minute / time.Duration(ds.options.RatePerMinute))
+	defer limiter.Stop()
+
+	for i, delivery := range due {
+		if i > 0 {
+			select {
+			case <-ds.stop:
+				return
+			case <-limiter.C:
+			}
+		}
+
+		// Claim the row so a second worker never sends the same payslip
+		claim := ds.db.Model(&models.PayslipEmail{}).
+			Where("id = ? AND status = ?", delivery.ID, "queued").
+			Updates(map[string]interface{}{"status": "sending", "updated_at": time.Now()})
+		if claim.Error != nil || claim.RowsAffected == 0 {
+			continue
+		}
+
+		ds.deliver(delivery)
+	}
+}
+
+func (ds *DistributionService) deliver(delivery models.PayslipEmail) {
+	delivery.Attempts++
+
+	err := ds.send(delivery)
+	switch {
+	case err == nil:
+		now := time.Now()
+		delivery.Status = "sent"
+		delivery.SentAt = &now
+		delivery.LastError = ""
+	case errors.Is(err, ErrEmailDisabled) || delivery.Attempts >= ds.options.MaxAttempts:
+		delivery.Status = "failed"
+		delivery.LastError = err.Error()
+	default:
+		// Back off 1, 4, 9, ... minutes between attempts
+		delivery.Status = "queued"
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = time.Now().Add(time.Duration(delivery.Attempts*delivery.Attempts) * time.Minute)
+	}
+
+	if err != nil {
+		ds.logger.Warn(fmt.Sprintf("Payslip email %d to %s failed (attempt %d): %v",
+			delivery.ID, delivery.Recipient, delivery.Attempts, err))
+	}
+
+	if saveErr := ds.db.Model(&models.PayslipEmail{}).Where("id = ?", delivery.ID).
+		Updates(map[string]interface{}{
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"last_error":      delivery.LastError,
+			"next_attempt_at": delivery.NextAttemptAt,
+			"sent_at":         delivery.SentAt,
+		}).Error; saveErr != nil {
+		ds.logger.Error("Failed to update payslip email status: " + saveErr.Error())
+	}
+}
+
+func (ds *DistributionService) send(delivery models.PayslipEmail) error {
+	// Settings may have changed since the email was queued
+	if !ds.emailEnabled(delivery.CompanyID) {
+		return ErrEmailDisabled
+	}
+
+	doc, err := ds.payslipService.GetDocument(delivery.PayslipID, delivery.CompanyID)
+	if err != nil {
+		return fmt.Errorf("failed to load payslip: %w", err)
+	}
+
+	var password string
+	if delivery.Protect {
+		password = Password(doc.Payslip)
+	}
+
+	var pdf bytes.Buffer
+	if err := RenderPDF(&pdf, *doc, password); err != nil {
+		return fmt.Errorf("failed to render payslip: %w", err)
+	}
+
+	period := doc.Payslip.PayrollPeriod
+	periodName := fmt.Sprintf("%s %d", time.Month(period.Month).String(), period.Year)
+
+	title := Title(doc.Payslip)
+	body := fmt.Sprintf("Dear %s,\n\nPlease find attached your %s for %s.\n", doc.Payslip.EmployeeName, strings.ToLower(title), periodName)
+	if delivery.Protect {
+		body += "\nThe attachment is password protected. Open it with your national ID number, without spaces or dashes.\n"
+	}
+	body += fmt.Sprintf("\nRegards,\n%s\n", doc.Company.Name)
+
+	return ds.mailer.Send(email.Message{
+		To:      delivery.Recipient,
+		Subject: fmt.Sprintf("Your %s for %s - %s", strings.ToLower(title), periodName, doc.Company.Name),
+		Body:    body,
+		Attachments: []email.Attachment{{
+			FileName:    FileName(doc.Payslip),
+			ContentType: "application/pdf",
+			Data:        pdf.Bytes(),
+		}},
+	})
+}
+
+func (ds *DistributionService) checkEnabled(companyID uint) error {
+	if !ds.mailer.Configured() {
+		return email.ErrNotConfigured
+	}
+	if !ds.emailEnabled(companyID) {
+		return ErrEmailDisabled
+	}
+	return nil
+}
+
+// emailEnabled reports whether the company allows email notifications. Companies
+// without a settings record use the default, which is enabled.
+func (ds *DistributionService) emailEnabled(companyID uint) bool {
+	var settings models.CompanySettings
+	if err := ds.db.Where("company_id = ?", companyID).First(&settings).Error; err != nil {
+		return true
+	}
+	return settings.EmailNotifications
+}
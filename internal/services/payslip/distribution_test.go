@@ -0,0 +1,177 @@
+package payslip
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/services/email"
+	"gm58-hr-backend/pkg/logger"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeMailer records messages instead of sending them, failing with err when it is set
+type fakeMailer struct {
+	mu           sync.Mutex
+	unconfigured bool
+	err          error
+	sent         []email.Message
+}
+
+func (m *fakeMailer) Configured() bool {
+	return !m.unconfigured
+}
+
+func (m *fakeMailer) Send(message email.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+// setupDistribution creates an approved period with a payslip for each employee; the
+// first employee has no email address.
+func setupDistribution(t *testing.T, employees int, options DistributionOptions) (*gorm.DB, *DistributionService, *fakeMailer, models.PayrollPeriod) {
+	db, company := openTestDB(t)
+	require.NoError(t, db.Create(&models.CompanySettings{CompanyID: company.ID, EmailNotifications: true}).Error)
+	period := createPeriod(t, db, company.ID, 2024, 1, models.PeriodTypeRegular, "approved")
+
+	for i := 0; i < employees; i++ {
+		employee := models.Employee{
+			CompanyID: company.ID, EmployeeNumber: fmt.Sprintf("EMP%05d", i), FirstName: "Employee", LastName: fmt.Sprint(i),
+			NationalID: fmt.Sprintf("63-%06dA00", i), BasicSalary: decimal.NewFromInt(500), CurrencyID: company.BaseCurrencyID,
+			IsActive: true, EmploymentStatus: "active",
+		}
+		if i > 0 {
+			employee.Email = fmt.Sprintf("employee%d@bench.test", i)
+		}
+		require.NoError(t, db.Create(&employee).Error)
+		require.NoError(t, db.Create(&models.Payslip{
+			CompanyID: company.ID, PayrollPeriodID: period.ID, EmployeeID: employee.ID, CurrencyID: company.BaseCurrencyID,
+			EmployeeNumber: employee.EmployeeNumber, EmployeeName: employee.FullName(), NationalID: employee.NationalID,
+			BasicSalary: employee.BasicSalary, TotalEarnings: employee.BasicSalary, NetPay: employee.BasicSalary, Status: "approved",
+		}).Error)
+	}
+
+	mailer := &fakeMailer{}
+	service := NewDistributionService(db, NewPayslipService(db), mailer, logger.New("error"), options)
+	return db, service, mailer, period
+}
+
+func deliveries(t *testing.T, db *gorm.DB) []models.PayslipEmail {
+	var found []models.PayslipEmail
+	require.NoError(t, db.Order("id").Find(&found).Error)
+	return found
+}
+
+func TestQueuePeriodSkipsAndDoesNotRequeue(t *testing.T) {
+	db, service, mailer, period := setupDistribution(t, 3, DistributionOptions{})
+
+	result, err := service.QueuePeriod(period.ID, period.CompanyID, 1, true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Queued)
+	require.Len(t, result.Skipped, 1)
+	assert.Equal(t, "EMP00000", result.Skipped[0].EmployeeNumber)
+
+	result, err = service.QueuePeriod(period.ID, period.CompanyID, 1, true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Queued)
+	assert.Equal(t, 2, result.AlreadyQueued)
+
+	require.NoError(t, db.Model(&models.CompanySettings{}).Where("company_id = ?", period.CompanyID).
+		Update("email_notifications", false).Error)
+	_, err = service.QueuePeriod(period.ID, period.CompanyID, 1, true, nil)
+	assert.ErrorIs(t, err, ErrEmailDisabled)
+
+	mailer.unconfigured = true
+	_, err = service.QueuePeriod(period.ID, period.CompanyID, 1, true, nil)
+	assert.ErrorIs(t, err, email.ErrNotConfigured)
+
+	require.NoError(t, db.Model(&period).Update("status", "processed").Error)
+	_, err = service.QueuePeriod(period.ID, period.CompanyID, 1, true, nil)
+	assert.ErrorIs(t, err, ErrPeriodNotApproved)
+}
+
+func TestDispatchDueSendsUpToRateLimit(t *testing.T) {
+	db, service, mailer, period := setupDistribution(t, 3, DistributionOptions{RatePerMinute: 1})
+	_, err := service.QueuePeriod(period.ID, period.CompanyID, 1, true, nil)
+	require.NoError(t, err)
+
+	// One message a minute, so each pass sends one
+	service.dispatchDue()
+	require.Len(t, mailer.sent, 1)
+	sent := deliveries(t, db)
+	assert.Equal(t, "sent", sent[0].Status)
+	assert.NotNil(t, sent[0].SentAt)
+	assert.Equal(t, "queued", sent[1].Status)
+
+	message := mailer.sent[0]
+	assert.Equal(t, "employee1@bench.test", message.To)
+	require.Len(t, message.Attachments, 1)
+	assert.Equal(t, "payslip-2024-01-EMP00001.pdf", message.Attachments[0].FileName)
+	assert.Contains(t, message.Body, "password protected")
+
+	service.dispatchDue()
+	require.Len(t, mailer.sent, 2)
+	assert.Equal(t, "employee2@bench.test", mailer.sent[1].To)
+}
+
+func TestDeliveryBacksOffThenFailsAndResends(t *testing.T) {
+	db, service, mailer, period := setupDistribution(t, 2, DistributionOptions{RatePerMinute: 60, MaxAttempts: 2})
+	_, err := service.QueuePeriod(period.ID, period.CompanyID, 1, false, nil)
+	require.NoError(t, err)
+
+	mailer.err = errors.New("connection refused")
+	service.dispatchDue()
+	delivery := deliveries(t, db)[0]
+	assert.Equal(t, "queued", delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, "connection refused", delivery.LastError)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), delivery.NextAttemptAt, 5*time.Second)
+
+	// Not due again until the backoff has passed
+	service.dispatchDue()
+	assert.Equal(t, 1, deliveries(t, db)[0].Attempts)
+
+	require.NoError(t, db.Model(&delivery).Update("next_attempt_at", time.Now().Add(-time.Second)).Error)
+	service.dispatchDue()
+	delivery = deliveries(t, db)[0]
+	assert.Equal(t, "failed", delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+
+	mailer.err = nil
+	resent, err := service.Resend(delivery.ID, period.CompanyID)
+	require.NoError(t, err)
+	assert.Equal(t, "queued", resent.Status)
+	assert.Equal(t, 0, resent.Attempts)
+	service.dispatchDue()
+	assert.Equal(t, "sent", deliveries(t, db)[0].Status)
+	require.Len(t, mailer.sent, 1)
+}
+
+func TestDeliveryFailsWhenEmailDisabledAfterQueueing(t *testing.T) {
+	db, service, mailer, period := setupDistribution(t, 2, DistributionOptions{})
+	_, err := service.QueuePeriod(period.ID, period.CompanyID, 1, false, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Model(&models.CompanySettings{}).Where("company_id = ?", period.CompanyID).
+		Update("email_notifications", false).Error)
+	service.dispatchDue()
+	assert.Empty(t, mailer.sent)
+	delivery := deliveries(t, db)[0]
+	assert.Equal(t, "failed", delivery.Status)
+	assert.Equal(t, ErrEmailDisabled.Error(), delivery.LastError)
+
+	_, err = service.ResendFailed(period.ID, period.CompanyID)
+	assert.ErrorIs(t, err, ErrEmailDisabled)
+}
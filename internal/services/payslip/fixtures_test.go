@@ -0,0 +1,42 @@
+package payslip
+
+import (
+	"testing"
+	"time"
+
+	"gm58-hr-backend/internal/database"
+	"gm58-hr-backend/internal/models"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// openTestDB returns a migrated in-memory database with a USD company
+func openTestDB(t *testing.T) (*gorm.DB, models.Company) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// Every connection to :memory: is a separate database
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, database.AutoMigrate(db))
+
+	usd := models.Currency{Code: "USD", Name: "US Dollar", Symbol: "$", IsActive: true, IsBaseCurrency: true}
+	require.NoError(t, db.Create(&usd).Error)
+	company := models.Company{Name: "Bench Co", Code: "BENCH", Email: "payroll@bench.test", BaseCurrencyID: usd.ID}
+	require.NoError(t, db.Create(&company).Error)
+	return db, company
+}
+
+// createPeriod adds a monthly period of the given type and status
+func createPeriod(t *testing.T, db *gorm.DB, companyID uint, year, month int, periodType, status string) models.PayrollPeriod {
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	period := models.PayrollPeriod{
+		CompanyID: companyID, PeriodType: periodType, Year: year, Month: month,
+		StartDate: start, EndDate: start.AddDate(0, 1, -1), Status: status,
+	}
+	require.NoError(t, db.Create(&period).Error)
+	return period
+}
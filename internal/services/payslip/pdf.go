@@ -0,0 +1,453 @@
+package payslip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gm58-hr-backend/internal/money"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/shopspring/decimal"
+)
+
+// logoClient fetches company logos; a slow logo host must not stall payslip generation.
+// Logo URLs are set by the tenant, so the client only connects to public addresses and
+// follows redirects only to other https URLs.
+var logoClient = &http.Client{
+	Timeout: 5 * time.Second,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: publicAddressOnly,
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 3 {
+			return errors.New("too many logo redirects")
+		}
+		return checkLogoURL(req.URL)
+	},
+}
+
+// logoCacheFor is how long a fetched logo is reused, so a batch of payslips fetches it once
+const logoCacheFor = 10 * time.Minute
+
+const (
+	pageMargin   = 15.0
+	contentWidth = 180.0 // A4 width less both margins
+	lineHeight   = 6.0
+)
+
+type pdfLine struct {
+	label  string
+	amount decimal.Decimal
+}
+
+// RenderPDF writes a printable payslip. When password is not empty the document is
+// encrypted and must be opened with that password; printing and copying stay allowed.
+func RenderPDF(w io.Writer, doc Document, password string) error {
+	payslip := doc.Payslip
+	period := payslip.PayrollPeriod
+	currency := payslip.Currency.Code
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(true, pageMargin)
+	if password != "" {
+		pdf.SetProtection(fpdf.CnProtectPrint|fpdf.CnProtectCopy, password, "")
+	}
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	title := Title(payslip)
+	pdf.SetTitle(tr(fmt.Sprintf("%s %s %04d-%02d", title, payslip.EmployeeNumber, period.Year, period.Month)), false)
+	pdf.SetAuthor(tr(doc.Company.Name), false)
+	pdf.AddPage()
+
+	// Header: logo on the left, company details on the right
+	headerTop := pdf.GetY()
+	if addLogo(pdf, doc.Logo, pageMargin, headerTop) {
+		pdf.SetXY(pageMargin+60, headerTop)
+	}
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(0, 7, tr(doc.Company.Name), "", 1, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, line := range []string{
+		joinNonEmpty(", ", doc.Company.Address, doc.Company.City, doc.Company.Country),
+		joinNonEmpty("  ", doc.Company.Phone, doc.Company.Email),
+		labelled("Tax number: ", doc.Company.TaxNumber),
+	} {
+		if line != "" {
+			pdf.CellFormat(0, 4.5, tr(line), "", 1, "R", false, 0, "")
+		}
+	}
+	if pdf.GetY() < headerTop+22 {
+		pdf.SetY(headerTop + 22)
+	}
+
+	pdf.Ln(3)
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 8, strings.ToUpper(title), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 5, fmt.Sprintf("Pay period: %s %d (%s to %s)",
+		time.Month(period.Month).String(), period.Year,
+		period.StartDate.Format("02 Jan 2006"), period.EndDate.Format("02 Jan 2006")), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 5, "Currency: "+currency, "", 1, "L", false, 0, "")
+	pdf.Ln(3)
+
+	// Employee and bank details side by side, as captured when the payslip was generated
+	details := [][2]string{
+		{"Employee", payslip.EmployeeName},
+		{"Employee number", payslip.EmployeeNumber},
+		{"National ID", payslip.NationalID},
+		{"Tax number", payslip.TaxNumber},
+		{"Position", payslip.PositionTitle},
+		{"Department", payslip.DepartmentName},
+	}
+	bank := [][2]string{
+		{"Payment method", payslip.PaymentMethod},
+		{"Bank", payslip.BankName},
+		{"Branch", payslip.BankBranch},
+		{"Account number", payslip.BankAccount},
+		{"Days worked", fmt.Sprintf("%d of %d", payslip.DaysWorked, payslip.WorkingDays)},
+	}
+	detailsTitle := "Employee details"
+	if payslip.IsContractor {
+		details[0][0], details[1][0] = "Contractor", "Contractor number"
+		detailsTitle = "Contractor details"
+	}
+	sectionTitle(pdf, detailsTitle, "Payment details")
+	half := contentWidth / 2
+	for i := 0; i < len(details) || i < len(bank); i++ {
+		for column, rows := range [][][2]string{details, bank} {
+			x := pageMargin + float64(column)*half
+			pdf.SetX(x)
+			if i >= len(rows) {
+				pdf.CellFormat(half, 5, "", "", 0, "L", false, 0, "")
+				continue
+			}
+			pdf.SetFont("Helvetica", "", 9)
+			pdf.CellFormat(32, 5, rows[i][0], "", 0, "L", false, 0, "")
+			pdf.SetFont("Helvetica", "B", 9)
+			pdf.CellFormat(half-32, 5, tr(rows[i][1]), "", 0, "L", false, 0, "")
+		}
+		pdf.Ln(5)
+	}
+	pdf.Ln(3)
+
+	basicLabel := "Basic salary"
+	if payslip.PayQuantity > 0 {
+		// Time-based pay shows the quantity paid and the rate
+		basicLabel = fmt.Sprintf("Pay (%g @ %s)", payslip.PayQuantity, formatAmount(payslip.PayRate, currency))
+	}
+	earnings := nonZero([]pdfLine{
+		{basicLabel, payslip.BasicSalary},
+		{"Overtime", payslip.Overtime},
+		{"Allowances", payslip.Allowances},
+		{"Bonus", payslip.Bonus},
+		{"Commission", payslip.Commission},
+		{"Other earnings", payslip.OtherEarnings},
+		{"Back pay", payslip.BackPay},
+		{"Leave pay", payslip.LeavePay},
+		{"Notice pay", payslip.NoticePay},
+		{"Severance pay", payslip.SeverancePay},
+	})
+	deductions := nonZero([]pdfLine{
+		{"PAYE", payslip.PayeeTax},
+		{"AIDS levy", payslip.AidsLevy},
+		{"NSSA", payslip.NSSAContribution},
+		{fmt.Sprintf("Withholding tax (%s%%)", payslip.WithholdingTaxRate), payslip.WithholdingTax},
+		{"Pension", payslip.PensionContribution},
+		{"Medical aid", payslip.MedicalAid},
+		{"Union dues", payslip.UnionDues},
+		{"Loan repayments", payslip.LoanDeductions},
+		{"Other deductions", payslip.OtherDeductions},
+	})
+
+	// Earnings on the left, deductions on the right
+	sectionTitle(pdf, "Earnings", "Deductions")
+	pdf.SetFont("Helvetica", "", 10)
+	for i := 0; i < len(earnings) || i < len(deductions); i++ {
+		for column, lines := range [][]pdfLine{earnings, deductions} {
+			pdf.SetX(pageMargin + float64(column)*half)
+			if i >= len(lines) {
+				pdf.CellFormat(half, lineHeight, "", "", 0, "L", false, 0, "")
+				continue
+			}
+			pdf.CellFormat(half-40, lineHeight, lines[i].label, "", 0, "L", false, 0, "")
+			pdf.CellFormat(35, lineHeight, formatAmount(lines[i].amount, currency), "", 0, "R", false, 0, "")
+			pdf.CellFormat(5, lineHeight, "", "", 0, "L", false, 0, "")
+		}
+		pdf.Ln(lineHeight)
+	}
+
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(half-40, lineHeight, "Total earnings", "T", 0, "L", false, 0, "")
+	pdf.CellFormat(35, lineHeight, formatAmount(payslip.TotalEarnings, currency), "T", 0, "R", false, 0, "")
+	pdf.CellFormat(5, lineHeight, "", "T", 0, "L", false, 0, "")
+	pdf.CellFormat(half-40, lineHeight, "Total deductions", "T", 0, "L", false, 0, "")
+	pdf.CellFormat(35, lineHeight, formatAmount(payslip.TotalDeductions, currency), "T", 0, "R", false, 0, "")
+	pdf.CellFormat(5, lineHeight, "", "T", 1, "L", false, 0, "")
+	pdf.Ln(3)
+
+	pdf.SetFillColor(230, 230, 230)
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(contentWidth-45, 9, "NET PAY", "1", 0, "L", true, 0, "")
+	pdf.CellFormat(45, 9, currency+" "+formatAmount(payslip.NetPay, currency), "1", 1, "R", true, 0, "")
+
+	if len(payslip.NetPaySplits) > 0 {
+		pdf.SetFont("Helvetica", "", 9)
+		for _, split := range payslip.NetPaySplits {
+			pdf.CellFormat(contentWidth-45, 5, fmt.Sprintf("Paid in %s (rate %s)", split.Currency.Code, split.ExchangeRate), "", 0, "L", false, 0, "")
+			pdf.CellFormat(45, 5, split.Currency.Code+" "+formatAmount(split.NetAmount, split.Currency.Code), "", 1, "R", false, 0, "")
+		}
+	}
+	pdf.Ln(4)
+
+	// Year to date
+	sectionTitle(pdf, fmt.Sprintf("Year to date (%d)", period.Year), "")
+	ytd := []pdfLine{
+		{"Gross earnings", doc.YTD.TotalEarnings},
+		{"PAYE", doc.YTD.PayeeTax},
+		{"AIDS levy", doc.YTD.AidsLevy},
+		{"NSSA", doc.YTD.NSSAContribution},
+		{"Total deductions", doc.YTD.TotalDeductions},
+		{"Net pay", doc.YTD.NetPay},
+	}
+	if payslip.IsContractor {
+		ytd = []pdfLine{
+			{"Gross payments", doc.YTD.TotalEarnings},
+			{"Withholding tax", doc.YTD.WithholdingTax},
+			{"Total deductions", doc.YTD.TotalDeductions},
+			{"Net pay", doc.YTD.NetPay},
+		}
+	}
+	columnWidth := contentWidth / float64(len(ytd))
+	pdf.SetFont("Helvetica", "", 8)
+	for _, line := range ytd {
+		pdf.CellFormat(columnWidth, 5, line.label, "1", 0, "C", false, 0, "")
+	}
+	pdf.Ln(5)
+	pdf.SetFont("Helvetica", "B", 9)
+	for _, line := range ytd {
+		pdf.CellFormat(columnWidth, 6, formatAmount(line.amount, currency), "1", 0, "C", false, 0, "")
+	}
+	pdf.Ln(10)
+
+	if payslip.EmployerNSSA.IsPositive() {
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(0, 4, fmt.Sprintf("Employer NSSA contribution (not deducted from your pay): %s %s",
+			currency, formatAmount(payslip.EmployerNSSA, currency)), "", 1, "L", false, 0, "")
+	}
+	// PAYE worked out under a directive did not use the tax table
+	taxBasis := labelled("Tax table: ", payslip.TaxTableVersion)
+	if payslip.TaxDirectiveNumber != "" {
+		taxBasis = labelled("Tax directive: ", payslip.TaxDirectiveNumber)
+	}
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.CellFormat(0, 4, tr(joinNonEmpty("  ",
+		taxBasis,
+		fmt.Sprintf("Exchange rate to base currency: %s", payslip.ExchangeRate))), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "I", 8)
+	pdf.CellFormat(0, 4, "Generated on "+time.Now().Format("02 Jan 2006 15:04"), "", 1, "L", false, 0, "")
+
+	return pdf.Output(w)
+}
+
+func sectionTitle(pdf *fpdf.Fpdf, left, right string) {
+	half := contentWidth / 2
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(40, 40, 40)
+	pdf.SetTextColor(255, 255, 255)
+	if right == "" {
+		pdf.CellFormat(contentWidth, 6, " "+left, "", 1, "L", true, 0, "")
+	} else {
+		pdf.CellFormat(half-2, 6, " "+left, "", 0, "L", true, 0, "")
+		pdf.CellFormat(4, 6, "", "", 0, "L", false, 0, "")
+		pdf.CellFormat(half-2, 6, " "+right, "", 1, "L", true, 0, "")
+	}
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(1)
+}
+
+// addLogo draws the company logo if one was loaded. A missing or unreadable
+// logo is not an error; the payslip is printed without it.
+func addLogo(pdf *fpdf.Fpdf, data []byte, x, y float64) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	var imageType string
+	switch http.DetectContentType(data) {
+	case "image/png":
+		imageType = "PNG"
+	case "image/jpeg":
+		imageType = "JPG"
+	case "image/gif":
+		imageType = "GIF"
+	default:
+		return false
+	}
+
+	options := fpdf.ImageOptions{ImageType: imageType}
+	info := pdf.RegisterImageOptionsReader("logo", options, bytes.NewReader(data))
+	if info == nil || pdf.Err() {
+		pdf.ClearError()
+		return false
+	}
+	pdf.ImageOptions("logo", x, y, 0, 20, false, options, 0, "")
+	return true
+}
+
+// logoCache keeps recently fetched logos by URL. Failures are cached too, so an
+// unreachable logo host is not retried for every payslip in a batch.
+type logoCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedLogo
+}
+
+type cachedLogo struct {
+	data      []byte
+	fetchedAt time.Time
+}
+
+// load returns the logo at logoURL, fetching it when it is not cached. A logo that
+// cannot be fetched is returned empty.
+func (lc *logoCache) load(logoURL string) []byte {
+	if logoURL == "" {
+		return nil
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if cached, ok := lc.entries[logoURL]; ok && time.Since(cached.fetchedAt) < logoCacheFor {
+		return cached.data
+	}
+
+	data, err := loadLogo(logoURL)
+	if err != nil {
+		data = nil
+	}
+	if lc.entries == nil {
+		lc.entries = make(map[string]cachedLogo)
+	}
+	lc.entries[logoURL] = cachedLogo{data: data, fetchedAt: time.Now()}
+	return data
+}
+
+// loadLogo fetches a logo over https. Local files and plain http are not supported.
+func loadLogo(logoURL string) ([]byte, error) {
+	parsed, err := url.Parse(logoURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid logo URL: %w", err)
+	}
+	if err := checkLogoURL(parsed); err != nil {
+		return nil, err
+	}
+
+	resp, err := logoClient.Get(parsed.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("logo request failed with status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+}
+
+// checkLogoURL accepts https URLs naming a host, without credentials
+func checkLogoURL(logoURL *url.URL) error {
+	if logoURL.Scheme != "https" || logoURL.Hostname() == "" || logoURL.User != nil {
+		return fmt.Errorf("logo URL must be an https URL")
+	}
+	return nil
+}
+
+// publicAddressOnly refuses connections to loopback, private, link-local and other
+// non-public addresses. It runs after DNS resolution, so a public host name that
+// resolves to an internal address is refused as well.
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("logo host %s is not a public address", host)
+	}
+	return nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	// Carrier-grade NAT range, used for internal addresses by some cloud providers
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 100 && ip4[1]&0xc0 == 64 {
+		return false
+	}
+	return true
+}
+
+func nonZero(lines []pdfLine) []pdfLine {
+	var result []pdfLine
+	for _, line := range lines {
+		if !line.amount.IsZero() {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var values []string
+	for _, part := range parts {
+		if part != "" {
+			values = append(values, part)
+		}
+	}
+	return strings.Join(values, sep)
+}
+
+func labelled(label, value string) string {
+	if value == "" {
+		return ""
+	}
+	return label + value
+}
+
+// formatAmount formats money to the currency's minor units with thousands separators,
+// e.g. 12,345.60, or 12,346 in yen
+func formatAmount(amount decimal.Decimal, currency string) string {
+	sign := ""
+	if amount.IsNegative() {
+		sign = "-"
+		amount = amount.Neg()
+	}
+
+	text := amount.StringFixed(money.Places(currency))
+	whole, fraction := text, ""
+	if point := strings.IndexByte(text, '.'); point >= 0 {
+		whole, fraction = text[:point], text[point:]
+	}
+
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	return sign + grouped.String() + fraction
+}
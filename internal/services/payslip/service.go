@@ -0,0 +1,152 @@
+package payslip
+
+import (
+	"fmt"
+	"gm58-hr-backend/internal/models"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Document is everything printed on a payslip: the payslip itself, the issuing
+// company and the employee's year-to-date totals.
+type Document struct {
+	Payslip models.Payslip `json:"payslip"`
+	Company models.Company `json:"company"`
+	YTD     YTDTotals      `json:"ytd"`
+	Logo    []byte         `json:"-"` // Company logo image, empty when there is none or it cannot be fetched
+}
+
+// YTDTotals are the employee's totals for the tax year up to and including the payslip period.
+// Only payslips in the same currency as this one are included.
+type YTDTotals struct {
+	TotalEarnings    decimal.Decimal `json:"total_earnings"`
+	PayeeTax         decimal.Decimal `json:"payee_tax"`
+	AidsLevy         decimal.Decimal `json:"aids_levy"`
+	NSSAContribution decimal.Decimal `json:"nssa_contribution"`
+	WithholdingTax   decimal.Decimal `json:"withholding_tax"`
+	TotalDeductions  decimal.Decimal `json:"total_deductions"`
+	NetPay           decimal.Decimal `json:"net_pay"`
+}
+
+type PayslipService struct {
+	db    *gorm.DB
+	logos *logoCache
+}
+
+func NewPayslipService(db *gorm.DB) *PayslipService {
+	return &PayslipService{
+		db:    db,
+		logos: &logoCache{},
+	}
+}
+
+// GetDocument loads a single payslip for printing.
+func (ps *PayslipService) GetDocument(payslipID, companyID uint) (*Document, error) {
+	var payslip models.Payslip
+	if err := ps.payslipQuery().
+		Where("id = ? AND company_id = ?", payslipID, companyID).
+		First(&payslip).Error; err != nil {
+		return nil, err
+	}
+
+	documents, err := ps.buildDocuments(companyID, []models.Payslip{payslip})
+	if err != nil {
+		return nil, err
+	}
+	return &documents[0], nil
+}
+
+// GetPeriodDocuments loads every payslip in a period for printing, ordered by employee number.
+func (ps *PayslipService) GetPeriodDocuments(periodID, companyID uint) ([]Document, error) {
+	var payslips []models.Payslip
+	if err := ps.payslipQuery().
+		Where("payroll_period_id = ? AND company_id = ?", periodID, companyID).
+		Order("employee_number").
+		Find(&payslips).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch payslips: %w", err)
+	}
+
+	return ps.buildDocuments(companyID, payslips)
+}
+
+func (ps *PayslipService) payslipQuery() *gorm.DB {
+	return ps.db.Preload("Currency").Preload("PayrollPeriod").Preload("NetPaySplits.Currency")
+}
+
+func (ps *PayslipService) buildDocuments(companyID uint, payslips []models.Payslip) ([]Document, error) {
+	var company models.Company
+	if err := ps.db.First(&company, companyID).Error; err != nil {
+		return nil, fmt.Errorf("company not found: %w", err)
+	}
+
+	// The logo is fetched once for the whole batch
+	logo := ps.logos.load(company.LogoURL)
+
+	documents := make([]Document, 0, len(payslips))
+	for _, payslip := range payslips {
+		ytd, err := ps.yearToDate(payslip)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, Document{
+			Payslip: payslip,
+			Company: company,
+			YTD:     ytd,
+			Logo:    logo,
+		})
+	}
+	return documents, nil
+}
+
+// yearToDate sums the employee's payslips in the same calendar year from periods that have
+// been processed and end on or before the payslip's period, so draft runs and off-cycle runs
+// later in the month are left out.
+func (ps *PayslipService) yearToDate(payslip models.Payslip) (YTDTotals, error) {
+	var totals YTDTotals
+	err := ps.db.Model(&models.Payslip{}).
+		Select(`COALESCE(SUM(payslips.total_earnings), 0) AS total_earnings,
+			COALESCE(SUM(payslips.payee_tax), 0) AS payee_tax,
+			COALESCE(SUM(payslips.aids_levy), 0) AS aids_levy,
+			COALESCE(SUM(payslips.nssa_contribution), 0) AS nssa_contribution,
+			COALESCE(SUM(payslips.withholding_tax), 0) AS withholding_tax,
+			COALESCE(SUM(payslips.total_deductions), 0) AS total_deductions,
+			COALESCE(SUM(payslips.net_pay), 0) AS net_pay`).
+		Joins("JOIN payroll_periods ON payroll_periods.id = payslips.payroll_period_id").
+		Where("payslips.company_id = ? AND payslips.employee_id = ? AND payslips.currency_id = ?",
+			payslip.CompanyID, payslip.EmployeeID, payslip.CurrencyID).
+		Where("payroll_periods.year = ? AND payroll_periods.end_date <= ?",
+			payslip.PayrollPeriod.Year, payslip.PayrollPeriod.EndDate).
+		Where("payroll_periods.status IN ?", []string{"processed", "approved", "paid"}).
+		Scan(&totals).Error
+	if err != nil {
+		return totals, fmt.Errorf("failed to calculate year-to-date totals: %w", err)
+	}
+	return totals, nil
+}
+
+// Password returns the password used to protect a payslip PDF: the employee's national
+// ID as recorded on the payslip, with spaces and dashes removed, in upper case.
+func Password(payslip models.Payslip) string {
+	replacer := strings.NewReplacer(" ", "", "-", "")
+	return strings.ToUpper(replacer.Replace(payslip.NationalID))
+}
+
+// Title names the document: contractors receive a remittance advice rather than a payslip.
+func Title(payslip models.Payslip) string {
+	if payslip.IsContractor {
+		return "Remittance advice"
+	}
+	return "Payslip"
+}
+
+// FileName is the name used for a payslip PDF download or archive entry.
+func FileName(payslip models.Payslip) string {
+	prefix := "payslip"
+	if payslip.IsContractor {
+		prefix = "remittance-advice"
+	}
+	return fmt.Sprintf("%s-%04d-%02d-%s.pdf",
+		prefix, payslip.PayrollPeriod.Year, payslip.PayrollPeriod.Month, payslip.EmployeeNumber)
+}
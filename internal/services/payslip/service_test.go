@@ -0,0 +1,64 @@
+package payslip
+
+import (
+	"testing"
+
+	"gm58-hr-backend/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYearToDateCountsProcessedPeriodsUpToThisOne(t *testing.T) {
+	db, company := openTestDB(t)
+	zwg := models.Currency{Code: "ZWG", Name: "Zimbabwe Gold", Symbol: "ZiG", IsActive: true}
+	require.NoError(t, db.Create(&zwg).Error)
+	employee := models.Employee{
+		CompanyID: company.ID, EmployeeNumber: "EMP00000", FirstName: "Employee", LastName: "0",
+		NationalID: "63-000000A00", BasicSalary: decimal.NewFromInt(500), CurrencyID: company.BaseCurrencyID,
+		IsActive: true, EmploymentStatus: "active",
+	}
+	require.NoError(t, db.Create(&employee).Error)
+
+	pay := func(period models.PayrollPeriod, currencyID uint, earnings int64) models.Payslip {
+		payslip := models.Payslip{
+			CompanyID: company.ID, PayrollPeriodID: period.ID, EmployeeID: employee.ID, CurrencyID: currencyID,
+			EmployeeNumber: employee.EmployeeNumber, EmployeeName: employee.FullName(), NationalID: employee.NationalID,
+			BasicSalary: decimal.NewFromInt(earnings), TotalEarnings: decimal.NewFromInt(earnings),
+			PayeeTax: decimal.NewFromInt(earnings / 10), TotalDeductions: decimal.NewFromInt(earnings / 10),
+			NetPay: decimal.NewFromInt(earnings - earnings/10), Status: period.Status,
+		}
+		require.NoError(t, db.Create(&payslip).Error)
+		return payslip
+	}
+
+	// Counted: January and February in USD
+	pay(createPeriod(t, db, company.ID, 2024, 1, models.PeriodTypeRegular, "paid"), company.BaseCurrencyID, 500)
+	february := pay(createPeriod(t, db, company.ID, 2024, 2, models.PeriodTypeRegular, "approved"), company.BaseCurrencyID, 700)
+	// Left out: the previous tax year, another currency, a draft run and a later month
+	pay(createPeriod(t, db, company.ID, 2023, 12, models.PeriodTypeRegular, "paid"), company.BaseCurrencyID, 1000)
+	pay(createPeriod(t, db, company.ID, 2024, 1, models.PeriodTypeBonus, "paid"), zwg.ID, 2000)
+	pay(createPeriod(t, db, company.ID, 2024, 2, models.PeriodTypeCorrection, "draft"), company.BaseCurrencyID, 3000)
+	pay(createPeriod(t, db, company.ID, 2024, 3, models.PeriodTypeRegular, "processed"), company.BaseCurrencyID, 4000)
+
+	doc, err := NewPayslipService(db).GetDocument(february.ID, company.ID)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(1200).Equal(doc.YTD.TotalEarnings), doc.YTD.TotalEarnings.String())
+	assert.True(t, decimal.NewFromInt(120).Equal(doc.YTD.PayeeTax), doc.YTD.PayeeTax.String())
+	assert.True(t, decimal.NewFromInt(120).Equal(doc.YTD.TotalDeductions), doc.YTD.TotalDeductions.String())
+	assert.True(t, decimal.NewFromInt(1080).Equal(doc.YTD.NetPay), doc.YTD.NetPay.String())
+	assert.True(t, doc.YTD.WithholdingTax.IsZero())
+}
+
+func TestPasswordIsNormalisedNationalID(t *testing.T) {
+	cases := map[string]string{
+		"63-123456A00":   "63123456A00",
+		"63 123456 a 00": "63123456A00",
+		" 08-2345678-k-": "082345678K",
+		"":               "",
+	}
+	for nationalID, expected := range cases {
+		assert.Equal(t, expected, Password(models.Payslip{NationalID: nationalID}), nationalID)
+	}
+}
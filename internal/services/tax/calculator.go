@@ -3,7 +3,10 @@ package tax
 import (
 	"math"
 	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
 	"gm58-hr-backend/internal/services/currency"
+
+	"github.com/shopspring/decimal"
 )
 
 type TaxCalculator struct {
@@ -17,6 +20,16 @@ type TaxBracket struct {
 	Deduction float64
 }
 
+// TaxTableVersion identifies the monthly PAYE brackets below and is recorded on every
+// payslip. Change it whenever the brackets change.
+const TaxTableVersion = "ZW-USD-MONTHLY-2024"
+
+// AidsLevyRate is charged on PAYE and NSSARate on gross earnings
+const (
+	AidsLevyRate = 0.03
+	NSSARate     = 0.03
+)
+
 func NewTaxCalculator(currencyService *currency.CurrencyService) *TaxCalculator {
 	return &TaxCalculator{
 		currencyService: currencyService,
@@ -34,63 +47,79 @@ func (tc *TaxCalculator) GetMonthlyTaxBrackets() []TaxBracket {
 	}
 }
 
-func (tc *TaxCalculator) CalculateMonthlyPAYE(grossSalary float64, employeeCurrency string) (float64, error) {
-	if grossSalary <= 0 {
-		return 0, nil
+func (tc *TaxCalculator) CalculateMonthlyPAYE(grossSalary decimal.Decimal, employeeCurrency string) (decimal.Decimal, error) {
+	if !grossSalary.IsPositive() {
+		return decimal.Zero, nil
 	}
 
 	// Convert to USD for tax calculation if needed
-	grossSalaryUSD := grossSalary
-	if employeeCurrency != "USD" {
-		convertedAmount, err := tc.currencyService.ConvertAmount(grossSalary, employeeCurrency, "USD")
-		if err != nil {
-			return 0, err
-		}
-		grossSalaryUSD = convertedAmount
+	grossSalaryUSD, err := tc.currencyService.ConvertMoney(grossSalary, employeeCurrency, "USD")
+	if err != nil {
+		return decimal.Zero, err
 	}
 
-	brackets := tc.GetMonthlyTaxBrackets()
-	var tax float64
+	tax := tc.monthlyPAYEInUSD(grossSalaryUSD)
 
-	for _, bracket := range brackets {
-		if grossSalaryUSD >= bracket.Min && (grossSalaryUSD <= bracket.Max || math.IsInf(bracket.Max, 1)) {
-			tax = grossSalaryUSD*bracket.Rate - bracket.Deduction
-			if tax < 0 {
-				tax = 0
-			}
-			break
-		}
+	// Convert tax back to employee currency if needed
+	return tc.currencyService.ConvertMoney(tax, "USD", employeeCurrency)
+}
+
+// CalculateMonthlyPAYEAtRates is CalculateMonthlyPAYE with the conversion rates to and
+// from USD supplied, for callers that look the rates up once for many employees.
+func (tc *TaxCalculator) CalculateMonthlyPAYEAtRates(grossSalary, toUSD, fromUSD decimal.Decimal) decimal.Decimal {
+	if !grossSalary.IsPositive() {
+		return decimal.Zero
 	}
+	return tc.monthlyPAYEInUSD(grossSalary.Mul(toUSD)).Mul(fromUSD)
+}
 
-	// Convert tax back to employee currency if needed
-	if employeeCurrency != "USD" {
-		convertedTax, err := tc.currencyService.ConvertAmount(tax, "USD", employeeCurrency)
-		if err != nil {
-			return 0, err
+// monthlyPAYEInUSD applies the monthly tax brackets to a USD gross salary
+func (tc *TaxCalculator) monthlyPAYEInUSD(grossSalaryUSD decimal.Decimal) decimal.Decimal {
+	if !grossSalaryUSD.IsPositive() {
+		return decimal.Zero
+	}
+
+	return tc.MonthlyTaxBracket(grossSalaryUSD).Tax(grossSalaryUSD)
+}
+
+// MonthlyTaxBracket returns the monthly bracket a USD gross salary falls in. Brackets are
+// ordered, so the first one whose ceiling covers the salary applies. Matching on the
+// ceiling alone avoids gaps such as 100.00-100.01 between brackets.
+func (tc *TaxCalculator) MonthlyTaxBracket(grossSalaryUSD decimal.Decimal) TaxBracket {
+	brackets := tc.GetMonthlyTaxBrackets()
+	for _, bracket := range brackets {
+		if math.IsInf(bracket.Max, 1) || grossSalaryUSD.LessThanOrEqual(money.FromFloat(bracket.Max)) {
+			return bracket
 		}
-		return convertedTax, nil
 	}
+	return brackets[len(brackets)-1]
+}
 
-	return tax, nil
+// Tax is the PAYE the bracket charges on a USD gross salary
+func (b TaxBracket) Tax(grossSalaryUSD decimal.Decimal) decimal.Decimal {
+	tax := grossSalaryUSD.Mul(money.FromFloat(b.Rate)).Sub(money.FromFloat(b.Deduction))
+	if tax.IsNegative() {
+		return decimal.Zero
+	}
+	return tax
 }
 
-func (tc *TaxCalculator) CalculateAidsLevy(payeeTax float64) float64 {
-	return payeeTax * 0.03
+func (tc *TaxCalculator) CalculateAidsLevy(payeeTax decimal.Decimal) decimal.Decimal {
+	return payeeTax.Mul(money.FromFloat(AidsLevyRate))
 }
 
-func (tc *TaxCalculator) CalculateNSSAContribution(grossSalary float64, employeeCurrency string) (float64, error) {
+func (tc *TaxCalculator) CalculateNSSAContribution(grossSalary decimal.Decimal, employeeCurrency string) (decimal.Decimal, error) {
 	// NSSA contribution is 3% of gross salary
-	nssaRate := 0.03
-	contribution := grossSalary * nssaRate
+	contribution := grossSalary.Mul(money.FromFloat(NSSARate))
 
 	return contribution, nil
 }
 
-func (tc *TaxCalculator) CalculatePensionContribution(grossSalary, pensionRate float64) float64 {
+func (tc *TaxCalculator) CalculatePensionContribution(grossSalary decimal.Decimal, pensionRate float64) decimal.Decimal {
 	if pensionRate <= 0 {
-		return 0
+		return decimal.Zero
 	}
-	return grossSalary * (pensionRate / 100)
+	return money.Percent(grossSalary, pensionRate)
 }
 
 func (tc *TaxCalculator) CalculateYTDTax(employee models.Employee, currentYear int) (float64, error) {
@@ -0,0 +1,25 @@
+package tax
+
+import (
+	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/money"
+
+	"github.com/shopspring/decimal"
+)
+
+// CalculateDirectivePAYE returns the PAYE set by a tax directive on taxable earnings, in
+// place of the bracket table. The tax never exceeds the earnings it is charged on.
+func (tc *TaxCalculator) CalculateDirectivePAYE(directive models.TaxDirective, taxable decimal.Decimal) decimal.Decimal {
+	if !taxable.IsPositive() {
+		return decimal.Zero
+	}
+
+	tax := decimal.Zero
+	switch directive.Type {
+	case models.DirectiveTypeFixedPercentage:
+		tax = money.Percent(taxable, directive.Rate)
+	case models.DirectiveTypeFixedAmount, models.DirectiveTypeLumpSum:
+		tax = directive.Amount
+	}
+	return decimal.Min(decimal.Max(tax, decimal.Zero), taxable)
+}
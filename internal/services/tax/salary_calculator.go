@@ -0,0 +1,160 @@
+package tax
+
+import (
+	"fmt"
+	"gm58-hr-backend/internal/money"
+
+	"github.com/shopspring/decimal"
+)
+
+// SalaryOptions control which statutory deductions apply when calculating a salary
+// breakdown. They mirror the company's payroll tax settings.
+type SalaryOptions struct {
+	Currency       string  `json:"currency"`
+	PensionRate    float64 `json:"pension_rate"` // Percentage of gross salary
+	EnablePAYE     bool    `json:"enable_paye"`
+	EnableAidsLevy bool    `json:"enable_aids_levy"`
+	EnableNSSA     bool    `json:"enable_nssa"`
+}
+
+// SalaryBreakdown is a gross to net calculation in a single currency.
+type SalaryBreakdown struct {
+	Currency            string  `json:"currency"`
+	GrossSalary         float64 `json:"gross_salary"`
+	PayeeTax            float64 `json:"payee_tax"`
+	AidsLevy            float64 `json:"aids_levy"`
+	NSSAContribution    float64 `json:"nssa_contribution"`
+	PensionContribution float64 `json:"pension_contribution"`
+	TotalDeductions     float64 `json:"total_deductions"`
+	NetPay              float64 `json:"net_pay"`
+}
+
+// maxGrossUpCents bounds the gross-up search at 100 million in any currency
+const maxGrossUpCents = 10_000_000_000
+
+// salaryRates converts between the salary currency and USD, in which the tax brackets are set
+type salaryRates struct {
+	toUSD   decimal.Decimal
+	fromUSD decimal.Decimal
+}
+
+// CalculateNetSalary breaks a monthly gross salary down into deductions and net pay.
+func (tc *TaxCalculator) CalculateNetSalary(grossSalary float64, options SalaryOptions) (*SalaryBreakdown, error) {
+	if grossSalary < 0 {
+		return nil, fmt.Errorf("gross salary cannot be negative")
+	}
+
+	rates, err := tc.salaryRates(options.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := tc.salaryBreakdown(money.FromFloat(grossSalary), options, rates).breakdown()
+	return &breakdown, nil
+}
+
+// CalculateGrossSalary finds the monthly gross salary, to the cent, that pays at least
+// targetNetPay. Net pay rises with gross pay, so a binary search over cents converges.
+func (tc *TaxCalculator) CalculateGrossSalary(targetNetPay float64, options SalaryOptions) (*SalaryBreakdown, error) {
+	if targetNetPay < 0 {
+		return nil, fmt.Errorf("net pay cannot be negative")
+	}
+
+	rates, err := tc.salaryRates(options.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	target := roundCents(money.FromFloat(targetNetPay))
+	low := target.Shift(2).Floor().IntPart()
+	high := low
+	for tc.salaryBreakdown(decimal.New(high, -2), options, rates).netPay.LessThan(target) {
+		if high >= maxGrossUpCents {
+			return nil, fmt.Errorf("net pay of %.2f cannot be reached", targetNetPay)
+		}
+		high = high*2 + 100
+		if high > maxGrossUpCents {
+			high = maxGrossUpCents
+		}
+	}
+
+	for low < high {
+		mid := low + (high-low)/2
+		if tc.salaryBreakdown(decimal.New(mid, -2), options, rates).netPay.GreaterThanOrEqual(target) {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+
+	breakdown := tc.salaryBreakdown(decimal.New(high, -2), options, rates).breakdown()
+	return &breakdown, nil
+}
+
+// exactBreakdown is a salary breakdown in exact amounts, rounded to the cent
+type exactBreakdown struct {
+	currency                              string
+	grossSalary, payeeTax, aidsLevy, nssa decimal.Decimal
+	pension, totalDeductions, netPay      decimal.Decimal
+}
+
+// breakdown returns the amounts as the calculator reports them
+func (b exactBreakdown) breakdown() SalaryBreakdown {
+	return SalaryBreakdown{
+		Currency:            b.currency,
+		GrossSalary:         b.grossSalary.InexactFloat64(),
+		PayeeTax:            b.payeeTax.InexactFloat64(),
+		AidsLevy:            b.aidsLevy.InexactFloat64(),
+		NSSAContribution:    b.nssa.InexactFloat64(),
+		PensionContribution: b.pension.InexactFloat64(),
+		TotalDeductions:     b.totalDeductions.InexactFloat64(),
+		NetPay:              b.netPay.InexactFloat64(),
+	}
+}
+
+// salaryBreakdown applies the same deductions as payroll processing, rounded to the cent.
+func (tc *TaxCalculator) salaryBreakdown(grossSalary decimal.Decimal, options SalaryOptions, rates salaryRates) exactBreakdown {
+	breakdown := exactBreakdown{
+		currency:    options.Currency,
+		grossSalary: roundCents(grossSalary),
+	}
+
+	if options.EnablePAYE {
+		breakdown.payeeTax = roundCents(tc.CalculateMonthlyPAYEAtRates(grossSalary, rates.toUSD, rates.fromUSD))
+	}
+	if options.EnableAidsLevy {
+		breakdown.aidsLevy = roundCents(tc.CalculateAidsLevy(breakdown.payeeTax))
+	}
+	if options.EnableNSSA {
+		nssa, _ := tc.CalculateNSSAContribution(grossSalary, options.Currency)
+		breakdown.nssa = roundCents(nssa)
+	}
+	breakdown.pension = roundCents(tc.CalculatePensionContribution(grossSalary, options.PensionRate))
+
+	breakdown.totalDeductions = money.Sum(breakdown.payeeTax, breakdown.aidsLevy, breakdown.nssa, breakdown.pension)
+	breakdown.netPay = breakdown.grossSalary.Sub(breakdown.totalDeductions)
+	return breakdown
+}
+
+// salaryRates looks up the conversion rates once so a gross-up search does not hit
+// the currency service on every iteration.
+func (tc *TaxCalculator) salaryRates(currencyCode string) (salaryRates, error) {
+	one := decimal.NewFromInt(1)
+	if currencyCode == "" || currencyCode == "USD" {
+		return salaryRates{toUSD: one, fromUSD: one}, nil
+	}
+
+	toUSD, err := tc.currencyService.ConvertMoney(one, currencyCode, "USD")
+	if err != nil {
+		return salaryRates{}, fmt.Errorf("failed to get %s exchange rate: %w", currencyCode, err)
+	}
+	fromUSD, err := tc.currencyService.ConvertMoney(one, "USD", currencyCode)
+	if err != nil {
+		return salaryRates{}, fmt.Errorf("failed to get %s exchange rate: %w", currencyCode, err)
+	}
+	return salaryRates{toUSD: toUSD, fromUSD: fromUSD}, nil
+}
+
+func roundCents(amount decimal.Decimal) decimal.Decimal {
+	return amount.Round(2)
+}
@@ -0,0 +1,37 @@
+package tax
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrossSalaryRoundTrip(t *testing.T) {
+	calculator := NewTaxCalculator(nil)
+	options := SalaryOptions{Currency: "USD", PensionRate: 5, EnablePAYE: true, EnableAidsLevy: true, EnableNSSA: true}
+
+	for _, net := range []float64{0, 80, 250, 999.99, 1500, 4321.87} {
+		breakdown, err := calculator.CalculateGrossSalary(net, options)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, breakdown.NetPay, net)
+		assert.Less(t, breakdown.NetPay-net, 0.05, "gross %.2f overshoots net %.2f", breakdown.GrossSalary, net)
+
+		check, err := calculator.CalculateNetSalary(breakdown.GrossSalary, options)
+		require.NoError(t, err)
+		assert.Equal(t, breakdown, check)
+	}
+}
+
+func TestNetSalaryBreakdown(t *testing.T) {
+	calculator := NewTaxCalculator(nil)
+
+	breakdown, err := calculator.CalculateNetSalary(1500, SalaryOptions{Currency: "USD", EnablePAYE: true, EnableAidsLevy: true, EnableNSSA: true})
+	require.NoError(t, err)
+
+	// 1500 falls in the 30% bracket: 1500 * 0.30 - 85
+	assert.Equal(t, 365.0, breakdown.PayeeTax)
+	assert.Equal(t, 10.95, breakdown.AidsLevy)
+	assert.Equal(t, 45.0, breakdown.NSSAContribution)
+	assert.Equal(t, 1079.05, breakdown.NetPay)
+}
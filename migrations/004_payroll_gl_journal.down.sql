@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS gl_account_mappings;
+ALTER TABLE departments DROP COLUMN IF EXISTS cost_centre;
+ALTER TABLE payslips DROP COLUMN IF EXISTS employer_nssa;
@@ -0,0 +1,22 @@
+-- Employer contributions posted to the general ledger
+ALTER TABLE payslips ADD COLUMN employer_nssa DECIMAL(15,2) DEFAULT 0;
+
+-- Department cost centre code for journal exports
+ALTER TABLE departments ADD COLUMN cost_centre VARCHAR(50);
+
+-- Chart-of-accounts mapping per company
+CREATE TABLE gl_account_mappings (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    component VARCHAR(50) NOT NULL,
+    component_type VARCHAR(30),
+    account_code VARCHAR(50) NOT NULL,
+    account_name VARCHAR(255),
+    contra_account_code VARCHAR(50),
+    contra_account_name VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT idx_gl_mapping_company_component UNIQUE(company_id, component)
+);
+
+CREATE INDEX idx_gl_account_mappings_company_id ON gl_account_mappings(company_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS payslip_net_pays;
+DROP TABLE IF EXISTS salary_components;
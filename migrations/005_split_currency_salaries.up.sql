@@ -0,0 +1,33 @@
+-- Salary components paid in their own currency
+CREATE TABLE salary_components (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id),
+    employee_id INTEGER NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    name VARCHAR(100),
+    currency_id INTEGER NOT NULL REFERENCES currencies(id),
+    amount DECIMAL(15,2) DEFAULT 0,
+    percentage DECIMAL(5,2) DEFAULT 0,
+    is_percentage BOOLEAN DEFAULT false,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX idx_salary_components_employee_id ON salary_components(employee_id);
+CREATE INDEX idx_salary_components_company_id ON salary_components(company_id);
+CREATE INDEX idx_salary_components_deleted_at ON salary_components(deleted_at);
+
+-- Net pay per currency for split-currency payslips
+CREATE TABLE payslip_net_pays (
+    id SERIAL PRIMARY KEY,
+    payslip_id INTEGER NOT NULL REFERENCES payslips(id) ON DELETE CASCADE,
+    currency_id INTEGER NOT NULL REFERENCES currencies(id),
+    exchange_rate DECIMAL(15,6),
+    gross_amount DECIMAL(15,2),
+    net_amount DECIMAL(15,2),
+    net_amount_payslip_currency DECIMAL(15,2),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_payslip_net_pays_payslip_id ON payslip_net_pays(payslip_id);
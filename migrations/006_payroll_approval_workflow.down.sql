@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS payroll_approvals;
+DROP TABLE IF EXISTS payroll_approval_steps;
+ALTER TABLE payroll_periods DROP COLUMN IF EXISTS approval_level;
@@ -0,0 +1,31 @@
+-- Approval levels completed for the current run
+ALTER TABLE payroll_periods ADD COLUMN approval_level INTEGER DEFAULT 0;
+
+-- Ordered approval chain per company
+CREATE TABLE payroll_approval_steps (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    level INTEGER NOT NULL,
+    name VARCHAR(100),
+    approver_role VARCHAR(20),
+    approver_user_id INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT idx_approval_step_company_level UNIQUE(company_id, level)
+);
+
+-- Approval and rejection history
+CREATE TABLE payroll_approvals (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id),
+    payroll_period_id INTEGER NOT NULL REFERENCES payroll_periods(id) ON DELETE CASCADE,
+    level INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    role VARCHAR(20),
+    comment TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_payroll_approvals_payroll_period_id ON payroll_approvals(payroll_period_id);
+CREATE INDEX idx_payroll_approvals_company_id ON payroll_approvals(company_id);
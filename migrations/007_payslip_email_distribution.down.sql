@@ -0,0 +1 @@
+DROP TABLE IF EXISTS payslip_emails;
@@ -0,0 +1,23 @@
+-- Payslip email queue with per-recipient delivery status
+CREATE TABLE payslip_emails (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id),
+    payroll_period_id INTEGER NOT NULL REFERENCES payroll_periods(id) ON DELETE CASCADE,
+    payslip_id INTEGER NOT NULL REFERENCES payslips(id) ON DELETE CASCADE,
+    employee_id INTEGER NOT NULL REFERENCES employees(id),
+    recipient VARCHAR(255) NOT NULL,
+    protect BOOLEAN DEFAULT false,
+    status VARCHAR(20) DEFAULT 'queued',
+    attempts INTEGER DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP,
+    requested_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_payslip_emails_company_id ON payslip_emails(company_id);
+CREATE INDEX idx_payslip_emails_payroll_period_id ON payslip_emails(payroll_period_id);
+CREATE INDEX idx_payslip_emails_payslip_id ON payslip_emails(payslip_id);
+CREATE INDEX idx_payslip_emails_status ON payslip_emails(status);
@@ -0,0 +1,3 @@
+ALTER TABLE payslips DROP COLUMN IF EXISTS back_pay;
+DROP TABLE IF EXISTS back_pay_lines;
+DROP TABLE IF EXISTS salary_adjustments;
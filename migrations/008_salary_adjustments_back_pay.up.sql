@@ -0,0 +1,45 @@
+-- Backdated salary adjustments and the per-period back pay they produce
+CREATE TABLE salary_adjustments (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id),
+    employee_id INTEGER NOT NULL REFERENCES employees(id),
+    currency_id INTEGER REFERENCES currencies(id),
+    previous_salary DECIMAL(15,2) NOT NULL,
+    new_salary DECIMAL(15,2) NOT NULL,
+    effective_date DATE NOT NULL,
+    reason TEXT,
+    status VARCHAR(20) DEFAULT 'pending',
+    total_arrears DECIMAL(15,2) DEFAULT 0,
+    total_tax DECIMAL(15,2) DEFAULT 0,
+    total_nssa DECIMAL(15,2) DEFAULT 0,
+    net_arrears DECIMAL(15,2) DEFAULT 0,
+    applied_period_id INTEGER REFERENCES payroll_periods(id),
+    applied_payslip_id INTEGER REFERENCES payslips(id) ON DELETE SET NULL,
+    created_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE back_pay_lines (
+    id SERIAL PRIMARY KEY,
+    salary_adjustment_id INTEGER NOT NULL REFERENCES salary_adjustments(id) ON DELETE CASCADE,
+    payroll_period_id INTEGER NOT NULL REFERENCES payroll_periods(id),
+    original_payslip_id INTEGER NOT NULL REFERENCES payslips(id),
+    proration_factor DECIMAL(7,6) DEFAULT 1,
+    salary_difference DECIMAL(15,2) DEFAULT 0,
+    original_earnings DECIMAL(15,2) DEFAULT 0,
+    revised_earnings DECIMAL(15,2) DEFAULT 0,
+    extra_paye DECIMAL(15,2) DEFAULT 0,
+    extra_aids_levy DECIMAL(15,2) DEFAULT 0,
+    extra_nssa DECIMAL(15,2) DEFAULT 0,
+    net_arrears DECIMAL(15,2) DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE payslips ADD COLUMN back_pay DECIMAL(15,2) DEFAULT 0;
+
+CREATE INDEX idx_salary_adjustments_company_id ON salary_adjustments(company_id);
+CREATE INDEX idx_salary_adjustments_employee_id ON salary_adjustments(employee_id);
+CREATE INDEX idx_salary_adjustments_applied_payslip_id ON salary_adjustments(applied_payslip_id);
+CREATE INDEX idx_back_pay_lines_salary_adjustment_id ON back_pay_lines(salary_adjustment_id);
+CREATE INDEX idx_back_pay_lines_original_payslip_id ON back_pay_lines(original_payslip_id);
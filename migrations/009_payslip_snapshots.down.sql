@@ -0,0 +1,15 @@
+ALTER TABLE payslips
+    DROP COLUMN IF EXISTS employee_number,
+    DROP COLUMN IF EXISTS employee_name,
+    DROP COLUMN IF EXISTS national_id,
+    DROP COLUMN IF EXISTS tax_number,
+    DROP COLUMN IF EXISTS position_title,
+    DROP COLUMN IF EXISTS department_name,
+    DROP COLUMN IF EXISTS cost_centre,
+    DROP COLUMN IF EXISTS payment_method,
+    DROP COLUMN IF EXISTS bank_name,
+    DROP COLUMN IF EXISTS bank_branch,
+    DROP COLUMN IF EXISTS bank_code,
+    DROP COLUMN IF EXISTS bank_account,
+    DROP COLUMN IF EXISTS swift_code,
+    DROP COLUMN IF EXISTS tax_table_version;
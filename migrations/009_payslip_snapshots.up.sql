@@ -0,0 +1,36 @@
+-- Employee details and calculation rates captured on each payslip when it is generated
+ALTER TABLE payslips
+    ADD COLUMN employee_number VARCHAR(50),
+    ADD COLUMN employee_name VARCHAR(255),
+    ADD COLUMN national_id VARCHAR(50),
+    ADD COLUMN tax_number VARCHAR(50),
+    ADD COLUMN position_title VARCHAR(255),
+    ADD COLUMN department_name VARCHAR(255),
+    ADD COLUMN cost_centre VARCHAR(50),
+    ADD COLUMN payment_method VARCHAR(50),
+    ADD COLUMN bank_name VARCHAR(255),
+    ADD COLUMN bank_branch VARCHAR(255),
+    ADD COLUMN bank_code VARCHAR(50),
+    ADD COLUMN bank_account VARCHAR(100),
+    ADD COLUMN swift_code VARCHAR(50),
+    ADD COLUMN tax_table_version VARCHAR(50);
+
+-- Existing payslips take the employee details as they are today
+UPDATE payslips SET
+    employee_number = e.employee_number,
+    employee_name = CONCAT_WS(' ', e.first_name, NULLIF(e.middle_name, ''), e.last_name),
+    national_id = e.national_id,
+    tax_number = e.tax_number,
+    position_title = p.title,
+    department_name = d.name,
+    cost_centre = d.cost_centre,
+    payment_method = e.payment_method,
+    bank_name = e.bank_name,
+    bank_branch = e.bank_branch,
+    bank_code = e.bank_code,
+    bank_account = e.bank_account,
+    swift_code = e.swift_code
+FROM employees e
+LEFT JOIN positions p ON p.id = e.position_id
+LEFT JOIN departments d ON d.id = e.department_id
+WHERE e.id = payslips.employee_id;
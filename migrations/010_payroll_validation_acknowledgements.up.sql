@@ -0,0 +1,12 @@
+-- Pre-payroll validation warnings accepted for a period
+CREATE TABLE payroll_validation_acknowledgements (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id),
+    payroll_period_id INTEGER NOT NULL REFERENCES payroll_periods(id) ON DELETE CASCADE,
+    issue_key VARCHAR(255) NOT NULL,
+    comment TEXT,
+    acknowledged_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_validation_ack_period_issue ON payroll_validation_acknowledgements(payroll_period_id, issue_key);
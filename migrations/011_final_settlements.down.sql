@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS final_settlements;
+DROP TABLE IF EXISTS employee_loans;
+ALTER TABLE company_settings DROP COLUMN IF EXISTS severance_months_per_year;
+ALTER TABLE leave_types DROP COLUMN IF EXISTS paid_on_exit;
+ALTER TABLE payslips
+    DROP COLUMN IF EXISTS leave_pay,
+    DROP COLUMN IF EXISTS notice_pay,
+    DROP COLUMN IF EXISTS severance_pay;
+DELETE FROM payslips WHERE payroll_period_id IN (SELECT id FROM payroll_periods WHERE period_type <> 'regular');
+DELETE FROM payroll_periods WHERE period_type <> 'regular';
+DROP INDEX IF EXISTS idx_payroll_periods_regular_month;
+ALTER TABLE payroll_periods ADD CONSTRAINT payroll_periods_company_year_month_unique UNIQUE(company_id, year, month);
+ALTER TABLE payroll_periods DROP COLUMN IF EXISTS period_type;
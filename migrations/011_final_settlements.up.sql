@@ -0,0 +1,66 @@
+-- Termination periods for final settlements, which may share a month with the regular period
+ALTER TABLE payroll_periods ADD COLUMN period_type VARCHAR(20) DEFAULT 'regular';
+UPDATE payroll_periods SET period_type = 'regular' WHERE period_type IS NULL;
+ALTER TABLE payroll_periods DROP CONSTRAINT IF EXISTS payroll_periods_year_month_key;
+ALTER TABLE payroll_periods DROP CONSTRAINT IF EXISTS payroll_periods_company_year_month_unique;
+CREATE UNIQUE INDEX idx_payroll_periods_regular_month ON payroll_periods(company_id, year, month) WHERE period_type = 'regular';
+
+ALTER TABLE payslips
+    ADD COLUMN leave_pay DECIMAL(15,2) DEFAULT 0,
+    ADD COLUMN notice_pay DECIMAL(15,2) DEFAULT 0,
+    ADD COLUMN severance_pay DECIMAL(15,2) DEFAULT 0;
+
+ALTER TABLE leave_types ADD COLUMN paid_on_exit BOOLEAN DEFAULT false;
+UPDATE leave_types SET paid_on_exit = true WHERE name = 'Annual Leave';
+
+ALTER TABLE company_settings ADD COLUMN severance_months_per_year DECIMAL(5,2) DEFAULT 0.5;
+
+CREATE TABLE employee_loans (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id),
+    employee_id INTEGER NOT NULL REFERENCES employees(id),
+    description TEXT,
+    currency_id INTEGER REFERENCES currencies(id),
+    principal DECIMAL(15,2) NOT NULL,
+    balance DECIMAL(15,2) NOT NULL,
+    issued_date DATE,
+    status VARCHAR(20) DEFAULT 'active',
+    created_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE final_settlements (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id),
+    employee_id INTEGER NOT NULL REFERENCES employees(id),
+    payroll_period_id INTEGER NOT NULL REFERENCES payroll_periods(id),
+    payslip_id INTEGER REFERENCES payslips(id) ON DELETE SET NULL,
+    termination_date DATE NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+    notice_days INTEGER DEFAULT 0,
+    currency_id INTEGER REFERENCES currencies(id),
+    status VARCHAR(20) DEFAULT 'pending',
+    daily_rate DECIMAL(15,2) DEFAULT 0,
+    pro_rata_days INTEGER DEFAULT 0,
+    pro_rata_salary DECIMAL(15,2) DEFAULT 0,
+    leave_days DECIMAL(8,2) DEFAULT 0,
+    leave_pay DECIMAL(15,2) DEFAULT 0,
+    notice_pay DECIMAL(15,2) DEFAULT 0,
+    service_years DECIMAL(8,2) DEFAULT 0,
+    severance_pay DECIMAL(15,2) DEFAULT 0,
+    total_earnings DECIMAL(15,2) DEFAULT 0,
+    payee_tax DECIMAL(15,2) DEFAULT 0,
+    aids_levy DECIMAL(15,2) DEFAULT 0,
+    nssa_contribution DECIMAL(15,2) DEFAULT 0,
+    loan_recovery DECIMAL(15,2) DEFAULT 0,
+    loan_balance DECIMAL(15,2) DEFAULT 0,
+    net_pay DECIMAL(15,2) DEFAULT 0,
+    created_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_employee_loans_employee_id ON employee_loans(employee_id);
+CREATE INDEX idx_final_settlements_employee_id ON final_settlements(employee_id);
+CREATE INDEX idx_final_settlements_payroll_period_id ON final_settlements(payroll_period_id);
@@ -0,0 +1,13 @@
+-- Employees selected for bonus and correction periods, with the amounts paid to each
+CREATE TABLE payroll_period_employees (
+    id SERIAL PRIMARY KEY,
+    payroll_period_id INTEGER NOT NULL REFERENCES payroll_periods(id) ON DELETE CASCADE,
+    employee_id INTEGER NOT NULL REFERENCES employees(id),
+    bonus DECIMAL(15,2) DEFAULT 0,
+    other_earnings DECIMAL(15,2) DEFAULT 0,
+    other_deductions DECIMAL(15,2) DEFAULT 0,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_period_employee ON payroll_period_employees(payroll_period_id, employee_id);
@@ -0,0 +1,3 @@
+ALTER TABLE payslips DROP COLUMN IF EXISTS tax_free_earnings;
+ALTER TABLE payroll_period_employees DROP COLUMN IF EXISTS tax_free_amount;
+DROP TABLE IF EXISTS bonus_policies;
@@ -0,0 +1,18 @@
+-- Annual bonus (13th cheque) rules, one per company
+CREATE TABLE bonus_policies (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL UNIQUE REFERENCES companies(id) ON DELETE CASCADE,
+    name VARCHAR(100) DEFAULT '13th cheque',
+    multiple DECIMAL(5,2) DEFAULT 1,
+    min_service_months INTEGER DEFAULT 0,
+    prorate_service BOOLEAN DEFAULT true,
+    payout_month INTEGER DEFAULT 12,
+    tax_free_threshold DECIMAL(15,2) DEFAULT 0,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Part of a bonus exempt from PAYE
+ALTER TABLE payroll_period_employees ADD COLUMN tax_free_amount DECIMAL(15,2) DEFAULT 0;
+ALTER TABLE payslips ADD COLUMN tax_free_earnings DECIMAL(15,2) DEFAULT 0;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS time_entries;
+ALTER TABLE payslips DROP COLUMN IF EXISTS pay_rate;
+ALTER TABLE payslips DROP COLUMN IF EXISTS pay_quantity;
+ALTER TABLE employees DROP COLUMN IF EXISTS pay_rate;
+ALTER TABLE employees DROP COLUMN IF EXISTS pay_type;
@@ -0,0 +1,26 @@
+-- Hourly, daily and piece-rate employees are paid a rate for approved time instead of a salary
+ALTER TABLE employees ADD COLUMN pay_type VARCHAR(20) DEFAULT 'salaried';
+ALTER TABLE employees ADD COLUMN pay_rate DECIMAL(15,4) DEFAULT 0;
+
+ALTER TABLE payslips ADD COLUMN pay_quantity DECIMAL(10,2) DEFAULT 0;
+ALTER TABLE payslips ADD COLUMN pay_rate DECIMAL(15,4) DEFAULT 0;
+
+-- Hours, days or units worked, paid once approved
+CREATE TABLE time_entries (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    employee_id INTEGER NOT NULL REFERENCES employees(id),
+    work_date DATE NOT NULL,
+    quantity DECIMAL(10,2) NOT NULL,
+    description TEXT,
+    status VARCHAR(20) DEFAULT 'pending',
+    reviewed_by INTEGER REFERENCES users(id),
+    reviewed_at TIMESTAMP,
+    created_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_time_entries_company_id ON time_entries(company_id);
+CREATE INDEX idx_time_entries_employee_id ON time_entries(employee_id);
+CREATE INDEX idx_time_entries_work_date ON time_entries(work_date);
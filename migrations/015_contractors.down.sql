@@ -0,0 +1,5 @@
+ALTER TABLE payslips DROP COLUMN IF EXISTS withholding_tax_rate;
+ALTER TABLE payslips DROP COLUMN IF EXISTS withholding_tax;
+ALTER TABLE payslips DROP COLUMN IF EXISTS is_contractor;
+ALTER TABLE employees DROP COLUMN IF EXISTS withholding_tax_rate;
+ALTER TABLE company_settings DROP COLUMN IF EXISTS withholding_tax_rate;
@@ -0,0 +1,7 @@
+-- Independent contractors (employment_type 'contractor') have tax withheld instead of PAYE and NSSA
+ALTER TABLE company_settings ADD COLUMN withholding_tax_rate DECIMAL(5,2) DEFAULT 30;
+ALTER TABLE employees ADD COLUMN withholding_tax_rate DECIMAL(5,2);
+
+ALTER TABLE payslips ADD COLUMN is_contractor BOOLEAN DEFAULT false;
+ALTER TABLE payslips ADD COLUMN withholding_tax DECIMAL(15,2) DEFAULT 0;
+ALTER TABLE payslips ADD COLUMN withholding_tax_rate DECIMAL(5,2) DEFAULT 0;
@@ -0,0 +1,4 @@
+ALTER TABLE tax_certificates DROP COLUMN IF EXISTS tax_directives;
+ALTER TABLE payslips DROP COLUMN IF EXISTS tax_directive_number;
+ALTER TABLE payslips DROP COLUMN IF EXISTS tax_directive_id;
+DROP TABLE IF EXISTS tax_directives;
@@ -0,0 +1,27 @@
+-- Tax directives replace the PAYE brackets with a fixed percentage or amount, or set the
+-- tax on a single lump sum
+CREATE TABLE tax_directives (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    employee_id INTEGER NOT NULL REFERENCES employees(id),
+    directive_number VARCHAR(50) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    scope VARCHAR(20) DEFAULT 'all',
+    rate DECIMAL(5,2) DEFAULT 0,
+    amount DECIMAL(15,2) DEFAULT 0,
+    valid_from DATE NOT NULL,
+    valid_to DATE,
+    status VARCHAR(20) DEFAULT 'active',
+    notes TEXT,
+    created_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_tax_directive_number ON tax_directives(company_id, directive_number);
+CREATE INDEX idx_tax_directives_employee_id ON tax_directives(employee_id);
+
+ALTER TABLE payslips ADD COLUMN tax_directive_id INTEGER REFERENCES tax_directives(id);
+ALTER TABLE payslips ADD COLUMN tax_directive_number VARCHAR(50);
+
+ALTER TABLE tax_certificates ADD COLUMN tax_directives TEXT;
@@ -0,0 +1,19 @@
+-- Calculation trace recorded for each payslip, so its figures can be explained later
+CREATE TABLE payslip_calculation_steps (
+    id SERIAL PRIMARY KEY,
+    payslip_id INTEGER NOT NULL REFERENCES payslips(id) ON DELETE CASCADE,
+    sequence INTEGER NOT NULL,
+    section VARCHAR(20) NOT NULL,
+    component VARCHAR(50) NOT NULL,
+    description TEXT,
+    source_amount DECIMAL(15,2) DEFAULT 0,
+    source_currency VARCHAR(3),
+    exchange_rate DECIMAL(15,6) DEFAULT 0,
+    basis DECIMAL(15,2) DEFAULT 0,
+    rate DECIMAL(15,4) DEFAULT 0,
+    amount DECIMAL(15,2) NOT NULL,
+    currency VARCHAR(3),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_payslip_calculation_steps_payslip_id ON payslip_calculation_steps(payslip_id);
@@ -0,0 +1,2 @@
+ALTER TABLE company_settings DROP COLUMN IF EXISTS rounding_level;
+ALTER TABLE company_settings DROP COLUMN IF EXISTS rounding_mode;
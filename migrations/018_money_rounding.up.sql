@@ -0,0 +1,3 @@
+-- Per-company rounding rules for payroll money amounts
+ALTER TABLE company_settings ADD COLUMN rounding_mode VARCHAR(20) DEFAULT 'half_up';
+ALTER TABLE company_settings ADD COLUMN rounding_level VARCHAR(20) DEFAULT 'line';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS forecast_exchange_rates;
+DROP TABLE IF EXISTS forecast_hires;
+DROP TABLE IF EXISTS forecast_increases;
+DROP TABLE IF EXISTS forecast_scenarios;
@@ -0,0 +1,52 @@
+-- Saved payroll cost forecasting scenarios and their assumptions
+CREATE TABLE forecast_scenarios (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    start_year INTEGER NOT NULL,
+    start_month INTEGER NOT NULL,
+    months INTEGER DEFAULT 12,
+    created_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_forecast_scenarios_company_id ON forecast_scenarios(company_id);
+
+-- Salary increases, for one department or everyone when department_id is null
+CREATE TABLE forecast_increases (
+    id SERIAL PRIMARY KEY,
+    scenario_id INTEGER NOT NULL REFERENCES forecast_scenarios(id) ON DELETE CASCADE,
+    year INTEGER NOT NULL,
+    month INTEGER NOT NULL,
+    percentage DECIMAL(5,2) NOT NULL,
+    department_id INTEGER REFERENCES departments(id)
+);
+
+CREATE INDEX idx_forecast_increases_scenario_id ON forecast_increases(scenario_id);
+
+-- Planned new headcount by position
+CREATE TABLE forecast_hires (
+    id SERIAL PRIMARY KEY,
+    scenario_id INTEGER NOT NULL REFERENCES forecast_scenarios(id) ON DELETE CASCADE,
+    position_id INTEGER NOT NULL REFERENCES positions(id),
+    headcount INTEGER NOT NULL,
+    year INTEGER NOT NULL,
+    month INTEGER NOT NULL,
+    basic_salary DECIMAL(15,2) DEFAULT 0
+);
+
+CREATE INDEX idx_forecast_hires_scenario_id ON forecast_hires(scenario_id);
+
+-- Assumed units of a currency per unit of base currency from a month
+CREATE TABLE forecast_exchange_rates (
+    id SERIAL PRIMARY KEY,
+    scenario_id INTEGER NOT NULL REFERENCES forecast_scenarios(id) ON DELETE CASCADE,
+    currency_code VARCHAR(3) NOT NULL,
+    year INTEGER DEFAULT 0,
+    month INTEGER DEFAULT 0,
+    rate DECIMAL(15,6) NOT NULL
+);
+
+CREATE INDEX idx_forecast_exchange_rates_scenario_id ON forecast_exchange_rates(scenario_id);
@@ -0,0 +1,21 @@
+-- Effective-dated salary and pay currency history for each employee
+CREATE TABLE compensation_changes (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    employee_id INTEGER NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    previous_salary DECIMAL(15,2) NOT NULL,
+    new_salary DECIMAL(15,2) NOT NULL,
+    previous_currency_id INTEGER REFERENCES currencies(id),
+    currency_id INTEGER REFERENCES currencies(id),
+    effective_date DATE NOT NULL,
+    reason VARCHAR(20) NOT NULL,
+    notes TEXT,
+    approved_by INTEGER REFERENCES users(id),
+    salary_adjustment_id INTEGER REFERENCES salary_adjustments(id),
+    applied_at TIMESTAMP,
+    created_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_compensation_changes_company_id ON compensation_changes(company_id);
+CREATE INDEX idx_compensation_changes_employee_id ON compensation_changes(employee_id);
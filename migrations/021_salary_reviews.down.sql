@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS salary_review_approvals;
+DROP TABLE IF EXISTS salary_review_proposals;
+DROP TABLE IF EXISTS salary_review_rules;
+DROP TABLE IF EXISTS salary_reviews;
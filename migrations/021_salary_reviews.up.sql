@@ -0,0 +1,62 @@
+-- Salary review cycles: increase rules, per-employee proposals and the approval trail
+CREATE TABLE salary_reviews (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    effective_date DATE NOT NULL,
+    reason VARCHAR(20) DEFAULT 'annual_increase',
+    budget DECIMAL(15,2) DEFAULT 0,
+    status VARCHAR(20) DEFAULT 'draft',
+    approval_level INTEGER DEFAULT 0,
+    submitted_by INTEGER REFERENCES users(id),
+    submitted_at TIMESTAMP,
+    approved_by INTEGER REFERENCES users(id),
+    approved_at TIMESTAMP,
+    created_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE salary_review_rules (
+    id SERIAL PRIMARY KEY,
+    review_id INTEGER NOT NULL REFERENCES salary_reviews(id) ON DELETE CASCADE,
+    scope VARCHAR(20) NOT NULL,
+    scope_id INTEGER,
+    type VARCHAR(20) NOT NULL,
+    value DECIMAL(15,2) NOT NULL
+);
+
+CREATE TABLE salary_review_proposals (
+    id SERIAL PRIMARY KEY,
+    review_id INTEGER NOT NULL REFERENCES salary_reviews(id) ON DELETE CASCADE,
+    employee_id INTEGER NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    department_id INTEGER REFERENCES departments(id),
+    currency_id INTEGER REFERENCES currencies(id),
+    current_salary DECIMAL(15,2) NOT NULL,
+    proposed_salary DECIMAL(15,2) NOT NULL,
+    increase DECIMAL(15,2) NOT NULL,
+    increase_percent DECIMAL(7,2),
+    rule_scope VARCHAR(20),
+    overridden BOOLEAN DEFAULT false,
+    notes TEXT,
+    compensation_change_id INTEGER REFERENCES compensation_changes(id),
+    error TEXT
+);
+
+CREATE TABLE salary_review_approvals (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    review_id INTEGER NOT NULL REFERENCES salary_reviews(id) ON DELETE CASCADE,
+    level INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    user_id INTEGER REFERENCES users(id),
+    role VARCHAR(50),
+    comment TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_salary_reviews_company_id ON salary_reviews(company_id);
+CREATE INDEX idx_salary_review_rules_review_id ON salary_review_rules(review_id);
+CREATE INDEX idx_salary_review_proposals_review_id ON salary_review_proposals(review_id);
+CREATE INDEX idx_salary_review_proposals_employee_id ON salary_review_proposals(employee_id);
+CREATE INDEX idx_salary_review_approvals_review_id ON salary_review_approvals(review_id);
@@ -0,0 +1,2 @@
+ALTER TABLE employees DROP COLUMN IF EXISTS salary_band_override;
+ALTER TABLE company_settings DROP COLUMN IF EXISTS salary_band_policy;
@@ -0,0 +1,3 @@
+-- Salary band policy per company and the reason an employee's salary is outside their band
+ALTER TABLE company_settings ADD COLUMN salary_band_policy VARCHAR(20) DEFAULT 'warn';
+ALTER TABLE employees ADD COLUMN salary_band_override TEXT;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_time_entries_paid_payslip_id;
+ALTER TABLE time_entries DROP COLUMN IF EXISTS paid_payslip_id;
+ALTER TABLE time_entries DROP COLUMN IF EXISTS paid_period_id;
@@ -0,0 +1,17 @@
+-- The run that paid an approved time entry, so time approved after its period closed is paid by a later run
+ALTER TABLE time_entries ADD COLUMN paid_period_id INTEGER REFERENCES payroll_periods(id);
+ALTER TABLE time_entries ADD COLUMN paid_payslip_id INTEGER REFERENCES payslips(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_time_entries_paid_payslip_id ON time_entries(paid_payslip_id);
+
+-- Approved time already paid by processed regular runs
+UPDATE time_entries
+SET paid_period_id = payroll_periods.id, paid_payslip_id = payslips.id
+FROM payroll_periods
+JOIN payslips ON payslips.payroll_period_id = payroll_periods.id
+WHERE time_entries.status = 'approved'
+    AND payroll_periods.company_id = time_entries.company_id
+    AND payroll_periods.period_type = 'regular'
+    AND payroll_periods.status IN ('processed', 'approved', 'paid')
+    AND time_entries.work_date BETWEEN payroll_periods.start_date AND payroll_periods.end_date
+    AND payslips.employee_id = time_entries.employee_id;
@@ -0,0 +1 @@
+ALTER TABLE compensation_changes DROP COLUMN IF EXISTS salary_band_override;
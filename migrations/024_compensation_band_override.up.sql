@@ -0,0 +1,2 @@
+-- The reason recorded for accepting a salary change outside the position's band
+ALTER TABLE compensation_changes ADD COLUMN salary_band_override TEXT;
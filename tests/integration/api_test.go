@@ -7,12 +7,16 @@ import (
 	"net/http/httptest"
 	"testing"
 	"gm58-hr-backend/internal/api/routes"
+	"gm58-hr-backend/internal/config"
 	"gm58-hr-backend/internal/database"
 	"gm58-hr-backend/internal/models"
+	"gm58-hr-backend/internal/services/email"
+	"gm58-hr-backend/internal/services/payslip"
 	"gm58-hr-backend/pkg/logger"
 	"gm58-hr-backend/pkg/redis"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/driver/sqlite"
@@ -43,7 +47,10 @@ func (suite *APITestSuite) SetupSuite() {
 	redisClient := &redis.Client{}
 	logger := logger.New("info")
 	
-	routes.SetupRoutes(suite.router, db, redisClient, logger)
+	payslipService := payslip.NewPayslipService(db)
+	distributionService := payslip.NewDistributionService(db, payslipService, email.NewSMTPMailer(email.SMTPConfig{}), logger, payslip.DistributionOptions{})
+
+	routes.SetupRoutes(suite.router, db, redisClient, logger, &config.Config{}, payslipService, distributionService)
 	
 	suite.token = suite.getAuthToken()
 }
@@ -68,8 +75,8 @@ func (suite *APITestSuite) seedTestData(db *gorm.DB) {
 	position := models.Position{
 		Title:        "Software Developer",
 		DepartmentID: dept.ID,
-		MinSalary:    3000,
-		MaxSalary:    8000,
+		MinSalary:    decimal.NewFromInt(3000),
+		MaxSalary:    decimal.NewFromInt(8000),
 		CurrencyID:   usd.ID,
 		IsActive:     true,
 	}